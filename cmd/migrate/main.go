@@ -0,0 +1,115 @@
+// Command migrate manages the database schema.
+//
+//	migrate up               apply all pending migrations
+//	migrate down [n]         roll back the last n migrations (default 1)
+//	migrate status           list migrations and whether they are applied
+//	migrate create <name>    add empty up/down files for a new migration
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/config"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/database"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/migrate"
+)
+
+func main() {
+	dir := flag.String("dir", migrate.Dir, "directory new migrations are created in")
+	flag.Usage = usage
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	if args[0] == "create" {
+		if len(args) != 2 {
+			log.Fatal("usage: migrate create <name>")
+		}
+		up, down, err := migrate.Create(*dir, args[1])
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(up)
+		fmt.Println(down)
+		return
+	}
+
+	switch args[0] {
+	case "up", "down", "status":
+	default:
+		usage()
+		os.Exit(2)
+	}
+
+	config.LoadEnv()
+	database.Connect()
+	sqlDB, err := database.DB.DB()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	migrator, err := migrate.New(sqlDB)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Applied %d migration(s)\n", applied)
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				log.Fatalf("invalid number of steps %q", args[1])
+			}
+		}
+		rolledBack, err := migrator.Down(ctx, steps)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Rolled back %d migration(s)\n", rolledBack)
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, s := range statuses {
+			state := "pending"
+			switch {
+			case s.Missing:
+				state = "applied " + s.AppliedAt.Format("2006-01-02T15:04:05Z07:00") + " (no file)"
+			case s.AppliedAt != nil:
+				state = "applied " + s.AppliedAt.Format("2006-01-02T15:04:05Z07:00")
+			}
+			fmt.Printf("%04d  %-40s %s\n", s.Version, s.Name, state)
+		}
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, strings.TrimSpace(`
+usage: migrate [-dir path] <command>
+
+commands:
+  up              apply all pending migrations
+  down [n]        roll back the last n migrations (default 1)
+  status          list migrations and whether they are applied
+  create <name>   add empty up/down files for a new migration`))
+}
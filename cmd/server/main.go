@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/99designs/gqlgen/graphql/handler"
 	"github.com/99designs/gqlgen/graphql/playground"
@@ -14,10 +17,20 @@ import (
 	"github.com/vishnujoshi062/tshirt-ecommerce-api/config"
 	"github.com/vishnujoshi062/tshirt-ecommerce-api/graph"
 	"github.com/vishnujoshi062/tshirt-ecommerce-api/graph/generated"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/courier"
 	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/database"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/events"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/handlers"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/invoice"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/loaders"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/mail"
 	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/middleware"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/notify"
 	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/repository"
 	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/service"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/shipping"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/tax"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/webhooks"
 )
 
 func main() {
@@ -58,7 +71,11 @@ func main() {
 
 	// Connect to database
 	database.Connect()
-	database.Migrate()
+	// Replicas can migrate on boot; the migration lock keeps them from racing.
+	// Set AUTO_MIGRATE=false to run cmd/migrate as a release step instead.
+	if config.GetBoolEnv("AUTO_MIGRATE", true) {
+		database.Migrate()
+	}
 
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(database.DB)
@@ -67,28 +84,297 @@ func main() {
 	orderRepo := repository.NewOrderRepository(database.DB)
 	paymentRepo := repository.NewPaymentRepository(database.DB)
 	promoCodeRepo := repository.NewPromoCodeRepository(database.DB)
+	inventoryRepo := repository.NewInventoryRepository(database.DB)
+	webhookEventRepo := repository.NewWebhookEventRepository(database.DB)
+	refundRepo := repository.NewRefundRepository(database.DB)
+	addressRepo := repository.NewAddressRepository(database.DB)
+	invoiceRepo := repository.NewInvoiceRepository(database.DB)
+	shipmentRepo := repository.NewShipmentRepository(database.DB)
+	returnRepo := repository.NewReturnRepository(database.DB)
+	outboxRepo := repository.NewOutboxRepository(database.DB)
 
 	// Initialize services
-	paymentService := service.NewPaymentService()
+	eventBus := service.NewEventBus(
+		database.DB,
+		outboxRepo,
+		config.GetIntEnv("OUTBOX_MAX_ATTEMPTS", 10),
+		config.GetDurationEnv("OUTBOX_RETENTION", 7*24*time.Hour),
+	)
+	paymentService := service.NewPaymentService(paymentRepo, refundRepo, eventBus)
+	// Refunds reach Razorpay through the outbox, outside the transaction
+	// that records them
+	paymentService.Subscribe(eventBus)
 	promoCodeService := service.NewPromoService(promoCodeRepo)
+	inventoryService := service.NewInventoryService(
+		database.DB,
+		inventoryRepo,
+		config.GetDurationEnv("RESERVATION_TTL", 15*time.Minute),
+		eventBus,
+		config.GetIntEnv("LOW_STOCK_THRESHOLD", 5),
+	)
+
+	productOptionsService := service.NewProductOptionsService(
+		productRepo,
+		config.GetDurationEnv("PRODUCT_OPTIONS_CACHE_TTL", 5*time.Minute),
+	)
+	cartService := service.NewCartService(database.DB, cartRepo)
+	addressService := service.NewAddressService(database.DB, addressRepo)
+	// TAX_RATES_FILE points at a JSON rate table (see internal/tax/rates.json)
+	// so GST changes can be rolled out without a release.
+	taxRates := tax.Default()
+	if path := config.GetEnv("TAX_RATES_FILE", ""); path != "" {
+		rates, err := tax.Load(path)
+		if err != nil {
+			log.Fatalf("Failed to load tax rates: %v", err)
+		}
+		taxRates = rates
+	}
+	taxService := service.NewTaxService(taxRates)
+	// SHIPPING_METHODS_FILE replaces the built-in methods and rates (see
+	// internal/shipping/methods.json).
+	shippingConfig := shipping.Default()
+	if path := config.GetEnv("SHIPPING_METHODS_FILE", ""); path != "" {
+		loaded, err := shipping.Load(path)
+		if err != nil {
+			log.Fatalf("Failed to load shipping methods: %v", err)
+		}
+		shippingConfig = loaded
+	}
+	shippingService := service.NewShippingService(shippingConfig)
+	invoiceService := service.NewInvoiceService(database.DB, invoiceRepo, taxService, invoice.Party{
+		Name:    config.GetEnv("SELLER_NAME", ""),
+		GSTIN:   strings.ToUpper(config.GetEnv("SELLER_GSTIN", "")),
+		Address: strings.Split(config.GetEnv("SELLER_ADDRESS", ""), "|"),
+		Phone:   config.GetEnv("SELLER_PHONE", ""),
+	})
+	// Invoices are issued, and numbered, as orders are confirmed
+	invoiceService.Subscribe(eventBus)
+	userService := service.NewUserService(
+		database.DB,
+		userRepo,
+		config.GetDurationEnv("USER_SYNC_INTERVAL", 10*time.Minute),
+	)
+	orderService := service.NewOrderService(
+		database.DB,
+		orderRepo,
+		paymentRepo,
+		inventoryService,
+		paymentService,
+		eventBus,
+		config.GetBoolEnv("AUTO_REFUND_ON_CANCEL", true),
+	)
+
+	// COURIER picks the integration used to book shipments. "fake" keeps
+	// shipments in memory and is meant for development.
+	var courierClient courier.Courier
+	switch name := config.GetEnv("COURIER", ""); name {
+	case "":
+		log.Println("COURIER is not set; shipments cannot be booked")
+	case courier.FakeName:
+		courierClient = courier.NewFake(config.GetEnv("COURIER_WEBHOOK_SECRET", ""))
+	case courier.ShiprocketName:
+		courierClient = courier.NewShiprocket(courier.ShiprocketConfig{
+			APIURL:         config.GetEnv("SHIPROCKET_API_URL", ""),
+			Email:          config.GetEnv("SHIPROCKET_EMAIL", ""),
+			Password:       config.GetEnv("SHIPROCKET_PASSWORD", ""),
+			PickupLocation: config.GetEnv("SHIPROCKET_PICKUP_LOCATION", "Primary"),
+			WebhookToken:   config.GetEnv("SHIPROCKET_WEBHOOK_TOKEN", ""),
+		})
+	default:
+		log.Fatalf("Unknown COURIER %q", name)
+	}
+	shipmentService := service.NewShipmentService(
+		database.DB,
+		shipmentRepo,
+		orderService,
+		shippingService,
+		courierClient,
+		taxRates.HSN,
+	)
+	returnService := service.NewReturnService(
+		database.DB,
+		returnRepo,
+		shipmentRepo,
+		inventoryService,
+		paymentService,
+		taxService,
+		eventBus,
+		config.GetDurationEnv("RETURN_WINDOW", 14*24*time.Hour),
+	)
+
+	// Release stock held by checkouts that were never paid
+	inventoryService.StartReservationSweeper(
+		context.Background(),
+		config.GetDurationEnv("RESERVATION_SWEEP_INTERVAL", time.Minute),
+	)
+
+	// Deliver domain events recorded in the outbox to their handlers
+	eventBus.Subscribe(events.StockLow, "log-low-stock", func(ctx context.Context, e events.Event) error {
+		var stock events.Stock
+		if err := e.Decode(&stock); err != nil {
+			return err
+		}
+		log.Printf("INVENTORY: variant %s is low on stock (%d available)", stock.VariantID, stock.Available)
+		return nil
+	})
+
+	// MAILER picks how customer email is sent: "smtp", or "file" to write
+	// .eml files to MAIL_DIR during development.
+	var mailer mail.Mailer
+	mailFrom := config.GetEnv("MAIL_FROM", "T-Shirt Store <orders@localhost>")
+	switch name := config.GetEnv("MAILER", ""); name {
+	case "":
+		log.Println("MAILER is not set; customers will not be emailed")
+	case "smtp":
+		mailer = mail.NewSMTP(mail.SMTPConfig{
+			Host:     config.GetEnv("SMTP_HOST", ""),
+			Port:     config.GetIntEnv("SMTP_PORT", 587),
+			Username: config.GetEnv("SMTP_USERNAME", ""),
+			Password: config.GetEnv("SMTP_PASSWORD", ""),
+			From:     mailFrom,
+		})
+	case "file":
+		sink, err := mail.NewFileSink(config.GetEnv("MAIL_DIR", "mail"), mailFrom)
+		if err != nil {
+			log.Fatalf("Failed to create mail directory: %v", err)
+		}
+		mailer = sink
+	default:
+		log.Fatalf("Unknown MAILER %q", name)
+	}
+	// MAIL_TEMPLATES_DIR replaces the built-in email templates (see
+	// internal/notify/templates).
+	mailTemplates, err := notify.Default()
+	if dir := config.GetEnv("MAIL_TEMPLATES_DIR", ""); dir != "" {
+		mailTemplates, err = notify.Load(os.DirFS(dir))
+	}
+	if err != nil {
+		log.Fatalf("Failed to load email templates: %v", err)
+	}
+	notificationService := service.NewNotificationService(
+		userRepo,
+		orderRepo,
+		shipmentRepo,
+		mailer,
+		mailTemplates,
+		service.NotificationConfig{
+			StoreName:         config.GetEnv("STORE_NAME", "T-Shirt Store"),
+			StoreURL:          config.GetEnv("STORE_URL", "http://localhost:3000"),
+			APIURL:            config.GetEnv("PUBLIC_API_URL", "http://localhost:8080"),
+			UnsubscribeSecret: config.GetEnv("UNSUBSCRIBE_SECRET", ""),
+		},
+	)
+	if mailer != nil {
+		notificationService.Subscribe(eventBus)
+	}
+
+	// Remind customers about carts they left. The reminders are marketing
+	// mail, so they need a mailer and UNSUBSCRIBE_SECRET.
+	discountPercent := config.GetIntEnv("ABANDONED_CART_DISCOUNT_PERCENT", 0)
+	if discountPercent < 0 || discountPercent > 100 {
+		log.Fatalf("ABANDONED_CART_DISCOUNT_PERCENT must be between 0 and 100")
+	}
+	cartRecoveryService := service.NewCartRecoveryService(
+		database.DB,
+		repository.NewCartRecoveryRepository(database.DB),
+		userRepo,
+		taxService,
+		notificationService,
+		service.CartRecoveryConfig{
+			IdleAfter:         config.GetDurationEnv("ABANDONED_CART_AFTER", 4*time.Hour),
+			MaxAge:            config.GetDurationEnv("ABANDONED_CART_MAX_AGE", 7*24*time.Hour),
+			LinkTTL:           config.GetDurationEnv("CART_RECOVERY_LINK_TTL", 7*24*time.Hour),
+			RecoveryURL:       strings.TrimRight(notificationService.Config.StoreURL, "/") + "/cart/recover",
+			DiscountPercent:   discountPercent,
+			PromoTTL:          config.GetDurationEnv("ABANDONED_CART_PROMO_TTL", 72*time.Hour),
+			AttributionWindow: config.GetDurationEnv("CART_RECOVERY_ATTRIBUTION_WINDOW", 7*24*time.Hour),
+		},
+	)
+	cartRecoveryService.Subscribe(eventBus)
+	if mailer != nil {
+		cartRecoveryService.Start(
+			context.Background(),
+			config.GetDurationEnv("ABANDONED_CART_INTERVAL", 15*time.Minute),
+		)
+	}
+
+	// Wishlist alerts are marketing mail too
+	wishlistService := service.NewWishlistService(
+		database.DB,
+		repository.NewWishlistRepository(database.DB),
+		productRepo,
+		cartRepo,
+		userRepo,
+		notificationService,
+	)
+	if mailer != nil {
+		wishlistService.Subscribe(eventBus)
+	}
+
+	// Send events to the merchant webhook endpoints admins register
+	webhookEndpointRepo := repository.NewWebhookEndpointRepository(database.DB)
+	webhookService := service.NewWebhookService(
+		database.DB,
+		webhookEndpointRepo,
+		config.GetIntEnv("WEBHOOK_MAX_ATTEMPTS", 10),
+		config.GetDurationEnv("WEBHOOK_LOG_RETENTION", 30*24*time.Hour),
+		config.GetBoolEnv("WEBHOOK_ALLOW_HTTP", false),
+		config.GetBoolEnv("WEBHOOK_ALLOW_PRIVATE_NETWORKS", false),
+	)
+	webhookService.Subscribe(eventBus)
+	webhookService.Start(
+		context.Background(),
+		config.GetDurationEnv("WEBHOOK_POLL_INTERVAL", 5*time.Second),
+	)
+
+	eventBus.Start(
+		context.Background(),
+		config.GetDurationEnv("OUTBOX_POLL_INTERVAL", time.Second),
+	)
 
 	// Initialize resolver
 	resolver := &graph.Resolver{
-		DB:                database.DB,
-		UserRepository:    userRepo,
-		ProductRepository: productRepo,
-		CartRepository:    cartRepo,
-		OrderRepository:   orderRepo,
-		PaymentRepository: paymentRepo,
-		PaymentService:    paymentService,
-		PromoCodeRepo:     promoCodeRepo,
-		PromoCodeService:  promoCodeService,
+		DB:                    database.DB,
+		UserRepository:        userRepo,
+		ProductRepository:     productRepo,
+		CartRepository:        cartRepo,
+		OrderRepository:       orderRepo,
+		PaymentRepository:     paymentRepo,
+		PaymentService:        paymentService,
+		PromoCodeRepo:         promoCodeRepo,
+		PromoCodeService:      promoCodeService,
+		InventoryRepository:   inventoryRepo,
+		InventoryService:      inventoryService,
+		OrderService:          orderService,
+		RefundRepository:      refundRepo,
+		ProductOptionsService: productOptionsService,
+		CartService:           cartService,
+		UserService:           userService,
+		AddressRepository:     addressRepo,
+		AddressService:        addressService,
+		InvoiceService:        invoiceService,
+		TaxService:            taxService,
+		ShippingService:       shippingService,
+		ShipmentRepository:    shipmentRepo,
+		ShipmentService:       shipmentService,
+		ReturnRepository:      returnRepo,
+		ReturnService:         returnService,
+		EventBus:              eventBus,
+		WebhookEndpointRepo:   webhookEndpointRepo,
+		WebhookService:        webhookService,
+		CartRecoveryService:   cartRecoveryService,
+		WishlistService:       wishlistService,
+		GuestSessionTTL:       config.GetDurationEnv("GUEST_SESSION_TTL", 30*24*time.Hour),
 	}
 
 	// Create GraphQL server
 	srv := handler.NewDefaultServer(generated.NewExecutableSchema(generated.Config{
 		Resolvers: resolver,
+		Directives: generated.DirectiveRoot{
+			Auth: graph.AuthDirective,
+		},
 	}))
+	srv.AroundFields(graph.AnonymousAccess)
 
 	// Setup router
 	router := chi.NewRouter()
@@ -96,10 +382,39 @@ func main() {
 	// Middleware - CORS must be first
 	router.Use(middleware.CorsMiddleware().Handler)
 	router.Use(middleware.AuthMiddleware)
+	router.Use(middleware.UserSyncMiddleware(userService))
 
 	// Routes
 	router.Handle("/", playground.Handler("GraphQL Playground", "/query"))
-	router.Handle("/query", srv)
+	router.Handle("/query", loaders.Middleware(database.DB)(srv))
+
+	// Webhooks
+	razorpayWebhook := webhooks.NewRazorpayHandler(
+		database.DB,
+		orderRepo,
+		paymentRepo,
+		refundRepo,
+		webhookEventRepo,
+		paymentService,
+		orderService,
+	)
+	router.Post("/webhooks/razorpay", razorpayWebhook.ServeHTTP)
+
+	clerkWebhook := webhooks.NewClerkHandler(database.DB, webhookEventRepo, userService)
+	router.Post("/webhooks/clerk", clerkWebhook.ServeHTTP)
+
+	if courierClient != nil {
+		courierWebhook := webhooks.NewCourierHandler(database.DB, courierClient, shipmentService)
+		router.Post("/webhooks/courier", courierWebhook.ServeHTTP)
+	}
+
+	// Invoices
+	router.Get("/invoices/{orderID}.pdf", handlers.NewInvoiceHandler(orderRepo, invoiceService).ServeHTTP)
+
+	// Unsubscribe links in marketing mail
+	unsubscribe := handlers.NewUnsubscribeHandler(notificationService)
+	router.Get("/unsubscribe", unsubscribe.ServeHTTP)
+	router.Post("/unsubscribe", unsubscribe.ServeHTTP)
 
 	// OAuth routes
 	router.Get("/auth/google", handleGoogleLogin)
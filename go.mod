@@ -7,6 +7,8 @@ require (
 	github.com/clerk/clerk-sdk-go/v2 v2.5.0
 	github.com/go-chi/chi/v5 v5.2.3
 	github.com/go-chi/cors v1.2.2
+	github.com/go-pdf/fpdf v0.9.0
+	github.com/graph-gophers/dataloader/v7 v7.1.0
 	github.com/joho/godotenv v1.5.1
 	github.com/lib/pq v1.10.9
 	github.com/razorpay/razorpay-go v1.4.0
@@ -0,0 +1,125 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.83
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/graph/generated"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/graph/model"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/middleware"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+)
+
+// ID is the resolver for the id field.
+func (r *addressResolver) ID(ctx context.Context, obj *models.Address) (string, error) {
+	return strconv.FormatUint(uint64(obj.ID), 10), nil
+}
+
+// CreatedAt is the resolver for the createdAt field.
+func (r *addressResolver) CreatedAt(ctx context.Context, obj *models.Address) (string, error) {
+	return obj.CreatedAt.Format("2006-01-02T15:04:05Z07:00"), nil
+}
+
+// AddAddress is the resolver for the addAddress field.
+func (r *mutationResolver) AddAddress(ctx context.Context, input model.AddressInput) (*models.Address, error) {
+	user, err := middleware.RequireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	address := &models.Address{UserID: user.UserID}
+	applyAddressInput(address, input)
+
+	if err := r.AddressService.AddAddress(address); err != nil {
+		return nil, err
+	}
+
+	return address, nil
+}
+
+// UpdateAddress is the resolver for the updateAddress field.
+func (r *mutationResolver) UpdateAddress(ctx context.Context, id string, input model.AddressInput) (*models.Address, error) {
+	user, err := middleware.RequireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	address, err := r.userAddress(id, user.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	applyAddressInput(address, input)
+
+	if err := r.AddressService.UpdateAddress(address); err != nil {
+		return nil, err
+	}
+
+	return address, nil
+}
+
+// DeleteAddress is the resolver for the deleteAddress field.
+func (r *mutationResolver) DeleteAddress(ctx context.Context, id string) (bool, error) {
+	user, err := middleware.RequireUser(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	address, err := r.userAddress(id, user.UserID)
+	if err != nil {
+		return false, err
+	}
+
+	if err := r.AddressService.DeleteAddress(address); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// SetDefaultAddress is the resolver for the setDefaultAddress field.
+func (r *mutationResolver) SetDefaultAddress(ctx context.Context, id string) (*models.Address, error) {
+	user, err := middleware.RequireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	address, err := r.userAddress(id, user.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.AddressService.SetDefaultAddress(address); err != nil {
+		return nil, err
+	}
+
+	return address, nil
+}
+
+// MyAddresses is the resolver for the myAddresses field.
+func (r *queryResolver) MyAddresses(ctx context.Context) ([]*models.Address, error) {
+	user, err := middleware.RequireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses, err := r.AddressRepository.GetAddressesByUserID(user.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]*models.Address, len(addresses))
+	for i := range addresses {
+		out[i] = &addresses[i]
+	}
+	return out, nil
+}
+
+// Address returns generated.AddressResolver implementation.
+func (r *Resolver) Address() generated.AddressResolver { return &addressResolver{r} }
+
+type addressResolver struct{ *Resolver }
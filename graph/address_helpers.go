@@ -0,0 +1,78 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/graph/model"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/service"
+	"gorm.io/gorm"
+)
+
+// applyAddressInput copies the input onto address. isDefault is only applied
+// when true; a default is replaced by choosing another, not by unsetting it.
+func applyAddressInput(address *models.Address, in model.AddressInput) {
+	address.Name = in.Name
+	address.Line1 = in.Line1
+	address.Line2 = in.Line2
+	address.City = in.City
+	address.State = in.State
+	address.PostalCode = in.PostalCode
+	address.Country = deref(in.Country)
+	address.Phone = in.Phone
+	if in.IsDefault != nil && *in.IsDefault {
+		address.IsDefault = true
+	}
+}
+
+func (r *Resolver) userAddress(id string, userID string) (*models.Address, error) {
+	addressID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address ID")
+	}
+
+	address, err := r.AddressRepository.GetAddressForUser(uint(addressID), userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("address not found")
+	}
+	return address, err
+}
+
+// shippingAddressFor works out where an order ships: a saved address, a new
+// structured address, a free-text address, or else the user's default.
+// The returned snapshot is empty for free-text addresses.
+func (r *Resolver) shippingAddressFor(userID string, input model.CreateOrderInput) (string, models.OrderAddress, error) {
+	switch {
+	case input.AddressID != nil:
+		address, err := r.userAddress(*input.AddressID, userID)
+		if err != nil {
+			return "", models.OrderAddress{}, err
+		}
+		snapshot := service.SnapshotAddress(address)
+		return service.FormatAddress(snapshot), snapshot, nil
+
+	case input.Address != nil:
+		var address models.Address
+		applyAddressInput(&address, *input.Address)
+		if err := service.NormalizeAddress(&address); err != nil {
+			return "", models.OrderAddress{}, err
+		}
+		snapshot := service.SnapshotAddress(&address)
+		return service.FormatAddress(snapshot), snapshot, nil
+
+	case input.ShippingAddress != nil && *input.ShippingAddress != "":
+		return *input.ShippingAddress, models.OrderAddress{}, nil
+	}
+
+	address, err := r.AddressRepository.GetDefaultAddress(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", models.OrderAddress{}, errors.New("shipping address required")
+	}
+	if err != nil {
+		return "", models.OrderAddress{}, err
+	}
+	snapshot := service.SnapshotAddress(address)
+	return service.FormatAddress(snapshot), snapshot, nil
+}
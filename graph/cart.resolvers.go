@@ -13,8 +13,11 @@ import (
 
 	"github.com/vishnujoshi062/tshirt-ecommerce-api/graph/generated"
 	"github.com/vishnujoshi062/tshirt-ecommerce-api/graph/model"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/auth"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/loaders"
 	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/middleware"
 	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
 	"gorm.io/gorm"
 )
 
@@ -25,37 +28,48 @@ func (r *cartResolver) ID(ctx context.Context, obj *models.Cart) (string, error)
 
 // Items is the resolver for the items field.
 func (r *cartResolver) Items(ctx context.Context, obj *models.Cart) ([]*models.CartItem, error) {
-	var items []*models.CartItem
-	err := r.DB.
-		Where("cart_id = ?", obj.ID).
-		Preload("Variant").
-		Preload("Variant.Product").
-		Find(&items).Error
-
-	return items, err
-}
-
-// TotalAmount is the resolver for the totalAmount field.
-func (r *cartResolver) TotalAmount(ctx context.Context, obj *models.Cart) (float64, error) {
-	var items []models.CartItem
+	items, err := loaders.GetCartItems(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
 
-	err := r.DB.
-		Preload("Variant").
-		Preload("Variant.Product").
-		Where("cart_id = ?", obj.ID).
-		Find(&items).Error
+	out := make([]*models.CartItem, len(items))
+	for i := range items {
+		out[i] = &items[i]
+	}
+	return out, nil
+}
 
+// Subtotal is the resolver for the subtotal field.
+func (r *cartResolver) Subtotal(ctx context.Context, obj *models.Cart) (*money.Money, error) {
+	totals, err := r.cartTax(ctx, obj)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
+	return &totals.Gross, nil
+}
 
-	var total float64
-	for _, it := range items {
-		price := it.Variant.Product.BasePrice + it.Variant.PriceModifier
-		total += float64(it.Quantity) * price
+// TaxAmount is the resolver for the taxAmount field.
+func (r *cartResolver) TaxAmount(ctx context.Context, obj *models.Cart) (*money.Money, error) {
+	totals, err := r.cartTax(ctx, obj)
+	if err != nil {
+		return nil, err
 	}
+	return &totals.Tax, nil
+}
 
-	return total, nil
+// TaxIncluded is the resolver for the taxIncluded field.
+func (r *cartResolver) TaxIncluded(ctx context.Context, obj *models.Cart) (bool, error) {
+	return r.TaxService.Rates.PricesIncludeTax, nil
+}
+
+// TotalAmount is the resolver for the totalAmount field.
+func (r *cartResolver) TotalAmount(ctx context.Context, obj *models.Cart) (*money.Money, error) {
+	totals, err := r.cartTax(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	return &totals.Total, nil
 }
 
 // CreatedAt is the resolver for the createdAt field.
@@ -75,16 +89,12 @@ func (r *cartItemResolver) ID(ctx context.Context, obj *models.CartItem) (string
 
 // ProductID is the resolver for the productId field.
 func (r *cartItemResolver) ProductID(ctx context.Context, obj *models.CartItem) (string, error) {
-	if obj.Variant.Product.ID != 0 {
-		return strconv.FormatUint(uint64(obj.Variant.Product.ID), 10), nil
-	}
-
-	var v models.ProductVariant
-	if err := r.DB.Preload("Product").First(&v, obj.VariantID).Error; err != nil {
+	variant, err := r.cartItemVariant(ctx, obj)
+	if err != nil {
 		return "", err
 	}
 
-	return strconv.FormatUint(uint64(v.Product.ID), 10), nil
+	return strconv.FormatUint(uint64(variant.ProductID), 10), nil
 }
 
 // VariantID is the resolver for the variantId field.
@@ -94,17 +104,13 @@ func (r *cartItemResolver) VariantID(ctx context.Context, obj *models.CartItem)
 }
 
 // UnitPrice is the resolver for the unitPrice field.
-func (r *cartItemResolver) UnitPrice(ctx context.Context, obj *models.CartItem) (float64, error) {
-	if obj.Variant.Product.ID != 0 {
-		return obj.Variant.Product.BasePrice + obj.Variant.PriceModifier, nil
-	}
-
-	var v models.ProductVariant
-	if err := r.DB.Preload("Product").First(&v, obj.VariantID).Error; err != nil {
-		return 0, err
+func (r *cartItemResolver) UnitPrice(ctx context.Context, obj *models.CartItem) (*money.Money, error) {
+	variant, err := r.cartItemVariant(ctx, obj)
+	if err != nil {
+		return nil, err
 	}
 
-	return v.Product.BasePrice + v.PriceModifier, nil
+	return r.ProductVariant().Price(ctx, variant)
 }
 
 // CreatedAt is the resolver for the createdAt field.
@@ -117,16 +123,24 @@ func (r *cartItemResolver) UpdatedAt(ctx context.Context, obj *models.CartItem)
 	return obj.AddedAt.Format(time.RFC3339), nil
 }
 
+// CreateGuestSession is the resolver for the createGuestSession field.
+func (r *mutationResolver) CreateGuestSession(ctx context.Context) (*model.GuestSession, error) {
+	token, session, err := auth.NewGuestSession(r.GuestSessionTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start guest session: %w", err)
+	}
+
+	return &model.GuestSession{
+		Token:     token,
+		ExpiresAt: session.ExpiresAt.Format(time.RFC3339),
+	}, nil
+}
+
 // AddToCart is the resolver for the addToCart field.
 func (r *mutationResolver) AddToCart(ctx context.Context, input model.AddToCartInput) (*model.AddToCartPayload, error) {
-	user := middleware.GetUserFromContext(ctx)
-
-	// Dev mode: use dev user if no auth provided
-	var userID string
-	if user != nil {
-		userID = user.UserID
-	} else {
-		userID = "user_dev_123"
+	owner, err := currentCartOwner(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	if input.VariantID == nil {
@@ -138,18 +152,9 @@ func (r *mutationResolver) AddToCart(ctx context.Context, input model.AddToCartI
 		return nil, fmt.Errorf("invalid variant ID")
 	}
 
-	// ✅ FIXED: Clerk UserID is string
-	cart, err := r.CartRepository.GetCartByUserID(userID)
+	cart, err := r.findOrCreateCart(owner)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			userIDPtr := &userID
-			cart = &models.Cart{UserID: userIDPtr}
-			if err := r.DB.Create(cart).Error; err != nil {
-				return nil, err
-			}
-		} else {
-			return nil, err
-		}
+		return nil, err
 	}
 
 	var existing models.CartItem
@@ -174,8 +179,11 @@ func (r *mutationResolver) AddToCart(ctx context.Context, input model.AddToCartI
 	} else {
 		return nil, fmt.Errorf("failed to check existing cart item: %w", err)
 	}
+	if err := r.CartRepository.Touch(cart.ID); err != nil {
+		return nil, fmt.Errorf("failed to update cart: %w", err)
+	}
 
-	cart, err = r.CartRepository.GetCartByUserID(userID)
+	cart, err = r.findCart(owner)
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve cart: %w", err)
 	}
@@ -184,14 +192,9 @@ func (r *mutationResolver) AddToCart(ctx context.Context, input model.AddToCartI
 
 // RemoveCartItem is the resolver for the removeCartItem field.
 func (r *mutationResolver) RemoveCartItem(ctx context.Context, input model.RemoveCartItemInput) (*model.RemoveCartItemPayload, error) {
-	user := middleware.GetUserFromContext(ctx)
-
-	// Dev mode: use dev user if no auth provided
-	var userID string
-	if user != nil {
-		userID = user.UserID
-	} else {
-		userID = "user_dev_123"
+	owner, err := currentCartOwner(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	id, _ := strconv.ParseUint(input.CartItemID, 10, 32)
@@ -201,53 +204,44 @@ func (r *mutationResolver) RemoveCartItem(ctx context.Context, input model.Remov
 		return nil, fmt.Errorf("cart item not found")
 	}
 
-	if item.Cart.UserID == nil || *item.Cart.UserID != userID {
-		return nil, fmt.Errorf("forbidden")
+	if !owner.owns(ctx, &item.Cart) {
+		return nil, middleware.ErrForbidden
 	}
 
 	r.DB.Delete(&item)
+	r.CartRepository.Touch(item.CartID)
 
-	cart, _ := r.CartRepository.GetCartByUserID(userID)
+	cart, _ := r.findCart(owner)
 	return &model.RemoveCartItemPayload{Cart: cart}, nil
 }
 
 // ClearCart is the resolver for the clearCart field.
 func (r *mutationResolver) ClearCart(ctx context.Context, input model.ClearCartInput) (*model.ClearCartPayload, error) {
-	user := middleware.GetUserFromContext(ctx)
-
-	// Dev mode: use dev user if no auth provided
-	var userID string
-	if user != nil {
-		userID = user.UserID
-	} else {
-		userID = "user_dev_123"
+	owner, err := currentCartOwner(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	cart, err := r.CartRepository.GetCartByUserID(userID)
+	cart, err := r.findCart(owner)
 	if err != nil {
 		return nil, err
 	}
 
 	r.CartRepository.ClearCart(cart.ID)
 
-	cart, _ = r.CartRepository.GetCartByUserID(userID)
+	cart, _ = r.findCart(owner)
 	return &model.ClearCartPayload{Cart: cart}, nil
 }
 
 // AttachCartToUser is the resolver for the attachCartToUser field.
 func (r *mutationResolver) AttachCartToUser(ctx context.Context, input model.AttachCartToUserInput) (*model.AttachCartToUserPayload, error) {
-	user := middleware.GetUserFromContext(ctx)
-
-	// Dev mode: use dev user if no auth provided
-	var userID string
-	if user != nil {
-		userID = user.UserID
-	} else {
-		userID = "user_dev_123"
+	user, err := middleware.RequireUser(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	// Verify that the userId in the input matches the authenticated user
-	if input.UserID != userID {
+	if input.UserID != user.UserID {
 		return nil, fmt.Errorf("forbidden: can only attach cart to your own account")
 	}
 
@@ -259,53 +253,52 @@ func (r *mutationResolver) AttachCartToUser(ctx context.Context, input model.Att
 
 	// Find the cart
 	var cart models.Cart
-	if err := r.DB.Preload("CartItems").
-		Preload("CartItems.Variant").
-		Preload("CartItems.Variant.Product").
-		First(&cart, uint(cartID)).Error; err != nil {
+	if err := r.DB.First(&cart, uint(cartID)).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, fmt.Errorf("cart not found")
 		}
 		return nil, err
 	}
 
-	// Check if cart is already attached to a different user
-	if cart.UserID != nil && *cart.UserID != userID {
-		return nil, fmt.Errorf("forbidden: cart belongs to another user")
+	// Already attached: nothing to merge
+	if cart.UserID != nil {
+		if *cart.UserID != user.UserID {
+			return nil, fmt.Errorf("forbidden: cart belongs to another user")
+		}
+		merged, err := r.CartRepository.GetCartByUserID(user.UserID)
+		if err != nil {
+			return nil, err
+		}
+		return &model.AttachCartToUserPayload{Cart: merged}, nil
 	}
 
-	// Attach cart to user
-	userIDPtr := &userID
-	cart.UserID = userIDPtr
-	if err := r.DB.Save(&cart).Error; err != nil {
-		return nil, fmt.Errorf("failed to attach cart to user: %w", err)
+	// Only the guest session that built the cart may claim it
+	session := middleware.GetGuestSessionFromContext(ctx)
+	if session == nil || cart.GuestSessionID == nil || *cart.GuestSessionID != session.ID {
+		return nil, fmt.Errorf("forbidden: cart belongs to another guest session")
 	}
 
-	// Reload cart with all relationships
-	if err := r.DB.Preload("CartItems").
-		Preload("CartItems.Variant").
-		Preload("CartItems.Variant.Product").
-		First(&cart, cart.ID).Error; err != nil {
-		return nil, err
+	merged, err := r.CartService.MergeGuestCart(cart.ID, user.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to attach cart to user: %w", err)
 	}
 
-	return &model.AttachCartToUserPayload{Cart: &cart}, nil
+	return &model.AttachCartToUserPayload{Cart: merged}, nil
 }
 
 // GetCart is the resolver for the getCart field.
 func (r *queryResolver) GetCart(ctx context.Context, cartID *string, forUser *bool) (*models.Cart, error) {
 	if forUser != nil && *forUser {
-		user := middleware.GetUserFromContext(ctx)
-
-		// Dev mode: use dev user if no auth provided
-		var userID string
-		if user != nil {
-			userID = user.UserID
-		} else {
-			userID = "user_dev_123"
+		owner, err := currentCartOwner(ctx)
+		if err != nil {
+			return nil, err
 		}
 
-		return r.CartRepository.GetCartByUserID(userID)
+		cart, err := r.findCart(owner)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &models.Cart{CartItems: []models.CartItem{}}, nil
+		}
+		return cart, err
 	}
 
 	if cartID != nil {
@@ -322,6 +315,11 @@ func (r *queryResolver) GetCart(ctx context.Context, cartID *string, forUser *bo
 			return &models.Cart{CartItems: []models.CartItem{}}, nil
 		}
 
+		owner, _ := currentCartOwner(ctx)
+		if !owner.owns(ctx, &cart) {
+			return nil, middleware.ErrForbidden
+		}
+
 		return &cart, nil
 	}
 
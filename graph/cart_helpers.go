@@ -0,0 +1,107 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/loaders"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/middleware"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/tax"
+	"gorm.io/gorm"
+)
+
+var errNoCartOwner = errors.New("sign in or start a guest session to use a cart")
+
+// cartOwner is whoever the current request's cart belongs to: the signed-in
+// user, or otherwise the guest session.
+type cartOwner struct {
+	UserID         *string
+	GuestSessionID *string
+}
+
+func currentCartOwner(ctx context.Context) (cartOwner, error) {
+	if user := middleware.GetUserFromContext(ctx); user != nil {
+		return cartOwner{UserID: &user.UserID}, nil
+	}
+	if session := middleware.GetGuestSessionFromContext(ctx); session != nil {
+		return cartOwner{GuestSessionID: &session.ID}, nil
+	}
+	return cartOwner{}, errNoCartOwner
+}
+
+// owns reports whether cart belongs to the owner. Admins may read any user's
+// cart but never a guest's.
+func (o cartOwner) owns(ctx context.Context, cart *models.Cart) bool {
+	if cart.UserID != nil {
+		if o.UserID != nil && *o.UserID == *cart.UserID {
+			return true
+		}
+		return middleware.IsAdmin(ctx)
+	}
+	if cart.GuestSessionID != nil {
+		return o.GuestSessionID != nil && *o.GuestSessionID == *cart.GuestSessionID
+	}
+	return false
+}
+
+func (r *Resolver) findCart(owner cartOwner) (*models.Cart, error) {
+	if owner.UserID != nil {
+		return r.CartRepository.GetCartByUserID(*owner.UserID)
+	}
+	return r.CartRepository.GetCartByGuestSessionID(*owner.GuestSessionID)
+}
+
+func (r *Resolver) findOrCreateCart(owner cartOwner) (*models.Cart, error) {
+	cart, err := r.findCart(owner)
+	if err == nil {
+		return cart, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	cart = &models.Cart{
+		UserID:         owner.UserID,
+		GuestSessionID: owner.GuestSessionID,
+	}
+	if err := r.DB.Create(cart).Error; err != nil {
+		return nil, err
+	}
+	return cart, nil
+}
+
+// cartItemVariant returns the item's variant with its product, using the
+// preloaded association when the cart query fetched it.
+func (r *cartItemResolver) cartItemVariant(ctx context.Context, obj *models.CartItem) (*models.ProductVariant, error) {
+	if obj.Variant.ID != 0 {
+		return &obj.Variant, nil
+	}
+
+	return loaders.GetVariant(ctx, obj.VariantID)
+}
+
+// cartTax works out the totals of a cart at today's tax rates. The items
+// are loaded once per request for all the total fields.
+func (r *Resolver) cartTax(ctx context.Context, cart *models.Cart) (tax.Result, error) {
+	items, err := loaders.GetCartItems(ctx, cart)
+	if err != nil {
+		return tax.Result{}, err
+	}
+	return r.TaxService.QuoteCart(items), nil
+}
+
+// parseReportDate reads an optional YYYY-MM-DD report bound as midnight
+// UTC.
+func parseReportDate(date *string, name string) (*time.Time, error) {
+	if date == nil {
+		return nil, nil
+	}
+	t, err := time.Parse("2006-01-02", *date)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a date like 2006-01-02", name)
+	}
+	return &t, nil
+}
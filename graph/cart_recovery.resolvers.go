@@ -0,0 +1,78 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.83
+
+import (
+	"context"
+	"errors"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/graph/generated"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/graph/model"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/middleware"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/repository"
+	"gorm.io/gorm"
+)
+
+// ConversionRate is the resolver for the conversionRate field.
+func (r *cartRecoveryReportResolver) ConversionRate(ctx context.Context, obj *repository.CartRecoveryReport) (float64, error) {
+	if obj.RemindersSent == 0 {
+		return 0, nil
+	}
+	return float64(obj.OrdersRecovered) / float64(obj.RemindersSent), nil
+}
+
+// RecoverCart is the resolver for the recoverCart field.
+func (r *mutationResolver) RecoverCart(ctx context.Context, token string) (*model.RecoveredCart, error) {
+	user, err := middleware.RequireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rec, err := r.CartRecoveryService.Redeem(user.UserID, token)
+	if err != nil {
+		return nil, err
+	}
+
+	cart, err := r.CartRepository.GetCartByUserID(user.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		cart, err = &models.Cart{CartItems: []models.CartItem{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	out := &model.RecoveredCart{Cart: cart}
+	if rec.PromoCode != nil {
+		out.PromoCode = &rec.PromoCode.Code
+	}
+	return out, nil
+}
+
+// CartRecoveryReport is the resolver for the cartRecoveryReport field.
+func (r *queryResolver) CartRecoveryReport(ctx context.Context, from *string, to *string) (*repository.CartRecoveryReport, error) {
+	start, err := parseReportDate(from, "from")
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseReportDate(to, "to")
+	if err != nil {
+		return nil, err
+	}
+	if end != nil {
+		// to is inclusive
+		next := end.AddDate(0, 0, 1)
+		end = &next
+	}
+
+	return r.CartRecoveryService.Report(start, end)
+}
+
+// CartRecoveryReport returns generated.CartRecoveryReportResolver implementation.
+func (r *Resolver) CartRecoveryReport() generated.CartRecoveryReportResolver {
+	return &cartRecoveryReportResolver{r}
+}
+
+type cartRecoveryReportResolver struct{ *Resolver }
@@ -0,0 +1,59 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.83
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/graph/generated"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/middleware"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+)
+
+// ID is the resolver for the id field.
+func (r *invoiceResolver) ID(ctx context.Context, obj *models.Invoice) (string, error) {
+	return strconv.FormatUint(uint64(obj.ID), 10), nil
+}
+
+// OrderID is the resolver for the orderID field.
+func (r *invoiceResolver) OrderID(ctx context.Context, obj *models.Invoice) (string, error) {
+	return strconv.FormatUint(uint64(obj.OrderID), 10), nil
+}
+
+// PDFURL is the resolver for the pdfUrl field.
+func (r *invoiceResolver) PDFURL(ctx context.Context, obj *models.Invoice) (string, error) {
+	return fmt.Sprintf("/invoices/%d.pdf", obj.OrderID), nil
+}
+
+// IssuedAt is the resolver for the issuedAt field.
+func (r *invoiceResolver) IssuedAt(ctx context.Context, obj *models.Invoice) (string, error) {
+	return obj.IssuedAt.Format("2006-01-02T15:04:05Z07:00"), nil
+}
+
+// GenerateInvoice is the resolver for the generateInvoice field.
+func (r *mutationResolver) GenerateInvoice(ctx context.Context, orderID string) (*models.Invoice, error) {
+	id, err := strconv.ParseUint(orderID, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid order ID")
+	}
+
+	order, err := r.OrderRepository.GetOrderByID(uint(id))
+	if err != nil {
+		return nil, fmt.Errorf("order not found")
+	}
+	if err := middleware.RequireOwner(ctx, order.UserID, constants.RoleSupport, constants.RoleFulfillment); err != nil {
+		return nil, err
+	}
+
+	return r.InvoiceService.IssueInvoice(order.ID)
+}
+
+// Invoice returns generated.InvoiceResolver implementation.
+func (r *Resolver) Invoice() generated.InvoiceResolver { return &invoiceResolver{r} }
+
+type invoiceResolver struct{ *Resolver }
@@ -3,7 +3,13 @@
 package model
 
 import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+
 	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
 )
 
 type AddToCartInput struct {
@@ -17,6 +23,25 @@ type AddToCartPayload struct {
 	Cart *models.Cart `json:"cart"`
 }
 
+type AddToWishlistInput struct {
+	ProductID         string  `json:"productId"`
+	VariantID         *string `json:"variantId,omitempty"`
+	NotifyBackInStock *bool   `json:"notifyBackInStock,omitempty"`
+	NotifyPriceDrop   *bool   `json:"notifyPriceDrop,omitempty"`
+}
+
+type AddressInput struct {
+	Name       string  `json:"name"`
+	Line1      string  `json:"line1"`
+	Line2      *string `json:"line2,omitempty"`
+	City       string  `json:"city"`
+	State      string  `json:"state"`
+	PostalCode string  `json:"postalCode"`
+	Country    *string `json:"country,omitempty"`
+	Phone      string  `json:"phone"`
+	IsDefault  *bool   `json:"isDefault,omitempty"`
+}
+
 type AttachCartToUserInput struct {
 	CartID string `json:"cartId"`
 	UserID string `json:"userId"`
@@ -40,8 +65,28 @@ type ClearCartPayload struct {
 }
 
 type CreateOrderInput struct {
-	ShippingAddress string  `json:"shippingAddress"`
-	PromoCode       *string `json:"promoCode,omitempty"`
+	AddressID       *string       `json:"addressId,omitempty"`
+	Address         *AddressInput `json:"address,omitempty"`
+	ShippingAddress *string       `json:"shippingAddress,omitempty"`
+	PromoCode       *string       `json:"promoCode,omitempty"`
+	ShippingMethod  *string       `json:"shippingMethod,omitempty"`
+}
+
+type CreateWebhookEndpointInput struct {
+	URL         string   `json:"url"`
+	Description *string  `json:"description,omitempty"`
+	Events      []string `json:"events,omitempty"`
+	Secret      *string  `json:"secret,omitempty"`
+}
+
+type FacetValue struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+type GuestSession struct {
+	Token     string `json:"token"`
+	ExpiresAt string `json:"expiresAt"`
 }
 
 type LoginInput struct {
@@ -52,40 +97,86 @@ type LoginInput struct {
 type Mutation struct {
 }
 
+type PageInfo struct {
+	HasNextPage bool    `json:"hasNextPage"`
+	EndCursor   *string `json:"endCursor,omitempty"`
+}
+
+type ProductConnection struct {
+	Edges      []*ProductEdge `json:"edges"`
+	PageInfo   *PageInfo      `json:"pageInfo"`
+	TotalCount int            `json:"totalCount"`
+}
+
+type ProductEdge struct {
+	Cursor string          `json:"cursor"`
+	Node   *models.Product `json:"node"`
+}
+
+type ProductFilter struct {
+	IsActive       *bool        `json:"isActive,omitempty"`
+	Category       *string      `json:"category,omitempty"`
+	Brand          *string      `json:"brand,omitempty"`
+	Fit            *string      `json:"fit,omitempty"`
+	Material       *string      `json:"material,omitempty"`
+	Neckline       *string      `json:"neckline,omitempty"`
+	SleeveType     *string      `json:"sleeveType,omitempty"`
+	Size           *string      `json:"size,omitempty"`
+	Color          *string      `json:"color,omitempty"`
+	MinPrice       *money.Money `json:"minPrice,omitempty"`
+	MaxPrice       *money.Money `json:"maxPrice,omitempty"`
+	Featured       *bool        `json:"featured,omitempty"`
+	LimitedEdition *bool        `json:"limitedEdition,omitempty"`
+	InStock        *bool        `json:"inStock,omitempty"`
+}
+
 type ProductInput struct {
-	Name             string   `json:"name"`
-	Description      *string  `json:"description,omitempty"`
-	DesignImageURL   *string  `json:"designImageURL,omitempty"`
-	ImageURLs        []string `json:"imageURLs,omitempty"`
-	BasePrice        float64  `json:"basePrice"`
-	Material         *string  `json:"material,omitempty"`
-	Neckline         *string  `json:"neckline,omitempty"`
-	SleeveType       *string  `json:"sleeveType,omitempty"`
-	Fit              *string  `json:"fit,omitempty"`
-	Brand            *string  `json:"brand,omitempty"`
-	Category         *string  `json:"category,omitempty"`
-	CareInstructions *string  `json:"careInstructions,omitempty"`
-	Weight           *float64 `json:"weight,omitempty"`
-	Featured         *bool    `json:"featured,omitempty"`
-	LimitedEdition   *bool    `json:"limitedEdition,omitempty"`
+	Name             string      `json:"name"`
+	Description      *string     `json:"description,omitempty"`
+	DesignImageURL   *string     `json:"designImageURL,omitempty"`
+	ImageURLs        []string    `json:"imageURLs,omitempty"`
+	BasePrice        money.Money `json:"basePrice"`
+	Material         *string     `json:"material,omitempty"`
+	Neckline         *string     `json:"neckline,omitempty"`
+	SleeveType       *string     `json:"sleeveType,omitempty"`
+	Fit              *string     `json:"fit,omitempty"`
+	Brand            *string     `json:"brand,omitempty"`
+	Category         *string     `json:"category,omitempty"`
+	CareInstructions *string     `json:"careInstructions,omitempty"`
+	Weight           *float64    `json:"weight,omitempty"`
+	Featured         *bool       `json:"featured,omitempty"`
+	LimitedEdition   *bool       `json:"limitedEdition,omitempty"`
 }
 
 type ProductOptions struct {
-	Sizes       []string `json:"sizes"`
-	Colors      []string `json:"colors"`
-	Materials   []string `json:"materials"`
-	Necklines   []string `json:"necklines"`
-	SleeveTypes []string `json:"sleeveTypes"`
-	Fits        []string `json:"fits"`
+	Sizes            []string      `json:"sizes"`
+	Colors           []string      `json:"colors"`
+	Materials        []string      `json:"materials"`
+	Necklines        []string      `json:"necklines"`
+	SleeveTypes      []string      `json:"sleeveTypes"`
+	Fits             []string      `json:"fits"`
+	SizeCounts       []*FacetValue `json:"sizeCounts"`
+	ColorCounts      []*FacetValue `json:"colorCounts"`
+	MaterialCounts   []*FacetValue `json:"materialCounts"`
+	NecklineCounts   []*FacetValue `json:"necklineCounts"`
+	SleeveTypeCounts []*FacetValue `json:"sleeveTypeCounts"`
+	FitCounts        []*FacetValue `json:"fitCounts"`
+}
+
+type ProductSearchResult struct {
+	Product       *models.Product `json:"product"`
+	Rank          float64         `json:"rank"`
+	NameHighlight string          `json:"nameHighlight"`
+	Snippet       *string         `json:"snippet,omitempty"`
 }
 
 type ProductVariantInput struct {
-	ProductID     string  `json:"productID"`
-	Size          string  `json:"size"`
-	Color         *string `json:"color,omitempty"`
-	PriceModifier float64 `json:"priceModifier"`
-	Sku           string  `json:"sku"`
-	StockQuantity int     `json:"stockQuantity"`
+	ProductID     string      `json:"productID"`
+	Size          string      `json:"size"`
+	Color         *string     `json:"color,omitempty"`
+	PriceModifier money.Money `json:"priceModifier"`
+	Sku           string      `json:"sku"`
+	StockQuantity int         `json:"stockQuantity"`
 }
 
 type PromoCodeInput struct {
@@ -99,19 +190,24 @@ type PromoCodeInput struct {
 }
 
 type PromoCodeValidation struct {
-	IsValid        bool    `json:"isValid"`
-	DiscountAmount float64 `json:"discountAmount"`
-	Message        *string `json:"message,omitempty"`
+	IsValid        bool        `json:"isValid"`
+	DiscountAmount money.Money `json:"discountAmount"`
+	Message        *string     `json:"message,omitempty"`
 }
 
 type Query struct {
 }
 
 type RazorpayOrder struct {
-	ID       string  `json:"id"`
-	Amount   float64 `json:"amount"`
-	Currency string  `json:"currency"`
-	Receipt  *string `json:"receipt,omitempty"`
+	ID       string      `json:"id"`
+	Amount   money.Money `json:"amount"`
+	Currency string      `json:"currency"`
+	Receipt  *string     `json:"receipt,omitempty"`
+}
+
+type RecoveredCart struct {
+	Cart      *models.Cart `json:"cart"`
+	PromoCode *string      `json:"promoCode,omitempty"`
 }
 
 type RegisterInput struct {
@@ -131,9 +227,166 @@ type RemoveCartItemPayload struct {
 	Cart *models.Cart `json:"cart"`
 }
 
+type ReturnRequestInput struct {
+	OrderItemID       string   `json:"orderItemId"`
+	Quantity          int      `json:"quantity"`
+	Reason            string   `json:"reason"`
+	Comment           *string  `json:"comment,omitempty"`
+	Photos            []string `json:"photos,omitempty"`
+	ExchangeVariantID *string  `json:"exchangeVariantId,omitempty"`
+}
+
+type UpdateWebhookEndpointInput struct {
+	URL         *string  `json:"url,omitempty"`
+	Description *string  `json:"description,omitempty"`
+	Events      []string `json:"events,omitempty"`
+	Active      *bool    `json:"active,omitempty"`
+}
+
+type UserConnection struct {
+	Edges      []*UserEdge `json:"edges"`
+	PageInfo   *PageInfo   `json:"pageInfo"`
+	TotalCount int         `json:"totalCount"`
+}
+
+type UserEdge struct {
+	Cursor string       `json:"cursor"`
+	Node   *models.User `json:"node"`
+}
+
 type VerifyPaymentInput struct {
 	OrderID           string `json:"orderID"`
 	RazorpayOrderID   string `json:"razorpayOrderID"`
 	RazorpayPaymentID string `json:"razorpayPaymentID"`
 	RazorpaySignature string `json:"razorpaySignature"`
 }
+
+type WebhookEndpointSecret struct {
+	Endpoint *models.WebhookEndpoint `json:"endpoint"`
+	Secret   string                  `json:"secret"`
+}
+
+type WishlistAlertsInput struct {
+	NotifyBackInStock *bool `json:"notifyBackInStock,omitempty"`
+	NotifyPriceDrop   *bool `json:"notifyPriceDrop,omitempty"`
+}
+
+type ProductSort string
+
+const (
+	ProductSortNewest     ProductSort = "NEWEST"
+	ProductSortPriceAsc   ProductSort = "PRICE_ASC"
+	ProductSortPriceDesc  ProductSort = "PRICE_DESC"
+	ProductSortPopularity ProductSort = "POPULARITY"
+)
+
+var AllProductSort = []ProductSort{
+	ProductSortNewest,
+	ProductSortPriceAsc,
+	ProductSortPriceDesc,
+	ProductSortPopularity,
+}
+
+func (e ProductSort) IsValid() bool {
+	switch e {
+	case ProductSortNewest, ProductSortPriceAsc, ProductSortPriceDesc, ProductSortPopularity:
+		return true
+	}
+	return false
+}
+
+func (e ProductSort) String() string {
+	return string(e)
+}
+
+func (e *ProductSort) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ProductSort(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ProductSort", str)
+	}
+	return nil
+}
+
+func (e ProductSort) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *ProductSort) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e ProductSort) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type Role string
+
+const (
+	RoleUser           Role = "USER"
+	RoleAdmin          Role = "ADMIN"
+	RoleCatalogManager Role = "CATALOG_MANAGER"
+	RoleFulfillment    Role = "FULFILLMENT"
+	RoleSupport        Role = "SUPPORT"
+)
+
+var AllRole = []Role{
+	RoleUser,
+	RoleAdmin,
+	RoleCatalogManager,
+	RoleFulfillment,
+	RoleSupport,
+}
+
+func (e Role) IsValid() bool {
+	switch e {
+	case RoleUser, RoleAdmin, RoleCatalogManager, RoleFulfillment, RoleSupport:
+		return true
+	}
+	return false
+}
+
+func (e Role) String() string {
+	return string(e)
+}
+
+func (e *Role) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = Role(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid Role", str)
+	}
+	return nil
+}
+
+func (e Role) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *Role) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e Role) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
@@ -10,32 +10,36 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/vishnujoshi062/tshirt-ecommerce-api/graph/generated"
 	"github.com/vishnujoshi062/tshirt-ecommerce-api/graph/model"
 	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/events"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/loaders"
 	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/middleware"
 	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
 	"gorm.io/gorm"
 )
 
 // CreateOrder is the resolver for the createOrder field.
 func (r *mutationResolver) CreateOrder(ctx context.Context, input model.CreateOrderInput) (*models.Order, error) {
-	user := middleware.GetUserFromContext(ctx)
-
-	// Dev mode: use dev user if no auth provided
-	var userID string
-	if user != nil {
-		userID = user.UserID
-	} else {
-		userID = "user_dev_123"
+	user, err := middleware.RequireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	userID := user.UserID
+
+	shippingAddress, shippingDetails, err := r.shippingAddressFor(userID, input)
+	if err != nil {
+		return nil, err
 	}
 
 	var order *models.Order
-	var finalTotal float64
 
 	// Start transaction
-	err := r.DB.Transaction(func(tx *gorm.DB) error {
+	err = r.DB.Transaction(func(tx *gorm.DB) error {
 		// Get cart and items
 		cart, err := r.CartRepository.GetCartByUserID(userID)
 		if err != nil {
@@ -57,22 +61,13 @@ func (r *mutationResolver) CreateOrder(ctx context.Context, input model.CreateOr
 			return errors.New("cart is empty")
 		}
 
-		var subtotal float64
+		subtotal := money.INR(0)
 		orderItems := []models.OrderItem{}
 
 		for _, item := range cartItems {
-			price := item.Variant.Product.BasePrice + item.Variant.PriceModifier
-			sub := price * float64(item.Quantity)
-			subtotal += sub
-
-			var inventory models.Inventory
-			if err := tx.Where("variant_id = ?", item.VariantID).First(&inventory).Error; err != nil {
-				return err
-			}
-
-			if inventory.StockQuantity < item.Quantity {
-				return fmt.Errorf("out of stock for variant %d", item.VariantID)
-			}
+			price := item.Variant.Product.BasePrice.Add(item.Variant.PriceModifier)
+			sub := price.Mul(item.Quantity)
+			subtotal = subtotal.Add(sub)
 
 			orderItems = append(orderItems, models.OrderItem{
 				VariantID: item.VariantID,
@@ -83,7 +78,7 @@ func (r *mutationResolver) CreateOrder(ctx context.Context, input model.CreateOr
 		}
 
 		// Apply promo code if provided
-		discount := 0.0
+		discount := money.INR(0)
 		if input.PromoCode != nil && *input.PromoCode != "" {
 			// Re-validate promo
 			validation, err := r.PromoCodeService.ValidatePromoCode(*input.PromoCode, subtotal)
@@ -120,30 +115,39 @@ func (r *mutationResolver) CreateOrder(ctx context.Context, input model.CreateOr
 			}
 		}
 
-		finalTotal = subtotal - discount
+		var shippingMethod string
+		if input.ShippingMethod != nil {
+			shippingMethod = *input.ShippingMethod
+		}
+		shippingOption, err := r.ShippingService.Quote(cartItems, shippingDetails.PostalCode, shippingMethod)
+		if err != nil {
+			return err
+		}
 
 		// Create order
 		order = &models.Order{
 			UserID:          userID,
-			TotalAmount:     finalTotal,
 			Discount:        discount,
+			ShippingMethod:  &shippingOption.Code,
+			ShippingCost:    shippingOption.Cost,
 			PromoCode:       input.PromoCode,
 			Status:          constants.OrderPending,
-			ShippingAddress: input.ShippingAddress,
+			ShippingAddress: shippingAddress,
+			ShippingDetails: shippingDetails,
 			OrderItems:      orderItems,
 		}
+		r.TaxService.ApplyToOrder(order, time.Now())
 
 		if err := tx.Create(order).Error; err != nil {
 			return err
 		}
 
-		// Reduce inventory
-		for _, item := range cartItems {
-			if err := tx.Model(&models.Inventory{}).
-				Where("variant_id = ?", item.VariantID).
-				Update("stock_quantity", gorm.Expr("stock_quantity - ?", item.Quantity)).Error; err != nil {
-				return err
-			}
+		// Reserve inventory until the order is paid or the reservation expires
+		if err := r.InventoryService.ReserveForOrder(tx, order.ID, order.OrderItems); err != nil {
+			return err
+		}
+		if err := r.EventBus.Publish(tx, events.OrderPlaced, events.NewOrder(order, "")); err != nil {
+			return err
 		}
 
 		// Clear cart
@@ -170,9 +174,10 @@ func (r *mutationResolver) UpdateOrderStatus(ctx context.Context, orderID string
 		return nil, err
 	}
 
-	order.Status = status
-
-	if err := r.OrderRepository.UpdateOrder(order); err != nil {
+	err = r.DB.Transaction(func(tx *gorm.DB) error {
+		return r.OrderService.TransitionStatus(tx, order, status)
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -188,13 +193,16 @@ func (r *mutationResolver) CancelOrder(ctx context.Context, orderID string) (*mo
 		return nil, err
 	}
 
-	if order.Status == constants.OrderShipped || order.Status == constants.OrderDelivered {
-		return nil, errors.New("order already shipped")
+	if err := middleware.RequireOwner(ctx, order.UserID, constants.RoleSupport); err != nil {
+		return nil, err
 	}
 
-	order.Status = constants.OrderCancelled
-
-	r.OrderRepository.UpdateOrder(order)
+	err = r.DB.Transaction(func(tx *gorm.DB) error {
+		return r.OrderService.TransitionStatus(tx, order, constants.OrderCancelled)
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	return order, nil
 }
@@ -206,9 +214,42 @@ func (r *orderResolver) ID(ctx context.Context, obj *models.Order) (string, erro
 
 // Items is the resolver for the items field.
 func (r *orderResolver) Items(ctx context.Context, obj *models.Order) ([]*models.OrderItem, error) {
-	var items []*models.OrderItem
-	r.DB.Where("order_id = ?", obj.ID).Find(&items)
-	return items, nil
+	if obj.OrderItems != nil {
+		items := make([]*models.OrderItem, len(obj.OrderItems))
+		for i := range obj.OrderItems {
+			items[i] = &obj.OrderItems[i]
+		}
+		return items, nil
+	}
+
+	return loaders.GetOrderItems(ctx, obj.ID)
+}
+
+// ShippingDetails is the resolver for the shippingDetails field.
+func (r *orderResolver) ShippingDetails(ctx context.Context, obj *models.Order) (*models.OrderAddress, error) {
+	if obj.ShippingDetails.Line1 == "" {
+		return nil, nil
+	}
+
+	return &obj.ShippingDetails, nil
+}
+
+// Payment is the resolver for the payment field.
+func (r *orderResolver) Payment(ctx context.Context, obj *models.Order) (*models.Payment, error) {
+	if obj.Payment != nil {
+		return obj.Payment, nil
+	}
+
+	return loaders.GetPayment(ctx, obj.ID)
+}
+
+// Shipment is the resolver for the shipment field.
+func (r *orderResolver) Shipment(ctx context.Context, obj *models.Order) (*models.Shipment, error) {
+	shipment, err := r.ShipmentRepository.GetShipmentByOrderID(obj.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return shipment, err
 }
 
 // CreatedAt is the resolver for the createdAt field.
@@ -231,6 +272,24 @@ func (r *orderItemResolver) OrderID(ctx context.Context, obj *models.OrderItem)
 	return strconv.FormatUint(uint64(obj.OrderID), 10), nil
 }
 
+// Variant is the resolver for the variant field.
+func (r *orderItemResolver) Variant(ctx context.Context, obj *models.OrderItem) (*models.ProductVariant, error) {
+	if obj.Variant.ID != 0 {
+		return &obj.Variant, nil
+	}
+
+	return loaders.GetVariant(ctx, obj.VariantID)
+}
+
+// TaxRate is the resolver for the taxRate field.
+func (r *orderItemResolver) TaxRate(ctx context.Context, obj *models.OrderItem) (*float64, error) {
+	if obj.TaxRate == nil {
+		return nil, nil
+	}
+	rate := float64(*obj.TaxRate) / 100
+	return &rate, nil
+}
+
 // ID is the resolver for the id field.
 func (r *paymentResolver) ID(ctx context.Context, obj *models.Payment) (string, error) {
 	return strconv.FormatUint(uint64(obj.ID), 10), nil
@@ -241,6 +300,20 @@ func (r *paymentResolver) OrderID(ctx context.Context, obj *models.Payment) (str
 	return strconv.FormatUint(uint64(obj.OrderID), 10), nil
 }
 
+// Refunds is the resolver for the refunds field.
+func (r *paymentResolver) Refunds(ctx context.Context, obj *models.Payment) ([]*models.Refund, error) {
+	refunds, err := r.RefundRepository.GetRefundsByPaymentID(obj.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]*models.Refund, len(refunds))
+	for i := range refunds {
+		out[i] = &refunds[i]
+	}
+	return out, nil
+}
+
 // CreatedAt is the resolver for the createdAt field.
 func (r *paymentResolver) CreatedAt(ctx context.Context, obj *models.Payment) (string, error) {
 	return obj.CreatedAt.Format("2006-01-02T15:04:05Z07:00"), nil
@@ -248,15 +321,11 @@ func (r *paymentResolver) CreatedAt(ctx context.Context, obj *models.Payment) (s
 
 // MyOrders is the resolver for the myOrders field.
 func (r *queryResolver) MyOrders(ctx context.Context) ([]*models.Order, error) {
-	user := middleware.GetUserFromContext(ctx)
-
-	// Dev mode: use dev user if no auth provided
-	var userID string
-	if user != nil {
-		userID = user.UserID
-	} else {
-		userID = "user_dev_123"
+	user, err := middleware.RequireUser(ctx)
+	if err != nil {
+		return nil, err
 	}
+	userID := user.UserID
 
 	orders, err := r.OrderRepository.GetOrdersByUserID(userID)
 	if err != nil {
@@ -280,6 +349,10 @@ func (r *queryResolver) Order(ctx context.Context, id string) (*models.Order, er
 		return nil, err
 	}
 
+	if err := middleware.RequireOwner(ctx, order.UserID, constants.RoleSupport, constants.RoleFulfillment); err != nil {
+		return nil, err
+	}
+
 	return order, nil
 }
 
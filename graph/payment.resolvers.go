@@ -8,10 +8,13 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strconv"
 
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/graph/generated"
 	"github.com/vishnujoshi062/tshirt-ecommerce-api/graph/model"
-	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/middleware"
 	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
 	"gorm.io/gorm"
 )
 
@@ -44,13 +47,16 @@ func (r *mutationResolver) CreateRazorpayOrder(ctx context.Context, orderID stri
 		log.Printf("Order not found: %v", err)
 		return nil, fmt.Errorf("order not found: %v", err)
 	}
-	log.Printf("Found order with amount: %.2f", order.TotalAmount)
+	if err := middleware.RequireOwner(ctx, order.UserID); err != nil {
+		return nil, err
+	}
+	log.Printf("Found order with amount: %s", order.TotalAmount)
 
 	// Create Razorpay order
 	receipt := fmt.Sprintf("order_%d", order.ID)
-	log.Printf("Calling Razorpay API with amount: %.2f, currency: INR, receipt: %s", order.TotalAmount, receipt)
+	log.Printf("Calling Razorpay API with amount: %s, receipt: %s", order.TotalAmount, receipt)
 
-	razorpayOrderData, err := r.PaymentService.CreateOrder(order.TotalAmount, "INR", receipt)
+	razorpayOrderData, err := r.PaymentService.CreateOrder(order.TotalAmount, receipt)
 	if err != nil {
 		log.Printf("Error creating Razorpay order: %v", err)
 		return nil, fmt.Errorf("failed to create Razorpay order: %v (make sure RAZORPAY_KEY_ID and RAZORPAY_KEY_SECRET are set)", err)
@@ -65,13 +71,19 @@ func (r *mutationResolver) CreateRazorpayOrder(ctx context.Context, orderID stri
 		return nil, fmt.Errorf("invalid response from Razorpay API")
 	}
 
+	// Remember the Razorpay order so webhooks can find this order
+	if err := r.DB.Model(order).Update("razorpay_order_id", razorpayOrderID).Error; err != nil {
+		log.Printf("Error saving Razorpay order ID: %v", err)
+		return nil, fmt.Errorf("failed to save Razorpay order ID: %v", err)
+	}
+
 	// Handle amount - could be int or float64
-	var amountPaise int
+	var amountPaise int64
 	switch v := razorpayOrderData["amount"].(type) {
 	case int:
-		amountPaise = v
+		amountPaise = int64(v)
 	case float64:
-		amountPaise = int(v)
+		amountPaise = int64(v)
 	default:
 		log.Printf("Error: invalid amount format from Razorpay")
 		return nil, fmt.Errorf("invalid amount format in Razorpay response")
@@ -82,12 +94,9 @@ func (r *mutationResolver) CreateRazorpayOrder(ctx context.Context, orderID stri
 		currency = "INR" // default
 	}
 
-	// Convert amount from paise to rupees
-	amountRupees := float64(amountPaise) / 100
-
 	razorpayOrder := &model.RazorpayOrder{
 		ID:       razorpayOrderID,
-		Amount:   amountRupees,
+		Amount:   money.New(amountPaise, currency),
 		Currency: currency,
 		Receipt:  &receipt,
 	}
@@ -98,8 +107,6 @@ func (r *mutationResolver) CreateRazorpayOrder(ctx context.Context, orderID stri
 
 // VerifyPayment is the resolver for the verifyPayment field.
 func (r *mutationResolver) VerifyPayment(ctx context.Context, input model.VerifyPaymentInput) (*models.Payment, error) {
-	log.Printf("VerifyPayment called with input: %+v", input)
-
 	// Convert orderID from string to uint
 	var orderID uint
 	_, err := fmt.Sscanf(input.OrderID, "%d", &orderID)
@@ -115,7 +122,14 @@ func (r *mutationResolver) VerifyPayment(ctx context.Context, input model.Verify
 		log.Printf("Error getting order: %v", err)
 		return nil, fmt.Errorf("order not found: %v", err)
 	}
-	log.Printf("Found order: %+v", order)
+	if err := middleware.RequireOwner(ctx, order.UserID); err != nil {
+		return nil, err
+	}
+	// The signature only vouches for the Razorpay order it names, which must
+	// be the one created for this order
+	if order.RazorpayOrderID == nil || *order.RazorpayOrderID != input.RazorpayOrderID {
+		return nil, fmt.Errorf("payment is not for order %d", order.ID)
+	}
 
 	// Verify the Razorpay signature
 	isValid := r.PaymentService.VerifySignature(input.RazorpayOrderID, input.RazorpayPaymentID, input.RazorpaySignature)
@@ -124,61 +138,72 @@ func (r *mutationResolver) VerifyPayment(ctx context.Context, input model.Verify
 		return nil, fmt.Errorf("invalid payment signature")
 	}
 
-	// Check if payment already exists
-	existingPayment, err := r.PaymentRepository.GetPaymentByOrderID(orderID)
-	if err != nil && err != gorm.ErrRecordNotFound {
-		log.Printf("Error checking existing payment: %v", err)
-		return nil, fmt.Errorf("error checking existing payment: %v", err)
-	}
-
-	if err == nil && existingPayment != nil && existingPayment.ID > 0 {
-		log.Printf("Updating existing payment: %+v", existingPayment)
-		// Update existing payment
-		existingPayment.Status = constants.PaymentCompleted
-		existingPayment.TransactionID = input.RazorpayPaymentID
-		existingPayment.PaymentMethod = "razorpay"
-		err = r.PaymentRepository.UpdatePayment(existingPayment)
-		if err != nil {
-			log.Printf("Error updating payment: %v", err)
-			return nil, fmt.Errorf("failed to update payment: %v", err)
-		}
-
-		// Update order status
-		order.Status = constants.OrderConfirmed
-		err = r.OrderRepository.UpdateOrder(order)
-		if err != nil {
-			log.Printf("Error updating order status: %v", err)
-			return nil, fmt.Errorf("failed to update order status: %v", err)
-		}
-
-		log.Printf("Successfully updated payment: %+v", existingPayment)
-		return existingPayment, nil
-	}
-
-	log.Printf("Creating new payment")
-	// Create new payment record
-	payment := &models.Payment{
-		OrderID:       orderID,
-		Amount:        order.TotalAmount,
-		Status:        constants.PaymentCompleted,
-		PaymentMethod: "razorpay",
-		TransactionID: input.RazorpayPaymentID,
-	}
-
-	err = r.PaymentRepository.CreatePayment(payment)
+	// Record the payment, commit reserved stock and confirm the order. The
+	// Razorpay webhook goes through the same path, so whichever arrives
+	// second is a no-op.
+	var payment *models.Payment
+	err = r.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		payment, err = r.OrderService.ConfirmPayment(tx, order, input.RazorpayPaymentID, "razorpay")
+		return err
+	})
 	if err != nil {
-		log.Printf("Error creating payment: %v", err)
-		return nil, fmt.Errorf("failed to create payment: %v", err)
+		log.Printf("Error confirming payment: %v", err)
+		return nil, fmt.Errorf("failed to confirm payment: %v", err)
+	}
+
+	log.Printf("Confirmed payment %d for order %d", payment.ID, order.ID)
+	return payment, nil
+}
+
+// RefundPayment is the resolver for the refundPayment field.
+func (r *mutationResolver) RefundPayment(ctx context.Context, orderID string, amount *money.Money, reason *string) (*models.Refund, error) {
+	var id uint
+	if _, err := fmt.Sscanf(orderID, "%d", &id); err != nil {
+		return nil, fmt.Errorf("invalid order ID: %v", err)
 	}
 
-	// Update order status to confirmed
-	order.Status = constants.OrderConfirmed
-	err = r.OrderRepository.UpdateOrder(order)
+	refundReason := ""
+	if reason != nil {
+		refundReason = *reason
+	}
+
+	var refund *models.Refund
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		refund, err = r.PaymentService.RefundPayment(tx, id, amount, refundReason)
+		return err
+	})
 	if err != nil {
-		log.Printf("Error updating order status: %v", err)
-		return nil, fmt.Errorf("failed to update order status: %v", err)
+		log.Printf("Error refunding order %d: %v", id, err)
+		return nil, err
 	}
 
-	log.Printf("Successfully created payment: %+v", payment)
-	return payment, nil
+	log.Printf("Refund created: %+v", refund)
+	return refund, nil
+}
+
+// ID is the resolver for the id field.
+func (r *refundResolver) ID(ctx context.Context, obj *models.Refund) (string, error) {
+	return strconv.FormatUint(uint64(obj.ID), 10), nil
+}
+
+// OrderID is the resolver for the orderID field.
+func (r *refundResolver) OrderID(ctx context.Context, obj *models.Refund) (string, error) {
+	return strconv.FormatUint(uint64(obj.OrderID), 10), nil
 }
+
+// PaymentID is the resolver for the paymentID field.
+func (r *refundResolver) PaymentID(ctx context.Context, obj *models.Refund) (string, error) {
+	return strconv.FormatUint(uint64(obj.PaymentID), 10), nil
+}
+
+// CreatedAt is the resolver for the createdAt field.
+func (r *refundResolver) CreatedAt(ctx context.Context, obj *models.Refund) (string, error) {
+	return obj.CreatedAt.Format("2006-01-02T15:04:05Z07:00"), nil
+}
+
+// Refund returns generated.RefundResolver implementation.
+func (r *Resolver) Refund() generated.RefundResolver { return &refundResolver{r} }
+
+type refundResolver struct{ *Resolver }
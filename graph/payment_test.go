@@ -0,0 +1,80 @@
+package graph
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/graph/model"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/auth"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/middleware"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/repository"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/service"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/testdb"
+)
+
+func TestVerifyPaymentForAnotherOrder(t *testing.T) {
+	db := testdb.Open(t)
+	t.Setenv("RAZORPAY_KEY_SECRET", "rzp_secret")
+	bus := service.NewEventBus(db, repository.NewOutboxRepository(db), 3, time.Hour)
+	orderRepo := repository.NewOrderRepository(db)
+	paymentRepo := repository.NewPaymentRepository(db)
+	payments := service.NewPaymentService(paymentRepo, repository.NewRefundRepository(db), bus)
+	inventory := service.NewInventoryService(db, repository.NewInventoryRepository(db), time.Hour, bus, 0)
+	r := &mutationResolver{&Resolver{
+		DB:              db,
+		OrderRepository: orderRepo,
+		PaymentService:  payments,
+		OrderService:    service.NewOrderService(db, orderRepo, paymentRepo, inventory, payments, bus, false),
+	}}
+	ctx := context.WithValue(context.Background(), middleware.UserContextKey, &auth.ClerkClaims{UserID: "user_1"})
+
+	newOrder := func(razorpayOrderID string, total int64) *models.Order {
+		order := &models.Order{
+			UserID:          "user_1",
+			Subtotal:        money.INR(total),
+			TotalAmount:     money.INR(total),
+			Status:          constants.OrderPending,
+			ShippingAddress: "1 MG Road, Bengaluru 560001",
+			RazorpayOrderID: &razorpayOrderID,
+		}
+		if err := db.Create(order).Error; err != nil {
+			t.Fatal(err)
+		}
+		return order
+	}
+	cheap := newOrder("order_rzp_cheap", 19900)
+	dear := newOrder("order_rzp_dear", 999900)
+
+	// A genuine signature for paying the cheap order
+	mac := hmac.New(sha256.New, []byte("rzp_secret"))
+	mac.Write([]byte("order_rzp_cheap|pay_cheap"))
+	input := model.VerifyPaymentInput{
+		OrderID:           fmt.Sprint(dear.ID),
+		RazorpayOrderID:   "order_rzp_cheap",
+		RazorpayPaymentID: "pay_cheap",
+		RazorpaySignature: hex.EncodeToString(mac.Sum(nil)),
+	}
+	if _, err := r.VerifyPayment(ctx, input); err == nil {
+		t.Fatal("the cheap order's payment confirmed the dear one")
+	}
+	var got models.Order
+	if err := db.First(&got, dear.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != constants.OrderPending {
+		t.Fatalf("dear order is %s, want pending", got.Status)
+	}
+
+	input.OrderID = fmt.Sprint(cheap.ID)
+	if _, err := r.VerifyPayment(ctx, input); err != nil {
+		t.Fatalf("verifying the cheap order's own payment: %v", err)
+	}
+}
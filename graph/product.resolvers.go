@@ -6,13 +6,19 @@ package graph
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
 
 	"github.com/lib/pq"
 	"github.com/vishnujoshi062/tshirt-ecommerce-api/graph/generated"
 	"github.com/vishnujoshi062/tshirt-ecommerce-api/graph/model"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/events"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/loaders"
 	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/repository"
+	"gorm.io/gorm"
 )
 
 // ID is the resolver for the id field.
@@ -87,6 +93,7 @@ func (r *mutationResolver) CreateProduct(ctx context.Context, input model.Produc
 	if err := r.ProductRepository.CreateProduct(product); err != nil {
 		return nil, fmt.Errorf("failed to create product: %w", err)
 	}
+	r.ProductOptionsService.Invalidate()
 
 	return product, nil
 }
@@ -103,6 +110,7 @@ func (r *mutationResolver) UpdateProduct(ctx context.Context, id string, input m
 		return nil, fmt.Errorf("product not found")
 	}
 
+	previousPrice := product.BasePrice
 	product.Name = input.Name
 	product.Description = input.Description
 	product.BasePrice = input.BasePrice
@@ -152,9 +160,19 @@ func (r *mutationResolver) UpdateProduct(ctx context.Context, id string, input m
 		product.LimitedEdition = *input.LimitedEdition
 	}
 
-	if err := r.ProductRepository.UpdateProduct(product); err != nil {
+	err = r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := r.ProductRepository.WithTx(tx).UpdateProduct(product); err != nil {
+			return err
+		}
+		if product.BasePrice.Cmp(previousPrice) == 0 {
+			return nil
+		}
+		return r.EventBus.Publish(tx, events.ProductPriceChanged, events.NewProductPrice(product, previousPrice))
+	})
+	if err != nil {
 		return nil, fmt.Errorf("failed to update product: %w", err)
 	}
+	r.ProductOptionsService.Invalidate()
 
 	return product, nil
 }
@@ -169,6 +187,7 @@ func (r *mutationResolver) DeleteProduct(ctx context.Context, id string) (bool,
 	if err := r.ProductRepository.DeleteProduct(uint(productID)); err != nil {
 		return false, fmt.Errorf("failed to delete product: %w", err)
 	}
+	r.ProductOptionsService.Invalidate()
 
 	return true, nil
 }
@@ -200,6 +219,7 @@ func (r *mutationResolver) CreateProductVariant(ctx context.Context, input model
 	if err := r.DB.Create(&inventory).Error; err != nil {
 		return nil, fmt.Errorf("failed to create inventory: %w", err)
 	}
+	r.ProductOptionsService.Invalidate()
 
 	return &variant, nil
 }
@@ -211,18 +231,21 @@ func (r *mutationResolver) UpdateInventory(ctx context.Context, variantID string
 		return nil, fmt.Errorf("invalid variant ID")
 	}
 
-	var inv models.Inventory
-	if err := r.DB.Where("variant_id = ?", uint(varID)).First(&inv).Error; err != nil {
+	var inv *models.Inventory
+	err = r.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		inv, err = r.InventoryService.SetStock(tx, uint(varID), quantity)
+		return err
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, fmt.Errorf("inventory not found")
 	}
-
-	inv.StockQuantity = quantity
-
-	if err := r.DB.Save(&inv).Error; err != nil {
+	if err != nil {
 		return nil, fmt.Errorf("failed to update inventory: %w", err)
 	}
+	r.ProductOptionsService.Invalidate()
 
-	return &inv, nil
+	return inv, nil
 }
 
 // ID is the resolver for the id field.
@@ -240,6 +263,23 @@ func (r *productResolver) ImageURLs(ctx context.Context, obj *models.Product) ([
 	return []string(obj.ImageURLs), nil
 }
 
+// Variants is the resolver for the variants field.
+func (r *productResolver) Variants(ctx context.Context, obj *models.Product) ([]*models.ProductVariant, error) {
+	variants := obj.Variants
+	if variants == nil {
+		var err error
+		if variants, err = loaders.GetVariantsByProduct(ctx, obj.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	out := make([]*models.ProductVariant, len(variants))
+	for i := range variants {
+		out[i] = &variants[i]
+	}
+	return out, nil
+}
+
 // CreatedAt is the resolver for the createdAt field.
 func (r *productResolver) CreatedAt(ctx context.Context, obj *models.Product) (string, error) {
 	return obj.CreatedAt.Format("2006-01-02T15:04:05Z07:00"), nil
@@ -256,53 +296,75 @@ func (r *productVariantResolver) ProductID(ctx context.Context, obj *models.Prod
 }
 
 // Price is the resolver for the price field.
-func (r *productVariantResolver) Price(ctx context.Context, obj *models.ProductVariant) (float64, error) {
-	var product models.Product
-	if err := r.DB.First(&product, obj.ProductID).Error; err != nil {
-		return 0, fmt.Errorf("failed to load product")
+func (r *productVariantResolver) Price(ctx context.Context, obj *models.ProductVariant) (*money.Money, error) {
+	product, err := r.ProductVariant().Product(ctx, obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load product")
 	}
 
-	return product.BasePrice + obj.PriceModifier, nil
+	price := product.BasePrice.Add(obj.PriceModifier)
+	return &price, nil
 }
 
-// Products is the resolver for the products field.
-func (r *queryResolver) Products(ctx context.Context, isActive *bool) ([]*models.Product, error) {
-	products, err := r.ProductRepository.GetAllProducts()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get products")
+// Inventory is the resolver for the inventory field.
+func (r *productVariantResolver) Inventory(ctx context.Context, obj *models.ProductVariant) (*models.Inventory, error) {
+	if obj.Inventory != nil {
+		return obj.Inventory, nil
 	}
 
-	var out []*models.Product
+	return loaders.GetInventory(ctx, obj.ID)
+}
 
-	for i := range products {
+// Product is the resolver for the product field.
+func (r *productVariantResolver) Product(ctx context.Context, obj *models.ProductVariant) (*models.Product, error) {
+	if obj.Product != nil {
+		return obj.Product, nil
+	}
 
-		if isActive != nil && *isActive != products[i].IsActive {
-			continue
-		}
+	return loaders.GetProduct(ctx, obj.ProductID)
+}
 
-		if products[i].Variants == nil {
-			products[i].Variants = []models.ProductVariant{}
-		}
+// Products is the resolver for the products field.
+func (r *queryResolver) Products(ctx context.Context, filter *model.ProductFilter, sort *model.ProductSort, first *int, after *string) (*model.ProductConnection, error) {
+	pageSize := repository.DefaultProductPageSize
+	if first != nil {
+		pageSize = *first
+	}
+
+	cursor := ""
+	if after != nil {
+		cursor = *after
+	}
 
-		// Ensure inventory is loaded for each variant
-		for j := range products[i].Variants {
-			if products[i].Variants[j].Inventory == nil {
-				var inventory models.Inventory
-				if err := r.DB.Where("variant_id = ?", products[i].Variants[j].ID).First(&inventory).Error; err == nil {
-					products[i].Variants[j].Inventory = &inventory
-				}
-			}
+	page, err := r.ProductRepository.ListProducts(toProductFilter(filter), toProductSort(sort), pageSize, cursor)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			return nil, err
 		}
+		return nil, fmt.Errorf("failed to get products: %w", err)
+	}
+
+	conn := &model.ProductConnection{
+		Edges:      make([]*model.ProductEdge, len(page.Products)),
+		PageInfo:   &model.PageInfo{HasNextPage: page.HasNextPage},
+		TotalCount: int(page.TotalCount),
+	}
 
-		// Backward compatibility: populate imageURLs from designImageURL if empty
-		if len(products[i].ImageURLs) == 0 && products[i].DesignImageURL != "" {
-			products[i].ImageURLs = pq.StringArray{products[i].DesignImageURL}
+	for i := range page.Products {
+		if page.Products[i].Variants == nil {
+			page.Products[i].Variants = []models.ProductVariant{}
 		}
+		conn.Edges[i] = &model.ProductEdge{
+			Cursor: page.Cursors[i],
+			Node:   &page.Products[i],
+		}
+	}
 
-		out = append(out, &products[i])
+	if n := len(page.Cursors); n > 0 {
+		conn.PageInfo.EndCursor = &page.Cursors[n-1]
 	}
 
-	return out, nil
+	return conn, nil
 }
 
 // Product is the resolver for the product field.
@@ -321,16 +383,6 @@ func (r *queryResolver) Product(ctx context.Context, id string) (*models.Product
 		product.Variants = []models.ProductVariant{}
 	}
 
-	// Ensure inventory is loaded for each variant
-	for i := range product.Variants {
-		if product.Variants[i].Inventory == nil {
-			var inventory models.Inventory
-			if err := r.DB.Where("variant_id = ?", product.Variants[i].ID).First(&inventory).Error; err == nil {
-				product.Variants[i].Inventory = &inventory
-			}
-		}
-	}
-
 	// Backward compatibility: populate imageURLs from designImageURL if empty
 	if len(product.ImageURLs) == 0 && product.DesignImageURL != "" {
 		product.ImageURLs = pq.StringArray{product.DesignImageURL}
@@ -341,12 +393,67 @@ func (r *queryResolver) Product(ctx context.Context, id string) (*models.Product
 
 // ProductsByCategory is the resolver for the productsByCategory field.
 func (r *queryResolver) ProductsByCategory(ctx context.Context, category string) ([]*models.Product, error) {
-	return []*models.Product{}, nil
+	active := true
+	page, err := r.ProductRepository.ListProducts(repository.ProductFilter{
+		IsActive: &active,
+		Category: category,
+	}, repository.ProductSortNewest, repository.MaxProductPageSize, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get products: %w", err)
+	}
+
+	out := make([]*models.Product, len(page.Products))
+	for i := range page.Products {
+		out[i] = &page.Products[i]
+	}
+
+	return out, nil
 }
 
-// ✅ No panic – safe return
-func (r *queryResolver) ProductOptions(ctx context.Context) (*model.ProductOptions, error) {
-	return &model.ProductOptions{}, nil
+// SearchProducts is the resolver for the searchProducts field.
+func (r *queryResolver) SearchProducts(ctx context.Context, query string, first *int) ([]*model.ProductSearchResult, error) {
+	limit := repository.DefaultSearchLimit
+	if first != nil {
+		limit = *first
+	}
+
+	hits, err := r.ProductRepository.SearchProducts(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search products: %w", err)
+	}
+
+	out := make([]*model.ProductSearchResult, len(hits))
+	for i := range hits {
+		result := &model.ProductSearchResult{
+			Product:       &hits[i].Product,
+			Rank:          hits[i].Rank,
+			NameHighlight: hits[i].NameHighlight,
+		}
+		if hits[i].Snippet != "" {
+			result.Snippet = &hits[i].Snippet
+		}
+		out[i] = result
+	}
+
+	return out, nil
+}
+
+// ProductOptions is the resolver for the productOptions field.
+func (r *queryResolver) ProductOptions(ctx context.Context, filter *model.ProductFilter) (*model.ProductOptions, error) {
+	options, err := r.ProductOptionsService.GetOptions(toProductFilter(filter))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product options: %w", err)
+	}
+
+	out := &model.ProductOptions{}
+	out.Sizes, out.SizeCounts = toFacetValues(options.Sizes)
+	out.Colors, out.ColorCounts = toFacetValues(options.Colors)
+	out.Materials, out.MaterialCounts = toFacetValues(options.Materials)
+	out.Necklines, out.NecklineCounts = toFacetValues(options.Necklines)
+	out.SleeveTypes, out.SleeveTypeCounts = toFacetValues(options.SleeveTypes)
+	out.Fits, out.FitCounts = toFacetValues(options.Fits)
+
+	return out, nil
 }
 
 // Inventory returns generated.InventoryResolver implementation.
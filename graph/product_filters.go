@@ -0,0 +1,67 @@
+package graph
+
+import (
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/graph/model"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/repository"
+)
+
+// toProductFilter converts the GraphQL filter input into the repository
+// filter. A nil input matches every product.
+func toProductFilter(in *model.ProductFilter) repository.ProductFilter {
+	if in == nil {
+		return repository.ProductFilter{}
+	}
+
+	return repository.ProductFilter{
+		IsActive:       in.IsActive,
+		Category:       deref(in.Category),
+		Brand:          deref(in.Brand),
+		Fit:            deref(in.Fit),
+		Material:       deref(in.Material),
+		Neckline:       deref(in.Neckline),
+		SleeveType:     deref(in.SleeveType),
+		Size:           deref(in.Size),
+		Color:          deref(in.Color),
+		MinPrice:       in.MinPrice,
+		MaxPrice:       in.MaxPrice,
+		Featured:       in.Featured,
+		LimitedEdition: in.LimitedEdition,
+		InStock:        in.InStock,
+	}
+}
+
+func toProductSort(in *model.ProductSort) repository.ProductSort {
+	if in == nil {
+		return repository.ProductSortNewest
+	}
+
+	switch *in {
+	case model.ProductSortPriceAsc:
+		return repository.ProductSortPriceAsc
+	case model.ProductSortPriceDesc:
+		return repository.ProductSortPriceDesc
+	case model.ProductSortPopularity:
+		return repository.ProductSortPopularity
+	default:
+		return repository.ProductSortNewest
+	}
+}
+
+// toFacetValues splits facet counts into the plain value list and the
+// value/count pairs exposed on ProductOptions.
+func toFacetValues(counts []repository.FacetCount) ([]string, []*model.FacetValue) {
+	values := make([]string, len(counts))
+	facets := make([]*model.FacetValue, len(counts))
+	for i, c := range counts {
+		values[i] = c.Value
+		facets[i] = &model.FacetValue{Value: c.Value, Count: int(c.Count)}
+	}
+	return values, facets
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/vishnujoshi062/tshirt-ecommerce-api/graph/generated"
 	"github.com/vishnujoshi062/tshirt-ecommerce-api/graph/model"
 	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
 )
 
 // CreatePromoCode is the resolver for the createPromoCode field.
@@ -32,6 +33,12 @@ func (r *mutationResolver) TogglePromoCodeStatus(ctx context.Context, id string)
 	return r.Resolver.PromoCodeService.ToggleStatus(ctx, id)
 }
 
+// DiscountValue is the resolver for the discountValue field.
+func (r *promoCodeResolver) DiscountValue(ctx context.Context, obj *models.PromoCode) (float64, error) {
+	// Stored in hundredths: basis points or paise
+	return float64(obj.DiscountValue) / 100, nil
+}
+
 // ValidFrom is the resolver for the validFrom field.
 func (r *promoCodeResolver) ValidFrom(ctx context.Context, obj *models.PromoCode) (*string, error) {
 	if obj.ValidFrom == nil {
@@ -71,7 +78,7 @@ func (r *queryResolver) PromoCode(ctx context.Context, code string) (*models.Pro
 }
 
 // ValidatePromoCode is the resolver for the validatePromoCode field.
-func (r *queryResolver) ValidatePromoCode(ctx context.Context, code string, orderAmount float64) (*model.PromoCodeValidation, error) {
+func (r *queryResolver) ValidatePromoCode(ctx context.Context, code string, orderAmount money.Money) (*model.PromoCodeValidation, error) {
 	result, err := r.Resolver.PromoCodeService.ValidatePromoCode(code, orderAmount)
 	if err != nil {
 		return nil, err
@@ -1,6 +1,8 @@
 package graph
 
 import (
+	"time"
+
 	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/repository"
 	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/service"
 	"gorm.io/gorm"
@@ -11,13 +13,35 @@ import (
 // It serves as dependency injection for your app, add any dependencies you require here.
 
 type Resolver struct {
-	DB                *gorm.DB
-	UserRepository    *repository.UserRepository
-	ProductRepository *repository.ProductRepository
-	CartRepository    *repository.CartRepository
-	OrderRepository   *repository.OrderRepository
-	PaymentRepository *repository.PaymentRepository
-	PaymentService    *service.PaymentService
-	PromoCodeRepo    *repository.PromoCodeRepository
-	PromoCodeService *service.PromoService 
+	DB                    *gorm.DB
+	UserRepository        *repository.UserRepository
+	ProductRepository     *repository.ProductRepository
+	CartRepository        *repository.CartRepository
+	OrderRepository       *repository.OrderRepository
+	PaymentRepository     *repository.PaymentRepository
+	PaymentService        *service.PaymentService
+	PromoCodeRepo         *repository.PromoCodeRepository
+	PromoCodeService      *service.PromoService
+	InventoryRepository   *repository.InventoryRepository
+	InventoryService      *service.InventoryService
+	OrderService          *service.OrderService
+	RefundRepository      *repository.RefundRepository
+	ProductOptionsService *service.ProductOptionsService
+	CartService           *service.CartService
+	UserService           *service.UserService
+	AddressRepository     *repository.AddressRepository
+	AddressService        *service.AddressService
+	InvoiceService        *service.InvoiceService
+	TaxService            *service.TaxService
+	ShippingService       *service.ShippingService
+	ShipmentRepository    *repository.ShipmentRepository
+	ShipmentService       *service.ShipmentService
+	ReturnRepository      *repository.ReturnRepository
+	ReturnService         *service.ReturnService
+	EventBus              *service.EventBus
+	WebhookEndpointRepo   *repository.WebhookEndpointRepository
+	WebhookService        *service.WebhookService
+	CartRecoveryService   *service.CartRecoveryService
+	WishlistService       *service.WishlistService
+	GuestSessionTTL       time.Duration
 }
@@ -0,0 +1,238 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.83
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/graph/generated"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/graph/model"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/loaders"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/middleware"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/service"
+	"gorm.io/gorm"
+)
+
+// RequestReturn is the resolver for the requestReturn field.
+func (r *mutationResolver) RequestReturn(ctx context.Context, input model.ReturnRequestInput) (*models.ReturnRequest, error) {
+	user, err := middleware.RequireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	itemID, err := strconv.ParseUint(input.OrderItemID, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid order item ID")
+	}
+	in := service.ReturnInput{
+		OrderItemID: uint(itemID),
+		Quantity:    input.Quantity,
+		Reason:      input.Reason,
+		Comment:     input.Comment,
+		Photos:      input.Photos,
+	}
+	if input.ExchangeVariantID != nil {
+		variantID, err := strconv.ParseUint(*input.ExchangeVariantID, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exchange variant ID")
+		}
+		id := uint(variantID)
+		in.ExchangeVariantID = &id
+	}
+
+	req, err := r.ReturnService.RequestReturn(user.UserID, in)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("order item not found")
+	}
+	return req, err
+}
+
+// CancelReturn is the resolver for the cancelReturn field.
+func (r *mutationResolver) CancelReturn(ctx context.Context, id string) (*models.ReturnRequest, error) {
+	returnID, err := parseReturnID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := r.ReturnRepository.GetReturnRequestByID(returnID)
+	if err != nil {
+		return returnResult(nil, err)
+	}
+	if err := middleware.RequireOwner(ctx, req.UserID, constants.RoleSupport); err != nil {
+		return nil, err
+	}
+
+	return returnResult(r.ReturnService.CancelReturn(returnID))
+}
+
+// ApproveReturn is the resolver for the approveReturn field.
+func (r *mutationResolver) ApproveReturn(ctx context.Context, id string) (*models.ReturnRequest, error) {
+	returnID, err := parseReturnID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return returnResult(r.ReturnService.Approve(returnID))
+}
+
+// RejectReturn is the resolver for the rejectReturn field.
+func (r *mutationResolver) RejectReturn(ctx context.Context, id string, reason string) (*models.ReturnRequest, error) {
+	returnID, err := parseReturnID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return returnResult(r.ReturnService.Reject(returnID, reason))
+}
+
+// ScheduleReturnPickup is the resolver for the scheduleReturnPickup field.
+func (r *mutationResolver) ScheduleReturnPickup(ctx context.Context, id string, trackingNumber *string) (*models.ReturnRequest, error) {
+	returnID, err := parseReturnID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	tracking := ""
+	if trackingNumber != nil {
+		tracking = *trackingNumber
+	}
+	return returnResult(r.ReturnService.SchedulePickup(returnID, tracking))
+}
+
+// ReceiveReturn is the resolver for the receiveReturn field.
+func (r *mutationResolver) ReceiveReturn(ctx context.Context, id string) (*models.ReturnRequest, error) {
+	returnID, err := parseReturnID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return returnResult(r.ReturnService.MarkReceived(returnID))
+}
+
+// InspectReturn is the resolver for the inspectReturn field.
+func (r *mutationResolver) InspectReturn(ctx context.Context, id string, passed bool, notes *string) (*models.ReturnRequest, error) {
+	returnID, err := parseReturnID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	inspection := ""
+	if notes != nil {
+		inspection = *notes
+	}
+	return returnResult(r.ReturnService.Inspect(returnID, passed, inspection))
+}
+
+// CompleteReturn is the resolver for the completeReturn field.
+func (r *mutationResolver) CompleteReturn(ctx context.Context, id string) (*models.ReturnRequest, error) {
+	returnID, err := parseReturnID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return returnResult(r.ReturnService.Complete(returnID))
+}
+
+// MyReturns is the resolver for the myReturns field.
+func (r *queryResolver) MyReturns(ctx context.Context) ([]*models.ReturnRequest, error) {
+	user, err := middleware.RequireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reqs, err := r.ReturnRepository.GetReturnRequestsByUserID(user.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return returnRequestList(reqs), nil
+}
+
+// ReturnRequests is the resolver for the returnRequests field.
+func (r *queryResolver) ReturnRequests(ctx context.Context, status *string, resolution *string) ([]*models.ReturnRequest, error) {
+	reqs, err := r.ReturnRepository.GetReturnRequests(status, resolution)
+	if err != nil {
+		return nil, err
+	}
+	return returnRequestList(reqs), nil
+}
+
+// ID is the resolver for the id field.
+func (r *returnRequestResolver) ID(ctx context.Context, obj *models.ReturnRequest) (string, error) {
+	return strconv.FormatUint(uint64(obj.ID), 10), nil
+}
+
+// OrderID is the resolver for the orderID field.
+func (r *returnRequestResolver) OrderID(ctx context.Context, obj *models.ReturnRequest) (string, error) {
+	return strconv.FormatUint(uint64(obj.OrderID), 10), nil
+}
+
+// OrderItemID is the resolver for the orderItemID field.
+func (r *returnRequestResolver) OrderItemID(ctx context.Context, obj *models.ReturnRequest) (string, error) {
+	return strconv.FormatUint(uint64(obj.OrderItemID), 10), nil
+}
+
+// OrderItem is the resolver for the orderItem field.
+func (r *returnRequestResolver) OrderItem(ctx context.Context, obj *models.ReturnRequest) (*models.OrderItem, error) {
+	if obj.OrderItem != nil {
+		return obj.OrderItem, nil
+	}
+
+	items, err := loaders.GetOrderItems(ctx, obj.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		if item.ID == obj.OrderItemID {
+			return item, nil
+		}
+	}
+	return nil, fmt.Errorf("order item %d not found", obj.OrderItemID)
+}
+
+// Photos is the resolver for the photos field.
+func (r *returnRequestResolver) Photos(ctx context.Context, obj *models.ReturnRequest) ([]string, error) {
+	if obj.Photos == nil {
+		return []string{}, nil
+	}
+	return obj.Photos, nil
+}
+
+// ExchangeVariant is the resolver for the exchangeVariant field.
+func (r *returnRequestResolver) ExchangeVariant(ctx context.Context, obj *models.ReturnRequest) (*models.ProductVariant, error) {
+	if obj.ExchangeVariantID == nil {
+		return nil, nil
+	}
+
+	return loaders.GetVariant(ctx, *obj.ExchangeVariantID)
+}
+
+// Refund is the resolver for the refund field.
+func (r *returnRequestResolver) Refund(ctx context.Context, obj *models.ReturnRequest) (*models.Refund, error) {
+	if obj.RefundID == nil {
+		return nil, nil
+	}
+
+	return r.RefundRepository.GetRefundByID(*obj.RefundID)
+}
+
+// CreatedAt is the resolver for the createdAt field.
+func (r *returnRequestResolver) CreatedAt(ctx context.Context, obj *models.ReturnRequest) (string, error) {
+	return obj.CreatedAt.Format("2006-01-02T15:04:05Z07:00"), nil
+}
+
+// UpdatedAt is the resolver for the updatedAt field.
+func (r *returnRequestResolver) UpdatedAt(ctx context.Context, obj *models.ReturnRequest) (string, error) {
+	return obj.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"), nil
+}
+
+// ReturnRequest returns generated.ReturnRequestResolver implementation.
+func (r *Resolver) ReturnRequest() generated.ReturnRequestResolver { return &returnRequestResolver{r} }
+
+type returnRequestResolver struct{ *Resolver }
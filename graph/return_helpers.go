@@ -0,0 +1,35 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// parseReturnID reads the ID of a return request.
+func parseReturnID(id string) (uint, error) {
+	n, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid return request ID")
+	}
+	return uint(n), nil
+}
+
+// returnResult turns a missing return request into a readable error.
+func returnResult(req *models.ReturnRequest, err error) (*models.ReturnRequest, error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("return request not found")
+	}
+	return req, err
+}
+
+func returnRequestList(reqs []models.ReturnRequest) []*models.ReturnRequest {
+	out := make([]*models.ReturnRequest, len(reqs))
+	for i := range reqs {
+		out[i] = &reqs[i]
+	}
+	return out
+}
@@ -0,0 +1,94 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.83
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/graph/generated"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/middleware"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// CreateShipment is the resolver for the createShipment field.
+func (r *mutationResolver) CreateShipment(ctx context.Context, orderID string) (*models.Shipment, error) {
+	id, err := strconv.ParseUint(orderID, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid order ID")
+	}
+
+	return r.ShipmentService.CreateShipment(ctx, uint(id))
+}
+
+// TrackShipment is the resolver for the trackShipment field.
+func (r *queryResolver) TrackShipment(ctx context.Context, orderID string) (*models.Shipment, error) {
+	id, err := strconv.ParseUint(orderID, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid order ID")
+	}
+
+	order, err := r.OrderRepository.GetOrderByID(uint(id))
+	if err != nil {
+		return nil, fmt.Errorf("order not found")
+	}
+	if err := middleware.RequireOwner(ctx, order.UserID, constants.RoleSupport, constants.RoleFulfillment); err != nil {
+		return nil, err
+	}
+
+	shipment, err := r.ShipmentService.TrackShipment(ctx, order.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("order %d has not been shipped yet", order.ID)
+	}
+	return shipment, err
+}
+
+// ID is the resolver for the id field.
+func (r *shipmentResolver) ID(ctx context.Context, obj *models.Shipment) (string, error) {
+	return strconv.FormatUint(uint64(obj.ID), 10), nil
+}
+
+// OrderID is the resolver for the orderID field.
+func (r *shipmentResolver) OrderID(ctx context.Context, obj *models.Shipment) (string, error) {
+	return strconv.FormatUint(uint64(obj.OrderID), 10), nil
+}
+
+// EstimatedDelivery is the resolver for the estimatedDelivery field.
+func (r *shipmentResolver) EstimatedDelivery(ctx context.Context, obj *models.Shipment) (*string, error) {
+	return formatOptionalTime(obj.EstimatedDelivery), nil
+}
+
+// DeliveredAt is the resolver for the deliveredAt field.
+func (r *shipmentResolver) DeliveredAt(ctx context.Context, obj *models.Shipment) (*string, error) {
+	return formatOptionalTime(obj.DeliveredAt), nil
+}
+
+// CreatedAt is the resolver for the createdAt field.
+func (r *shipmentResolver) CreatedAt(ctx context.Context, obj *models.Shipment) (string, error) {
+	return obj.CreatedAt.Format("2006-01-02T15:04:05Z07:00"), nil
+}
+
+// UpdatedAt is the resolver for the updatedAt field.
+func (r *shipmentResolver) UpdatedAt(ctx context.Context, obj *models.Shipment) (string, error) {
+	return obj.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"), nil
+}
+
+// OccurredAt is the resolver for the occurredAt field.
+func (r *shipmentEventResolver) OccurredAt(ctx context.Context, obj *models.ShipmentEvent) (string, error) {
+	return obj.OccurredAt.Format("2006-01-02T15:04:05Z07:00"), nil
+}
+
+// Shipment returns generated.ShipmentResolver implementation.
+func (r *Resolver) Shipment() generated.ShipmentResolver { return &shipmentResolver{r} }
+
+// ShipmentEvent returns generated.ShipmentEventResolver implementation.
+func (r *Resolver) ShipmentEvent() generated.ShipmentEventResolver { return &shipmentEventResolver{r} }
+
+type shipmentResolver struct{ *Resolver }
+type shipmentEventResolver struct{ *Resolver }
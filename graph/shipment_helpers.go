@@ -0,0 +1,15 @@
+package graph
+
+import (
+	"time"
+)
+
+// formatOptionalTime formats t like the other timestamps in the API, or
+// returns nil if it isn't set.
+func formatOptionalTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	s := t.Format("2006-01-02T15:04:05Z07:00")
+	return &s
+}
@@ -0,0 +1,48 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.83
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/middleware"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/shipping"
+)
+
+// ShippingOptions is the resolver for the shippingOptions field.
+func (r *queryResolver) ShippingOptions(ctx context.Context, cartID string, postalCode string) ([]*shipping.Option, error) {
+	id, err := strconv.ParseUint(cartID, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cart ID")
+	}
+
+	var cart models.Cart
+	if err := r.DB.
+		Preload("CartItems").
+		Preload("CartItems.Variant").
+		Preload("CartItems.Variant.Product").
+		First(&cart, uint(id)).Error; err != nil {
+		return nil, fmt.Errorf("cart not found")
+	}
+
+	owner, _ := currentCartOwner(ctx)
+	if !owner.owns(ctx, &cart) {
+		return nil, middleware.ErrForbidden
+	}
+
+	options, err := r.ShippingService.Options(cart.CartItems, postalCode)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*shipping.Option, len(options))
+	for i := range options {
+		result[i] = &options[i]
+	}
+	return result, nil
+}
@@ -0,0 +1,209 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/graph/generated"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/auth"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/loaders"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/middleware"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/repository"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/service"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/tax"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/testdb"
+	"gorm.io/gorm"
+)
+
+const productListingQuery = `{
+	products(first: 20) {
+		totalCount
+		edges {
+			node {
+				id
+				name
+				basePrice
+				imageURLs
+				variants {
+					id
+					size
+					color
+					price
+					inventory { availableQuantity }
+					product { id name }
+				}
+			}
+		}
+	}
+}`
+
+const guestCartQuery = `{
+	getCart(forUser: true) {
+		id
+		items { id productId quantity unitPrice }
+		subtotal
+		taxAmount
+		taxIncluded
+		totalAmount
+	}
+}`
+
+// countQueries counts every statement db sends from now on.
+func countQueries(t *testing.T, db *gorm.DB) *atomic.Int64 {
+	t.Helper()
+	var n atomic.Int64
+	count := func(*gorm.DB) { n.Add(1) }
+	for name, err := range map[string]error{
+		"query": db.Callback().Query().After("gorm:query").Register("test:count_queries", count),
+		"row":   db.Callback().Row().After("gorm:row").Register("test:count_queries", count),
+		"raw":   db.Callback().Raw().After("gorm:raw").Register("test:count_queries", count),
+	} {
+		if err != nil {
+			t.Fatalf("register %s callback: %v", name, err)
+		}
+	}
+	return &n
+}
+
+func newStorefrontServer(db *gorm.DB) http.Handler {
+	srv := handler.New(generated.NewExecutableSchema(generated.Config{
+		Resolvers: &Resolver{
+			DB:                db,
+			ProductRepository: repository.NewProductRepository(db),
+			CartRepository:    repository.NewCartRepository(db),
+			TaxService:        service.NewTaxService(tax.Default()),
+		},
+		Directives: generated.DirectiveRoot{
+			Auth: AuthDirective,
+		},
+	}))
+	srv.AddTransport(transport.POST{})
+	srv.AroundFields(AnonymousAccess)
+	return loaders.Middleware(db)(srv)
+}
+
+func createProducts(t *testing.T, db *gorm.DB, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		product := &models.Product{Name: fmt.Sprintf("Tee %d", i), BasePrice: money.INR(49900)}
+		if err := db.Create(product).Error; err != nil {
+			t.Fatal(err)
+		}
+		for _, size := range []string{"S", "M", "L"} {
+			variant := &models.ProductVariant{ProductID: product.ID, Size: size, SKU: fmt.Sprintf("TEE-%d-%s", i, size)}
+			if err := db.Create(variant).Error; err != nil {
+				t.Fatal(err)
+			}
+			if err := db.Create(&models.Inventory{VariantID: variant.ID, StockQuantity: 5}).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestProductListingQueryCount(t *testing.T) {
+	for _, products := range []int{1, 12} {
+		t.Run(fmt.Sprintf("%d products", products), func(t *testing.T) {
+			db := testdb.Open(t)
+			createProducts(t, db, products)
+			c := client.New(newStorefrontServer(db))
+			queries := countQueries(t, db)
+
+			var resp struct {
+				Products struct {
+					TotalCount int
+					Edges      []struct {
+						Node struct {
+							ID        string
+							Name      string
+							BasePrice interface{}
+							ImageURLs []string
+							Variants  []struct {
+								ID        string
+								Size      string
+								Color     *string
+								Price     interface{}
+								Inventory struct{ AvailableQuantity int }
+								Product   struct{ ID, Name string }
+							}
+						}
+					}
+				}
+			}
+			if err := c.Post(productListingQuery, &resp); err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Products.Edges) != products {
+				t.Fatalf("%d products listed, want %d", len(resp.Products.Edges), products)
+			}
+			if got := resp.Products.Edges[0].Node.Variants[0].Inventory.AvailableQuantity; got != 5 {
+				t.Fatalf("available quantity = %d, want 5", got)
+			}
+
+			// Count, page keys, products, their variants and inventory, and
+			// one batched load for the variants' product field
+			if got := queries.Load(); got != 6 {
+				t.Fatalf("listing ran %d queries, want 6 however many products it shows", got)
+			}
+		})
+	}
+}
+
+func TestGuestCartQueryCount(t *testing.T) {
+	db := testdb.Open(t)
+	createProducts(t, db, 3)
+	session := &auth.GuestSession{ID: "guest_1"}
+	cart := &models.Cart{GuestSessionID: &session.ID}
+	if err := db.Create(cart).Error; err != nil {
+		t.Fatal(err)
+	}
+	var variants []models.ProductVariant
+	if err := db.Find(&variants).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range variants {
+		if err := db.Create(&models.CartItem{CartID: cart.ID, VariantID: v.ID, Quantity: 1}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	srv := newStorefrontServer(db)
+	c := client.New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), middleware.GuestSessionContextKey, session)
+		srv.ServeHTTP(w, r.WithContext(ctx))
+	}))
+	queries := countQueries(t, db)
+
+	var resp struct {
+		GetCart struct {
+			ID    string
+			Items []struct {
+				ID, ProductID string
+				Quantity      int
+				UnitPrice     interface{}
+			}
+			Subtotal, TaxAmount, TotalAmount interface{}
+			TaxIncluded                      bool
+		}
+	}
+	if err := c.Post(guestCartQuery, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.GetCart.Items) != len(variants) {
+		t.Fatalf("%d cart items, want %d", len(resp.GetCart.Items), len(variants))
+	}
+
+	// The cart with its items, then the items with their variants and
+	// products once for the item list and all three totals
+	if got := queries.Load(); got != 5 {
+		t.Fatalf("cart ran %d queries, want 5", got)
+	}
+}
@@ -9,15 +9,87 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/vishnujoshi062/tshirt-ecommerce-api/graph/generated"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/graph/model"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
 	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/middleware"
 	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/repository"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/service"
 )
 
 // UpdateProfile is the resolver for the updateProfile field.
 func (r *mutationResolver) UpdateProfile(ctx context.Context, name *string, phone *string, address *string) (*models.User, error) {
-	panic(fmt.Errorf("not implemented: UpdateProfile - updateProfile"))
+	claims, err := middleware.RequireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := r.UserRepository.GetUserByClerkID(claims.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if name != nil {
+		if strings.TrimSpace(*name) == "" {
+			return nil, errors.New("name cannot be empty")
+		}
+		user.Name = strings.TrimSpace(*name)
+	}
+	if phone != nil {
+		user.Phone = ""
+		if strings.TrimSpace(*phone) != "" {
+			if user.Phone, err = service.NormalizeIndianPhone(*phone); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if address != nil {
+		user.Address = strings.TrimSpace(*address)
+	}
+
+	if err := r.UserRepository.UpdateUser(user); err != nil {
+		return nil, fmt.Errorf("failed to update profile: %w", err)
+	}
+
+	return user, nil
+}
+
+// UpdateEmailPreferences is the resolver for the updateEmailPreferences field.
+func (r *mutationResolver) UpdateEmailPreferences(ctx context.Context, marketingEmails bool) (*models.User, error) {
+	claims, err := middleware.RequireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := r.UserRepository.GetUserByClerkID(claims.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	user.MarketingEmails = marketingEmails
+	if err := r.UserRepository.UpdateUser(user); err != nil {
+		return nil, fmt.Errorf("failed to update email preferences: %w", err)
+	}
+
+	return user, nil
+}
+
+// SetUserRole is the resolver for the setUserRole field.
+func (r *mutationResolver) SetUserRole(ctx context.Context, userID string, role model.Role) (*models.User, error) {
+	user, err := r.userByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.UserService.SetRole(ctx, user, roleName(role)); err != nil {
+		return nil, err
+	}
+
+	return user, nil
 }
 
 // Me is the resolver for the me field.
@@ -28,8 +100,8 @@ func (r *queryResolver) Me(ctx context.Context) (*models.User, error) {
 		return nil, errors.New("unauthorized: no valid token provided")
 	}
 
-	// Get user from database
-	user, err := r.UserRepository.GetUserByID(claims.UserID)
+	// Get user from database; UserSyncMiddleware has provisioned it
+	user, err := r.UserRepository.GetUserByClerkID(claims.UserID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
@@ -39,7 +111,52 @@ func (r *queryResolver) Me(ctx context.Context) (*models.User, error) {
 
 // GetUser is the resolver for the getUser field.
 func (r *queryResolver) GetUser(ctx context.Context, id string) (*models.User, error) {
-	panic(fmt.Errorf("not implemented: GetUser - getUser"))
+	user, err := r.userByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// ListUsers is the resolver for the listUsers field.
+func (r *queryResolver) ListUsers(ctx context.Context, search *string, role *model.Role, first *int, after *string) (*model.UserConnection, error) {
+	pageSize := repository.DefaultUserPageSize
+	if first != nil {
+		pageSize = *first
+	}
+
+	userFilter := repository.UserFilter{Search: deref(search)}
+	if role != nil {
+		userFilter.Role = roleName(*role)
+	}
+
+	page, err := r.UserRepository.ListUsers(userFilter, pageSize, deref(after))
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	conn := &model.UserConnection{
+		Edges:      make([]*model.UserEdge, len(page.Users)),
+		PageInfo:   &model.PageInfo{HasNextPage: page.HasNextPage},
+		TotalCount: int(page.TotalCount),
+	}
+
+	for i := range page.Users {
+		conn.Edges[i] = &model.UserEdge{
+			Cursor: page.Cursors[i],
+			Node:   &page.Users[i],
+		}
+	}
+
+	if n := len(page.Cursors); n > 0 {
+		conn.PageInfo.EndCursor = &page.Cursors[n-1]
+	}
+
+	return conn, nil
 }
 
 // ID is the resolver for the id field.
@@ -47,6 +164,46 @@ func (r *userResolver) ID(ctx context.Context, obj *models.User) (string, error)
 	return strconv.FormatUint(uint64(obj.ID), 10), nil
 }
 
+// Orders is the resolver for the orders field.
+func (r *userResolver) Orders(ctx context.Context, obj *models.User) ([]*models.Order, error) {
+	if obj.ClerkUserID == nil {
+		return []*models.Order{}, nil
+	}
+
+	if err := middleware.RequireOwner(ctx, *obj.ClerkUserID, constants.RoleSupport, constants.RoleFulfillment); err != nil {
+		return nil, err
+	}
+
+	orders, err := r.OrderRepository.GetOrdersByUserID(*obj.ClerkUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]*models.Order, len(orders))
+	for i := range orders {
+		out[i] = &orders[i]
+	}
+	return out, nil
+}
+
+// LifetimeSpend is the resolver for the lifetimeSpend field.
+func (r *userResolver) LifetimeSpend(ctx context.Context, obj *models.User) (*money.Money, error) {
+	if obj.ClerkUserID == nil {
+		zero := money.INR(0)
+		return &zero, nil
+	}
+
+	if err := middleware.RequireOwner(ctx, *obj.ClerkUserID, constants.RoleSupport, constants.RoleFulfillment); err != nil {
+		return nil, err
+	}
+
+	total, err := r.OrderRepository.GetLifetimeSpend(*obj.ClerkUserID)
+	if err != nil {
+		return nil, err
+	}
+	return &total, nil
+}
+
 // CreatedAt is the resolver for the createdAt field.
 func (r *userResolver) CreatedAt(ctx context.Context, obj *models.User) (string, error) {
 	return obj.CreatedAt.Format("2006-01-02T15:04:05Z07:00"), nil
@@ -0,0 +1,23 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// userByID loads a user by their local (not Clerk) ID.
+func (r *Resolver) userByID(id string) (*models.User, error) {
+	if _, err := strconv.ParseUint(id, 10, 32); err != nil {
+		return nil, fmt.Errorf("invalid user ID")
+	}
+
+	user, err := r.UserRepository.GetUserByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("user not found")
+	}
+	return user, err
+}
@@ -0,0 +1,216 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.83
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/graph/generated"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/graph/model"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/service"
+	"gorm.io/gorm"
+)
+
+// CreateWebhookEndpoint is the resolver for the createWebhookEndpoint field.
+func (r *mutationResolver) CreateWebhookEndpoint(ctx context.Context, input model.CreateWebhookEndpointInput) (*model.WebhookEndpointSecret, error) {
+	endpoint, err := r.WebhookService.CreateEndpoint(service.WebhookEndpointInput{
+		URL:         &input.URL,
+		Description: input.Description,
+		Events:      input.Events,
+		Secret:      input.Secret,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &model.WebhookEndpointSecret{Endpoint: endpoint, Secret: endpoint.Secret}, nil
+}
+
+// UpdateWebhookEndpoint is the resolver for the updateWebhookEndpoint field.
+func (r *mutationResolver) UpdateWebhookEndpoint(ctx context.Context, id string, input model.UpdateWebhookEndpointInput) (*models.WebhookEndpoint, error) {
+	endpointID, err := parseWebhookID(id)
+	if err != nil {
+		return nil, err
+	}
+	return webhookEndpointResult(r.WebhookService.UpdateEndpoint(endpointID, service.WebhookEndpointInput{
+		URL:         input.URL,
+		Description: input.Description,
+		Events:      input.Events,
+		Active:      input.Active,
+	}))
+}
+
+// DeleteWebhookEndpoint is the resolver for the deleteWebhookEndpoint field.
+func (r *mutationResolver) DeleteWebhookEndpoint(ctx context.Context, id string) (bool, error) {
+	endpointID, err := parseWebhookID(id)
+	if err != nil {
+		return false, err
+	}
+	if _, err := webhookEndpointResult(nil, r.WebhookService.DeleteEndpoint(endpointID)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RotateWebhookSecret is the resolver for the rotateWebhookSecret field.
+func (r *mutationResolver) RotateWebhookSecret(ctx context.Context, id string) (*model.WebhookEndpointSecret, error) {
+	endpointID, err := parseWebhookID(id)
+	if err != nil {
+		return nil, err
+	}
+	endpoint, err := webhookEndpointResult(r.WebhookService.RotateSecret(endpointID))
+	if err != nil {
+		return nil, err
+	}
+	return &model.WebhookEndpointSecret{Endpoint: endpoint, Secret: endpoint.Secret}, nil
+}
+
+// ReplayWebhookDelivery is the resolver for the replayWebhookDelivery field.
+func (r *mutationResolver) ReplayWebhookDelivery(ctx context.Context, id string) (*models.WebhookDelivery, error) {
+	deliveryID, err := parseWebhookID(id)
+	if err != nil {
+		return nil, err
+	}
+	delivery, err := r.WebhookService.Replay(deliveryID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("webhook delivery or its endpoint not found")
+	}
+	return delivery, err
+}
+
+// WebhookEndpoints is the resolver for the webhookEndpoints field.
+func (r *queryResolver) WebhookEndpoints(ctx context.Context) ([]*models.WebhookEndpoint, error) {
+	endpoints, err := r.WebhookEndpointRepo.GetEndpoints()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*models.WebhookEndpoint, len(endpoints))
+	for i := range endpoints {
+		out[i] = &endpoints[i]
+	}
+	return out, nil
+}
+
+// WebhookEndpoint is the resolver for the webhookEndpoint field.
+func (r *queryResolver) WebhookEndpoint(ctx context.Context, id string) (*models.WebhookEndpoint, error) {
+	endpointID, err := parseWebhookID(id)
+	if err != nil {
+		return nil, err
+	}
+	endpoint, err := r.WebhookEndpointRepo.GetEndpointByID(endpointID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return endpoint, err
+}
+
+// WebhookEventTypes is the resolver for the webhookEventTypes field.
+func (r *queryResolver) WebhookEventTypes(ctx context.Context) ([]string, error) {
+	return service.WebhookEventTypes, nil
+}
+
+// ID is the resolver for the id field.
+func (r *webhookDeliveryResolver) ID(ctx context.Context, obj *models.WebhookDelivery) (string, error) {
+	return strconv.FormatUint(uint64(obj.ID), 10), nil
+}
+
+// EndpointID is the resolver for the endpointID field.
+func (r *webhookDeliveryResolver) EndpointID(ctx context.Context, obj *models.WebhookDelivery) (string, error) {
+	return strconv.FormatUint(uint64(obj.EndpointID), 10), nil
+}
+
+// EventID is the resolver for the eventID field.
+func (r *webhookDeliveryResolver) EventID(ctx context.Context, obj *models.WebhookDelivery) (*string, error) {
+	return optionalID(obj.EventID), nil
+}
+
+// Payload is the resolver for the payload field.
+func (r *webhookDeliveryResolver) Payload(ctx context.Context, obj *models.WebhookDelivery) (string, error) {
+	return string(obj.Payload), nil
+}
+
+// NextAttemptAt is the resolver for the nextAttemptAt field.
+func (r *webhookDeliveryResolver) NextAttemptAt(ctx context.Context, obj *models.WebhookDelivery) (*string, error) {
+	if obj.Status != constants.WebhookDeliveryPending {
+		return nil, nil
+	}
+	return formatOptionalTime(&obj.NextAttemptAt), nil
+}
+
+// ReplayOfID is the resolver for the replayOfID field.
+func (r *webhookDeliveryResolver) ReplayOfID(ctx context.Context, obj *models.WebhookDelivery) (*string, error) {
+	return optionalID(obj.ReplayOfID), nil
+}
+
+// DeliveredAt is the resolver for the deliveredAt field.
+func (r *webhookDeliveryResolver) DeliveredAt(ctx context.Context, obj *models.WebhookDelivery) (*string, error) {
+	return formatOptionalTime(obj.DeliveredAt), nil
+}
+
+// CreatedAt is the resolver for the createdAt field.
+func (r *webhookDeliveryResolver) CreatedAt(ctx context.Context, obj *models.WebhookDelivery) (string, error) {
+	return obj.CreatedAt.Format("2006-01-02T15:04:05Z07:00"), nil
+}
+
+// ID is the resolver for the id field.
+func (r *webhookEndpointResolver) ID(ctx context.Context, obj *models.WebhookEndpoint) (string, error) {
+	return strconv.FormatUint(uint64(obj.ID), 10), nil
+}
+
+// Events is the resolver for the events field.
+func (r *webhookEndpointResolver) Events(ctx context.Context, obj *models.WebhookEndpoint) ([]string, error) {
+	if obj.Events == nil {
+		return []string{}, nil
+	}
+	return obj.Events, nil
+}
+
+// Deliveries is the resolver for the deliveries field.
+func (r *webhookEndpointResolver) Deliveries(ctx context.Context, obj *models.WebhookEndpoint, status *string, limit *int) ([]*models.WebhookDelivery, error) {
+	n := 50
+	if limit != nil {
+		n = *limit
+	}
+	if n < 1 || n > maxWebhookDeliveries {
+		return nil, fmt.Errorf("limit must be between 1 and %d", maxWebhookDeliveries)
+	}
+
+	deliveries, err := r.WebhookEndpointRepo.GetDeliveries(obj.ID, status, n)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*models.WebhookDelivery, len(deliveries))
+	for i := range deliveries {
+		out[i] = &deliveries[i]
+	}
+	return out, nil
+}
+
+// CreatedAt is the resolver for the createdAt field.
+func (r *webhookEndpointResolver) CreatedAt(ctx context.Context, obj *models.WebhookEndpoint) (string, error) {
+	return obj.CreatedAt.Format("2006-01-02T15:04:05Z07:00"), nil
+}
+
+// UpdatedAt is the resolver for the updatedAt field.
+func (r *webhookEndpointResolver) UpdatedAt(ctx context.Context, obj *models.WebhookEndpoint) (string, error) {
+	return obj.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"), nil
+}
+
+// WebhookDelivery returns generated.WebhookDeliveryResolver implementation.
+func (r *Resolver) WebhookDelivery() generated.WebhookDeliveryResolver {
+	return &webhookDeliveryResolver{r}
+}
+
+// WebhookEndpoint returns generated.WebhookEndpointResolver implementation.
+func (r *Resolver) WebhookEndpoint() generated.WebhookEndpointResolver {
+	return &webhookEndpointResolver{r}
+}
+
+type webhookDeliveryResolver struct{ *Resolver }
+type webhookEndpointResolver struct{ *Resolver }
@@ -0,0 +1,38 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// maxWebhookDeliveries caps how much of a delivery log one query returns.
+const maxWebhookDeliveries = 200
+
+// parseWebhookID reads the ID of a webhook endpoint or delivery.
+func parseWebhookID(id string) (uint, error) {
+	n, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid webhook ID")
+	}
+	return uint(n), nil
+}
+
+// webhookEndpointResult turns a missing endpoint into a readable error.
+func webhookEndpointResult(endpoint *models.WebhookEndpoint, err error) (*models.WebhookEndpoint, error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("webhook endpoint not found")
+	}
+	return endpoint, err
+}
+
+func optionalID(id *uint) *string {
+	if id == nil {
+		return nil
+	}
+	s := strconv.FormatUint(uint64(*id), 10)
+	return &s
+}
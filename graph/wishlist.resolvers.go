@@ -0,0 +1,228 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.83
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/graph/generated"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/graph/model"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/loaders"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/middleware"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/service"
+)
+
+// AddToWishlist is the resolver for the addToWishlist field.
+func (r *mutationResolver) AddToWishlist(ctx context.Context, input model.AddToWishlistInput) (*models.WishlistItem, error) {
+	user, err := middleware.RequireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	productID, err := parseWishlistID(input.ProductID, "product ID")
+	if err != nil {
+		return nil, err
+	}
+	variantID, err := parseOptionalWishlistID(input.VariantID, "variant ID")
+	if err != nil {
+		return nil, err
+	}
+
+	return r.WishlistService.Add(user.UserID, service.WishlistInput{
+		ProductID:         productID,
+		VariantID:         variantID,
+		NotifyBackInStock: input.NotifyBackInStock,
+		NotifyPriceDrop:   input.NotifyPriceDrop,
+	})
+}
+
+// RemoveFromWishlist is the resolver for the removeFromWishlist field.
+func (r *mutationResolver) RemoveFromWishlist(ctx context.Context, itemID string) (*models.Wishlist, error) {
+	user, err := middleware.RequireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, err := parseWishlistID(itemID, "wishlist item ID")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.WishlistService.Remove(user.UserID, id); err != nil {
+		return nil, err
+	}
+	return r.WishlistService.GetWishlist(user.UserID)
+}
+
+// SetWishlistAlerts is the resolver for the setWishlistAlerts field.
+func (r *mutationResolver) SetWishlistAlerts(ctx context.Context, itemID string, input model.WishlistAlertsInput) (*models.WishlistItem, error) {
+	user, err := middleware.RequireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, err := parseWishlistID(itemID, "wishlist item ID")
+	if err != nil {
+		return nil, err
+	}
+
+	return r.WishlistService.SetAlerts(user.UserID, id, input.NotifyBackInStock, input.NotifyPriceDrop)
+}
+
+// MoveWishlistItemToCart is the resolver for the moveWishlistItemToCart field.
+func (r *mutationResolver) MoveWishlistItemToCart(ctx context.Context, itemID string, variantID *string, quantity *int) (*models.Cart, error) {
+	user, err := middleware.RequireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, err := parseWishlistID(itemID, "wishlist item ID")
+	if err != nil {
+		return nil, err
+	}
+	variant, err := parseOptionalWishlistID(variantID, "variant ID")
+	if err != nil {
+		return nil, err
+	}
+	qty := 1
+	if quantity != nil {
+		qty = *quantity
+	}
+
+	if _, err := r.WishlistService.MoveToCart(user.UserID, id, variant, qty); err != nil {
+		return nil, err
+	}
+	return r.findCart(cartOwner{UserID: &user.UserID})
+}
+
+// SaveCartItemForLater is the resolver for the saveCartItemForLater field.
+func (r *mutationResolver) SaveCartItemForLater(ctx context.Context, cartItemID string) (*models.WishlistItem, error) {
+	user, err := middleware.RequireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, err := parseWishlistID(cartItemID, "cart item ID")
+	if err != nil {
+		return nil, err
+	}
+
+	return r.WishlistService.SaveForLater(user.UserID, id)
+}
+
+// MyWishlist is the resolver for the myWishlist field.
+func (r *queryResolver) MyWishlist(ctx context.Context) (*models.Wishlist, error) {
+	user, err := middleware.RequireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.WishlistService.GetWishlist(user.UserID)
+}
+
+// ID is the resolver for the id field.
+func (r *wishlistResolver) ID(ctx context.Context, obj *models.Wishlist) (string, error) {
+	return strconv.FormatUint(uint64(obj.ID), 10), nil
+}
+
+// UpdatedAt is the resolver for the updatedAt field.
+func (r *wishlistResolver) UpdatedAt(ctx context.Context, obj *models.Wishlist) (string, error) {
+	return obj.UpdatedAt.Format(time.RFC3339), nil
+}
+
+// ID is the resolver for the id field.
+func (r *wishlistItemResolver) ID(ctx context.Context, obj *models.WishlistItem) (string, error) {
+	return strconv.FormatUint(uint64(obj.ID), 10), nil
+}
+
+// ProductID is the resolver for the productId field.
+func (r *wishlistItemResolver) ProductID(ctx context.Context, obj *models.WishlistItem) (string, error) {
+	return strconv.FormatUint(uint64(obj.ProductID), 10), nil
+}
+
+// VariantID is the resolver for the variantId field.
+func (r *wishlistItemResolver) VariantID(ctx context.Context, obj *models.WishlistItem) (*string, error) {
+	return optionalID(obj.VariantID), nil
+}
+
+// Product is the resolver for the product field.
+func (r *wishlistItemResolver) Product(ctx context.Context, obj *models.WishlistItem) (*models.Product, error) {
+	if obj.Product != nil {
+		return obj.Product, nil
+	}
+
+	return loaders.GetProduct(ctx, obj.ProductID)
+}
+
+// Variant is the resolver for the variant field.
+func (r *wishlistItemResolver) Variant(ctx context.Context, obj *models.WishlistItem) (*models.ProductVariant, error) {
+	if obj.VariantID == nil {
+		return nil, nil
+	}
+	if obj.Variant != nil {
+		return obj.Variant, nil
+	}
+
+	return loaders.GetVariant(ctx, *obj.VariantID)
+}
+
+// Price is the resolver for the price field.
+func (r *wishlistItemResolver) Price(ctx context.Context, obj *models.WishlistItem) (*money.Money, error) {
+	product, err := r.WishlistItem().Product(ctx, obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load product")
+	}
+	price := product.BasePrice
+
+	variant, err := r.WishlistItem().Variant(ctx, obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load variant")
+	}
+	if variant != nil {
+		price = price.Add(variant.PriceModifier)
+	}
+	return &price, nil
+}
+
+// InStock is the resolver for the inStock field.
+func (r *wishlistItemResolver) InStock(ctx context.Context, obj *models.WishlistItem) (bool, error) {
+	variantIDs := []uint{}
+	if obj.VariantID != nil {
+		variantIDs = append(variantIDs, *obj.VariantID)
+	} else {
+		variants, err := loaders.GetVariantsByProduct(ctx, obj.ProductID)
+		if err != nil {
+			return false, err
+		}
+		for _, v := range variants {
+			variantIDs = append(variantIDs, v.ID)
+		}
+	}
+
+	for _, id := range variantIDs {
+		inv, err := loaders.GetInventory(ctx, id)
+		if err != nil {
+			return false, err
+		}
+		if inv != nil && inv.StockQuantity-inv.ReservedQuantity > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// AddedAt is the resolver for the addedAt field.
+func (r *wishlistItemResolver) AddedAt(ctx context.Context, obj *models.WishlistItem) (string, error) {
+	return obj.AddedAt.Format(time.RFC3339), nil
+}
+
+// Wishlist returns generated.WishlistResolver implementation.
+func (r *Resolver) Wishlist() generated.WishlistResolver { return &wishlistResolver{r} }
+
+// WishlistItem returns generated.WishlistItemResolver implementation.
+func (r *Resolver) WishlistItem() generated.WishlistItemResolver { return &wishlistItemResolver{r} }
+
+type wishlistResolver struct{ *Resolver }
+type wishlistItemResolver struct{ *Resolver }
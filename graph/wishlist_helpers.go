@@ -0,0 +1,28 @@
+package graph
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// parseWishlistID reads an ID given to the wishlist mutations; name says
+// which in the error.
+func parseWishlistID(id string, name string) (uint, error) {
+	n, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s", name)
+	}
+	return uint(n), nil
+}
+
+// parseOptionalWishlistID is parseWishlistID for IDs that may be left out.
+func parseOptionalWishlistID(id *string, name string) (*uint, error) {
+	if id == nil {
+		return nil, nil
+	}
+	n, err := parseWishlistID(*id, name)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/clerk/clerk-sdk-go/v2"
+	"github.com/clerk/clerk-sdk-go/v2/user"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
+)
+
+// ClerkProfile is the part of a Clerk user that is mirrored into the local
+// users table.
+type ClerkProfile struct {
+	ClerkUserID string
+	Email       string
+	// EmailVerified reports whether Clerk has verified Email. Only a
+	// verified email links an account made before Clerk.
+	EmailVerified bool
+	Name          string
+	Phone         string
+	Role          string
+}
+
+// FetchClerkProfile loads a user from the Clerk Backend API. Session tokens
+// only carry the user ID by default, so this fills in the rest on first sign-in.
+func FetchClerkProfile(ctx context.Context, clerkUserID string) (*ClerkProfile, error) {
+	u, err := user.Get(ctx, clerkUserID)
+	if err != nil {
+		return nil, err
+	}
+	return ProfileFromClerkUser(u), nil
+}
+
+// ProfileFromClerkUser extracts the profile from a Clerk user object, as
+// returned by the API or sent in user.* webhooks.
+func ProfileFromClerkUser(u *clerk.User) *ClerkProfile {
+	profile := &ClerkProfile{
+		ClerkUserID: u.ID,
+		Role:        constants.RoleUser,
+	}
+
+	for _, e := range u.EmailAddresses {
+		if profile.Email == "" || (u.PrimaryEmailAddressID != nil && e.ID == *u.PrimaryEmailAddressID) {
+			profile.Email = e.EmailAddress
+			profile.EmailVerified = e.Verification != nil && e.Verification.Status == "verified"
+		}
+	}
+	for _, p := range u.PhoneNumbers {
+		if profile.Phone == "" || (u.PrimaryPhoneNumberID != nil && p.ID == *u.PrimaryPhoneNumberID) {
+			profile.Phone = p.PhoneNumber
+		}
+	}
+
+	var names []string
+	if u.FirstName != nil && *u.FirstName != "" {
+		names = append(names, *u.FirstName)
+	}
+	if u.LastName != nil && *u.LastName != "" {
+		names = append(names, *u.LastName)
+	}
+	profile.Name = strings.Join(names, " ")
+
+	var metadata struct {
+		Role string `json:"role"`
+	}
+	if len(u.PublicMetadata) > 0 && json.Unmarshal(u.PublicMetadata, &metadata) == nil && metadata.Role != "" {
+		profile.Role = metadata.Role
+	}
+
+	return profile
+}
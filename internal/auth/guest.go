@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidGuestToken = errors.New("invalid guest session token")
+
+// GuestSession identifies an anonymous shopper. Its ID keys the guest's cart.
+type GuestSession struct {
+	ID        string
+	ExpiresAt time.Time
+}
+
+// NewGuestSession starts a guest session and returns it with its signed
+// token. The token has the form "<id>.<expiry unix>.<signature>".
+func NewGuestSession(ttl time.Duration) (string, *GuestSession, error) {
+	secret, err := guestSecret()
+	if err != nil {
+		return "", nil, err
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", nil, err
+	}
+
+	session := &GuestSession{
+		ID:        "guest_" + hex.EncodeToString(buf),
+		ExpiresAt: time.Now().Add(ttl).Truncate(time.Second),
+	}
+
+	payload := session.ID + "." + strconv.FormatInt(session.ExpiresAt.Unix(), 10)
+	return payload + "." + signGuestPayload(secret, payload), session, nil
+}
+
+// ValidateGuestToken checks the token's signature and expiry.
+func ValidateGuestToken(token string) (*GuestSession, error) {
+	secret, err := guestSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || !strings.HasPrefix(parts[0], "guest_") {
+		return nil, ErrInvalidGuestToken
+	}
+
+	payload := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(signGuestPayload(secret, payload)), []byte(parts[2])) {
+		return nil, ErrInvalidGuestToken
+	}
+
+	expiry, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidGuestToken
+	}
+
+	expiresAt := time.Unix(expiry, 0)
+	if time.Now().After(expiresAt) {
+		return nil, errors.New("guest session expired")
+	}
+
+	return &GuestSession{ID: parts[0], ExpiresAt: expiresAt}, nil
+}
+
+func guestSecret() ([]byte, error) {
+	secret := os.Getenv("GUEST_SESSION_SECRET")
+	if secret == "" {
+		return nil, errors.New("GUEST_SESSION_SECRET is not set")
+	}
+	return []byte(secret), nil
+}
+
+func signGuestPayload(secret []byte, payload string) string {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
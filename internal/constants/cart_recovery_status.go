@@ -0,0 +1,10 @@
+package constants
+
+// Cart recovery statuses. A recovery is pending until the reminder is
+// handed to the notifier, and skipped when the customer doesn't want one.
+const (
+	CartRecoveryPending = "pending"
+	CartRecoverySent    = "sent"
+	CartRecoverySkipped = "skipped"
+	CartRecoveryFailed  = "failed"
+)
@@ -6,4 +6,5 @@ const (
 	OrderCancelled = "cancelled"
 	OrderShipped   = "shipped"
 	OrderDelivered = "delivered"
+	OrderReturned  = "returned"
 )
@@ -1,8 +1,15 @@
 package constants
 
 const (
-	PaymentPending   = "pending"
-	PaymentCompleted = "completed"
-	PaymentFailed    = "failed"
+	PaymentPending           = "pending"
+	PaymentCompleted         = "completed"
+	PaymentFailed            = "failed"
+	PaymentRefunded          = "refunded"
+	PaymentPartiallyRefunded = "partially_refunded"
 )
 
+// IsCapturedPaymentStatus reports whether a payment in status has taken the
+// customer's money, whether or not any of it has been refunded since.
+func IsCapturedPaymentStatus(status string) bool {
+	return status == PaymentCompleted || status == PaymentPartiallyRefunded || status == PaymentRefunded
+}
@@ -0,0 +1,7 @@
+package constants
+
+const (
+	RefundPending   = "pending"
+	RefundProcessed = "processed"
+	RefundFailed    = "failed"
+)
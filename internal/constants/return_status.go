@@ -0,0 +1,50 @@
+package constants
+
+const (
+	ReturnRequested       = "requested"
+	ReturnApproved        = "approved"
+	ReturnRejected        = "rejected"
+	ReturnPickupScheduled = "pickup_scheduled"
+	ReturnReceived        = "received"
+	ReturnInspected       = "inspected"
+	ReturnRefunded        = "refunded"
+	ReturnExchanged       = "exchanged"
+	ReturnCancelled       = "cancelled"
+)
+
+const (
+	ReturnResolutionRefund   = "refund"
+	ReturnResolutionExchange = "exchange"
+)
+
+const (
+	ReturnReasonTooSmall       = "too_small"
+	ReturnReasonTooLarge       = "too_large"
+	ReturnReasonDefective      = "defective"
+	ReturnReasonDamaged        = "damaged_in_transit"
+	ReturnReasonWrongItem      = "wrong_item"
+	ReturnReasonNotAsDescribed = "not_as_described"
+	ReturnReasonChangedMind    = "changed_mind"
+	ReturnReasonOther          = "other"
+)
+
+// ReturnReasons lists every reason a customer can give for a return.
+var ReturnReasons = []string{
+	ReturnReasonTooSmall, ReturnReasonTooLarge, ReturnReasonDefective, ReturnReasonDamaged,
+	ReturnReasonWrongItem, ReturnReasonNotAsDescribed, ReturnReasonChangedMind, ReturnReasonOther,
+}
+
+func IsValidReturnReason(reason string) bool {
+	for _, r := range ReturnReasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// IsOpenReturnStatus reports whether a return in status still counts
+// against the quantity of its order item.
+func IsOpenReturnStatus(status string) bool {
+	return status != ReturnRejected && status != ReturnCancelled
+}
@@ -0,0 +1,21 @@
+package constants
+
+const (
+	RoleUser           = "user"
+	RoleAdmin          = "admin"
+	RoleCatalogManager = "catalog_manager"
+	RoleFulfillment    = "fulfillment"
+	RoleSupport        = "support"
+)
+
+// Roles lists every role a user can be given.
+var Roles = []string{RoleUser, RoleAdmin, RoleCatalogManager, RoleFulfillment, RoleSupport}
+
+func IsValidRole(role string) bool {
+	for _, r := range Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
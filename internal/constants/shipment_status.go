@@ -0,0 +1,39 @@
+package constants
+
+// ShipmentBooking is a shipment waiting for the courier to accept it. It
+// has no tracking number yet, and couriers never report it, so it is not
+// one of the ShipmentStatuses.
+const ShipmentBooking = "booking"
+
+const (
+	ShipmentBooked         = "booked"
+	ShipmentPickedUp       = "picked_up"
+	ShipmentInTransit      = "in_transit"
+	ShipmentOutForDelivery = "out_for_delivery"
+	ShipmentDelivered      = "delivered"
+	ShipmentFailed         = "delivery_failed"
+	ShipmentReturning      = "returning"
+	ShipmentReturned       = "returned"
+	ShipmentCancelled      = "cancelled"
+)
+
+// ShipmentStatuses lists every status a courier can report.
+var ShipmentStatuses = []string{
+	ShipmentBooked, ShipmentPickedUp, ShipmentInTransit, ShipmentOutForDelivery,
+	ShipmentDelivered, ShipmentFailed, ShipmentReturning, ShipmentReturned, ShipmentCancelled,
+}
+
+func IsValidShipmentStatus(status string) bool {
+	for _, s := range ShipmentStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// IsFinalShipmentStatus reports whether a shipment in status will not move
+// any further.
+func IsFinalShipmentStatus(status string) bool {
+	return status == ShipmentDelivered || status == ShipmentReturned || status == ShipmentCancelled
+}
@@ -0,0 +1,97 @@
+// Package courier books shipments with delivery partners and follows their
+// tracking. Each partner is an adapter behind the Courier interface; Fake
+// stands in for a real one in development and tests.
+package courier
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
+)
+
+var (
+	// ErrInvalidWebhook is returned for tracking webhooks that fail
+	// authentication or can't be decoded.
+	ErrInvalidWebhook = errors.New("invalid tracking webhook")
+	// ErrUnknownShipment is returned for tracking numbers the courier
+	// doesn't know.
+	ErrUnknownShipment = errors.New("unknown shipment")
+)
+
+// Courier is a delivery partner.
+type Courier interface {
+	// Name identifies the courier on stored shipments, e.g. "shiprocket".
+	Name() string
+	// Book hands a parcel over to the courier for pickup.
+	Book(ctx context.Context, req BookingRequest) (*Booking, error)
+	// Track fetches the latest tracking of a shipment.
+	Track(ctx context.Context, trackingNumber string) (*Tracking, error)
+	// ParseWebhook authenticates a tracking update pushed by the courier and
+	// decodes it.
+	ParseWebhook(header http.Header, body []byte) ([]Tracking, error)
+}
+
+// Address is where a parcel is delivered.
+type Address struct {
+	Name       string
+	Email      string
+	Phone      string
+	Line1      string
+	Line2      string
+	City       string
+	State      string
+	PostalCode string
+	Country    string
+}
+
+// Item is a line of the order being shipped.
+type Item struct {
+	SKU       string
+	Name      string
+	HSN       string
+	Quantity  int
+	UnitPrice money.Money
+}
+
+// BookingRequest describes a prepaid parcel to be picked up from the
+// courier's configured pickup location.
+type BookingRequest struct {
+	// Reference is our order number.
+	Reference string
+	OrderDate time.Time
+	To        Address
+	Items     []Item
+	// Value is what the customer paid for the parcel.
+	Value    money.Money
+	WeightKg float64
+}
+
+// Booking is a shipment accepted by the courier.
+type Booking struct {
+	TrackingNumber string
+	// CourierName is the carrier that will deliver, which differs from the
+	// courier itself for aggregators such as Shiprocket.
+	CourierName       string
+	TrackingURL       string
+	EstimatedDelivery *time.Time
+}
+
+// Event is a scan or status change in a shipment's journey. Status is one
+// of the constants.Shipment* values.
+type Event struct {
+	Status      string
+	Description string
+	Location    string
+	OccurredAt  time.Time
+}
+
+// Tracking is what the courier knows about a shipment.
+type Tracking struct {
+	TrackingNumber    string
+	Status            string
+	EstimatedDelivery *time.Time
+	Events            []Event
+}
@@ -0,0 +1,156 @@
+package courier
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
+)
+
+// FakeName is the name of the fake courier on stored shipments.
+const FakeName = "fake"
+
+// fakeTransitDays is how long the fake courier promises delivery takes.
+const fakeTransitDays = 3
+
+// Fake is an in-memory courier for development and tests. Shipments only
+// move when Advance is called or a webhook is posted, and are forgotten on
+// restart.
+type Fake struct {
+	// Secret keys the hex HMAC-SHA256 of webhook bodies sent in the
+	// X-Courier-Signature header. Webhooks are rejected without it.
+	Secret string
+	// Now is the clock, time.Now unless a test sets it.
+	Now func() time.Time
+
+	mu        sync.Mutex
+	booked    int
+	shipments map[string]*Tracking
+}
+
+func NewFake(secret string) *Fake {
+	return &Fake{
+		Secret:    secret,
+		Now:       time.Now,
+		shipments: map[string]*Tracking{},
+	}
+}
+
+func (f *Fake) Name() string {
+	return FakeName
+}
+
+func (f *Fake) Book(ctx context.Context, req BookingRequest) (*Booking, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.booked++
+	now := f.Now()
+	eta := now.AddDate(0, 0, fakeTransitDays)
+	trackingNumber := fmt.Sprintf("FAKE%010d", f.booked)
+	f.shipments[trackingNumber] = &Tracking{
+		TrackingNumber:    trackingNumber,
+		Status:            constants.ShipmentBooked,
+		EstimatedDelivery: &eta,
+		Events: []Event{{
+			Status:      constants.ShipmentBooked,
+			Description: "Shipment booked for order " + req.Reference,
+			OccurredAt:  now,
+		}},
+	}
+
+	return &Booking{
+		TrackingNumber:    trackingNumber,
+		CourierName:       "Fake Express",
+		EstimatedDelivery: &eta,
+	}, nil
+}
+
+func (f *Fake) Track(ctx context.Context, trackingNumber string) (*Tracking, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	t, ok := f.shipments[trackingNumber]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownShipment, trackingNumber)
+	}
+	tracking := *t
+	tracking.Events = append([]Event(nil), t.Events...)
+	return &tracking, nil
+}
+
+// Advance moves a shipment to status as if the courier had scanned it at
+// location, and returns its tracking.
+func (f *Fake) Advance(trackingNumber, status, location string) (*Tracking, error) {
+	if !constants.IsValidShipmentStatus(status) {
+		return nil, fmt.Errorf("unknown shipment status %q", status)
+	}
+
+	f.mu.Lock()
+	t, ok := f.shipments[trackingNumber]
+	if ok {
+		t.Status = status
+		t.Events = append(t.Events, Event{
+			Status:      status,
+			Description: "Shipment " + status,
+			Location:    location,
+			OccurredAt:  f.Now(),
+		})
+	}
+	f.mu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownShipment, trackingNumber)
+	}
+	return f.Track(context.Background(), trackingNumber)
+}
+
+// fakeWebhook is the body of a fake courier webhook: one event for one
+// shipment.
+type fakeWebhook struct {
+	TrackingNumber string    `json:"trackingNumber"`
+	Status         string    `json:"status"`
+	Description    string    `json:"description"`
+	Location       string    `json:"location"`
+	OccurredAt     time.Time `json:"occurredAt"`
+}
+
+func (f *Fake) ParseWebhook(header http.Header, body []byte) ([]Tracking, error) {
+	if f.Secret == "" {
+		return nil, fmt.Errorf("%w: no webhook secret is configured", ErrInvalidWebhook)
+	}
+	h := hmac.New(sha256.New, []byte(f.Secret))
+	h.Write(body)
+	if !hmac.Equal([]byte(hex.EncodeToString(h.Sum(nil))), []byte(header.Get("X-Courier-Signature"))) {
+		return nil, fmt.Errorf("%w: bad signature", ErrInvalidWebhook)
+	}
+
+	var w fakeWebhook
+	if err := json.Unmarshal(body, &w); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWebhook, err)
+	}
+	if w.TrackingNumber == "" || !constants.IsValidShipmentStatus(w.Status) {
+		return nil, fmt.Errorf("%w: missing tracking number or unknown status %q", ErrInvalidWebhook, w.Status)
+	}
+	if w.OccurredAt.IsZero() {
+		w.OccurredAt = f.Now()
+	}
+
+	return []Tracking{{
+		TrackingNumber: w.TrackingNumber,
+		Status:         w.Status,
+		Events: []Event{{
+			Status:      w.Status,
+			Description: w.Description,
+			Location:    w.Location,
+			OccurredAt:  w.OccurredAt,
+		}},
+	}}, nil
+}
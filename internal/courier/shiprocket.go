@@ -0,0 +1,401 @@
+package courier
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
+)
+
+// ShiprocketName is the name of the Shiprocket courier on stored shipments.
+const ShiprocketName = "shiprocket"
+
+const (
+	shiprocketAPIURL = "https://apiv2.shiprocket.in/v1/external"
+	// Shiprocket tokens last ten days; renew a day early.
+	shiprocketTokenTTL = 9 * 24 * time.Hour
+	// shiprocketTimeLayout is how Shiprocket writes times, in IST.
+	shiprocketTimeLayout = "2006-01-02 15:04:05"
+)
+
+var ist = time.FixedZone("IST", 5*60*60+30*60)
+
+// ShiprocketConfig holds the API user and defaults for parcels.
+type ShiprocketConfig struct {
+	// APIURL overrides the Shiprocket API, e.g. to point at a fake server.
+	APIURL   string
+	Email    string
+	Password string
+	// PickupLocation is the nickname of a pickup address set up in the
+	// Shiprocket panel.
+	PickupLocation string
+	// WebhookToken is the token configured for the tracking webhook, which
+	// Shiprocket sends in the x-api-key header.
+	WebhookToken string
+	// Parcel dimensions in centimetres, a folded-garment mailer unless set.
+	LengthCm  float64
+	BreadthCm float64
+	HeightCm  float64
+}
+
+// Shiprocket books shipments through the Shiprocket aggregator, which picks
+// the carrier for each parcel.
+type Shiprocket struct {
+	Config ShiprocketConfig
+	Client *http.Client
+
+	mu           sync.Mutex
+	token        string
+	tokenExpires time.Time
+}
+
+func NewShiprocket(config ShiprocketConfig) *Shiprocket {
+	if config.APIURL == "" {
+		config.APIURL = shiprocketAPIURL
+	}
+	if config.LengthCm == 0 || config.BreadthCm == 0 || config.HeightCm == 0 {
+		config.LengthCm, config.BreadthCm, config.HeightCm = 30, 25, 5
+	}
+	return &Shiprocket{
+		Config: config,
+		Client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (s *Shiprocket) Name() string {
+	return ShiprocketName
+}
+
+type shiprocketOrderItem struct {
+	Name         string `json:"name"`
+	SKU          string `json:"sku"`
+	Units        int    `json:"units"`
+	SellingPrice string `json:"selling_price"`
+	HSN          string `json:"hsn,omitempty"`
+}
+
+type shiprocketOrder struct {
+	OrderID             string                `json:"order_id"`
+	OrderDate           string                `json:"order_date"`
+	PickupLocation      string                `json:"pickup_location"`
+	BillingCustomerName string                `json:"billing_customer_name"`
+	BillingLastName     string                `json:"billing_last_name"`
+	BillingAddress      string                `json:"billing_address"`
+	BillingAddress2     string                `json:"billing_address_2"`
+	BillingCity         string                `json:"billing_city"`
+	BillingPincode      string                `json:"billing_pincode"`
+	BillingState        string                `json:"billing_state"`
+	BillingCountry      string                `json:"billing_country"`
+	BillingEmail        string                `json:"billing_email"`
+	BillingPhone        string                `json:"billing_phone"`
+	ShippingIsBilling   bool                  `json:"shipping_is_billing"`
+	OrderItems          []shiprocketOrderItem `json:"order_items"`
+	PaymentMethod       string                `json:"payment_method"`
+	SubTotal            string                `json:"sub_total"`
+	Length              float64               `json:"length"`
+	Breadth             float64               `json:"breadth"`
+	Height              float64               `json:"height"`
+	Weight              float64               `json:"weight"`
+}
+
+func (s *Shiprocket) Book(ctx context.Context, req BookingRequest) (*Booking, error) {
+	// Addresses carry ISO country codes; Shiprocket wants the name
+	country := req.To.Country
+	if country == "" || strings.EqualFold(country, "IN") {
+		country = "India"
+	}
+
+	order := shiprocketOrder{
+		OrderID:             req.Reference,
+		OrderDate:           req.OrderDate.In(ist).Format("2006-01-02 15:04"),
+		PickupLocation:      s.Config.PickupLocation,
+		BillingCustomerName: req.To.Name,
+		BillingAddress:      req.To.Line1,
+		BillingAddress2:     req.To.Line2,
+		BillingCity:         req.To.City,
+		BillingPincode:      req.To.PostalCode,
+		BillingState:        req.To.State,
+		BillingCountry:      country,
+		BillingEmail:        req.To.Email,
+		BillingPhone:        req.To.Phone,
+		ShippingIsBilling:   true,
+		PaymentMethod:       "Prepaid",
+		SubTotal:            req.Value.Decimal(),
+		Length:              s.Config.LengthCm,
+		Breadth:             s.Config.BreadthCm,
+		Height:              s.Config.HeightCm,
+		Weight:              req.WeightKg,
+	}
+	for _, item := range req.Items {
+		order.OrderItems = append(order.OrderItems, shiprocketOrderItem{
+			Name:         item.Name,
+			SKU:          item.SKU,
+			Units:        item.Quantity,
+			SellingPrice: item.UnitPrice.Decimal(),
+			HSN:          item.HSN,
+		})
+	}
+
+	var created struct {
+		ShipmentID int64 `json:"shipment_id"`
+	}
+	if err := s.call(ctx, http.MethodPost, "/orders/create/adhoc", order, &created); err != nil {
+		return nil, fmt.Errorf("failed to create Shiprocket order: %w", err)
+	}
+	if created.ShipmentID == 0 {
+		return nil, fmt.Errorf("Shiprocket created no shipment for order %s", req.Reference)
+	}
+
+	var assigned struct {
+		AWBAssignStatus int `json:"awb_assign_status"`
+		Response        struct {
+			Data struct {
+				AWBCode     string `json:"awb_code"`
+				CourierName string `json:"courier_name"`
+			} `json:"data"`
+		} `json:"response"`
+	}
+	if err := s.call(ctx, http.MethodPost, "/courier/assign/awb", map[string]interface{}{
+		"shipment_id": created.ShipmentID,
+	}, &assigned); err != nil {
+		return nil, fmt.Errorf("failed to assign AWB to Shiprocket shipment %d: %w", created.ShipmentID, err)
+	}
+	if assigned.AWBAssignStatus != 1 || assigned.Response.Data.AWBCode == "" {
+		return nil, fmt.Errorf("Shiprocket assigned no AWB to shipment %d", created.ShipmentID)
+	}
+
+	if err := s.call(ctx, http.MethodPost, "/courier/generate/pickup", map[string]interface{}{
+		"shipment_id": []int64{created.ShipmentID},
+	}, nil); err != nil {
+		return nil, fmt.Errorf("failed to request pickup of Shiprocket shipment %d: %w", created.ShipmentID, err)
+	}
+
+	awb := assigned.Response.Data.AWBCode
+	return &Booking{
+		TrackingNumber: awb,
+		CourierName:    assigned.Response.Data.CourierName,
+		TrackingURL:    "https://shiprocket.co/tracking/" + url.PathEscape(awb),
+	}, nil
+}
+
+// shiprocketScan is a tracking activity, as returned by the tracking API and
+// sent in webhooks.
+type shiprocketScan struct {
+	Date          string `json:"date"`
+	Activity      string `json:"activity"`
+	Location      string `json:"location"`
+	SRStatusLabel string `json:"sr-status-label"`
+}
+
+func (s *Shiprocket) Track(ctx context.Context, trackingNumber string) (*Tracking, error) {
+	var res struct {
+		TrackingData struct {
+			TrackStatus   int `json:"track_status"`
+			ShipmentTrack []struct {
+				CurrentStatus string `json:"current_status"`
+			} `json:"shipment_track"`
+			Activities []shiprocketScan `json:"shipment_track_activities"`
+			ETD        string           `json:"etd"`
+		} `json:"tracking_data"`
+	}
+	if err := s.call(ctx, http.MethodGet, "/courier/track/awb/"+url.PathEscape(trackingNumber), nil, &res); err != nil {
+		return nil, err
+	}
+	data := res.TrackingData
+	if data.TrackStatus == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownShipment, trackingNumber)
+	}
+
+	tracking := &Tracking{
+		TrackingNumber:    trackingNumber,
+		EstimatedDelivery: parseShiprocketTime(data.ETD),
+		Events:            shiprocketEvents(data.Activities),
+	}
+	if len(data.ShipmentTrack) > 0 {
+		tracking.Status = shiprocketStatus(data.ShipmentTrack[0].CurrentStatus)
+	}
+	return tracking, nil
+}
+
+// shiprocketWebhook is the body of a Shiprocket tracking webhook.
+type shiprocketWebhook struct {
+	AWB           string           `json:"awb"`
+	CurrentStatus string           `json:"current_status"`
+	ETD           string           `json:"etd"`
+	Scans         []shiprocketScan `json:"scans"`
+}
+
+func (s *Shiprocket) ParseWebhook(header http.Header, body []byte) ([]Tracking, error) {
+	token := header.Get("x-api-key")
+	if s.Config.WebhookToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.Config.WebhookToken)) != 1 {
+		return nil, fmt.Errorf("%w: bad token", ErrInvalidWebhook)
+	}
+
+	var w shiprocketWebhook
+	if err := json.Unmarshal(body, &w); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWebhook, err)
+	}
+	if w.AWB == "" {
+		return nil, fmt.Errorf("%w: missing awb", ErrInvalidWebhook)
+	}
+
+	return []Tracking{{
+		TrackingNumber:    w.AWB,
+		Status:            shiprocketStatus(w.CurrentStatus),
+		EstimatedDelivery: parseShiprocketTime(w.ETD),
+		Events:            shiprocketEvents(w.Scans),
+	}}, nil
+}
+
+// call sends a JSON request to the Shiprocket API and decodes the response
+// into out, logging in first if needed.
+func (s *Shiprocket) call(ctx context.Context, method, path string, in, out interface{}) error {
+	token, err := s.login(ctx)
+	if err != nil {
+		return err
+	}
+	return s.do(ctx, method, path, token, in, out)
+}
+
+func (s *Shiprocket) login(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && time.Now().Before(s.tokenExpires) {
+		return s.token, nil
+	}
+
+	var res struct {
+		Token string `json:"token"`
+	}
+	err := s.do(ctx, http.MethodPost, "/auth/login", "", map[string]string{
+		"email":    s.Config.Email,
+		"password": s.Config.Password,
+	}, &res)
+	if err != nil {
+		return "", fmt.Errorf("failed to log in to Shiprocket: %w", err)
+	}
+	if res.Token == "" {
+		return "", fmt.Errorf("Shiprocket login returned no token")
+	}
+
+	s.token, s.tokenExpires = res.Token, time.Now().Add(shiprocketTokenTTL)
+	return s.token, nil
+}
+
+func (s *Shiprocket) do(ctx context.Context, method, path, token string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, s.Config.APIURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	res, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if res.StatusCode == http.StatusUnauthorized && token != "" {
+		// Force a fresh login on the next call
+		s.mu.Lock()
+		s.token = ""
+		s.mu.Unlock()
+	}
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("Shiprocket %s %s: %s: %s", method, path, res.Status, strings.TrimSpace(string(data)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+func shiprocketEvents(scans []shiprocketScan) []Event {
+	events := make([]Event, 0, len(scans))
+	for _, scan := range scans {
+		at := parseShiprocketTime(scan.Date)
+		if at == nil {
+			continue
+		}
+		status := shiprocketStatus(scan.SRStatusLabel)
+		if scan.SRStatusLabel == "" || scan.SRStatusLabel == "NA" {
+			status = shiprocketStatus(scan.Activity)
+		}
+		location := scan.Location
+		if location == "NA" {
+			location = ""
+		}
+		events = append(events, Event{
+			Status:      status,
+			Description: scan.Activity,
+			Location:    location,
+			OccurredAt:  *at,
+		})
+	}
+	return events
+}
+
+// shiprocketStatus maps a Shiprocket status label to a shipment status.
+// Labels that aren't recognised count as in transit.
+func shiprocketStatus(label string) string {
+	label = strings.ToUpper(strings.TrimSpace(label))
+	switch {
+	case label == "DELIVERED":
+		return constants.ShipmentDelivered
+	case label == "RTO DELIVERED":
+		return constants.ShipmentReturned
+	case strings.HasPrefix(label, "RTO"):
+		return constants.ShipmentReturning
+	case strings.HasPrefix(label, "CANCEL"):
+		return constants.ShipmentCancelled
+	case label == "OUT FOR DELIVERY":
+		return constants.ShipmentOutForDelivery
+	case label == "PICKED UP" || label == "SHIPPED":
+		return constants.ShipmentPickedUp
+	case label == "UNDELIVERED" || label == "LOST" || label == "DAMAGED" || label == "DESTROYED":
+		return constants.ShipmentFailed
+	case label == "" || label == "NA" || label == "NEW" || label == "AWB ASSIGNED" ||
+		strings.HasPrefix(label, "PICKUP") || label == "MANIFEST GENERATED" || label == "OUT FOR PICKUP":
+		return constants.ShipmentBooked
+	}
+	return constants.ShipmentInTransit
+}
+
+func parseShiprocketTime(s string) *time.Time {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	for _, layout := range []string{shiprocketTimeLayout, "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, ist); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
@@ -1,11 +1,12 @@
 package database
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
 
-	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/migrate"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -36,23 +37,23 @@ func Connect() {
 	log.Println("Database connection established")
 }
 
+// Migrate applies pending migrations from internal/migrate. Schema changes
+// are made there as SQL files rather than derived from the models.
 func Migrate() {
-	err := DB.AutoMigrate(
-		&models.User{},
-		&models.Product{},
-		&models.ProductVariant{},
-		&models.Inventory{},
-		&models.Cart{},
-		&models.CartItem{},
-		&models.Order{},
-		&models.OrderItem{},
-		&models.Payment{},
-		&models.PromoCode{},
-	)
+	sqlDB, err := DB.DB()
+	if err != nil {
+		log.Fatal("Migration failed:", err)
+	}
+
+	migrator, err := migrate.New(sqlDB)
+	if err != nil {
+		log.Fatal("Migration failed:", err)
+	}
 
+	applied, err := migrator.Up(context.Background())
 	if err != nil {
 		log.Fatal("Migration failed:", err)
 	}
 
-	log.Println("Database migration completed")
+	log.Printf("Database migration completed (%d applied)", applied)
 }
@@ -0,0 +1,211 @@
+// Package events describes the domain events the API records in its outbox
+// and the payload each one carries. Payloads are JSON so handlers outside
+// the process, such as merchant webhooks, can be given them as they are.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
+)
+
+// Event types. Order events carry an Order, payment events a Payment,
+// refund events a Refund, stock events a Stock, return events a Return and
+// product.price_changed a ProductPrice.
+const (
+	OrderPlaced    = "order.placed"
+	OrderConfirmed = "order.confirmed"
+	OrderCancelled = "order.cancelled"
+	OrderShipped   = "order.shipped"
+	OrderDelivered = "order.delivered"
+	OrderReturned  = "order.returned"
+
+	PaymentCaptured = "payment.captured"
+	PaymentFailed   = "payment.failed"
+	RefundRequested = "refund.requested"
+	RefundIssued    = "refund.issued"
+
+	StockUpdated = "stock.updated"
+	StockLow     = "stock.low"
+
+	ProductPriceChanged = "product.price_changed"
+
+	ReturnRequested = "return.requested"
+	ReturnUpdated   = "return.updated"
+)
+
+// All subscribes a handler to every event type.
+const All = "*"
+
+// Types lists every event type.
+var Types = []string{
+	OrderPlaced, OrderConfirmed, OrderCancelled, OrderShipped, OrderDelivered, OrderReturned,
+	PaymentCaptured, PaymentFailed, RefundRequested, RefundIssued,
+	StockUpdated, StockLow,
+	ProductPriceChanged,
+	ReturnRequested, ReturnUpdated,
+}
+
+func IsValidType(eventType string) bool {
+	for _, t := range Types {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Event is an outbox entry as handed to a handler.
+type Event struct {
+	ID         uint
+	Type       string
+	Payload    json.RawMessage
+	OccurredAt time.Time
+}
+
+// Decode unmarshals the event's payload into v.
+func (e Event) Decode(v interface{}) error {
+	return json.Unmarshal(e.Payload, v)
+}
+
+// Handler reacts to an event. Events are delivered at least once, so a
+// handler may see the same event again after a failure or a crash and must
+// be safe to run twice; Event.ID identifies repeats.
+type Handler func(ctx context.Context, e Event) error
+
+// Order is the payload of the order.* events. PreviousStatus is empty for
+// order.placed.
+type Order struct {
+	OrderID        string `json:"orderId"`
+	UserID         string `json:"userId"`
+	Status         string `json:"status"`
+	PreviousStatus string `json:"previousStatus,omitempty"`
+	TotalAmount    string `json:"totalAmount"`
+	Currency       string `json:"currency"`
+}
+
+func NewOrder(order *models.Order, previousStatus string) Order {
+	return Order{
+		OrderID:        formatID(order.ID),
+		UserID:         order.UserID,
+		Status:         order.Status,
+		PreviousStatus: previousStatus,
+		TotalAmount:    order.TotalAmount.Decimal(),
+		Currency:       order.TotalAmount.Currency,
+	}
+}
+
+// Payment is the payload of the payment.* events.
+type Payment struct {
+	PaymentID     string `json:"paymentId"`
+	OrderID       string `json:"orderId"`
+	UserID        string `json:"userId"`
+	Amount        string `json:"amount"`
+	Currency      string `json:"currency"`
+	Method        string `json:"method"`
+	TransactionID string `json:"transactionId,omitempty"`
+}
+
+func NewPayment(payment *models.Payment, userID string) Payment {
+	return Payment{
+		PaymentID:     formatID(payment.ID),
+		OrderID:       formatID(payment.OrderID),
+		UserID:        userID,
+		Amount:        payment.Amount.Decimal(),
+		Currency:      payment.Amount.Currency,
+		Method:        payment.PaymentMethod,
+		TransactionID: payment.TransactionID,
+	}
+}
+
+// Refund is the payload of refund.requested and refund.issued.
+type Refund struct {
+	RefundID  string `json:"refundId"`
+	PaymentID string `json:"paymentId"`
+	OrderID   string `json:"orderId"`
+	Amount    string `json:"amount"`
+	Currency  string `json:"currency"`
+	Reason    string `json:"reason,omitempty"`
+	Status    string `json:"status"`
+}
+
+func NewRefund(refund *models.Refund) Refund {
+	return Refund{
+		RefundID:  formatID(refund.ID),
+		PaymentID: formatID(refund.PaymentID),
+		OrderID:   formatID(refund.OrderID),
+		Amount:    refund.Amount.Decimal(),
+		Currency:  refund.Amount.Currency,
+		Reason:    refund.Reason,
+		Status:    refund.Status,
+	}
+}
+
+// Stock is the payload of the stock.* events. Available is what can still
+// be ordered: stock less what is reserved.
+type Stock struct {
+	VariantID        string `json:"variantId"`
+	StockQuantity    int    `json:"stockQuantity"`
+	ReservedQuantity int    `json:"reservedQuantity"`
+	Available        int    `json:"available"`
+}
+
+func NewStock(inv *models.Inventory) Stock {
+	return Stock{
+		VariantID:        formatID(inv.VariantID),
+		StockQuantity:    inv.StockQuantity,
+		ReservedQuantity: inv.ReservedQuantity,
+		Available:        inv.StockQuantity - inv.ReservedQuantity,
+	}
+}
+
+// ProductPrice is the payload of product.price_changed. Prices are the
+// product's base price; variants add their price modifier to it.
+type ProductPrice struct {
+	ProductID     string `json:"productId"`
+	Price         string `json:"price"`
+	PreviousPrice string `json:"previousPrice"`
+	Currency      string `json:"currency"`
+}
+
+func NewProductPrice(product *models.Product, previous money.Money) ProductPrice {
+	return ProductPrice{
+		ProductID:     formatID(product.ID),
+		Price:         product.BasePrice.Decimal(),
+		PreviousPrice: previous.Decimal(),
+		Currency:      product.BasePrice.Currency,
+	}
+}
+
+// Return is the payload of the return.* events.
+type Return struct {
+	ReturnID    string `json:"returnId"`
+	OrderID     string `json:"orderId"`
+	OrderItemID string `json:"orderItemId"`
+	UserID      string `json:"userId"`
+	Quantity    int    `json:"quantity"`
+	Reason      string `json:"reason"`
+	Resolution  string `json:"resolution"`
+	Status      string `json:"status"`
+}
+
+func NewReturn(req *models.ReturnRequest) Return {
+	return Return{
+		ReturnID:    formatID(req.ID),
+		OrderID:     formatID(req.OrderID),
+		OrderItemID: formatID(req.OrderItemID),
+		UserID:      req.UserID,
+		Quantity:    req.Quantity,
+		Reason:      req.Reason,
+		Resolution:  req.Resolution,
+		Status:      req.Status,
+	}
+}
+
+func formatID(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
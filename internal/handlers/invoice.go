@@ -0,0 +1,81 @@
+// Package handlers holds plain HTTP endpoints served next to the GraphQL API.
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/middleware"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/repository"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/service"
+	"gorm.io/gorm"
+)
+
+type InvoiceHandler struct {
+	OrderRepository *repository.OrderRepository
+	InvoiceService  *service.InvoiceService
+}
+
+func NewInvoiceHandler(orderRepo *repository.OrderRepository, invoiceService *service.InvoiceService) *InvoiceHandler {
+	return &InvoiceHandler{
+		OrderRepository: orderRepo,
+		InvoiceService:  invoiceService,
+	}
+}
+
+// ServeHTTP handles GET /invoices/{orderID}.pdf, issuing the invoice first if
+// the order doesn't have one yet. It is open to the same users as the order
+// itself.
+func (h *InvoiceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	orderID, err := strconv.ParseUint(chi.URLParam(r, "orderID"), 10, 32)
+	if err != nil {
+		http.Error(w, "invalid order ID", http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.OrderRepository.GetOrderByID(uint(orderID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, "order not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("INVOICE: failed to load order %d: %v", orderID, err)
+		http.Error(w, "failed to load order", http.StatusInternalServerError)
+		return
+	}
+
+	err = middleware.RequireOwner(r.Context(), order.UserID, constants.RoleSupport, constants.RoleFulfillment)
+	switch {
+	case errors.Is(err, middleware.ErrNotAuthenticated):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	case err != nil:
+		// Don't reveal that someone else's order exists
+		http.Error(w, "order not found", http.StatusNotFound)
+		return
+	}
+
+	invoice, err := h.InvoiceService.IssueInvoice(order.ID)
+	if errors.Is(err, service.ErrInvoiceNotAvailable) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("INVOICE: failed to issue invoice for order %d: %v", orderID, err)
+		http.Error(w, "failed to issue invoice", http.StatusInternalServerError)
+		return
+	}
+
+	filename := strings.ReplaceAll(invoice.Number, "/", "-") + ".pdf"
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", filename))
+	w.Header().Set("Content-Length", strconv.Itoa(len(invoice.PDF)))
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	w.Write(invoice.PDF)
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/notify"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/service"
+	"gorm.io/gorm"
+)
+
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>{{.Title}}</title></head>
+<body style="font-family:Helvetica,Arial,sans-serif;max-width:480px;margin:48px auto;">
+<h1 style="font-size:20px;">{{.Title}}</h1>
+<p>{{.Message}}</p>
+{{if .Token}}<form method="post"><input type="hidden" name="token" value="{{.Token}}"><button type="submit">Unsubscribe</button></form>{{end}}
+</body></html>
+`))
+
+type UnsubscribeHandler struct {
+	NotificationService *service.NotificationService
+}
+
+func NewUnsubscribeHandler(notificationService *service.NotificationService) *UnsubscribeHandler {
+	return &UnsubscribeHandler{NotificationService: notificationService}
+}
+
+// ServeHTTP handles /unsubscribe?token=..., the link in marketing mail. GET
+// asks for confirmation, since link scanners follow every URL in an email;
+// POST unsubscribes, which is also what mail clients send for one-click
+// List-Unsubscribe.
+func (h *UnsubscribeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token := r.FormValue("token")
+	if token == "" {
+		http.Error(w, "missing token", http.StatusBadRequest)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		h.render(w, http.StatusOK, "Unsubscribe", "Stop receiving offers and reminders by email? You will still get emails about your orders.", token)
+		return
+	}
+
+	err := h.NotificationService.Unsubscribe(token)
+	switch {
+	case errors.Is(err, notify.ErrInvalidUnsubscribeToken), errors.Is(err, gorm.ErrRecordNotFound):
+		h.render(w, http.StatusBadRequest, "Link not valid", "This unsubscribe link is not valid. You can turn off marketing email in your account settings.", "")
+	case err != nil:
+		log.Printf("UNSUBSCRIBE: %v", err)
+		http.Error(w, "failed to unsubscribe", http.StatusInternalServerError)
+	default:
+		h.render(w, http.StatusOK, "You have been unsubscribed", "You will no longer receive offers and reminders by email.", "")
+	}
+}
+
+func (h *UnsubscribeHandler) render(w http.ResponseWriter, status int, title, message, token string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	unsubscribePage.Execute(w, map[string]string{"Title": title, "Message": message, "Token": token})
+}
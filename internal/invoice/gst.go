@@ -0,0 +1,104 @@
+// Package invoice builds GST tax invoices: the tax breakdown of an order and
+// its PDF rendering.
+package invoice
+
+import (
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/tax"
+)
+
+// Item is an order line as sold.
+type Item struct {
+	Description string
+	HSN         string
+	Quantity    int
+	UnitPrice   money.Money
+}
+
+// Line is an item with its share of the order discount and its GST.
+type Line struct {
+	Item
+	Gross    money.Money
+	Discount money.Money
+	Taxable  money.Money
+	Rate     int64
+	CGST     money.Money
+	SGST     money.Money
+	IGST     money.Money
+	Total    money.Money
+}
+
+// Breakdown is the tax summary of an invoice. Intra-state supplies split the
+// tax equally into CGST and SGST; inter-state supplies charge IGST.
+type Breakdown struct {
+	Lines      []Line
+	Interstate bool
+	// TaxIncluded is true when the unit prices already contain GST.
+	TaxIncluded bool
+	Gross       money.Money
+	Discount    money.Money
+	Taxable     money.Money
+	CGST        money.Money
+	SGST        money.Money
+	IGST        money.Money
+	Total       money.Money
+}
+
+// NewBreakdown splits the tax worked out for items into the heads charged on
+// the invoice. taxed must have a line for every item. Taxed shipping gets a
+// line of its own after the items, under the HSN of the goods it delivers.
+func NewBreakdown(items []Item, taxed tax.Result, interstate bool) Breakdown {
+	lines := taxed.Lines
+	if s := taxed.Shipping; s != nil {
+		shipping := Item{Description: "Shipping", Quantity: 1, UnitPrice: s.Gross}
+		if len(items) > 0 {
+			shipping.HSN = items[0].HSN
+		}
+		items = append(items[:len(items):len(items)], shipping)
+		lines = append(lines[:len(lines):len(lines)], *s)
+	}
+
+	b := Breakdown{
+		Lines:       make([]Line, len(items)),
+		Interstate:  interstate,
+		TaxIncluded: taxed.Included,
+		Gross:       taxed.Gross,
+		Discount:    taxed.Discount,
+		Taxable:     taxed.Taxable,
+		CGST:        money.INR(0),
+		SGST:        money.INR(0),
+		IGST:        money.INR(0),
+		Total:       taxed.Total,
+	}
+
+	if taxed.Shipping != nil {
+		b.Gross = b.Gross.Add(taxed.Shipping.Gross)
+	}
+
+	for i, item := range items {
+		t := lines[i]
+		line := &b.Lines[i]
+		*line = Line{
+			Item:     item,
+			Gross:    t.Gross,
+			Discount: t.Discount,
+			Taxable:  t.Taxable,
+			Rate:     t.Rate,
+			CGST:     money.INR(0),
+			SGST:     money.INR(0),
+			IGST:     money.INR(0),
+			Total:    t.Total,
+		}
+		if interstate {
+			line.IGST = t.Tax
+		} else {
+			line.CGST = money.New(money.DivRound(t.Tax.Amount, 2), t.Tax.Currency)
+			line.SGST = t.Tax.Sub(line.CGST)
+		}
+
+		b.CGST = b.CGST.Add(line.CGST)
+		b.SGST = b.SGST.Add(line.SGST)
+		b.IGST = b.IGST.Add(line.IGST)
+	}
+	return b
+}
@@ -0,0 +1,27 @@
+package invoice
+
+import (
+	"fmt"
+	"time"
+)
+
+// numberPrefix starts every invoice number. GST invoice numbers may be at
+// most 16 characters of letters, digits, "/" and "-".
+const numberPrefix = "INV"
+
+// FinancialYear returns the Indian financial year (April to March) that t
+// falls in, e.g. "26-27". Invoice numbers restart every financial year.
+func FinancialYear(t time.Time) string {
+	t = t.In(ist)
+	start := t.Year()
+	if t.Month() < time.April {
+		start--
+	}
+	return fmt.Sprintf("%02d-%02d", start%100, (start+1)%100)
+}
+
+// FormatNumber returns the invoice number for the seq'th invoice of a
+// financial year, e.g. "INV/26-27/000042".
+func FormatNumber(financialYear string, seq int64) string {
+	return fmt.Sprintf("%s/%s/%06d", numberPrefix, financialYear, seq)
+}
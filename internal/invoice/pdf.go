@@ -0,0 +1,264 @@
+package invoice
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
+)
+
+// Party is the seller or buyer on an invoice.
+type Party struct {
+	Name      string
+	GSTIN     string
+	Address   []string
+	Phone     string
+	StateCode string
+}
+
+// Document is everything printed on an invoice.
+type Document struct {
+	Number    string
+	IssuedAt  time.Time
+	OrderID   uint
+	OrderDate time.Time
+	Seller    Party
+	Buyer     Party
+	PromoCode string
+	Breakdown Breakdown
+	// Shipping is an untaxed delivery charge on top of the goods. Taxed
+	// shipping is a line of the Breakdown instead.
+	Shipping money.Money
+}
+
+// Total is the amount of the invoice, shipping included.
+func (d *Document) Total() money.Money {
+	return d.Breakdown.Total.Add(d.Shipping)
+}
+
+// ist is the time zone invoice dates are printed in.
+var ist = time.FixedZone("IST", 5*60*60+30*60)
+
+const (
+	pageMargin = 15.0
+	pageWidth  = 210.0 - 2*pageMargin
+	lineHeight = 5.0
+)
+
+type column struct {
+	title string
+	width float64
+	align string
+}
+
+// Render lays the invoice out on A4 pages and returns the PDF.
+func Render(doc *Document) ([]byte, error) {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(pageMargin, pageMargin, pageMargin)
+	pdf.SetAutoPageBreak(true, pageMargin)
+	pdf.SetCreationDate(doc.IssuedAt)
+	pdf.SetTitle("Tax Invoice "+doc.Number, true)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.CellFormat(pageWidth, 9, "TAX INVOICE", "", 1, "C", false, 0, "")
+	pdf.Ln(3)
+
+	// Seller on the left, invoice details on the right
+	top := pdf.GetY()
+	half := pageWidth / 2
+	writeParty(pdf, tr, "Sold by", doc.Seller, half)
+	sellerBottom := pdf.GetY()
+
+	pdf.SetXY(pageMargin+half, top)
+	details := [][2]string{
+		{"Invoice No.", doc.Number},
+		{"Invoice Date", doc.IssuedAt.In(ist).Format("02 Jan 2006")},
+		{"Order No.", strconv.FormatUint(uint64(doc.OrderID), 10)},
+		{"Order Date", doc.OrderDate.In(ist).Format("02 Jan 2006")},
+		{"Place of Supply", stateLabel(doc.Buyer.StateCode)},
+		{"Reverse Charge", "No"},
+	}
+	for _, d := range details {
+		pdf.SetX(pageMargin + half)
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.CellFormat(30, lineHeight, d[0], "", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.CellFormat(half-30, lineHeight, tr(d[1]), "", 1, "L", false, 0, "")
+	}
+	if pdf.GetY() < sellerBottom {
+		pdf.SetY(sellerBottom)
+	}
+	pdf.Ln(4)
+
+	writeParty(pdf, tr, "Bill to / Ship to", doc.Buyer, pageWidth)
+	pdf.Ln(4)
+
+	writeLines(pdf, tr, doc.Breakdown)
+	pdf.Ln(2)
+	writeTotals(pdf, doc)
+
+	pdf.Ln(6)
+	pdf.SetFont("Helvetica", "", 8)
+	footer := "This is a computer-generated invoice and does not require a signature."
+	if doc.Breakdown.TaxIncluded {
+		footer = "Prices are inclusive of GST. " + footer
+	}
+	pdf.MultiCell(pageWidth, 4, footer, "", "L", false)
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeParty(pdf *fpdf.Fpdf, tr func(string) string, heading string, p Party, width float64) {
+	x := pdf.GetX()
+	pdf.SetFont("Helvetica", "B", 9)
+	pdf.CellFormat(width, lineHeight, heading, "", 1, "L", false, 0, "")
+
+	pdf.SetX(x)
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.CellFormat(width, lineHeight, tr(p.Name), "", 1, "L", false, 0, "")
+
+	pdf.SetFont("Helvetica", "", 9)
+	lines := append([]string{}, p.Address...)
+	if p.Phone != "" {
+		lines = append(lines, "Phone: "+p.Phone)
+	}
+	if p.GSTIN != "" {
+		lines = append(lines, "GSTIN: "+p.GSTIN)
+	}
+	if p.StateCode != "" {
+		lines = append(lines, "State: "+stateLabel(p.StateCode))
+	}
+	for _, line := range lines {
+		pdf.SetX(x)
+		pdf.CellFormat(width, lineHeight, tr(fit(pdf, line, width)), "", 1, "L", false, 0, "")
+	}
+}
+
+func writeLines(pdf *fpdf.Fpdf, tr func(string) string, b Breakdown) {
+	columns := []column{
+		{"#", 7, "C"},
+		{"Description", 48, "L"},
+		{"HSN", 12, "C"},
+		{"Qty", 9, "R"},
+		{"Unit Price", 18, "R"},
+		{"Discount", 16, "R"},
+		{"Taxable", 18, "R"},
+		{"GST", 10, "R"},
+	}
+	if b.Interstate {
+		columns = append(columns, column{"IGST", 24, "R"})
+	} else {
+		columns = append(columns, column{"CGST", 12, "R"}, column{"SGST", 12, "R"})
+	}
+	columns = append(columns, column{"Total", 18, "R"})
+
+	pdf.SetFont("Helvetica", "B", 8)
+	pdf.SetFillColor(235, 235, 235)
+	for _, c := range columns {
+		pdf.CellFormat(c.width, 6, c.title, "1", 0, c.align, true, 0, "")
+	}
+	pdf.Ln(-1)
+
+	pdf.SetFont("Helvetica", "", 8)
+	for i, line := range b.Lines {
+		values := []string{
+			strconv.Itoa(i + 1),
+			line.Description,
+			line.HSN,
+			strconv.Itoa(line.Quantity),
+			line.UnitPrice.Decimal(),
+			line.Discount.Decimal(),
+			line.Taxable.Decimal(),
+			formatRate(line.Rate),
+		}
+		if b.Interstate {
+			values = append(values, line.IGST.Decimal())
+		} else {
+			values = append(values, line.CGST.Decimal(), line.SGST.Decimal())
+		}
+		values = append(values, line.Total.Decimal())
+
+		for j, c := range columns {
+			pdf.CellFormat(c.width, 6, tr(fit(pdf, values[j], c.width-2)), "1", 0, c.align, false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+}
+
+func writeTotals(pdf *fpdf.Fpdf, doc *Document) {
+	b := doc.Breakdown
+	rows := [][2]string{{"Gross amount", b.Gross.Decimal()}}
+	if !b.Discount.IsZero() {
+		label := "Discount"
+		if doc.PromoCode != "" {
+			label = fmt.Sprintf("Discount (%s)", doc.PromoCode)
+		}
+		rows = append(rows, [2]string{label, "-" + b.Discount.Decimal()})
+	}
+	rows = append(rows, [2]string{"Taxable value", b.Taxable.Decimal()})
+	if b.Interstate {
+		rows = append(rows, [2]string{"IGST", b.IGST.Decimal()})
+	} else {
+		rows = append(rows, [2]string{"CGST", b.CGST.Decimal()}, [2]string{"SGST", b.SGST.Decimal()})
+	}
+	if !doc.Shipping.IsZero() {
+		rows = append(rows, [2]string{"Shipping", doc.Shipping.Decimal()})
+	}
+
+	const labelWidth, valueWidth = 45.0, 30.0
+	x := pageMargin + pageWidth - labelWidth - valueWidth
+	pdf.SetFont("Helvetica", "", 9)
+	for _, row := range rows {
+		pdf.SetX(x)
+		pdf.CellFormat(labelWidth, lineHeight, row[0], "", 0, "L", false, 0, "")
+		pdf.CellFormat(valueWidth, lineHeight, row[1], "", 1, "R", false, 0, "")
+	}
+
+	pdf.SetX(x)
+	pdf.SetFont("Helvetica", "B", 10)
+	total := doc.Total()
+	pdf.CellFormat(labelWidth, 7, "Invoice total ("+currency(total)+")", "T", 0, "L", false, 0, "")
+	pdf.CellFormat(valueWidth, 7, total.Decimal(), "T", 1, "R", false, 0, "")
+}
+
+func stateLabel(code string) string {
+	if name := StateName(code); name != "" {
+		return fmt.Sprintf("%s (%s)", name, code)
+	}
+	return code
+}
+
+func formatRate(bps int64) string {
+	if bps%100 == 0 {
+		return fmt.Sprintf("%d%%", bps/100)
+	}
+	return fmt.Sprintf("%.2f%%", float64(bps)/100)
+}
+
+func currency(m money.Money) string {
+	if m.Currency == "" {
+		return money.DefaultCurrency
+	}
+	return m.Currency
+}
+
+// fit shortens s with an ellipsis until it fits in width at the current font.
+func fit(pdf *fpdf.Fpdf, s string, width float64) string {
+	if pdf.GetStringWidth(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && pdf.GetStringWidth(string(runes)+"...") > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
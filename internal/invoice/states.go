@@ -0,0 +1,90 @@
+package invoice
+
+import (
+	"strings"
+)
+
+// stateNames maps GST state codes to the state or union territory name.
+var stateNames = map[string]string{
+	"01": "Jammu and Kashmir",
+	"02": "Himachal Pradesh",
+	"03": "Punjab",
+	"04": "Chandigarh",
+	"05": "Uttarakhand",
+	"06": "Haryana",
+	"07": "Delhi",
+	"08": "Rajasthan",
+	"09": "Uttar Pradesh",
+	"10": "Bihar",
+	"11": "Sikkim",
+	"12": "Arunachal Pradesh",
+	"13": "Nagaland",
+	"14": "Manipur",
+	"15": "Mizoram",
+	"16": "Tripura",
+	"17": "Meghalaya",
+	"18": "Assam",
+	"19": "West Bengal",
+	"20": "Jharkhand",
+	"21": "Odisha",
+	"22": "Chhattisgarh",
+	"23": "Madhya Pradesh",
+	"24": "Gujarat",
+	"26": "Dadra and Nagar Haveli and Daman and Diu",
+	"27": "Maharashtra",
+	"29": "Karnataka",
+	"30": "Goa",
+	"31": "Lakshadweep",
+	"32": "Kerala",
+	"33": "Tamil Nadu",
+	"34": "Puducherry",
+	"35": "Andaman and Nicobar Islands",
+	"36": "Telangana",
+	"37": "Andhra Pradesh",
+	"38": "Ladakh",
+}
+
+// stateAliases covers ISO 3166-2:IN codes and older or informal names that
+// customers type into the state field.
+var stateAliases = map[string]string{
+	"jk": "01", "j&k": "01", "jammu & kashmir": "01",
+	"hp": "02", "pb": "03", "ch": "04",
+	"uk": "05", "ut": "05", "uttaranchal": "05",
+	"hr": "06", "dl": "07", "new delhi": "07", "nct of delhi": "07",
+	"rj": "08", "up": "09", "br": "10", "sk": "11", "ar": "12",
+	"nl": "13", "mn": "14", "mz": "15", "tr": "16", "ml": "17",
+	"as": "18", "wb": "19", "jh": "20",
+	"od": "21", "or": "21", "orissa": "21",
+	"cg": "22", "ct": "22", "mp": "23", "gj": "24",
+	"dn": "26", "dd": "26", "dh": "26",
+	"mh": "27", "ka": "29", "ga": "30", "ld": "31", "kl": "32",
+	"tn": "33", "py": "34", "pondicherry": "34",
+	"an": "35", "andaman & nicobar islands": "35",
+	"tg": "36", "ts": "36", "ap": "37", "la": "38",
+}
+
+// StateCode returns the two-digit GST code for a state name, code or common
+// abbreviation.
+func StateCode(state string) (string, bool) {
+	key := strings.ToLower(strings.Join(strings.Fields(state), " "))
+	if key == "" {
+		return "", false
+	}
+	if _, ok := stateNames[key]; ok {
+		return key, true
+	}
+	if code, ok := stateAliases[key]; ok {
+		return code, true
+	}
+	for code, name := range stateNames {
+		if strings.ToLower(name) == key {
+			return code, true
+		}
+	}
+	return "", false
+}
+
+// StateName returns the name of the state with the given GST code.
+func StateName(code string) string {
+	return stateNames[code]
+}
@@ -0,0 +1,259 @@
+package loaders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/graph-gophers/dataloader/v7"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"gorm.io/gorm"
+)
+
+type contextKey string
+
+const loadersKey = contextKey("dataloaders")
+
+// batchWait is how long a loader collects keys before running its query.
+const batchWait = 2 * time.Millisecond
+
+// Loaders batch the per-object lookups made by field resolvers into one query
+// per type. A new set is created for every request so cached rows never leak
+// between users or outlive the request.
+type Loaders struct {
+	ProductByID          *dataloader.Loader[uint, *models.Product]
+	VariantByID          *dataloader.Loader[uint, *models.ProductVariant]
+	VariantsByProductID  *dataloader.Loader[uint, []models.ProductVariant]
+	InventoryByVariantID *dataloader.Loader[uint, *models.Inventory]
+	OrderItemsByOrderID  *dataloader.Loader[uint, []*models.OrderItem]
+	PaymentByOrderID     *dataloader.Loader[uint, *models.Payment]
+	// CartItemsByCart is keyed by the cart object rather than its ID, so a
+	// cart reloaded after a mutation gets its items loaded again.
+	CartItemsByCart *dataloader.Loader[*models.Cart, []models.CartItem]
+}
+
+func NewLoaders(db *gorm.DB) *Loaders {
+	r := &reader{db: db}
+	return &Loaders{
+		ProductByID:          newLoader(r.getProducts),
+		VariantByID:          newLoader(r.getVariants),
+		VariantsByProductID:  newLoader(r.getVariantsByProduct),
+		InventoryByVariantID: newLoader(r.getInventories),
+		OrderItemsByOrderID:  newLoader(r.getOrderItems),
+		PaymentByOrderID:     newLoader(r.getPayments),
+		CartItemsByCart:      newLoader(r.getCartItems),
+	}
+}
+
+func newLoader[K comparable, V any](fetch dataloader.BatchFunc[K, V]) *dataloader.Loader[K, V] {
+	return dataloader.NewBatchedLoader(fetch, dataloader.WithWait[K, V](batchWait))
+}
+
+// Middleware attaches a fresh set of loaders to every request.
+func Middleware(db *gorm.DB) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), loadersKey, NewLoaders(db))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// ErrNoLoaders is returned when a resolver runs on a context that did not go
+// through Middleware.
+var ErrNoLoaders = errors.New("loaders: no loaders in context")
+
+// For returns the request's loaders.
+func For(ctx context.Context) (*Loaders, error) {
+	l, ok := ctx.Value(loadersKey).(*Loaders)
+	if !ok {
+		return nil, ErrNoLoaders
+	}
+	return l, nil
+}
+
+// load runs key through the loader pick chooses from the request's loaders.
+func load[K comparable, V any](ctx context.Context, pick func(*Loaders) *dataloader.Loader[K, V], key K) (V, error) {
+	l, err := For(ctx)
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+	return pick(l).Load(ctx, key)()
+}
+
+func GetProduct(ctx context.Context, id uint) (*models.Product, error) {
+	return load(ctx, func(l *Loaders) *dataloader.Loader[uint, *models.Product] { return l.ProductByID }, id)
+}
+
+func GetVariant(ctx context.Context, id uint) (*models.ProductVariant, error) {
+	return load(ctx, func(l *Loaders) *dataloader.Loader[uint, *models.ProductVariant] { return l.VariantByID }, id)
+}
+
+func GetVariantsByProduct(ctx context.Context, productID uint) ([]models.ProductVariant, error) {
+	return load(ctx, func(l *Loaders) *dataloader.Loader[uint, []models.ProductVariant] { return l.VariantsByProductID }, productID)
+}
+
+// GetInventory returns nil without error for a variant with no inventory row.
+func GetInventory(ctx context.Context, variantID uint) (*models.Inventory, error) {
+	return load(ctx, func(l *Loaders) *dataloader.Loader[uint, *models.Inventory] { return l.InventoryByVariantID }, variantID)
+}
+
+func GetOrderItems(ctx context.Context, orderID uint) ([]*models.OrderItem, error) {
+	return load(ctx, func(l *Loaders) *dataloader.Loader[uint, []*models.OrderItem] { return l.OrderItemsByOrderID }, orderID)
+}
+
+// GetPayment returns nil without error for an order that has no payment yet.
+func GetPayment(ctx context.Context, orderID uint) (*models.Payment, error) {
+	return load(ctx, func(l *Loaders) *dataloader.Loader[uint, *models.Payment] { return l.PaymentByOrderID }, orderID)
+}
+
+// GetCartItems returns a cart's items with their variants and products.
+func GetCartItems(ctx context.Context, cart *models.Cart) ([]models.CartItem, error) {
+	return load(ctx, func(l *Loaders) *dataloader.Loader[*models.Cart, []models.CartItem] { return l.CartItemsByCart }, cart)
+}
+
+type reader struct {
+	db *gorm.DB
+}
+
+func (r *reader) getProducts(ctx context.Context, ids []uint) []*dataloader.Result[*models.Product] {
+	var products []models.Product
+	err := r.db.WithContext(ctx).Unscoped().Where("id IN ?", ids).Find(&products).Error
+
+	byID := make(map[uint]*models.Product, len(products))
+	for i := range products {
+		byID[products[i].ID] = &products[i]
+	}
+	return results(ids, err, func(id uint) (*models.Product, error) {
+		p, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("product %d not found", id)
+		}
+		return p, nil
+	})
+}
+
+func (r *reader) getVariants(ctx context.Context, ids []uint) []*dataloader.Result[*models.ProductVariant] {
+	var variants []models.ProductVariant
+	err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&variants).Error
+
+	byID := make(map[uint]*models.ProductVariant, len(variants))
+	for i := range variants {
+		byID[variants[i].ID] = &variants[i]
+	}
+	return results(ids, err, func(id uint) (*models.ProductVariant, error) {
+		v, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("variant %d not found", id)
+		}
+		return v, nil
+	})
+}
+
+func (r *reader) getVariantsByProduct(ctx context.Context, productIDs []uint) []*dataloader.Result[[]models.ProductVariant] {
+	var variants []models.ProductVariant
+	err := r.db.WithContext(ctx).
+		Where("product_id IN ?", productIDs).
+		Order("id ASC").
+		Find(&variants).Error
+
+	byProduct := make(map[uint][]models.ProductVariant, len(productIDs))
+	for _, v := range variants {
+		byProduct[v.ProductID] = append(byProduct[v.ProductID], v)
+	}
+	return results(productIDs, err, func(id uint) ([]models.ProductVariant, error) {
+		if byProduct[id] == nil {
+			return []models.ProductVariant{}, nil
+		}
+		return byProduct[id], nil
+	})
+}
+
+func (r *reader) getInventories(ctx context.Context, variantIDs []uint) []*dataloader.Result[*models.Inventory] {
+	var inventories []models.Inventory
+	err := r.db.WithContext(ctx).Where("variant_id IN ?", variantIDs).Find(&inventories).Error
+
+	byVariant := make(map[uint]*models.Inventory, len(inventories))
+	for i := range inventories {
+		byVariant[inventories[i].VariantID] = &inventories[i]
+	}
+	return results(variantIDs, err, func(id uint) (*models.Inventory, error) {
+		return byVariant[id], nil
+	})
+}
+
+func (r *reader) getOrderItems(ctx context.Context, orderIDs []uint) []*dataloader.Result[[]*models.OrderItem] {
+	var items []*models.OrderItem
+	err := r.db.WithContext(ctx).
+		Where("order_id IN ?", orderIDs).
+		Order("id ASC").
+		Find(&items).Error
+
+	byOrder := make(map[uint][]*models.OrderItem, len(orderIDs))
+	for _, item := range items {
+		byOrder[item.OrderID] = append(byOrder[item.OrderID], item)
+	}
+	return results(orderIDs, err, func(id uint) ([]*models.OrderItem, error) {
+		if byOrder[id] == nil {
+			return []*models.OrderItem{}, nil
+		}
+		return byOrder[id], nil
+	})
+}
+
+func (r *reader) getPayments(ctx context.Context, orderIDs []uint) []*dataloader.Result[*models.Payment] {
+	var payments []models.Payment
+	err := r.db.WithContext(ctx).Where("order_id IN ?", orderIDs).Find(&payments).Error
+
+	byOrder := make(map[uint]*models.Payment, len(payments))
+	for i := range payments {
+		byOrder[payments[i].OrderID] = &payments[i]
+	}
+	return results(orderIDs, err, func(id uint) (*models.Payment, error) {
+		return byOrder[id], nil
+	})
+}
+
+func (r *reader) getCartItems(ctx context.Context, carts []*models.Cart) []*dataloader.Result[[]models.CartItem] {
+	ids := make([]uint, len(carts))
+	for i, cart := range carts {
+		ids[i] = cart.ID
+	}
+
+	var items []models.CartItem
+	err := r.db.WithContext(ctx).
+		Preload("Variant").
+		Preload("Variant.Product").
+		Where("cart_id IN ?", ids).
+		Order("id ASC").
+		Find(&items).Error
+
+	byCart := make(map[uint][]models.CartItem, len(ids))
+	for _, item := range items {
+		byCart[item.CartID] = append(byCart[item.CartID], item)
+	}
+	return results(carts, err, func(cart *models.Cart) ([]models.CartItem, error) {
+		if byCart[cart.ID] == nil {
+			return []models.CartItem{}, nil
+		}
+		return byCart[cart.ID], nil
+	})
+}
+
+// results builds the loader response in key order, failing every key if the
+// batch query itself failed.
+func results[K comparable, V any](keys []K, err error, lookup func(K) (V, error)) []*dataloader.Result[V] {
+	out := make([]*dataloader.Result[V], len(keys))
+	for i, key := range keys {
+		if err != nil {
+			out[i] = &dataloader.Result[V]{Error: err}
+			continue
+		}
+		v, lookupErr := lookup(key)
+		out[i] = &dataloader.Result[V]{Data: v, Error: lookupErr}
+	}
+	return out
+}
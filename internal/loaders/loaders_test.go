@@ -0,0 +1,16 @@
+package loaders
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestGetWithoutMiddleware(t *testing.T) {
+	if _, err := For(context.Background()); !errors.Is(err, ErrNoLoaders) {
+		t.Fatalf("For: err = %v, want ErrNoLoaders", err)
+	}
+	if _, err := GetProduct(context.Background(), 1); !errors.Is(err, ErrNoLoaders) {
+		t.Fatalf("GetProduct: err = %v, want ErrNoLoaders", err)
+	}
+}
@@ -0,0 +1,64 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// FileSink writes every message to an .eml file in Dir instead of sending
+// it, for development.
+type FileSink struct {
+	Dir  string
+	From string
+
+	seq atomic.Int64
+}
+
+func NewFileSink(dir, from string) (*FileSink, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileSink{Dir: dir, From: from}, nil
+}
+
+func (s *FileSink) Send(ctx context.Context, msg Message) error {
+	now := time.Now()
+	data, err := Encode(s.From, msg, now)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%04d.eml", now.Format("20060102T150405"), s.seq.Add(1))
+	return os.WriteFile(filepath.Join(s.Dir, name), data, 0o644)
+}
+
+// Memory keeps sent messages in memory, for tests.
+type Memory struct {
+	mu   sync.Mutex
+	sent []Message
+}
+
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (m *Memory) Send(ctx context.Context, msg Message) error {
+	if _, err := Encode("test@localhost", msg, time.Now()); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// Sent returns the messages sent so far, oldest first.
+func (m *Memory) Sent() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.sent...)
+}
@@ -0,0 +1,69 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// SMTPConfig configures an SMTP relay. Without a username no
+// authentication is attempted. The connection is upgraded with STARTTLS
+// whenever the server offers it.
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	// From is the sender, e.g. "Store <orders@example.com>".
+	From string
+}
+
+// SMTP sends mail through an SMTP relay.
+type SMTP struct {
+	Config SMTPConfig
+}
+
+func NewSMTP(cfg SMTPConfig) *SMTP {
+	if cfg.Port == 0 {
+		cfg.Port = 587
+	}
+	return &SMTP{Config: cfg}
+}
+
+func (s *SMTP) Send(ctx context.Context, msg Message) error {
+	data, err := Encode(s.Config.From, msg, time.Now())
+	if err != nil {
+		return err
+	}
+	from, err := mail.ParseAddress(s.Config.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender %q: %w", s.Config.From, err)
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("%w: bad recipient %q", ErrInvalidMessage, msg.To)
+	}
+
+	var auth smtp.Auth
+	if s.Config.Username != "" {
+		auth = smtp.PlainAuth("", s.Config.Username, s.Config.Password, s.Config.Host)
+	}
+	addr := net.JoinHostPort(s.Config.Host, strconv.Itoa(s.Config.Port))
+
+	// net/smtp has no context support, so give up waiting on cancellation
+	// and let the send finish in the background.
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(addr, auth, from.Address, []string{to.Address}, data)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -3,17 +3,70 @@ package middleware
 import (
 	"context"
 	"errors"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/auth"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
 )
 
-func RequireAdmin(ctx context.Context) error {
+var (
+	ErrNotAuthenticated = errors.New("not authenticated")
+	ErrForbidden        = errors.New("forbidden")
+)
+
+// RequireUser returns the signed-in user, or ErrNotAuthenticated.
+func RequireUser(ctx context.Context) (*auth.ClerkClaims, error) {
 	user := GetUserFromContext(ctx)
 	if user == nil {
-		return errors.New("not authenticated")
+		return nil, ErrNotAuthenticated
+	}
+	return user, nil
+}
+
+// RequireRole allows signed-in users whose role is one of roles. Admins pass
+// every role check.
+func RequireRole(ctx context.Context, roles ...string) error {
+	user, err := RequireUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	if hasRole(user, roles) {
+		return nil
+	}
+	return ErrForbidden
+}
+
+func RequireAdmin(ctx context.Context) error {
+	return RequireRole(ctx, constants.RoleAdmin)
+}
+
+// IsAdmin reports whether the signed-in user is an admin.
+func IsAdmin(ctx context.Context) bool {
+	return RequireAdmin(ctx) == nil
+}
+
+// RequireOwner allows the user whose ID is ownerID, an admin, or staff with
+// one of roles.
+func RequireOwner(ctx context.Context, ownerID string, roles ...string) error {
+	user, err := RequireUser(ctx)
+	if err != nil {
+		return err
 	}
 
-	if user.Role != "admin" {
-		return errors.New("forbidden")
+	if user.UserID == ownerID || hasRole(user, roles) {
+		return nil
 	}
+	return ErrForbidden
+}
 
-	return nil
+func hasRole(user *auth.ClerkClaims, roles []string) bool {
+	if user.Role == constants.RoleAdmin {
+		return true
+	}
+	for _, role := range roles {
+		if user.Role == role {
+			return true
+		}
+	}
+	return false
 }
@@ -10,6 +10,10 @@ import (
 type contextKey string
 
 const UserContextKey = contextKey("user")
+const GuestSessionContextKey = contextKey("guest_session")
+
+// GuestSessionHeader carries the token issued by createGuestSession.
+const GuestSessionHeader = "X-Guest-Session"
 
 func AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -20,6 +24,8 @@ func AuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		r = withGuestSession(r)
+
 		authHeader := r.Header.Get("Authorization")
 		log.Printf("AUTH MIDDLEWARE: method=%s path=%s auth_header=%s", r.Method, r.URL.Path, authHeader)
 
@@ -44,6 +50,31 @@ func AuthMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// withGuestSession attaches a valid guest session to the request. It is kept
+// alongside a signed-in user so the guest cart can be merged after login.
+func withGuestSession(r *http.Request) *http.Request {
+	token := r.Header.Get(GuestSessionHeader)
+	if token == "" {
+		return r
+	}
+
+	session, err := auth.ValidateGuestToken(token)
+	if err != nil {
+		log.Printf("AUTH MIDDLEWARE: Ignoring guest session, error: %v", err)
+		return r
+	}
+
+	return r.WithContext(context.WithValue(r.Context(), GuestSessionContextKey, session))
+}
+
+func GetGuestSessionFromContext(ctx context.Context) *auth.GuestSession {
+	session, ok := ctx.Value(GuestSessionContextKey).(*auth.GuestSession)
+	if !ok {
+		return nil
+	}
+	return session
+}
+
 func GetUserFromContext(ctx context.Context) *auth.ClerkClaims {
 	user, ok := ctx.Value(UserContextKey).(*auth.ClerkClaims)
 	if !ok {
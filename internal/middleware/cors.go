@@ -38,6 +38,7 @@ func CorsMiddleware() *cors.Cors {
 			"X-Requested-With",
 			"X-CSRF-Token",
 			"Content-Length",
+			"X-Guest-Session",
 		},
 		ExposedHeaders: []string{
 			"Link",
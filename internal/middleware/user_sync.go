@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/auth"
+)
+
+// UserSyncer provisions the local user for a signed-in Clerk identity.
+type UserSyncer interface {
+	SyncUser(ctx context.Context, claims *auth.ClerkClaims) error
+}
+
+// UserSyncMiddleware creates or refreshes the local user row for every
+// authenticated request. It must run after AuthMiddleware. A failed sync is
+// logged and the request continues, since the token itself is valid.
+func UserSyncMiddleware(syncer UserSyncer) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if claims := GetUserFromContext(r.Context()); claims != nil {
+				if err := syncer.SyncUser(r.Context(), claims); err != nil {
+					log.Printf("USER SYNC: failed to sync user %s: %v", claims.UserID, err)
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
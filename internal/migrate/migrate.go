@@ -0,0 +1,351 @@
+// Package migrate applies the versioned SQL migrations in migrations/. Each
+// version has an up and a down file named <version>_<name>.up.sql and
+// <version>_<name>.down.sql; applied versions are recorded in
+// schema_migrations.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var embedded embed.FS
+
+// Dir is where new migrations are created, relative to the module root.
+const Dir = "internal/migrate/migrations"
+
+// lockKey identifies the Postgres advisory lock held while migrating, so
+// replicas starting together apply each migration once.
+const lockKey int64 = 0x7473686972740001
+
+var (
+	fileName      = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+	migrationName = regexp.MustCompile(`^[a-z0-9_]+$`)
+)
+
+var ErrNoMigration = errors.New("no such migration")
+
+type Migration struct {
+	Version uint64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status is a migration together with when it was applied. AppliedAt is nil
+// for pending migrations. Missing is set for versions recorded in the
+// database that have no file in this build.
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+	Missing   bool
+}
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// New returns a migrator for the migrations built into the binary.
+func New(db *sql.DB) (*Migrator, error) {
+	sub, err := fs.Sub(embedded, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return NewFromFS(db, sub)
+}
+
+// NewFromFS returns a migrator for the migration files at the root of fsys.
+func NewFromFS(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Up applies every pending migration in version order and returns how many
+// were applied.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	applied := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range m.migrations {
+			if _, ok := done[mig.Version]; ok {
+				continue
+			}
+			if err := apply(ctx, conn, mig, true); err != nil {
+				return err
+			}
+			applied++
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down rolls back the most recent steps applied migrations and returns how
+// many were rolled back.
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	byVersion := make(map[uint64]Migration, len(m.migrations))
+	for _, mig := range m.migrations {
+		byVersion[mig.Version] = mig
+	}
+
+	rolledBack := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		versions := make([]uint64, 0, len(done))
+		for v := range done {
+			versions = append(versions, v)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+		for _, v := range versions {
+			if rolledBack == steps {
+				break
+			}
+			mig, ok := byVersion[v]
+			if !ok {
+				return fmt.Errorf("%w: version %d is applied but has no file", ErrNoMigration, v)
+			}
+			if err := apply(ctx, conn, mig, false); err != nil {
+				return err
+			}
+			rolledBack++
+		}
+		return nil
+	})
+	return rolledBack, err
+}
+
+// Status lists every known migration, and any applied version with no file,
+// in version order.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := ensureTable(ctx, conn); err != nil {
+		return nil, err
+	}
+	done, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		s := Status{Migration: mig}
+		if a, ok := done[mig.Version]; ok {
+			s.AppliedAt = &a.at
+			delete(done, mig.Version)
+		}
+		statuses = append(statuses, s)
+	}
+	for v, a := range done {
+		statuses = append(statuses, Status{Migration: Migration{Version: v, Name: a.name}, AppliedAt: &a.at, Missing: true})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// Create writes empty up and down files for a new migration in dir, numbered
+// after the highest version already there, and returns their paths.
+func Create(dir, name string) (string, string, error) {
+	name = strings.ToLower(strings.Join(strings.FieldsFunc(name, func(r rune) bool {
+		return r == ' ' || r == '-'
+	}), "_"))
+	if !migrationName.MatchString(name) {
+		return "", "", fmt.Errorf("invalid migration name %q: use letters, digits and underscores", name)
+	}
+
+	existing, err := load(os.DirFS(dir))
+	if err != nil {
+		return "", "", err
+	}
+	var next uint64 = 1
+	if len(existing) > 0 {
+		next = existing[len(existing)-1].Version + 1
+	}
+
+	base := filepath.Join(dir, fmt.Sprintf("%04d_%s", next, name))
+	up, down := base+".up.sql", base+".down.sql"
+	for _, path := range []string{up, down} {
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			return "", "", err
+		}
+	}
+	return up, down, nil
+}
+
+// withLock runs fn on a single connection holding the migration advisory
+// lock. Advisory locks belong to a session, so every statement must go
+// through that connection.
+func (m *Migrator) withLock(ctx context.Context, fn func(*sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockKey); err != nil {
+		return fmt.Errorf("failed to take migration lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockKey); err != nil {
+			log.Printf("Failed to release migration lock: %v", err)
+		}
+	}()
+
+	if err := ensureTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+// apply runs one migration up or down in a transaction together with its
+// schema_migrations bookkeeping.
+func apply(ctx context.Context, conn *sql.Conn, mig Migration, up bool) error {
+	direction, script := "up", mig.Up
+	if !up {
+		direction, script = "down", mig.Down
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if strings.TrimSpace(script) != "" {
+		if _, err := tx.ExecContext(ctx, script); err != nil {
+			return fmt.Errorf("migration %04d_%s %s failed: %w", mig.Version, mig.Name, direction, err)
+		}
+	}
+
+	if up {
+		_, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", mig.Version, mig.Name)
+	} else {
+		_, err = tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = $1", mig.Version)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Printf("Migrated %s: %04d_%s", direction, mig.Version, mig.Name)
+	return nil
+}
+
+func ensureTable(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version bigint PRIMARY KEY,
+		name text NOT NULL,
+		applied_at timestamptz NOT NULL DEFAULT now()
+	)`)
+	return err
+}
+
+type appliedMigration struct {
+	name string
+	at   time.Time
+}
+
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[uint64]appliedMigration, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, name, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	done := make(map[uint64]appliedMigration)
+	for rows.Next() {
+		var version uint64
+		var a appliedMigration
+		if err := rows.Scan(&version, &a.name, &a.at); err != nil {
+			return nil, err
+		}
+		done[version] = a
+	}
+	return done, rows.Err()
+}
+
+// load reads the migrations in fsys, checking that every version has exactly
+// one name and both an up and a down file.
+func load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	type files struct {
+		Migration
+		hasUp, hasDown bool
+	}
+	byVersion := make(map[uint64]*files)
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", entry.Name(), err)
+		}
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		f, ok := byVersion[version]
+		if !ok {
+			f = &files{Migration: Migration{Version: version, Name: match[2]}}
+			byVersion[version] = f
+		}
+		if f.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d is used by both %s and %s", version, f.Name, match[2])
+		}
+		if match[3] == "up" {
+			f.Up, f.hasUp = string(data), true
+		} else {
+			f.Down, f.hasDown = string(data), true
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, f := range byVersion {
+		if !f.hasUp || !f.hasDown {
+			return nil, fmt.Errorf("migration %04d_%s needs both an up and a down file", f.Version, f.Name)
+		}
+		migrations = append(migrations, f.Migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
@@ -0,0 +1,16 @@
+-- The pg_trgm extension is left installed; other database objects may use it.
+
+DROP TABLE IF EXISTS "webhook_events";
+DROP TABLE IF EXISTS "promo_codes";
+DROP TABLE IF EXISTS "refunds";
+DROP TABLE IF EXISTS "payments";
+DROP TABLE IF EXISTS "order_items";
+DROP TABLE IF EXISTS "orders";
+DROP TABLE IF EXISTS "cart_items";
+DROP TABLE IF EXISTS "carts";
+DROP TABLE IF EXISTS "inventory_reservations";
+DROP TABLE IF EXISTS "inventories";
+DROP TABLE IF EXISTS "product_variants";
+DROP TABLE IF EXISTS "products";
+DROP TABLE IF EXISTS "addresses";
+DROP TABLE IF EXISTS "users";
@@ -0,0 +1,254 @@
+-- Baseline: the schema as AutoMigrate left it, plus the product search
+-- columns and indexes. Every statement is idempotent so databases created
+-- before versioned migrations can adopt it as-is. Those databases already
+-- have users, carts, orders and payments, so CREATE TABLE IF NOT EXISTS
+-- leaves them alone; the columns added to them since are added separately
+-- before anything uses them.
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE TABLE IF NOT EXISTS "users" (
+    "id" bigserial,
+    "clerk_user_id" varchar(255),
+    "email" text NOT NULL,
+    "password_hash" text NOT NULL,
+    "name" text NOT NULL,
+    "phone" text,
+    "address" text,
+    "role" text DEFAULT 'user',
+    "o_auth_provider" text DEFAULT 'local',
+    "o_auth_id" text,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "clerk_user_id" varchar(255);
+CREATE INDEX IF NOT EXISTS "idx_users_deleted_at" ON "users" ("deleted_at");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_users_clerk_user_id" ON "users" ("clerk_user_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_users_email" ON "users" ("email");
+
+CREATE TABLE IF NOT EXISTS "addresses" (
+    "id" bigserial,
+    "user_id" varchar(255) NOT NULL,
+    "name" varchar(100) NOT NULL,
+    "line1" varchar(255) NOT NULL,
+    "line2" varchar(255),
+    "city" varchar(100) NOT NULL,
+    "state" varchar(100) NOT NULL,
+    "postal_code" varchar(20) NOT NULL,
+    "country" varchar(2) NOT NULL DEFAULT 'IN',
+    "phone" varchar(20) NOT NULL,
+    "is_default" boolean NOT NULL DEFAULT false,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_addresses_deleted_at" ON "addresses" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_addresses_user_id" ON "addresses" ("user_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_addresses_user_default" ON "addresses" ("user_id") WHERE is_default AND deleted_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS "products" (
+    "id" bigserial,
+    "name" text NOT NULL,
+    "description" text,
+    "design_image_url" text,
+    "image_urls" text[],
+    "base_price" bigint NOT NULL,
+    "material" varchar(100),
+    "neckline" varchar(50),
+    "sleeve_type" varchar(50),
+    "fit" varchar(50),
+    "brand" varchar(100),
+    "category" varchar(100),
+    "care_instructions" text,
+    "weight" decimal(10,2),
+    "featured" boolean DEFAULT false,
+    "limited_edition" boolean DEFAULT false,
+    "is_active" boolean DEFAULT true,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_products_deleted_at" ON "products" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "product_variants" (
+    "id" bigserial,
+    "product_id" bigint NOT NULL,
+    "size" text NOT NULL,
+    "color" text,
+    "price_modifier" bigint NOT NULL,
+    "sku" text NOT NULL,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_products_variants" FOREIGN KEY ("product_id") REFERENCES "products"("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_product_variants_sku" ON "product_variants" ("sku");
+
+CREATE TABLE IF NOT EXISTS "inventories" (
+    "id" bigserial,
+    "variant_id" bigint NOT NULL,
+    "stock_quantity" bigint NOT NULL DEFAULT 0,
+    "reserved_quantity" bigint NOT NULL DEFAULT 0,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_product_variants_inventory" FOREIGN KEY ("variant_id") REFERENCES "product_variants"("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_inventories_variant_id" ON "inventories" ("variant_id");
+
+CREATE TABLE IF NOT EXISTS "inventory_reservations" (
+    "id" bigserial,
+    "order_id" bigint NOT NULL,
+    "variant_id" bigint NOT NULL,
+    "quantity" bigint NOT NULL,
+    "status" varchar(20) NOT NULL,
+    "expires_at" timestamptz NOT NULL,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_inventory_reservations_expires_at" ON "inventory_reservations" ("expires_at");
+CREATE INDEX IF NOT EXISTS "idx_inventory_reservations_order_id" ON "inventory_reservations" ("order_id");
+CREATE INDEX IF NOT EXISTS "idx_inventory_reservations_status" ON "inventory_reservations" ("status");
+CREATE INDEX IF NOT EXISTS "idx_inventory_reservations_variant_id" ON "inventory_reservations" ("variant_id");
+
+CREATE TABLE IF NOT EXISTS "carts" (
+    "id" bigserial,
+    "user_id" text,
+    "guest_session_id" varchar(64),
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+ALTER TABLE "carts" ADD COLUMN IF NOT EXISTS "guest_session_id" varchar(64);
+CREATE INDEX IF NOT EXISTS "idx_carts_deleted_at" ON "carts" ("deleted_at");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_carts_guest_session_id" ON "carts" ("guest_session_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_carts_user_id" ON "carts" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "cart_items" (
+    "id" bigserial,
+    "cart_id" bigint NOT NULL,
+    "variant_id" bigint NOT NULL,
+    "quantity" bigint NOT NULL,
+    "added_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_cart_items_variant" FOREIGN KEY ("variant_id") REFERENCES "product_variants"("id"),
+    CONSTRAINT "fk_carts_cart_items" FOREIGN KEY ("cart_id") REFERENCES "carts"("id")
+);
+
+CREATE TABLE IF NOT EXISTS "orders" (
+    "id" bigserial,
+    "user_id" varchar(255) NOT NULL,
+    "total_amount" bigint NOT NULL,
+    "discount" bigint DEFAULT 0,
+    "promo_code" varchar(50),
+    "status" text NOT NULL,
+    "shipping_address" text NOT NULL,
+    "shipping_name" varchar(100),
+    "shipping_line1" varchar(255),
+    "shipping_line2" varchar(255),
+    "shipping_city" varchar(100),
+    "shipping_state" varchar(100),
+    "shipping_postal_code" varchar(20),
+    "shipping_country" varchar(2),
+    "shipping_phone" varchar(20),
+    "razorpay_order_id" varchar(64),
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+ALTER TABLE "orders"
+    ADD COLUMN IF NOT EXISTS "shipping_name" varchar(100),
+    ADD COLUMN IF NOT EXISTS "shipping_line1" varchar(255),
+    ADD COLUMN IF NOT EXISTS "shipping_line2" varchar(255),
+    ADD COLUMN IF NOT EXISTS "shipping_city" varchar(100),
+    ADD COLUMN IF NOT EXISTS "shipping_state" varchar(100),
+    ADD COLUMN IF NOT EXISTS "shipping_postal_code" varchar(20),
+    ADD COLUMN IF NOT EXISTS "shipping_country" varchar(2),
+    ADD COLUMN IF NOT EXISTS "shipping_phone" varchar(20),
+    ADD COLUMN IF NOT EXISTS "razorpay_order_id" varchar(64);
+CREATE INDEX IF NOT EXISTS "idx_orders_razorpay_order_id" ON "orders" ("razorpay_order_id");
+
+CREATE TABLE IF NOT EXISTS "order_items" (
+    "id" bigserial,
+    "order_id" bigint NOT NULL,
+    "variant_id" bigint NOT NULL,
+    "quantity" bigint NOT NULL,
+    "unit_price" bigint NOT NULL,
+    "subtotal" bigint NOT NULL,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_orders_order_items" FOREIGN KEY ("order_id") REFERENCES "orders"("id"),
+    CONSTRAINT "fk_order_items_variant" FOREIGN KEY ("variant_id") REFERENCES "product_variants"("id")
+);
+
+CREATE TABLE IF NOT EXISTS "payments" (
+    "id" bigserial,
+    "order_id" bigint,
+    "amount" bigint NOT NULL,
+    "amount_refunded" bigint NOT NULL DEFAULT 0,
+    "status" text NOT NULL,
+    "payment_method" text NOT NULL,
+    "transaction_id" text,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_orders_payment" FOREIGN KEY ("order_id") REFERENCES "orders"("id")
+);
+ALTER TABLE "payments" ADD COLUMN IF NOT EXISTS "amount_refunded" bigint NOT NULL DEFAULT 0;
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_payments_order_id" ON "payments" ("order_id");
+
+CREATE TABLE IF NOT EXISTS "refunds" (
+    "id" bigserial,
+    "payment_id" bigint NOT NULL,
+    "order_id" bigint NOT NULL,
+    "amount" bigint NOT NULL,
+    "reason" text,
+    "status" varchar(20) NOT NULL,
+    "razorpay_refund_id" varchar(64),
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_payments_refunds" FOREIGN KEY ("payment_id") REFERENCES "payments"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_refunds_order_id" ON "refunds" ("order_id");
+CREATE INDEX IF NOT EXISTS "idx_refunds_payment_id" ON "refunds" ("payment_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_refunds_razorpay_refund_id" ON "refunds" ("razorpay_refund_id");
+
+CREATE TABLE IF NOT EXISTS "promo_codes" (
+    "id" uuid DEFAULT gen_random_uuid(),
+    "code" text NOT NULL,
+    "discount_type" varchar(20) NOT NULL,
+    "discount_value" bigint NOT NULL,
+    "valid_from" timestamp,
+    "valid_until" timestamp,
+    "is_active" boolean DEFAULT true,
+    "usage_limit" bigint,
+    "usage_count" bigint DEFAULT 0,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_promo_codes_code" ON "promo_codes" ("code");
+
+CREATE TABLE IF NOT EXISTS "webhook_events" (
+    "id" bigserial,
+    "provider" varchar(30) NOT NULL,
+    "event_id" varchar(100) NOT NULL,
+    "event_type" varchar(100) NOT NULL,
+    "processed_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_webhook_events_provider_event" ON "webhook_events" ("provider","event_id");
+
+ALTER TABLE "products" ADD COLUMN IF NOT EXISTS "search_vector" tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(brand, '')), 'B') ||
+        setweight(to_tsvector('english', coalesce(category, '')), 'B') ||
+        setweight(to_tsvector('english', coalesce(material, '')), 'C') ||
+        setweight(to_tsvector('english', coalesce(description, '')), 'D')
+    ) STORED;
+CREATE INDEX IF NOT EXISTS "idx_products_search_vector" ON "products" USING GIN ("search_vector");
+CREATE INDEX IF NOT EXISTS "idx_products_name_trgm" ON "products" USING GIN ("name" gin_trgm_ops);
@@ -0,0 +1,10 @@
+ALTER TABLE "products" ALTER COLUMN "base_price" TYPE decimal USING "base_price" / 100.0;
+ALTER TABLE "product_variants" ALTER COLUMN "price_modifier" TYPE decimal USING "price_modifier" / 100.0;
+ALTER TABLE "orders" ALTER COLUMN "total_amount" TYPE decimal USING "total_amount" / 100.0;
+ALTER TABLE "orders" ALTER COLUMN "discount" TYPE decimal USING "discount" / 100.0;
+ALTER TABLE "order_items" ALTER COLUMN "unit_price" TYPE decimal USING "unit_price" / 100.0;
+ALTER TABLE "order_items" ALTER COLUMN "subtotal" TYPE decimal USING "subtotal" / 100.0;
+ALTER TABLE "payments" ALTER COLUMN "amount" TYPE decimal USING "amount" / 100.0;
+ALTER TABLE "payments" ALTER COLUMN "amount_refunded" TYPE decimal USING "amount_refunded" / 100.0;
+ALTER TABLE "refunds" ALTER COLUMN "amount" TYPE decimal USING "amount" / 100.0;
+ALTER TABLE "promo_codes" ALTER COLUMN "discount_value" TYPE decimal(10,2) USING "discount_value" / 100.0;
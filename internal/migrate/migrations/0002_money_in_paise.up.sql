@@ -0,0 +1,33 @@
+-- Databases created by AutoMigrate before money moved to paise still hold
+-- rupee amounts in decimal columns, which the baseline leaves alone. Convert
+-- them in place; promo code percentages become basis points the same way.
+-- Columns that are already bigint are skipped.
+DO $$
+DECLARE
+    col record;
+BEGIN
+    FOR col IN
+        SELECT table_name::text AS table_name, column_name::text AS column_name
+        FROM information_schema.columns
+        WHERE table_schema = current_schema()
+          AND data_type <> 'bigint'
+          AND (table_name::text, column_name::text) IN (VALUES
+              ('products', 'base_price'),
+              ('product_variants', 'price_modifier'),
+              ('orders', 'total_amount'),
+              ('orders', 'discount'),
+              ('order_items', 'unit_price'),
+              ('order_items', 'subtotal'),
+              ('payments', 'amount'),
+              ('payments', 'amount_refunded'),
+              ('refunds', 'amount'),
+              ('promo_codes', 'discount_value'))
+    LOOP
+        EXECUTE format(
+            'ALTER TABLE %I ALTER COLUMN %I DROP DEFAULT, ALTER COLUMN %I TYPE bigint USING round(%I::numeric * 100)::bigint',
+            col.table_name, col.column_name, col.column_name, col.column_name);
+    END LOOP;
+END $$;
+
+ALTER TABLE "orders" ALTER COLUMN "discount" SET DEFAULT 0;
+ALTER TABLE "payments" ALTER COLUMN "amount_refunded" SET DEFAULT 0;
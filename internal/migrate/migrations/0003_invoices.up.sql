@@ -0,0 +1,35 @@
+CREATE TABLE "invoices" (
+    "id" bigserial,
+    "order_id" bigint NOT NULL,
+    "number" varchar(16) NOT NULL,
+    "place_of_supply" varchar(2) NOT NULL,
+    "taxable_amount" bigint NOT NULL,
+    "cgst" bigint NOT NULL,
+    "sgst" bigint NOT NULL,
+    "igst" bigint NOT NULL,
+    "total_amount" bigint NOT NULL,
+    "pdf" bytea NOT NULL,
+    "issued_at" timestamptz NOT NULL,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_invoices_order" FOREIGN KEY ("order_id") REFERENCES "orders"("id")
+);
+CREATE UNIQUE INDEX "idx_invoices_order_id" ON "invoices" ("order_id");
+CREATE UNIQUE INDEX "idx_invoices_number" ON "invoices" ("number");
+
+CREATE TABLE "invoice_sequences" (
+    "financial_year" varchar(5),
+    "last_number" bigint NOT NULL,
+    PRIMARY KEY ("financial_year")
+);
+
+-- Issued invoices are legal documents: corrections are made with a credit
+-- note, never by editing or deleting the invoice.
+CREATE FUNCTION invoices_immutable() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'invoice % has been issued and cannot be changed', OLD.number;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "invoices_immutable"
+    BEFORE UPDATE OR DELETE ON "invoices"
+    FOR EACH ROW EXECUTE FUNCTION invoices_immutable();
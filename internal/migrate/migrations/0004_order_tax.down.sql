@@ -0,0 +1,10 @@
+ALTER TABLE "order_items"
+    DROP COLUMN IF EXISTS "discount",
+    DROP COLUMN IF EXISTS "taxable_amount",
+    DROP COLUMN IF EXISTS "tax_rate",
+    DROP COLUMN IF EXISTS "tax_amount";
+
+ALTER TABLE "orders"
+    DROP COLUMN IF EXISTS "subtotal",
+    DROP COLUMN IF EXISTS "tax_amount",
+    DROP COLUMN IF EXISTS "tax_included";
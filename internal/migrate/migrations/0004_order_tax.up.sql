@@ -0,0 +1,16 @@
+-- Orders placed before tax was worked out charged list prices less the
+-- discount, so their totals are treated as tax-inclusive. New orders say
+-- explicitly which way their tax went.
+ALTER TABLE "orders"
+    ADD COLUMN "subtotal" bigint NOT NULL DEFAULT 0,
+    ADD COLUMN "tax_amount" bigint NOT NULL DEFAULT 0,
+    ADD COLUMN "tax_included" boolean NOT NULL DEFAULT true;
+UPDATE "orders" SET "subtotal" = "total_amount" + COALESCE("discount", 0);
+ALTER TABLE "orders" ALTER COLUMN "tax_included" SET DEFAULT false;
+
+-- tax_rate stays NULL for the items of those orders.
+ALTER TABLE "order_items"
+    ADD COLUMN "discount" bigint NOT NULL DEFAULT 0,
+    ADD COLUMN "taxable_amount" bigint NOT NULL DEFAULT 0,
+    ADD COLUMN "tax_rate" integer,
+    ADD COLUMN "tax_amount" bigint NOT NULL DEFAULT 0;
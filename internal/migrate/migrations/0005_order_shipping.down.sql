@@ -0,0 +1,3 @@
+ALTER TABLE "orders"
+    DROP COLUMN IF EXISTS "shipping_method",
+    DROP COLUMN IF EXISTS "shipping_cost";
@@ -0,0 +1,3 @@
+ALTER TABLE "orders"
+    ADD COLUMN "shipping_method" varchar(32),
+    ADD COLUMN "shipping_cost" bigint NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "shipment_events";
+DROP TABLE IF EXISTS "shipments";
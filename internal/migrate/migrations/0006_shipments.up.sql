@@ -0,0 +1,30 @@
+CREATE TABLE "shipments" (
+    "id" bigserial,
+    "order_id" bigint NOT NULL,
+    "carrier" varchar(30) NOT NULL,
+    "courier_name" varchar(100),
+    "tracking_number" varchar(64) NOT NULL,
+    "tracking_url" text,
+    "status" varchar(30) NOT NULL,
+    "estimated_delivery" timestamptz,
+    "delivered_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_shipments_order" FOREIGN KEY ("order_id") REFERENCES "orders"("id")
+);
+CREATE UNIQUE INDEX "idx_shipments_order_id" ON "shipments" ("order_id");
+CREATE UNIQUE INDEX "idx_shipments_carrier_tracking_number" ON "shipments" ("carrier", "tracking_number");
+
+CREATE TABLE "shipment_events" (
+    "id" bigserial,
+    "shipment_id" bigint NOT NULL,
+    "status" varchar(30) NOT NULL,
+    "description" text,
+    "location" varchar(255),
+    "occurred_at" timestamptz NOT NULL,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_shipments_events" FOREIGN KEY ("shipment_id") REFERENCES "shipments"("id")
+);
+CREATE UNIQUE INDEX "idx_shipment_events_dedupe" ON "shipment_events" ("shipment_id", "status", "occurred_at");
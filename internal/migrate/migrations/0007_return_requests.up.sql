@@ -0,0 +1,30 @@
+CREATE TABLE "return_requests" (
+    "id" bigserial,
+    "order_id" bigint NOT NULL,
+    "order_item_id" bigint NOT NULL,
+    "user_id" varchar(255) NOT NULL,
+    "quantity" bigint NOT NULL,
+    "reason" varchar(30) NOT NULL,
+    "comment" text,
+    "photos" text[],
+    "resolution" varchar(10) NOT NULL,
+    "exchange_variant_id" bigint,
+    "exchange_held" boolean NOT NULL DEFAULT false,
+    "status" varchar(20) NOT NULL,
+    "pickup_tracking_number" varchar(64),
+    "inspection_notes" text,
+    "rejection_reason" text,
+    "refund_amount" bigint NOT NULL DEFAULT 0,
+    "refund_id" bigint,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_return_requests_order" FOREIGN KEY ("order_id") REFERENCES "orders"("id"),
+    CONSTRAINT "fk_return_requests_order_item" FOREIGN KEY ("order_item_id") REFERENCES "order_items"("id"),
+    CONSTRAINT "fk_return_requests_exchange_variant" FOREIGN KEY ("exchange_variant_id") REFERENCES "product_variants"("id"),
+    CONSTRAINT "fk_return_requests_refund" FOREIGN KEY ("refund_id") REFERENCES "refunds"("id")
+);
+CREATE INDEX "idx_return_requests_order_id" ON "return_requests" ("order_id");
+CREATE INDEX "idx_return_requests_order_item_id" ON "return_requests" ("order_item_id");
+CREATE INDEX "idx_return_requests_user_id" ON "return_requests" ("user_id");
+CREATE INDEX "idx_return_requests_status" ON "return_requests" ("status");
@@ -0,0 +1,14 @@
+CREATE TABLE "outbox_events" (
+    "id" bigserial,
+    "type" varchar(50) NOT NULL,
+    "payload" jsonb NOT NULL,
+    "status" varchar(20) NOT NULL,
+    "attempts" bigint NOT NULL DEFAULT 0,
+    "handlers" text[],
+    "last_error" text,
+    "next_attempt_at" timestamptz NOT NULL,
+    "delivered_at" timestamptz,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_outbox_events_due" ON "outbox_events" ("status", "next_attempt_at");
@@ -0,0 +1 @@
+ALTER TABLE "users" ADD COLUMN "marketing_emails" boolean NOT NULL DEFAULT true;
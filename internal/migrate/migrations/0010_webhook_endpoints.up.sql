@@ -0,0 +1,35 @@
+CREATE TABLE "webhook_endpoints" (
+    "id" bigserial,
+    "url" text NOT NULL,
+    "description" varchar(255),
+    "secret" varchar(100) NOT NULL,
+    "events" text[],
+    "active" boolean NOT NULL DEFAULT true,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_webhook_endpoints_deleted_at" ON "webhook_endpoints" ("deleted_at");
+
+CREATE TABLE "webhook_deliveries" (
+    "id" bigserial,
+    "endpoint_id" bigint NOT NULL,
+    "event_id" bigint,
+    "event_type" varchar(50) NOT NULL,
+    "payload" jsonb NOT NULL,
+    "status" varchar(20) NOT NULL,
+    "attempts" bigint NOT NULL DEFAULT 0,
+    "next_attempt_at" timestamptz NOT NULL,
+    "response_status" bigint,
+    "response_body" text,
+    "last_error" text,
+    "replay_of_id" bigint,
+    "delivered_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_webhook_deliveries_endpoint" FOREIGN KEY ("endpoint_id") REFERENCES "webhook_endpoints"("id")
+);
+CREATE INDEX "idx_webhook_deliveries_due" ON "webhook_deliveries" ("status", "next_attempt_at");
+CREATE UNIQUE INDEX "idx_webhook_deliveries_endpoint_event" ON "webhook_deliveries" ("endpoint_id", "event_id");
@@ -0,0 +1,25 @@
+CREATE TABLE "cart_recoveries" (
+    "id" bigserial,
+    "cart_id" bigint NOT NULL,
+    "cart_updated_at" timestamptz NOT NULL,
+    "user_id" varchar(255) NOT NULL,
+    "token_hash" varchar(64) NOT NULL,
+    "promo_code_id" uuid,
+    "item_count" bigint NOT NULL,
+    "cart_value" bigint NOT NULL,
+    "status" varchar(20) NOT NULL,
+    "last_error" text,
+    "sent_at" timestamptz,
+    "expires_at" timestamptz NOT NULL,
+    "recovered_at" timestamptz,
+    "order_id" bigint,
+    "converted_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_cart_recoveries_promo_code" FOREIGN KEY ("promo_code_id") REFERENCES "promo_codes"("id") ON DELETE SET NULL
+);
+CREATE INDEX "idx_cart_recoveries_sent_at" ON "cart_recoveries" ("sent_at");
+CREATE UNIQUE INDEX "idx_cart_recoveries_token_hash" ON "cart_recoveries" ("token_hash");
+CREATE INDEX "idx_cart_recoveries_user_id" ON "cart_recoveries" ("user_id");
+CREATE UNIQUE INDEX "idx_cart_recoveries_cart_spell" ON "cart_recoveries" ("cart_id", "cart_updated_at");
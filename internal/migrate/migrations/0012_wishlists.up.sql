@@ -0,0 +1,29 @@
+CREATE TABLE "wishlists" (
+    "id" bigserial,
+    "user_id" varchar(255) NOT NULL,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX "idx_wishlists_user_id" ON "wishlists" ("user_id");
+
+CREATE TABLE "wishlist_items" (
+    "id" bigserial,
+    "wishlist_id" bigint NOT NULL,
+    "product_id" bigint NOT NULL,
+    "variant_id" bigint,
+    "notify_back_in_stock" boolean NOT NULL DEFAULT false,
+    "notify_price_drop" boolean NOT NULL DEFAULT false,
+    "alert_price" bigint NOT NULL,
+    "awaiting_stock" boolean NOT NULL DEFAULT false,
+    "added_at" timestamptz NOT NULL,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_wishlists_items" FOREIGN KEY ("wishlist_id") REFERENCES "wishlists"("id") ON DELETE CASCADE,
+    CONSTRAINT "fk_wishlist_items_product" FOREIGN KEY ("product_id") REFERENCES "products"("id"),
+    CONSTRAINT "fk_wishlist_items_variant" FOREIGN KEY ("variant_id") REFERENCES "product_variants"("id")
+);
+CREATE INDEX "idx_wishlist_items_wishlist_id" ON "wishlist_items" ("wishlist_id");
+CREATE INDEX "idx_wishlist_items_product_id" ON "wishlist_items" ("product_id");
+CREATE INDEX "idx_wishlist_items_variant_id" ON "wishlist_items" ("variant_id");
+-- A product is saved once per variant, and once without one
+CREATE UNIQUE INDEX "idx_wishlist_items_product_variant" ON "wishlist_items" ("wishlist_id", "product_id", COALESCE("variant_id", 0));
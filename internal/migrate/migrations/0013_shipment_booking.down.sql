@@ -0,0 +1,3 @@
+DELETE FROM "shipments" WHERE "status" = 'booking';
+DROP INDEX IF EXISTS "idx_shipments_carrier_tracking_number";
+CREATE UNIQUE INDEX "idx_shipments_carrier_tracking_number" ON "shipments" ("carrier", "tracking_number");
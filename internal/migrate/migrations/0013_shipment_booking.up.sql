@@ -0,0 +1,4 @@
+-- Shipments are recorded before the courier is called and only get their
+-- tracking number once it answers.
+DROP INDEX IF EXISTS "idx_shipments_carrier_tracking_number";
+CREATE UNIQUE INDEX "idx_shipments_carrier_tracking_number" ON "shipments" ("carrier", "tracking_number") WHERE tracking_number <> '';
@@ -0,0 +1,3 @@
+-- Users without an email get a unique placeholder that no mail is sent to.
+UPDATE "users" SET "email" = 'user-' || "id" || '@invalid' WHERE "email" IS NULL;
+ALTER TABLE "users" ALTER COLUMN "email" SET NOT NULL;
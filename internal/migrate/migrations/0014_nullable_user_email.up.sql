@@ -0,0 +1,4 @@
+-- Clerk users who sign up with only a phone number have no email. They are
+-- stored with NULL, which the unique index lets any number of rows share.
+ALTER TABLE "users" ALTER COLUMN "email" DROP NOT NULL;
+UPDATE "users" SET "email" = NULL WHERE "email" = '';
@@ -0,0 +1,3 @@
+ALTER TABLE "orders"
+    DROP COLUMN IF EXISTS "shipping_tax_rate",
+    DROP COLUMN IF EXISTS "shipping_tax_amount";
@@ -0,0 +1,5 @@
+-- Shipping is taxed at the rate of the goods it delivers. Orders placed
+-- before keep a NULL shipping_tax_rate: their shipping was charged untaxed.
+ALTER TABLE "orders"
+    ADD COLUMN "shipping_tax_rate" integer,
+    ADD COLUMN "shipping_tax_amount" bigint NOT NULL DEFAULT 0;
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Address is an entry in a user's address book.
+type Address struct {
+	ID         uint    `gorm:"primaryKey;autoIncrement"`
+	UserID     string  `gorm:"type:varchar(255);not null;index;uniqueIndex:idx_addresses_user_default,where:is_default AND deleted_at IS NULL"`
+	Name       string  `gorm:"type:varchar(100);not null"`
+	Line1      string  `gorm:"type:varchar(255);not null"`
+	Line2      *string `gorm:"type:varchar(255)"`
+	City       string  `gorm:"type:varchar(100);not null"`
+	State      string  `gorm:"type:varchar(100);not null"`
+	PostalCode string  `gorm:"type:varchar(20);not null"`
+	Country    string  `gorm:"type:varchar(2);not null;default:'IN'"`
+	Phone      string  `gorm:"type:varchar(20);not null"`
+	IsDefault  bool    `gorm:"not null;default:false"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	DeletedAt  gorm.DeletedAt `gorm:"index"`
+}
+
+// OrderAddress is the copy of the shipping address kept on an order, so later
+// edits to the address book do not change past orders.
+type OrderAddress struct {
+	Name       string  `gorm:"type:varchar(100)"`
+	Line1      string  `gorm:"type:varchar(255)"`
+	Line2      *string `gorm:"type:varchar(255)"`
+	City       string  `gorm:"type:varchar(100)"`
+	State      string  `gorm:"type:varchar(100)"`
+	PostalCode string  `gorm:"type:varchar(20)"`
+	Country    string  `gorm:"type:varchar(2)"`
+	Phone      string  `gorm:"type:varchar(20)"`
+}
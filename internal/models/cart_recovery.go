@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
+)
+
+// CartRecovery is a reminder about a cart a customer left idle. There is
+// at most one per cart per idle spell: CartUpdatedAt is when the cart was
+// last changed, so changing it again starts a new spell. TokenHash is the
+// SHA-256 of the one-time recovery link's token. OrderID is set when the
+// customer orders within the attribution window after the reminder.
+type CartRecovery struct {
+	ID            uint        `gorm:"primaryKey;autoIncrement"`
+	CartID        uint        `gorm:"not null;uniqueIndex:idx_cart_recoveries_cart_spell"`
+	CartUpdatedAt time.Time   `gorm:"not null;uniqueIndex:idx_cart_recoveries_cart_spell"`
+	UserID        string      `gorm:"not null;type:varchar(255);index"`
+	TokenHash     string      `gorm:"not null;type:varchar(64);uniqueIndex"`
+	PromoCodeID   *string     `gorm:"type:uuid"`
+	ItemCount     int         `gorm:"not null"`
+	CartValue     money.Money `gorm:"not null"`
+	Status        string      `gorm:"not null;type:varchar(20)"`
+	LastError     *string     `gorm:"type:text"`
+	SentAt        *time.Time  `gorm:"index"`
+	ExpiresAt     time.Time   `gorm:"not null"`
+	RecoveredAt   *time.Time
+	OrderID       *uint
+	ConvertedAt   *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+
+	PromoCode *PromoCode `gorm:"foreignKey:PromoCodeID;constraint:OnDelete:SET NULL"`
+}
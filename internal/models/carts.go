@@ -9,6 +9,7 @@ import (
 type Cart struct {
     ID        uint `gorm:"primaryKey"`
     UserID    *string `gorm:"uniqueIndex"` // Make it nullable with *string for guest carts
+    GuestSessionID *string `gorm:"type:varchar(64);uniqueIndex"` // Set instead of UserID for guest carts
     CreatedAt time.Time
     UpdatedAt time.Time
     DeletedAt gorm.DeletedAt `gorm:"index"`
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
+)
+
+// Invoice is the GST tax invoice issued for a confirmed order. The rendered
+// PDF is stored with it, and the database rejects any change once it is
+// written.
+type Invoice struct {
+	ID            uint        `gorm:"primaryKey;autoIncrement"`
+	OrderID       uint        `gorm:"not null;uniqueIndex"`
+	Number        string      `gorm:"type:varchar(16);not null;uniqueIndex"`
+	PlaceOfSupply string      `gorm:"type:varchar(2);not null"`
+	TaxableAmount money.Money `gorm:"not null"`
+	CGST          money.Money `gorm:"not null"`
+	SGST          money.Money `gorm:"not null"`
+	IGST          money.Money `gorm:"not null"`
+	TotalAmount   money.Money `gorm:"not null"`
+	PDF           []byte      `gorm:"type:bytea;not null"`
+	IssuedAt      time.Time   `gorm:"not null"`
+}
+
+// InvoiceSequence holds the last invoice number used in a financial year.
+type InvoiceSequence struct {
+	FinancialYear string `gorm:"type:varchar(5);primaryKey"`
+	LastNumber    int64  `gorm:"not null"`
+}
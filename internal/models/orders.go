@@ -2,16 +2,34 @@ package models
 
 import (
 	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
 )
 
+// Order amounts: Subtotal is the items at list price, before the discount
+// and any tax added on top, and TotalAmount is what the customer pays,
+// shipping included. TaxIncluded is true when the item prices already
+// contained the tax.
 type Order struct {
-	ID              uint     `gorm:"primaryKey;autoIncrement"`
-	UserID          string   `gorm:"not null;type:varchar(255)"`
-	TotalAmount     float64  `gorm:"not null"`
-	Discount        float64  `gorm:"default:0"`
-	PromoCode       *string  `gorm:"type:varchar(50)"`
-	Status          string   `gorm:"not null"`
-	ShippingAddress string   `gorm:"not null"`
+	ID             uint        `gorm:"primaryKey;autoIncrement"`
+	UserID         string      `gorm:"not null;type:varchar(255)"`
+	Subtotal       money.Money `gorm:"not null;default:0"`
+	TotalAmount    money.Money `gorm:"not null"`
+	Discount       money.Money `gorm:"default:0"`
+	TaxAmount      money.Money `gorm:"not null;default:0"`
+	TaxIncluded    bool        `gorm:"not null;default:false"`
+	ShippingMethod *string     `gorm:"type:varchar(32)"`
+	ShippingCost   money.Money `gorm:"not null;default:0"`
+	// ShippingTaxRate is in basis points and nil when shipping was charged
+	// without tax. ShippingTaxAmount is part of TaxAmount.
+	ShippingTaxRate   *int64      `gorm:"type:integer"`
+	ShippingTaxAmount money.Money `gorm:"not null;default:0"`
+	PromoCode         *string     `gorm:"type:varchar(50)"`
+	Status            string      `gorm:"not null"`
+	ShippingAddress   string      `gorm:"not null"`
+	// ShippingDetails is empty for orders placed with a free-text address
+	ShippingDetails OrderAddress `gorm:"embedded;embeddedPrefix:shipping_"`
+	RazorpayOrderID *string      `gorm:"type:varchar(64);index"`
 	CreatedAt       time.Time
 	UpdatedAt       time.Time
 
@@ -20,24 +38,34 @@ type Order struct {
 }
 
 type OrderItem struct {
-	ID        uint    `gorm:"primaryKey;autoIncrement"`
-	OrderID   uint    `gorm:"not null"`
-	VariantID uint    `gorm:"not null"`
-	Quantity  int     `gorm:"not null"`
-	UnitPrice float64 `gorm:"not null"`
-	Subtotal  float64 `gorm:"not null"`
+	ID        uint        `gorm:"primaryKey;autoIncrement"`
+	OrderID   uint        `gorm:"not null"`
+	VariantID uint        `gorm:"not null"`
+	Quantity  int         `gorm:"not null"`
+	UnitPrice money.Money `gorm:"not null"`
+	Subtotal  money.Money `gorm:"not null"`
+	// Discount is the item's share of the order discount, and TaxableAmount
+	// what is left of Subtotal after it, less any tax it includes.
+	Discount      money.Money `gorm:"not null;default:0"`
+	TaxableAmount money.Money `gorm:"not null;default:0"`
+	// TaxRate is in basis points. It is nil for items ordered before tax was
+	// worked out per item.
+	TaxRate   *int64      `gorm:"type:integer"`
+	TaxAmount money.Money `gorm:"not null;default:0"`
 
 	Variant ProductVariant `gorm:"foreignKey:VariantID"`
 }
 
 type Payment struct {
-	ID            uint    `gorm:"primaryKey;autoIncrement"`
-	OrderID       uint    `gorm:"uniqueIndex"`
-	Amount        float64 `gorm:"not null"`
-	Status        string  `gorm:"not null"`
-	PaymentMethod string  `gorm:"not null"`
-	TransactionID string
-	CreatedAt     time.Time
-
-	Order *Order `gorm:"foreignKey:OrderID"`
+	ID             uint        `gorm:"primaryKey;autoIncrement"`
+	OrderID        uint        `gorm:"uniqueIndex"`
+	Amount         money.Money `gorm:"not null"`
+	AmountRefunded money.Money `gorm:"not null;default:0"`
+	Status         string      `gorm:"not null"`
+	PaymentMethod  string      `gorm:"not null"`
+	TransactionID  string
+	CreatedAt      time.Time
+
+	Order   *Order   `gorm:"foreignKey:OrderID"`
+	Refunds []Refund `gorm:"foreignKey:PaymentID"`
 }
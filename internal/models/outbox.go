@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// OutboxEvent is a domain event written in the same transaction as the
+// change it describes, waiting to be delivered to its handlers. Handlers
+// lists the ones that have already succeeded, so a retry only runs the
+// rest. NextAttemptAt doubles as the lease of the dispatcher working on it.
+type OutboxEvent struct {
+	ID            uint           `gorm:"primaryKey;autoIncrement"`
+	Type          string         `gorm:"not null;type:varchar(50)"`
+	Payload       []byte         `gorm:"not null;type:jsonb"`
+	Status        string         `gorm:"not null;type:varchar(20);index:idx_outbox_events_due,priority:1"`
+	Attempts      int            `gorm:"not null;default:0"`
+	Handlers      pq.StringArray `gorm:"type:text[]"`
+	LastError     *string        `gorm:"type:text"`
+	NextAttemptAt time.Time      `gorm:"not null;index:idx_outbox_events_due,priority:2"`
+	DeliveredAt   *time.Time
+	CreatedAt     time.Time
+}
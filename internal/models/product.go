@@ -3,6 +3,7 @@ package models
 import (
 	"time"
 	"github.com/lib/pq"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
 	"gorm.io/gorm"
 )
 
@@ -12,7 +13,7 @@ type Product struct {
 	Description    *string
 	DesignImageURL string         `gorm:"type:text"`
 	ImageURLs      pq.StringArray `gorm:"type:text[]"`      // NEW: Array of image URLs
-	BasePrice      float64 `gorm:"not null"`
+	BasePrice      money.Money `gorm:"not null"`
 
 	Material         string            `gorm:"type:varchar(100)"`
     Neckline         string            `gorm:"type:varchar(50)"`
@@ -37,7 +38,7 @@ type ProductVariant struct {
 	ProductID     uint   `gorm:"not null"`
 	Size          string `gorm:"not null"`
 	Color         *string
-	PriceModifier float64 `gorm:"not null"`
+	PriceModifier money.Money `gorm:"not null"`
 	SKU           string  `gorm:"uniqueIndex;not null"`
 	CreatedAt     time.Time
 
@@ -11,11 +11,13 @@ const (
 	DiscountTypeFixed      DiscountType = "fixed"
 )
 
+// PromoCode is a discount code. DiscountValue is in basis points (hundredths
+// of a percent) for percentage codes and in paise for fixed ones.
 type PromoCode struct {
 	ID            string       `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
 	Code          string       `gorm:"uniqueIndex;not null" json:"code"`
 	DiscountType  DiscountType `gorm:"type:varchar(20);not null" json:"discountType"`
-	DiscountValue float64      `gorm:"type:decimal(10,2);not null" json:"discountValue"`
+	DiscountValue int64        `gorm:"type:bigint;not null" json:"discountValue"`
 	ValidFrom     *time.Time   `gorm:"type:timestamp;null" json:"validFrom"`
 	ValidUntil    *time.Time   `gorm:"type:timestamp;null" json:"validUntil"`
 	IsActive      bool         `gorm:"default:true" json:"isActive"`
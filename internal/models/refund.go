@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
+)
+
+type Refund struct {
+	ID               uint        `gorm:"primaryKey;autoIncrement"`
+	PaymentID        uint        `gorm:"not null;index"`
+	OrderID          uint        `gorm:"not null;index"`
+	Amount           money.Money `gorm:"not null"`
+	Reason           string      `gorm:"type:text"`
+	Status           string      `gorm:"not null;type:varchar(20)"`
+	RazorpayRefundID *string     `gorm:"type:varchar(64);uniqueIndex"`
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+
+	Payment *Payment `gorm:"foreignKey:PaymentID"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// InventoryReservation holds stock for a pending order until it is paid,
+// cancelled or the reservation expires. While active, its quantity is
+// counted in Inventory.ReservedQuantity.
+type InventoryReservation struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	OrderID   uint      `gorm:"not null;index"`
+	VariantID uint      `gorm:"not null;index"`
+	Quantity  int       `gorm:"not null"`
+	Status    string    `gorm:"not null;type:varchar(20);index"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
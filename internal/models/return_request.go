@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
+)
+
+// ReturnRequest is a customer sending back some of an order item for a
+// refund or, when Resolution is exchange, for ExchangeVariant. While an
+// exchange is open its replacement is held in Inventory.ReservedQuantity;
+// ExchangeHeld records whether it still is.
+type ReturnRequest struct {
+	ID                   uint           `gorm:"primaryKey;autoIncrement"`
+	OrderID              uint           `gorm:"not null;index"`
+	OrderItemID          uint           `gorm:"not null;index"`
+	UserID               string         `gorm:"not null;type:varchar(255);index"`
+	Quantity             int            `gorm:"not null"`
+	Reason               string         `gorm:"not null;type:varchar(30)"`
+	Comment              *string        `gorm:"type:text"`
+	Photos               pq.StringArray `gorm:"type:text[]"`
+	Resolution           string         `gorm:"not null;type:varchar(10)"`
+	ExchangeVariantID    *uint
+	ExchangeHeld         bool        `gorm:"not null;default:false"`
+	Status               string      `gorm:"not null;type:varchar(20);index"`
+	PickupTrackingNumber *string     `gorm:"type:varchar(64)"`
+	InspectionNotes      *string     `gorm:"type:text"`
+	RejectionReason      *string     `gorm:"type:text"`
+	RefundAmount         money.Money `gorm:"not null;default:0"`
+	RefundID             *uint
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+
+	Order           *Order          `gorm:"foreignKey:OrderID"`
+	OrderItem       *OrderItem      `gorm:"foreignKey:OrderItemID"`
+	ExchangeVariant *ProductVariant `gorm:"foreignKey:ExchangeVariantID"`
+	Refund          *Refund         `gorm:"foreignKey:RefundID"`
+}
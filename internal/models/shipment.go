@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+)
+
+// Shipment is an order handed over to a courier. CourierName is the carrier
+// that delivers it, which for aggregators differs from Carrier, the courier
+// integration it was booked through. TrackingNumber is empty while the
+// shipment is booking.
+type Shipment struct {
+	ID                uint   `gorm:"primaryKey;autoIncrement"`
+	OrderID           uint   `gorm:"not null;uniqueIndex"`
+	Carrier           string `gorm:"not null;type:varchar(30);uniqueIndex:idx_shipments_carrier_tracking_number,where:tracking_number <> ''"`
+	CourierName       string `gorm:"type:varchar(100)"`
+	TrackingNumber    string `gorm:"not null;type:varchar(64);uniqueIndex:idx_shipments_carrier_tracking_number"`
+	TrackingURL       *string
+	Status            string `gorm:"not null;type:varchar(30)"`
+	EstimatedDelivery *time.Time
+	DeliveredAt       *time.Time
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+
+	Order  *Order          `gorm:"foreignKey:OrderID"`
+	Events []ShipmentEvent `gorm:"foreignKey:ShipmentID"`
+}
+
+// ShipmentEvent is a scan or status change reported by the courier. The
+// same event arriving from both a webhook and a tracking refresh is stored
+// once.
+type ShipmentEvent struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement"`
+	ShipmentID  uint      `gorm:"not null;uniqueIndex:idx_shipment_events_dedupe"`
+	Status      string    `gorm:"not null;type:varchar(30);uniqueIndex:idx_shipment_events_dedupe"`
+	Description string    `gorm:"type:text"`
+	Location    string    `gorm:"type:varchar(255)"`
+	OccurredAt  time.Time `gorm:"not null;uniqueIndex:idx_shipment_events_dedupe"`
+	CreatedAt   time.Time
+}
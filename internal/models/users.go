@@ -7,10 +7,11 @@ import (
 )
 
 type User struct {
-	ID            uint   `gorm:"primaryKey"`
-	Email         string `gorm:"uniqueIndex;not null"`
-	PasswordHash  string `gorm:"not null"`
-	Name          string `gorm:"not null"`
+	ID            uint    `gorm:"primaryKey"`
+	ClerkUserID   *string `gorm:"type:varchar(255);uniqueIndex"`
+	Email         *string `gorm:"uniqueIndex"` // nil for phone-only Clerk accounts
+	PasswordHash  string  `gorm:"not null"`
+	Name          string  `gorm:"not null"`
 	Phone         string
 	Address       string
 	Role          string `gorm:"default:'user'"`  // user, admin
@@ -20,6 +21,10 @@ type User struct {
 	UpdatedAt     time.Time
 	DeletedAt     gorm.DeletedAt `gorm:"index"`
 
-	// Note: Cart.UserID and Order.UserID store Clerk IDs (strings), not database user IDs
-	// Therefore, we cannot use foreign key relationships here
+	// MarketingEmails is the user's consent to offers and reminders.
+	// Order emails are sent regardless.
+	MarketingEmails bool `gorm:"not null;default:true"`
+
+	// Note: Cart.UserID and Order.UserID store Clerk IDs (strings), not database user IDs.
+	// They join to ClerkUserID; there is no foreign key because orders may predate the user row.
 }
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// WebhookEndpoint is a merchant system, such as an ERP, that is POSTed our
+// events. Events filters which event types it receives; empty means all.
+type WebhookEndpoint struct {
+	ID          uint           `gorm:"primaryKey;autoIncrement"`
+	URL         string         `gorm:"not null;type:text"`
+	Description string         `gorm:"type:varchar(255)"`
+	Secret      string         `gorm:"not null;type:varchar(100)"`
+	Events      pq.StringArray `gorm:"type:text[]"`
+	Active      bool           `gorm:"not null;default:true"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+}
+
+// WebhookDelivery is one event sent, or to be sent, to an endpoint, and
+// the log of how it went. EventID is the outbox event it came from, so an
+// event redelivered by the outbox is queued once per endpoint; replays
+// leave it empty and point at the delivery they repeat instead.
+type WebhookDelivery struct {
+	ID             uint      `gorm:"primaryKey;autoIncrement"`
+	EndpointID     uint      `gorm:"not null;uniqueIndex:idx_webhook_deliveries_endpoint_event"`
+	EventID        *uint     `gorm:"uniqueIndex:idx_webhook_deliveries_endpoint_event"`
+	EventType      string    `gorm:"not null;type:varchar(50)"`
+	Payload        []byte    `gorm:"not null;type:jsonb"`
+	Status         string    `gorm:"not null;type:varchar(20);index:idx_webhook_deliveries_due,priority:1"`
+	Attempts       int       `gorm:"not null;default:0"`
+	NextAttemptAt  time.Time `gorm:"not null;index:idx_webhook_deliveries_due,priority:2"`
+	ResponseStatus *int
+	ResponseBody   *string `gorm:"type:text"`
+	LastError      *string `gorm:"type:text"`
+	ReplayOfID     *uint
+	DeliveredAt    *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+
+	Endpoint *WebhookEndpoint `gorm:"foreignKey:EndpointID"`
+}
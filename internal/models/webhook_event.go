@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+// WebhookEvent records an inbound webhook that has been processed so that
+// provider retries of the same event are ignored.
+type WebhookEvent struct {
+	ID          uint   `gorm:"primaryKey;autoIncrement"`
+	Provider    string `gorm:"not null;type:varchar(30);uniqueIndex:idx_webhook_events_provider_event"`
+	EventID     string `gorm:"not null;type:varchar(100);uniqueIndex:idx_webhook_events_provider_event"`
+	EventType   string `gorm:"not null;type:varchar(100)"`
+	ProcessedAt time.Time
+}
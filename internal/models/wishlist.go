@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
+)
+
+// Wishlist is the products a customer has saved for later. Each customer
+// has at most one, keyed by their Clerk user ID.
+type Wishlist struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`
+	UserID    string `gorm:"not null;type:varchar(255);uniqueIndex"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	Items []WishlistItem `gorm:"foreignKey:WishlistID;constraint:OnDelete:CASCADE"`
+}
+
+// WishlistItem is a product on a wishlist, in a particular size and colour
+// when VariantID is set. AlertPrice is what a price drop is measured
+// against: the price when the item was saved or when the customer was last
+// told it dropped. AwaitingStock is set while the item is out of stock, so
+// a back-in-stock alert goes out once when it returns.
+type WishlistItem struct {
+	ID                uint        `gorm:"primaryKey;autoIncrement"`
+	WishlistID        uint        `gorm:"not null;index"`
+	ProductID         uint        `gorm:"not null;index"`
+	VariantID         *uint       `gorm:"index"`
+	NotifyBackInStock bool        `gorm:"not null;default:false"`
+	NotifyPriceDrop   bool        `gorm:"not null;default:false"`
+	AlertPrice        money.Money `gorm:"not null"`
+	AwaitingStock     bool        `gorm:"not null;default:false"`
+	AddedAt           time.Time   `gorm:"not null"`
+
+	Product *Product        `gorm:"foreignKey:ProductID"`
+	Variant *ProductVariant `gorm:"foreignKey:VariantID"`
+}
@@ -0,0 +1,62 @@
+package money
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// MarshalGQL writes the Money scalar as {"amount": <paise>, "currency": "INR"}.
+func (m Money) MarshalGQL(w io.Writer) {
+	fmt.Fprintf(w, `{"amount":%d,"currency":%s}`, m.Amount, strconv.Quote(m.currency()))
+}
+
+// UnmarshalGQL accepts the same object form or a decimal string of rupees
+// such as "199.99". Bare numbers are rejected: older clients sent rupees as
+// a Float, and reading 499 as paise would quietly store ₹4.99.
+func (m *Money) UnmarshalGQL(v interface{}) error {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		amount, err := paise(v["amount"])
+		if err != nil {
+			return err
+		}
+		currency, _ := v["currency"].(string)
+		if currency != "" && currency != DefaultCurrency {
+			return fmt.Errorf("%w: unsupported currency %q", ErrInvalidAmount, currency)
+		}
+		*m = New(amount, currency)
+		return nil
+	case string:
+		parsed, err := Parse(v, DefaultCurrency)
+		if err != nil {
+			return err
+		}
+		*m = parsed
+		return nil
+	default:
+		return fmt.Errorf("%w: %v; send {amount, currency} in paise or a rupee string such as \"199.99\"", ErrInvalidAmount, v)
+	}
+}
+
+func paise(v interface{}) (int64, error) {
+	switch v := v.(type) {
+	case int:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case json.Number:
+		amount, err := v.Int64()
+		if err != nil {
+			return 0, fmt.Errorf("%w: %s is not a whole number of paise", ErrInvalidAmount, v)
+		}
+		return amount, nil
+	case float64:
+		if v != float64(int64(v)) {
+			return 0, fmt.Errorf("%w: %v is not a whole number of paise", ErrInvalidAmount, v)
+		}
+		return int64(v), nil
+	}
+	return 0, fmt.Errorf("%w: %v", ErrInvalidAmount, v)
+}
@@ -0,0 +1,224 @@
+// Package money represents amounts as whole minor units (paise for INR) so
+// prices, totals and refunds add up exactly.
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is the store's currency. Amounts read from the database are
+// in this currency.
+const DefaultCurrency = "INR"
+
+// unitsPerMajor is the number of minor units in one major unit (paise per
+// rupee).
+const unitsPerMajor = 100
+
+var ErrInvalidAmount = errors.New("invalid money amount")
+
+// Money is an amount in minor units of Currency. The zero value is zero in
+// any currency.
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+// New returns amount paise of currency, or of DefaultCurrency if currency is
+// empty.
+func New(amount int64, currency string) Money {
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	return Money{Amount: amount, Currency: currency}
+}
+
+// INR returns amount paise.
+func INR(amount int64) Money {
+	return New(amount, DefaultCurrency)
+}
+
+// Parse reads a decimal amount in major units such as "199.99". More than two
+// decimal places are rounded half to even.
+func Parse(s string, currency string) (Money, error) {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	r.Mul(r, big.NewRat(unitsPerMajor, 1))
+
+	amount, err := roundRat(r)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	return New(amount, currency), nil
+}
+
+// FromFloat converts an amount in major units, rounding half to even at the
+// paisa. The float's shortest decimal form is used, so 199.99 is 19999 paise
+// rather than 19998.
+func FromFloat(f float64, currency string) (Money, error) {
+	return Parse(strconv.FormatFloat(f, 'f', -1, 64), currency)
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+func (m Money) Add(o Money) Money {
+	return Money{Amount: m.Amount + o.Amount, Currency: m.sameCurrency(o)}
+}
+
+func (m Money) Sub(o Money) Money {
+	return Money{Amount: m.Amount - o.Amount, Currency: m.sameCurrency(o)}
+}
+
+// Mul returns the amount times n, as for a line of n identical items.
+func (m Money) Mul(n int) Money {
+	return Money{Amount: m.Amount * int64(n), Currency: m.Currency}
+}
+
+// Percent returns bps basis points (hundredths of a percent) of the amount,
+// rounded half to even at the paisa so repeated discounts don't drift upward.
+func (m Money) Percent(bps int64) Money {
+	return Money{Amount: DivRound(m.Amount*bps, 10000), Currency: m.Currency}
+}
+
+// Cmp returns -1, 0 or +1 as m is less than, equal to or greater than o.
+func (m Money) Cmp(o Money) int {
+	m.sameCurrency(o)
+	switch {
+	case m.Amount < o.Amount:
+		return -1
+	case m.Amount > o.Amount:
+		return 1
+	}
+	return 0
+}
+
+// Min returns the smaller of m and o.
+func (m Money) Min(o Money) Money {
+	if m.Cmp(o) <= 0 {
+		return m
+	}
+	return o
+}
+
+// Major returns the amount in major units. Use it only for display and logs.
+func (m Money) Major() float64 {
+	return float64(m.Amount) / unitsPerMajor
+}
+
+// Decimal formats the amount in major units with two decimals, e.g. "199.99".
+func (m Money) Decimal() string {
+	sign := ""
+	amount := m.Amount
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, amount/unitsPerMajor, amount%unitsPerMajor)
+}
+
+func (m Money) String() string {
+	return m.Decimal() + " " + m.currency()
+}
+
+func (m Money) currency() string {
+	if m.Currency == "" {
+		return DefaultCurrency
+	}
+	return m.Currency
+}
+
+// sameCurrency returns the currency of an operation on m and o. Mixing
+// currencies is a programming error.
+func (m Money) sameCurrency(o Money) string {
+	switch {
+	case m.Currency == "":
+		return o.Currency
+	case o.Currency == "" || o.Currency == m.Currency:
+		return m.Currency
+	}
+	panic(fmt.Sprintf("money: currency mismatch %s and %s", m.Currency, o.Currency))
+}
+
+// Value stores the amount in minor units. Every money column holds the
+// store's currency.
+func (m Money) Value() (driver.Value, error) {
+	if m.Currency != "" && m.Currency != DefaultCurrency {
+		return nil, fmt.Errorf("money: cannot store %s amount", m.Currency)
+	}
+	return m.Amount, nil
+}
+
+func (m *Money) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case int64:
+		m.Amount = v
+	case []byte:
+		amount, err := strconv.ParseInt(string(v), 10, 64)
+		if err != nil {
+			return fmt.Errorf("money: cannot scan %q: %w", v, err)
+		}
+		m.Amount = amount
+	case string:
+		amount, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("money: cannot scan %q: %w", v, err)
+		}
+		m.Amount = amount
+	case nil:
+		m.Amount = 0
+	default:
+		return fmt.Errorf("money: cannot scan %T", src)
+	}
+	m.Currency = DefaultCurrency
+	return nil
+}
+
+// GormDataType makes AutoMigrate create money columns as bigint.
+func (Money) GormDataType() string {
+	return "bigint"
+}
+
+// DivRound divides a by b (b > 0), rounding half to even.
+func DivRound(a, b int64) int64 {
+	q, r := a/b, a%b
+	if r < 0 {
+		r = -r
+	}
+	switch twice := 2 * r; {
+	case twice > b, twice == b && q%2 != 0:
+		if a < 0 {
+			return q - 1
+		}
+		return q + 1
+	}
+	return q
+}
+
+func roundRat(r *big.Rat) (int64, error) {
+	q, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	twice := new(big.Int).Abs(rem)
+	twice.Lsh(twice, 1)
+	if c := twice.Cmp(r.Denom()); c > 0 || (c == 0 && q.Bit(0) == 1) {
+		if r.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	if !q.IsInt64() {
+		return 0, ErrInvalidAmount
+	}
+	return q.Int64(), nil
+}
@@ -0,0 +1,209 @@
+// Package notify renders the emails sent to customers. Every notification
+// has an HTML and a plain-text template, wrapped in a shared layout; the
+// text template also defines the subject.
+package notify
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/mail"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
+)
+
+// Notifications.
+const (
+	OrderPlaced     = "order_placed"
+	PaymentReceived = "payment_received"
+	OrderShipped    = "order_shipped"
+	OrderDelivered  = "order_delivered"
+	OrderCancelled  = "order_cancelled"
+	RefundIssued    = "refund_issued"
+
+	// CartReminder is marketing mail about a cart left idle.
+	CartReminder = "cart_reminder"
+	// BackInStock and PriceDrop are the wishlist alerts.
+	BackInStock = "back_in_stock"
+	PriceDrop   = "price_drop"
+)
+
+// Names lists every notification, each of which needs both templates.
+var Names = []string{
+	OrderPlaced, PaymentReceived, OrderShipped, OrderDelivered, OrderCancelled, RefundIssued,
+	CartReminder, BackInStock, PriceDrop,
+}
+
+var ErrUnknownTemplate = errors.New("unknown email template")
+
+//go:embed templates/*.tmpl
+var defaultFiles embed.FS
+
+// Data is what the templates are rendered with. Only the sections that
+// make sense for a notification are set.
+type Data struct {
+	StoreName string
+	// Name is the customer's name.
+	Name string
+	// Subject is filled in by Render for the HTML title.
+	Subject string
+	// UnsubscribeURL is set on marketing mail only.
+	UnsubscribeURL string
+
+	Order    *Order
+	Payment  *Payment
+	Shipment *Shipment
+	Refund   *Refund
+	Cart     *Cart
+	Product  *Product
+}
+
+// Order is an order with its amounts already formatted.
+type Order struct {
+	Number string
+	URL    string
+	// PreviousStatus is the status the order was cancelled from.
+	PreviousStatus string
+	Items          []Item
+	Subtotal       string
+	// Discount is empty when there is none.
+	Discount    string
+	Tax         string
+	TaxIncluded bool
+	Shipping    string
+	Total       string
+	// Address is the shipping address, one line per entry.
+	Address []string
+}
+
+type Item struct {
+	Name     string
+	Variant  string
+	Quantity int
+	Total    string
+}
+
+type Payment struct {
+	Amount string
+}
+
+type Shipment struct {
+	CourierName       string
+	TrackingNumber    string
+	TrackingURL       string
+	EstimatedDelivery string
+}
+
+type Refund struct {
+	Amount string
+	Reason string
+}
+
+// Cart is a cart the customer left. URL is the one-time link back to it.
+type Cart struct {
+	URL      string
+	Items    []Item
+	Subtotal string
+	// Promo is nil when no code comes with the reminder.
+	Promo *Promo
+}
+
+// Product is a product from the customer's wishlist. PreviousPrice is set
+// for price drops.
+type Product struct {
+	Name          string
+	Variant       string
+	URL           string
+	ImageURL      string
+	Price         string
+	PreviousPrice string
+}
+
+// Promo is a code given to the customer, with its discount formatted,
+// e.g. "10%" or "₹100.00".
+type Promo struct {
+	Code       string
+	Discount   string
+	ValidUntil string
+}
+
+// FormatMoney formats an amount for an email, e.g. "₹1299.00".
+func FormatMoney(m money.Money) string {
+	if m.Currency == "" || m.Currency == money.DefaultCurrency {
+		return "₹" + m.Decimal()
+	}
+	return m.Currency + " " + m.Decimal()
+}
+
+type template struct {
+	html *htmltemplate.Template
+	text *texttemplate.Template
+}
+
+// Templates holds the parsed templates of every notification.
+type Templates struct {
+	templates map[string]template
+}
+
+// Default returns the templates built into the binary.
+func Default() (*Templates, error) {
+	sub, err := fs.Sub(defaultFiles, "templates")
+	if err != nil {
+		return nil, err
+	}
+	return Load(sub)
+}
+
+// Load parses the templates in fsys, which must hold layout.html.tmpl,
+// layout.txt.tmpl and a NAME.html.tmpl and NAME.txt.tmpl for every
+// notification.
+func Load(fsys fs.FS) (*Templates, error) {
+	t := &Templates{templates: map[string]template{}}
+	for _, name := range Names {
+		html, err := htmltemplate.ParseFS(fsys, "layout.html.tmpl", name+".html.tmpl")
+		if err != nil {
+			return nil, fmt.Errorf("email template %s: %w", name, err)
+		}
+		text, err := texttemplate.ParseFS(fsys, "layout.txt.tmpl", name+".txt.tmpl")
+		if err != nil {
+			return nil, fmt.Errorf("email template %s: %w", name, err)
+		}
+		if text.Lookup("subject") == nil {
+			return nil, fmt.Errorf("email template %s: %s.txt.tmpl defines no subject", name, name)
+		}
+		t.templates[name] = template{html: html, text: text}
+	}
+	return t, nil
+}
+
+// Render renders notification name for to.
+func (t *Templates) Render(name, to string, data Data) (mail.Message, error) {
+	tmpl, ok := t.templates[name]
+	if !ok {
+		return mail.Message{}, fmt.Errorf("%w: %s", ErrUnknownTemplate, name)
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := tmpl.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return mail.Message{}, err
+	}
+	data.Subject = strings.TrimSpace(subject.String())
+	if err := tmpl.text.ExecuteTemplate(&text, "layout.txt.tmpl", data); err != nil {
+		return mail.Message{}, err
+	}
+	if err := tmpl.html.ExecuteTemplate(&html, "layout.html.tmpl", data); err != nil {
+		return mail.Message{}, err
+	}
+
+	return mail.Message{
+		To:      to,
+		Subject: data.Subject,
+		HTML:    html.String(),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+	}, nil
+}
@@ -0,0 +1,42 @@
+package notify
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+var ErrInvalidUnsubscribeToken = errors.New("invalid unsubscribe link")
+
+// UnsubscribeToken signs userID for the unsubscribe link in marketing mail.
+// The token has the form "<base64 user ID>.<base64 signature>" and doesn't
+// expire, since old emails must keep working.
+func UnsubscribeToken(secret, userID string) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(userID))
+	return payload + "." + signUnsubscribe(secret, payload)
+}
+
+// ParseUnsubscribeToken checks the token's signature and returns the user
+// it was made for.
+func ParseUnsubscribeToken(secret, token string) (string, error) {
+	if secret == "" {
+		return "", ErrInvalidUnsubscribeToken
+	}
+	payload, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signUnsubscribe(secret, payload)), []byte(sig)) {
+		return "", ErrInvalidUnsubscribeToken
+	}
+	userID, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil || len(userID) == 0 {
+		return "", ErrInvalidUnsubscribeToken
+	}
+	return string(userID), nil
+}
+
+func signUnsubscribe(secret, payload string) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte("unsubscribe:" + payload))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"gorm.io/gorm"
+)
+
+type AddressRepository struct {
+	DB *gorm.DB
+}
+
+func NewAddressRepository(db *gorm.DB) *AddressRepository {
+	return &AddressRepository{DB: db}
+}
+
+// WithTx returns a copy of the repository that runs its queries on tx.
+func (r *AddressRepository) WithTx(tx *gorm.DB) *AddressRepository {
+	return &AddressRepository{DB: tx}
+}
+
+func (r *AddressRepository) CreateAddress(address *models.Address) error {
+	return r.DB.Create(address).Error
+}
+
+func (r *AddressRepository) UpdateAddress(address *models.Address) error {
+	return r.DB.Save(address).Error
+}
+
+func (r *AddressRepository) DeleteAddress(address *models.Address) error {
+	return r.DB.Delete(address).Error
+}
+
+// GetAddressForUser returns the address only if it belongs to userID.
+func (r *AddressRepository) GetAddressForUser(id uint, userID string) (*models.Address, error) {
+	var address models.Address
+	err := r.DB.Where("id = ? AND user_id = ?", id, userID).First(&address).Error
+	return &address, err
+}
+
+// GetAddressesByUserID lists a user's addresses, default first.
+func (r *AddressRepository) GetAddressesByUserID(userID string) ([]models.Address, error) {
+	var addresses []models.Address
+	err := r.DB.Where("user_id = ?", userID).
+		Order("is_default DESC, updated_at DESC").
+		Find(&addresses).Error
+	return addresses, err
+}
+
+func (r *AddressRepository) GetDefaultAddress(userID string) (*models.Address, error) {
+	var address models.Address
+	err := r.DB.Where("user_id = ? AND is_default", userID).First(&address).Error
+	return &address, err
+}
+
+func (r *AddressRepository) CountByUserID(userID string) (int64, error) {
+	var count int64
+	err := r.DB.Model(&models.Address{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+// ClearDefault unsets the user's default address, so another can take its
+// place without tripping the one-default-per-user index.
+func (r *AddressRepository) ClearDefault(userID string) error {
+	return r.DB.Model(&models.Address{}).
+		Where("user_id = ? AND is_default", userID).
+		Update("is_default", false).Error
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CartRecoveryReport sums up the reminders sent in a period and what came
+// of them. Orders that were later cancelled don't count as recovered.
+type CartRecoveryReport struct {
+	RemindersSent    int
+	LinksOpened      int
+	OrdersRecovered  int
+	PromoCodesIssued int
+	PromoCodesUsed   int
+	AbandonedValue   money.Money
+	RecoveredRevenue money.Money
+}
+
+type CartRecoveryRepository struct {
+	DB *gorm.DB
+}
+
+func NewCartRecoveryRepository(db *gorm.DB) *CartRecoveryRepository {
+	return &CartRecoveryRepository{DB: db}
+}
+
+// WithTx returns a copy of the repository that runs its queries on tx.
+func (r *CartRecoveryRepository) WithTx(tx *gorm.DB) *CartRecoveryRepository {
+	return &CartRecoveryRepository{DB: tx}
+}
+
+// GetAbandonedCarts returns up to limit carts of signed-up customers who
+// accept marketing email that still have items and were last changed
+// between since and idleBefore, oldest first, with their items, variants
+// and products. Carts already reminded about since their last change are
+// left out.
+func (r *CartRecoveryRepository) GetAbandonedCarts(since, idleBefore time.Time, limit int) ([]models.Cart, error) {
+	var carts []models.Cart
+	err := r.DB.
+		Joins("JOIN users ON users.clerk_user_id = carts.user_id AND users.deleted_at IS NULL AND users.marketing_emails").
+		Where("carts.updated_at >= ? AND carts.updated_at < ?", since, idleBefore).
+		Where("EXISTS (SELECT 1 FROM cart_items WHERE cart_items.cart_id = carts.id)").
+		Where("NOT EXISTS (SELECT 1 FROM cart_recoveries WHERE cart_recoveries.cart_id = carts.id AND cart_recoveries.cart_updated_at = carts.updated_at)").
+		Order("carts.updated_at ASC").
+		Limit(limit).
+		Preload("CartItems").
+		Preload("CartItems.Variant").
+		Preload("CartItems.Variant.Product").
+		Find(&carts).Error
+	return carts, err
+}
+
+// CreateRecovery records a recovery and reports whether it was created;
+// it isn't when another run already took the same cart and idle spell.
+func (r *CartRecoveryRepository) CreateRecovery(rec *models.CartRecovery) (bool, error) {
+	result := r.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(rec)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *CartRecoveryRepository) UpdateRecovery(rec *models.CartRecovery) error {
+	return r.DB.Omit(clause.Associations).Save(rec).Error
+}
+
+// LockRecoveryByTokenHash returns the recovery a link's token belongs to,
+// with its promo code, holding a row lock until the transaction ends.
+func (r *CartRecoveryRepository) LockRecoveryByTokenHash(tokenHash string) (*models.CartRecovery, error) {
+	var rec models.CartRecovery
+	err := r.DB.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("PromoCode").
+		Where("token_hash = ?", tokenHash).
+		First(&rec).Error
+	return &rec, err
+}
+
+// AttributeOrder credits an order to the customer's latest reminder sent
+// between since and placedAt that has no order yet. It reports whether one
+// was credited; an order already credited to a reminder is left alone.
+func (r *CartRecoveryRepository) AttributeOrder(userID string, orderID uint, since, placedAt time.Time) (bool, error) {
+	result := r.DB.Exec(`
+		UPDATE cart_recoveries SET order_id = ?, converted_at = ?, updated_at = ?
+		WHERE id = (
+			SELECT id FROM cart_recoveries
+			WHERE user_id = ? AND status = ? AND order_id IS NULL AND sent_at >= ? AND sent_at <= ?
+			ORDER BY sent_at DESC
+			LIMIT 1
+		)
+		AND NOT EXISTS (SELECT 1 FROM cart_recoveries WHERE order_id = ?)`,
+		orderID, placedAt, time.Now(),
+		userID, constants.CartRecoverySent, since, placedAt,
+		orderID,
+	)
+	return result.RowsAffected > 0, result.Error
+}
+
+// Report sums up the reminders sent from from until to; either may be nil
+// to leave that end open.
+func (r *CartRecoveryRepository) Report(from, to *time.Time) (*CartRecoveryReport, error) {
+	var row struct {
+		RemindersSent    int
+		LinksOpened      int
+		OrdersRecovered  int
+		PromoCodesIssued int
+		PromoCodesUsed   int
+		AbandonedValue   int64
+		RecoveredRevenue int64
+	}
+	query := r.DB.Table("cart_recoveries").
+		Select(`COUNT(*) AS reminders_sent,
+			COUNT(cart_recoveries.recovered_at) AS links_opened,
+			COUNT(orders.id) FILTER (WHERE orders.status <> ?) AS orders_recovered,
+			COUNT(cart_recoveries.promo_code_id) AS promo_codes_issued,
+			COUNT(promo_codes.id) FILTER (WHERE promo_codes.usage_count > 0) AS promo_codes_used,
+			COALESCE(SUM(cart_recoveries.cart_value), 0) AS abandoned_value,
+			COALESCE(SUM(orders.total_amount) FILTER (WHERE orders.status <> ?), 0) AS recovered_revenue`,
+			constants.OrderCancelled, constants.OrderCancelled).
+		Joins("LEFT JOIN orders ON orders.id = cart_recoveries.order_id").
+		Joins("LEFT JOIN promo_codes ON promo_codes.id = cart_recoveries.promo_code_id").
+		Where("cart_recoveries.status = ?", constants.CartRecoverySent)
+	if from != nil {
+		query = query.Where("cart_recoveries.sent_at >= ?", *from)
+	}
+	if to != nil {
+		query = query.Where("cart_recoveries.sent_at < ?", *to)
+	}
+	if err := query.Scan(&row).Error; err != nil {
+		return nil, err
+	}
+
+	return &CartRecoveryReport{
+		RemindersSent:    row.RemindersSent,
+		LinksOpened:      row.LinksOpened,
+		OrdersRecovered:  row.OrdersRecovered,
+		PromoCodesIssued: row.PromoCodesIssued,
+		PromoCodesUsed:   row.PromoCodesUsed,
+		AbandonedValue:   money.INR(row.AbandonedValue),
+		RecoveredRevenue: money.INR(row.RecoveredRevenue),
+	}, nil
+}
@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
 	"gorm.io/gorm"
 )
@@ -19,6 +21,12 @@ func (r *CartRepository) GetCartByUserID(userID string) (*models.Cart, error) {
 	return &cart, err
 }
 
+func (r *CartRepository) GetCartByGuestSessionID(sessionID string) (*models.Cart, error) {
+	var cart models.Cart
+	err := r.DB.Preload("CartItems").Where("guest_session_id = ?", sessionID).First(&cart).Error
+	return &cart, err
+}
+
 func (r *CartRepository) AddItem(item *models.CartItem) error {
 	return r.DB.Create(item).Error
 }
@@ -34,3 +42,9 @@ func (r *CartRepository) RemoveItem(itemID uint) error {
 func (r *CartRepository) ClearCart(cartID uint) error {
 	return r.DB.Where("cart_id = ?", cartID).Delete(&models.CartItem{}).Error
 }
+
+// Touch marks a cart as changed now, which is how abandoned carts are told
+// apart from ones still in use.
+func (r *CartRepository) Touch(cartID uint) error {
+	return r.DB.Model(&models.Cart{}).Where("id = ?", cartID).Update("updated_at", time.Now()).Error
+}
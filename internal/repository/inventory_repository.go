@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"gorm.io/gorm"
+)
+
+type InventoryRepository struct {
+	DB *gorm.DB
+}
+
+func NewInventoryRepository(db *gorm.DB) *InventoryRepository {
+	return &InventoryRepository{DB: db}
+}
+
+func (r *InventoryRepository) GetByVariantID(variantID uint) (*models.Inventory, error) {
+	var inventory models.Inventory
+	err := r.DB.Where("variant_id = ?", variantID).First(&inventory).Error
+	return &inventory, err
+}
+
+// Reserve moves quantity into reserved_quantity if enough unreserved stock is
+// left. It returns the number of rows updated, so 0 means insufficient stock.
+func (r *InventoryRepository) Reserve(tx *gorm.DB, variantID uint, quantity int) (int64, error) {
+	result := tx.Model(&models.Inventory{}).
+		Where("variant_id = ? AND stock_quantity - reserved_quantity >= ?", variantID, quantity).
+		Update("reserved_quantity", gorm.Expr("reserved_quantity + ?", quantity))
+	return result.RowsAffected, result.Error
+}
+
+// Release gives reserved quantity back without touching stock.
+func (r *InventoryRepository) Release(tx *gorm.DB, variantID uint, quantity int) error {
+	return tx.Model(&models.Inventory{}).
+		Where("variant_id = ?", variantID).
+		Update("reserved_quantity", gorm.Expr("GREATEST(reserved_quantity - ?, 0)", quantity)).Error
+}
+
+// Commit turns a reservation into a sale: the quantity leaves both stock and
+// the reserved pool.
+func (r *InventoryRepository) Commit(tx *gorm.DB, variantID uint, quantity int) error {
+	return tx.Model(&models.Inventory{}).
+		Where("variant_id = ?", variantID).
+		Updates(map[string]interface{}{
+			"stock_quantity":    gorm.Expr("stock_quantity - ?", quantity),
+			"reserved_quantity": gorm.Expr("GREATEST(reserved_quantity - ?, 0)", quantity),
+		}).Error
+}
+
+// Deduct takes quantity straight out of unreserved stock. Like Reserve, 0 rows
+// affected means there was not enough stock.
+func (r *InventoryRepository) Deduct(tx *gorm.DB, variantID uint, quantity int) (int64, error) {
+	result := tx.Model(&models.Inventory{}).
+		Where("variant_id = ? AND stock_quantity - reserved_quantity >= ?", variantID, quantity).
+		Update("stock_quantity", gorm.Expr("stock_quantity - ?", quantity))
+	return result.RowsAffected, result.Error
+}
+
+// Restock puts sold quantity back into stock, e.g. after a cancellation or
+// return.
+func (r *InventoryRepository) Restock(tx *gorm.DB, variantID uint, quantity int) error {
+	return tx.Model(&models.Inventory{}).
+		Where("variant_id = ?", variantID).
+		Update("stock_quantity", gorm.Expr("stock_quantity + ?", quantity)).Error
+}
+
+func (r *InventoryRepository) CreateReservations(tx *gorm.DB, reservations []models.InventoryReservation) error {
+	if len(reservations) == 0 {
+		return nil
+	}
+	return tx.Create(&reservations).Error
+}
+
+func (r *InventoryRepository) GetReservationsByOrderID(tx *gorm.DB, orderID uint, statuses ...string) ([]models.InventoryReservation, error) {
+	var reservations []models.InventoryReservation
+	query := tx.Where("order_id = ?", orderID)
+	if len(statuses) > 0 {
+		query = query.Where("status IN ?", statuses)
+	}
+	err := query.Find(&reservations).Error
+	return reservations, err
+}
+
+func (r *InventoryRepository) UpdateReservationStatus(tx *gorm.DB, id uint, status string) error {
+	return tx.Model(&models.InventoryReservation{}).
+		Where("id = ?", id).
+		Update("status", status).Error
+}
+
+// GetExpiredOrderIDs lists orders that still hold active reservations past
+// their expiry.
+func (r *InventoryRepository) GetExpiredOrderIDs(now time.Time, limit int) ([]uint, error) {
+	var orderIDs []uint
+	err := r.DB.Model(&models.InventoryReservation{}).
+		Distinct("order_id").
+		Where("status = ? AND expires_at <= ?", constants.ReservationActive, now).
+		Limit(limit).
+		Pluck("order_id", &orderIDs).Error
+	return orderIDs, err
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"gorm.io/gorm"
+)
+
+type InvoiceRepository struct {
+	DB *gorm.DB
+}
+
+func NewInvoiceRepository(db *gorm.DB) *InvoiceRepository {
+	return &InvoiceRepository{DB: db}
+}
+
+// WithTx returns a copy of the repository that runs its queries on tx.
+func (r *InvoiceRepository) WithTx(tx *gorm.DB) *InvoiceRepository {
+	return &InvoiceRepository{DB: tx}
+}
+
+func (r *InvoiceRepository) GetInvoiceByOrderID(orderID uint) (*models.Invoice, error) {
+	var invoice models.Invoice
+	err := r.DB.Where("order_id = ?", orderID).First(&invoice).Error
+	return &invoice, err
+}
+
+func (r *InvoiceRepository) CreateInvoice(invoice *models.Invoice) error {
+	return r.DB.Create(invoice).Error
+}
+
+// NextInvoiceNumber takes the next number in a financial year's sequence. The
+// sequence row stays locked until the transaction ends, so numbers are
+// handed out without gaps when the invoice is created in the same
+// transaction.
+func (r *InvoiceRepository) NextInvoiceNumber(financialYear string) (int64, error) {
+	var next int64
+	err := r.DB.Raw(`INSERT INTO invoice_sequences (financial_year, last_number) VALUES (?, 1)
+		ON CONFLICT (financial_year) DO UPDATE SET last_number = invoice_sequences.last_number + 1
+		RETURNING last_number`, financialYear).Scan(&next).Error
+	return next, err
+}
@@ -1,7 +1,9 @@
 package repository
 
 import (
+"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
 "github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
 "gorm.io/gorm"
 )
 
@@ -13,6 +15,11 @@ func NewOrderRepository(db *gorm.DB) *OrderRepository {
 return &OrderRepository{DB: db}
 }
 
+// WithTx returns a copy of the repository that runs its queries on tx.
+func (r *OrderRepository) WithTx(tx *gorm.DB) *OrderRepository {
+return &OrderRepository{DB: tx}
+}
+
 func (r *OrderRepository) CreateOrder(order *models.Order) error {
 return r.DB.Create(order).Error
 }
@@ -50,6 +57,25 @@ Find(&orders).Error
 return orders, err
 }
 
+// GetLifetimeSpend sums what a user has paid across all orders, net of
+// refunds.
+func (r *OrderRepository) GetLifetimeSpend(userID string) (money.Money, error) {
+var total money.Money
+err := r.DB.Model(&models.Payment{}).
+Joins("JOIN orders ON orders.id = payments.order_id").
+Where("orders.user_id = ?", userID).
+Where("payments.status IN ?", []string{constants.PaymentCompleted, constants.PaymentPartiallyRefunded, constants.PaymentRefunded}).
+Select("COALESCE(SUM(payments.amount - payments.amount_refunded), 0)").
+Scan(&total).Error
+return total, err
+}
+
+func (r *OrderRepository) GetOrderByRazorpayOrderID(razorpayOrderID string) (*models.Order, error) {
+var order models.Order
+err := r.DB.Where("razorpay_order_id = ?", razorpayOrderID).First(&order).Error
+return &order, err
+}
+
 func (r *OrderRepository) UpdateOrder(order *models.Order) error {
 return r.DB.Save(order).Error
 }
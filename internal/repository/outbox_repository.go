@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OutboxRepository struct {
+	DB *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) *OutboxRepository {
+	return &OutboxRepository{DB: db}
+}
+
+// WithTx returns a copy of the repository that runs its queries on tx.
+func (r *OutboxRepository) WithTx(tx *gorm.DB) *OutboxRepository {
+	return &OutboxRepository{DB: tx}
+}
+
+func (r *OutboxRepository) CreateEvent(event *models.OutboxEvent) error {
+	return r.DB.Create(event).Error
+}
+
+func (r *OutboxRepository) UpdateEvent(event *models.OutboxEvent) error {
+	return r.DB.Save(event).Error
+}
+
+// ClaimDue leases up to limit pending events that are due by pushing their
+// next attempt out to leaseUntil, oldest first. Other dispatchers skip them
+// until the lease runs out, which is also how events claimed by a
+// dispatcher that died get picked up again.
+func (r *OutboxRepository) ClaimDue(now, leaseUntil time.Time, limit int) ([]models.OutboxEvent, error) {
+	var events []models.OutboxEvent
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", constants.OutboxPending, now).
+			Order("id ASC").
+			Limit(limit).
+			Find(&events).Error; err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, len(events))
+		for i := range events {
+			ids[i] = events[i].ID
+			events[i].NextAttemptAt = leaseUntil
+		}
+		return tx.Model(&models.OutboxEvent{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", leaseUntil).Error
+	})
+	return events, err
+}
+
+// DeleteDeliveredBefore removes events delivered before t and returns how
+// many were removed.
+func (r *OutboxRepository) DeleteDeliveredBefore(t time.Time) (int64, error) {
+	result := r.DB.
+		Where("status = ? AND delivered_at < ?", constants.OutboxDelivered, t).
+		Delete(&models.OutboxEvent{})
+	return result.RowsAffected, result.Error
+}
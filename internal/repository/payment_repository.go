@@ -13,10 +13,21 @@ func NewPaymentRepository(db *gorm.DB) *PaymentRepository {
 	return &PaymentRepository{DB: db}
 }
 
+// WithTx returns a copy of the repository that runs its queries on tx.
+func (r *PaymentRepository) WithTx(tx *gorm.DB) *PaymentRepository {
+	return &PaymentRepository{DB: tx}
+}
+
 func (r *PaymentRepository) CreatePayment(payment *models.Payment) error {
 	return r.DB.Create(payment).Error
 }
 
+func (r *PaymentRepository) GetPaymentByID(id uint) (*models.Payment, error) {
+	var payment models.Payment
+	err := r.DB.First(&payment, id).Error
+	return &payment, err
+}
+
 func (r *PaymentRepository) GetPaymentByOrderID(orderID uint) (*models.Payment, error) {
 	var payment models.Payment
 	err := r.DB.Where("order_id = ?", orderID).First(&payment).Error
@@ -26,3 +37,9 @@ func (r *PaymentRepository) GetPaymentByOrderID(orderID uint) (*models.Payment,
 func (r *PaymentRepository) UpdatePayment(payment *models.Payment) error {
 	return r.DB.Save(payment).Error
 }
+
+func (r *PaymentRepository) GetPaymentByTransactionID(transactionID string) (*models.Payment, error) {
+	var payment models.Payment
+	err := r.DB.Where("transaction_id = ?", transactionID).First(&payment).Error
+	return &payment, err
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+)
+
+const (
+	FacetSize       = "size"
+	FacetColor      = "color"
+	FacetMaterial   = "material"
+	FacetNeckline   = "neckline"
+	FacetSleeveType = "sleeve_type"
+	FacetFit        = "fit"
+)
+
+// FacetCount is one facet value and the number of products that have it.
+type FacetCount struct {
+	Value string
+	Count int64
+}
+
+type facetDef struct {
+	column  string
+	variant bool
+	// clear removes the facet's own selection from the filter, so choosing
+	// size M still shows how many products come in the other sizes.
+	clear func(*ProductFilter)
+}
+
+var facetDefs = map[string]facetDef{
+	FacetSize:       {column: "product_variants.size", variant: true, clear: func(f *ProductFilter) { f.Size = "" }},
+	FacetColor:      {column: "product_variants.color", variant: true, clear: func(f *ProductFilter) { f.Color = "" }},
+	FacetMaterial:   {column: "products.material", clear: func(f *ProductFilter) { f.Material = "" }},
+	FacetNeckline:   {column: "products.neckline", clear: func(f *ProductFilter) { f.Neckline = "" }},
+	FacetSleeveType: {column: "products.sleeve_type", clear: func(f *ProductFilter) { f.SleeveType = "" }},
+	FacetFit:        {column: "products.fit", clear: func(f *ProductFilter) { f.Fit = "" }},
+}
+
+// sizeOrder sorts apparel sizes smallest first; unknown sizes go last.
+var sizeOrder = map[string]int{
+	"XXS": 0, "XS": 1, "S": 2, "M": 3, "L": 4, "XL": 5, "XXL": 6, "2XL": 6, "XXXL": 7, "3XL": 7,
+}
+
+// FacetCounts counts products per value of facet among the products matching
+// filter, ignoring the filter's own selection for that facet.
+func (r *ProductRepository) FacetCounts(filter ProductFilter, facet string) ([]FacetCount, error) {
+	def, ok := facetDefs[facet]
+	if !ok {
+		return nil, fmt.Errorf("unknown facet %q", facet)
+	}
+
+	def.clear(&filter)
+
+	query := r.DB.Model(&models.Product{}).Scopes(productFilterScope(filter))
+	if def.variant {
+		query = query.Joins("JOIN product_variants ON product_variants.product_id = products.id")
+		// The variants counted must match the other variant filter too, so
+		// with red selected the size counts are for red shirts only
+		if filter.Size != "" {
+			query = query.Where("UPPER(product_variants.size) = UPPER(?)", filter.Size)
+		}
+		if filter.Color != "" {
+			query = query.Where("LOWER(product_variants.color) = LOWER(?)", filter.Color)
+		}
+		if filter.InStock != nil && *filter.InStock {
+			query = query.
+				Joins("JOIN inventories ON inventories.variant_id = product_variants.id").
+				Where("inventories.stock_quantity - inventories.reserved_quantity > 0")
+		}
+	}
+
+	var counts []FacetCount
+	err := query.
+		Select(fmt.Sprintf("%s AS value, COUNT(DISTINCT products.id) AS count", def.column)).
+		Where(fmt.Sprintf("%s IS NOT NULL AND %s <> ''", def.column, def.column)).
+		Group(def.column).
+		Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if facet == FacetSize {
+		sort.SliceStable(counts, func(i, j int) bool {
+			ri, rj := sizeRank(counts[i].Value), sizeRank(counts[j].Value)
+			if ri != rj {
+				return ri < rj
+			}
+			return counts[i].Value < counts[j].Value
+		})
+	} else {
+		sort.SliceStable(counts, func(i, j int) bool {
+			return strings.ToLower(counts[i].Value) < strings.ToLower(counts[j].Value)
+		})
+	}
+
+	return counts, nil
+}
+
+func sizeRank(size string) int {
+	if rank, ok := sizeOrder[strings.ToUpper(size)]; ok {
+		return rank
+	}
+	return len(sizeOrder)
+}
@@ -1,7 +1,14 @@
 package repository
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
 	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
 	"gorm.io/gorm"
 )
 
@@ -13,6 +20,11 @@ func NewProductRepository(db *gorm.DB) *ProductRepository {
 	return &ProductRepository{DB: db}
 }
 
+// WithTx returns a copy of the repository that runs its queries on tx.
+func (r *ProductRepository) WithTx(tx *gorm.DB) *ProductRepository {
+	return &ProductRepository{DB: tx}
+}
+
 func (r *ProductRepository) CreateProduct(product *models.Product) error {
 	return r.DB.Create(product).Error
 }
@@ -40,3 +52,262 @@ func (r *ProductRepository) UpdateProduct(product *models.Product) error {
 func (r *ProductRepository) DeleteProduct(id uint) error {
 	return r.DB.Delete(&models.Product{}, id).Error
 }
+
+type ProductSort string
+
+const (
+	ProductSortNewest     ProductSort = "newest"
+	ProductSortPriceAsc   ProductSort = "price_asc"
+	ProductSortPriceDesc  ProductSort = "price_desc"
+	ProductSortPopularity ProductSort = "popularity"
+)
+
+const (
+	DefaultProductPageSize = 20
+	MaxProductPageSize     = 100
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ProductFilter narrows ListProducts. Nil and empty fields are ignored.
+// Size, Color and InStock must all hold for the same variant.
+type ProductFilter struct {
+	IsActive       *bool
+	Category       string
+	Brand          string
+	Fit            string
+	Material       string
+	Neckline       string
+	SleeveType     string
+	Size           string
+	Color          string
+	MinPrice       *money.Money
+	MaxPrice       *money.Money
+	Featured       *bool
+	LimitedEdition *bool
+	InStock        *bool
+}
+
+type ProductPage struct {
+	Products    []models.Product
+	Cursors     []string
+	TotalCount  int64
+	HasNextPage bool
+}
+
+// productCursor is the decoded form of the opaque cursor handed to clients:
+// the sort key of the last row seen plus its ID as a tie-breaker.
+type productCursor struct {
+	Key string `json:"k"`
+	ID  uint   `json:"id"`
+}
+
+// productKey is a row of the paging query, before products are loaded.
+type productKey struct {
+	ID        uint
+	SortValue string
+}
+
+// ListProducts returns one page of products matching filter in the given
+// order, using keyset pagination so deep pages stay cheap.
+func (r *ProductRepository) ListProducts(filter ProductFilter, sort ProductSort, first int, after string) (*ProductPage, error) {
+	if first <= 0 {
+		first = DefaultProductPageSize
+	}
+	if first > MaxProductPageSize {
+		first = MaxProductPageSize
+	}
+
+	keyExpr, keyType, desc := productSortKey(sort)
+
+	base := r.DB.Model(&models.Product{}).Scopes(productFilterScope(filter)).Session(&gorm.Session{})
+
+	page := &ProductPage{}
+	if err := base.Count(&page.TotalCount).Error; err != nil {
+		return nil, err
+	}
+
+	query := base
+	if sort == ProductSortPopularity {
+		query = query.Joins("LEFT JOIN (?) AS sales ON sales.product_id = products.id", productSalesQuery(r.DB))
+	}
+	if after != "" {
+		cursor, err := decodeProductCursor(after)
+		if err != nil {
+			return nil, err
+		}
+		op := ">"
+		if desc {
+			op = "<"
+		}
+		query = query.Where(
+			fmt.Sprintf("(%[1]s %[2]s ?::%[3]s) OR (%[1]s = ?::%[3]s AND products.id %[2]s ?)", keyExpr, op, keyType),
+			cursor.Key, cursor.Key, cursor.ID,
+		)
+	}
+
+	direction := "ASC"
+	if desc {
+		direction = "DESC"
+	}
+
+	var keys []productKey
+	err := query.
+		Select(fmt.Sprintf("products.id AS id, (%s)::text AS sort_value", keyExpr)).
+		Order(fmt.Sprintf("%s %s, products.id %s", keyExpr, direction, direction)).
+		Limit(first + 1).
+		Scan(&keys).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if len(keys) > first {
+		page.HasNextPage = true
+		keys = keys[:first]
+	}
+	if len(keys) == 0 {
+		return page, nil
+	}
+
+	ids := make([]uint, len(keys))
+	for i, k := range keys {
+		ids[i] = k.ID
+	}
+
+	var products []models.Product
+	err = r.DB.Preload("Variants", func(db *gorm.DB) *gorm.DB {
+		return db.Preload("Inventory")
+	}).Where("id IN ?", ids).Find(&products).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uint]models.Product, len(products))
+	for _, p := range products {
+		byID[p.ID] = p
+	}
+
+	for _, k := range keys {
+		p, ok := byID[k.ID]
+		if !ok {
+			continue
+		}
+		page.Products = append(page.Products, p)
+		page.Cursors = append(page.Cursors, encodeProductCursor(productCursor{Key: k.SortValue, ID: k.ID}))
+	}
+
+	return page, nil
+}
+
+// productSortKey returns the SQL expression products are ordered by, the
+// type cursor values are cast back to, and whether the order is descending.
+func productSortKey(sort ProductSort) (string, string, bool) {
+	switch sort {
+	case ProductSortPriceAsc:
+		return "products.base_price", "bigint", false
+	case ProductSortPriceDesc:
+		return "products.base_price", "bigint", true
+	case ProductSortPopularity:
+		return "COALESCE(sales.units_sold, 0)", "numeric", true
+	default:
+		return "products.created_at", "timestamptz", true
+	}
+}
+
+// productSalesQuery sums units sold per product over paid orders.
+func productSalesQuery(db *gorm.DB) *gorm.DB {
+	return db.Table("order_items").
+		Select("product_variants.product_id, SUM(order_items.quantity) AS units_sold").
+		Joins("JOIN product_variants ON product_variants.id = order_items.variant_id").
+		Joins("JOIN orders ON orders.id = order_items.order_id").
+		Where("orders.status NOT IN ?", []string{constants.OrderPending, constants.OrderCancelled}).
+		Group("product_variants.product_id")
+}
+
+func productFilterScope(filter ProductFilter) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if filter.IsActive != nil {
+			db = db.Where("products.is_active = ?", *filter.IsActive)
+		}
+		if filter.Category != "" {
+			db = db.Where("LOWER(products.category) = LOWER(?)", filter.Category)
+		}
+		if filter.Brand != "" {
+			db = db.Where("LOWER(products.brand) = LOWER(?)", filter.Brand)
+		}
+		if filter.Fit != "" {
+			db = db.Where("LOWER(products.fit) = LOWER(?)", filter.Fit)
+		}
+		if filter.Material != "" {
+			db = db.Where("LOWER(products.material) = LOWER(?)", filter.Material)
+		}
+		if filter.Neckline != "" {
+			db = db.Where("LOWER(products.neckline) = LOWER(?)", filter.Neckline)
+		}
+		if filter.SleeveType != "" {
+			db = db.Where("LOWER(products.sleeve_type) = LOWER(?)", filter.SleeveType)
+		}
+		if filter.MinPrice != nil {
+			db = db.Where("products.base_price >= ?", *filter.MinPrice)
+		}
+		if filter.MaxPrice != nil {
+			db = db.Where("products.base_price <= ?", *filter.MaxPrice)
+		}
+		if filter.Featured != nil {
+			db = db.Where("products.featured = ?", *filter.Featured)
+		}
+		if filter.LimitedEdition != nil {
+			db = db.Where("products.limited_edition = ?", *filter.LimitedEdition)
+		}
+
+		if filter.Size != "" || filter.Color != "" || filter.InStock != nil {
+			variants := func() *gorm.DB {
+				q := db.Session(&gorm.Session{NewDB: true}).
+					Table("product_variants").
+					Select("1").
+					Joins("LEFT JOIN inventories ON inventories.variant_id = product_variants.id").
+					Where("product_variants.product_id = products.id")
+				if filter.Size != "" {
+					q = q.Where("UPPER(product_variants.size) = UPPER(?)", filter.Size)
+				}
+				if filter.Color != "" {
+					q = q.Where("LOWER(product_variants.color) = LOWER(?)", filter.Color)
+				}
+				return q
+			}
+			const available = "inventories.stock_quantity - inventories.reserved_quantity > 0"
+
+			switch {
+			case filter.InStock == nil:
+				db = db.Where("EXISTS (?)", variants())
+			case *filter.InStock:
+				db = db.Where("EXISTS (?)", variants().Where(available))
+			default:
+				// Out of stock: no matching variant has anything available
+				if filter.Size != "" || filter.Color != "" {
+					db = db.Where("EXISTS (?)", variants())
+				}
+				db = db.Where("NOT EXISTS (?)", variants().Where(available))
+			}
+		}
+
+		return db
+	}
+}
+
+func encodeProductCursor(c productCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeProductCursor(s string) (productCursor, error) {
+	var c productCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == 0 {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
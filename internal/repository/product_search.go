@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"html"
+	"strings"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 50
+
+	// ts_headline wraps matched terms in private-use characters rather than
+	// tags, so the text around them can be escaped before the markers
+	// become <mark> tags (see highlight).
+	markStart       = "\uE000"
+	markStop        = "\uE001"
+	headlineOptions = `StartSel="` + markStart + `", StopSel="` + markStop + `", HighlightAll=true`
+	snippetOptions  = `StartSel="` + markStart + `", StopSel="` + markStop + `", MaxFragments=2, MaxWords=20, MinWords=5`
+)
+
+var markTags = strings.NewReplacer(markStart, "<mark>", markStop, "</mark>")
+
+// highlight turns ts_headline output into HTML: the product's own text is
+// escaped and only the matched terms are wrapped in <mark> tags.
+func highlight(headline string) string {
+	return markTags.Replace(html.EscapeString(headline))
+}
+
+// ProductSearchHit is one ranked search result. NameHighlight and Snippet
+// are HTML with the matched terms in <mark> tags.
+type ProductSearchHit struct {
+	Product       models.Product
+	Rank          float64
+	NameHighlight string
+	Snippet       string
+}
+
+type searchRow struct {
+	ID            uint
+	Rank          float64
+	NameHighlight string
+	Snippet       string
+}
+
+// SearchProducts runs a full-text search over active products, ranked by the
+// weighted search_vector (name > brand/category > material > description).
+// Names within trigram distance of the query also match, so small typos
+// still find the product.
+func (r *ProductRepository) SearchProducts(query string, limit int) ([]ProductSearchHit, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return []ProductSearchHit{}, nil
+	}
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	if limit > MaxSearchLimit {
+		limit = MaxSearchLimit
+	}
+
+	var rows []searchRow
+	err := r.DB.Raw(`
+		SELECT
+			p.id,
+			ts_rank_cd(p.search_vector, q) + 0.5 * similarity(p.name, @term) AS rank,
+			ts_headline('english', p.name, q, @headline) AS name_highlight,
+			ts_headline('english', coalesce(p.description, ''), q, @snippet) AS snippet
+		FROM products p, websearch_to_tsquery('english', @term) q
+		WHERE p.deleted_at IS NULL
+			AND p.is_active = true
+			AND (p.search_vector @@ q OR p.name % @term OR @term <% p.name)
+		ORDER BY rank DESC, p.id DESC
+		LIMIT @limit`,
+		map[string]interface{}{
+			"term":     query,
+			"headline": headlineOptions,
+			"snippet":  snippetOptions,
+			"limit":    limit,
+		},
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return []ProductSearchHit{}, nil
+	}
+
+	ids := make([]uint, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
+
+	var products []models.Product
+	err = r.DB.Preload("Variants", func(db *gorm.DB) *gorm.DB {
+		return db.Preload("Inventory")
+	}).Where("id IN ?", ids).Find(&products).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uint]models.Product, len(products))
+	for _, p := range products {
+		byID[p.ID] = p
+	}
+
+	hits := make([]ProductSearchHit, 0, len(rows))
+	for _, row := range rows {
+		p, ok := byID[row.ID]
+		if !ok {
+			continue
+		}
+		hits = append(hits, ProductSearchHit{
+			Product:       p,
+			Rank:          row.Rank,
+			NameHighlight: highlight(row.NameHighlight),
+			Snippet:       highlight(row.Snippet),
+		})
+	}
+
+	return hits, nil
+}
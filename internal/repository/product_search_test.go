@@ -0,0 +1,11 @@
+package repository
+
+import "testing"
+
+func TestHighlightEscapesProductText(t *testing.T) {
+	got := highlight(`<img src=x onerror=alert(1)> ` + markStart + `Cotton` + markStop + ` & "linen"`)
+	want := `&lt;img src=x onerror=alert(1)&gt; <mark>Cotton</mark> &amp; &#34;linen&#34;`
+	if got != want {
+		t.Fatalf("highlight = %q, want %q", got, want)
+	}
+}
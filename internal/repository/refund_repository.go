@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RefundRepository struct {
+	DB *gorm.DB
+}
+
+func NewRefundRepository(db *gorm.DB) *RefundRepository {
+	return &RefundRepository{DB: db}
+}
+
+// WithTx returns a copy of the repository that runs its queries on tx.
+func (r *RefundRepository) WithTx(tx *gorm.DB) *RefundRepository {
+	return &RefundRepository{DB: tx}
+}
+
+func (r *RefundRepository) CreateRefund(refund *models.Refund) error {
+	return r.DB.Create(refund).Error
+}
+
+func (r *RefundRepository) GetRefundByID(id uint) (*models.Refund, error) {
+	var refund models.Refund
+	err := r.DB.First(&refund, id).Error
+	return &refund, err
+}
+
+// LockRefund returns a refund with a row lock held until the transaction
+// ends.
+func (r *RefundRepository) LockRefund(id uint) (*models.Refund, error) {
+	var refund models.Refund
+	err := r.DB.Clauses(clause.Locking{Strength: "UPDATE"}).First(&refund, id).Error
+	return &refund, err
+}
+
+func (r *RefundRepository) GetRefundsByPaymentID(paymentID uint) ([]models.Refund, error) {
+	var refunds []models.Refund
+	err := r.DB.Where("payment_id = ?", paymentID).Order("created_at ASC").Find(&refunds).Error
+	return refunds, err
+}
+
+func (r *RefundRepository) GetRefundByRazorpayID(razorpayRefundID string) (*models.Refund, error) {
+	var refund models.Refund
+	err := r.DB.Where("razorpay_refund_id = ?", razorpayRefundID).First(&refund).Error
+	return &refund, err
+}
+
+func (r *RefundRepository) UpdateRefund(refund *models.Refund) error {
+	return r.DB.Save(refund).Error
+}
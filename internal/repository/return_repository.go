@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ReturnRepository struct {
+	DB *gorm.DB
+}
+
+func NewReturnRepository(db *gorm.DB) *ReturnRepository {
+	return &ReturnRepository{DB: db}
+}
+
+// WithTx returns a copy of the repository that runs its queries on tx.
+func (r *ReturnRepository) WithTx(tx *gorm.DB) *ReturnRepository {
+	return &ReturnRepository{DB: tx}
+}
+
+func (r *ReturnRepository) CreateReturnRequest(req *models.ReturnRequest) error {
+	return r.DB.Create(req).Error
+}
+
+func (r *ReturnRepository) UpdateReturnRequest(req *models.ReturnRequest) error {
+	return r.DB.Save(req).Error
+}
+
+func (r *ReturnRepository) GetReturnRequestByID(id uint) (*models.ReturnRequest, error) {
+	var req models.ReturnRequest
+	err := r.DB.First(&req, id).Error
+	return &req, err
+}
+
+// LockReturnRequest returns a return request with a row lock held until the
+// transaction ends.
+func (r *ReturnRepository) LockReturnRequest(id uint) (*models.ReturnRequest, error) {
+	var req models.ReturnRequest
+	err := r.DB.Clauses(clause.Locking{Strength: "UPDATE"}).First(&req, id).Error
+	return &req, err
+}
+
+// GetReturnRequestsByUserID returns a customer's return requests, newest
+// first.
+func (r *ReturnRepository) GetReturnRequestsByUserID(userID string) ([]models.ReturnRequest, error) {
+	var reqs []models.ReturnRequest
+	err := r.DB.Where("user_id = ?", userID).Order("created_at DESC, id DESC").Find(&reqs).Error
+	return reqs, err
+}
+
+// GetReturnRequests is the staff queue: return requests filtered by status
+// and resolution when given, oldest first so they are worked in order.
+func (r *ReturnRepository) GetReturnRequests(status, resolution *string) ([]models.ReturnRequest, error) {
+	var reqs []models.ReturnRequest
+	query := r.DB.Order("created_at ASC, id ASC")
+	if status != nil {
+		query = query.Where("status = ?", *status)
+	}
+	if resolution != nil {
+		query = query.Where("resolution = ?", *resolution)
+	}
+	err := query.Find(&reqs).Error
+	return reqs, err
+}
+
+// OpenQuantity sums the quantity of an order item already claimed by
+// return requests that have not been rejected or cancelled.
+func (r *ReturnRepository) OpenQuantity(orderItemID uint) (int, error) {
+	var total int
+	err := r.DB.Model(&models.ReturnRequest{}).
+		Select("COALESCE(SUM(quantity), 0)").
+		Where("order_item_id = ? AND status NOT IN ?", orderItemID,
+			[]string{constants.ReturnRejected, constants.ReturnCancelled}).
+		Scan(&total).Error
+	return total, err
+}
+
+// RefundedQuantity sums the quantity of an order item already refunded
+// through returns.
+func (r *ReturnRepository) RefundedQuantity(orderItemID uint) (int, error) {
+	var total int
+	err := r.DB.Model(&models.ReturnRequest{}).
+		Select("COALESCE(SUM(quantity), 0)").
+		Where("order_item_id = ? AND status = ?", orderItemID, constants.ReturnRefunded).
+		Scan(&total).Error
+	return total, err
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ShipmentRepository struct {
+	DB *gorm.DB
+}
+
+func NewShipmentRepository(db *gorm.DB) *ShipmentRepository {
+	return &ShipmentRepository{DB: db}
+}
+
+// WithTx returns a copy of the repository that runs its queries on tx.
+func (r *ShipmentRepository) WithTx(tx *gorm.DB) *ShipmentRepository {
+	return &ShipmentRepository{DB: tx}
+}
+
+// GetShipmentByOrderID returns the order's shipment with its events, oldest
+// first.
+func (r *ShipmentRepository) GetShipmentByOrderID(orderID uint) (*models.Shipment, error) {
+	var shipment models.Shipment
+	err := r.DB.
+		Preload("Events", func(db *gorm.DB) *gorm.DB { return db.Order("occurred_at ASC, id ASC") }).
+		Where("order_id = ?", orderID).
+		First(&shipment).Error
+	return &shipment, err
+}
+
+// LockShipmentByTrackingNumber returns a shipment with a row lock held until
+// the transaction ends.
+func (r *ShipmentRepository) LockShipmentByTrackingNumber(carrier, trackingNumber string) (*models.Shipment, error) {
+	var shipment models.Shipment
+	err := r.DB.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("carrier = ? AND tracking_number = ?", carrier, trackingNumber).
+		First(&shipment).Error
+	return &shipment, err
+}
+
+func (r *ShipmentRepository) CreateShipment(shipment *models.Shipment) error {
+	return r.DB.Create(shipment).Error
+}
+
+func (r *ShipmentRepository) UpdateShipment(shipment *models.Shipment) error {
+	return r.DB.Save(shipment).Error
+}
+
+func (r *ShipmentRepository) DeleteShipment(shipment *models.Shipment) error {
+	return r.DB.Delete(shipment).Error
+}
+
+// AddEvents stores events, skipping any the shipment already has.
+func (r *ShipmentRepository) AddEvents(events []models.ShipmentEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	return r.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&events).Error
+}
+
+// LatestEvent returns the shipment's most recent event.
+func (r *ShipmentRepository) LatestEvent(shipmentID uint) (*models.ShipmentEvent, error) {
+	var event models.ShipmentEvent
+	err := r.DB.
+		Where("shipment_id = ?", shipmentID).
+		Order("occurred_at DESC, id DESC").
+		First(&event).Error
+	return &event, err
+}
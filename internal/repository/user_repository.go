@@ -1,10 +1,32 @@
 package repository
 
 import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+
 	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
 	"gorm.io/gorm"
 )
 
+const (
+	DefaultUserPageSize = 20
+	MaxUserPageSize     = 100
+)
+
+// UserFilter narrows ListUsers. Search matches email, name or phone.
+type UserFilter struct {
+	Search string
+	Role   string
+}
+
+type UserPage struct {
+	Users       []models.User
+	Cursors     []string
+	TotalCount  int64
+	HasNextPage bool
+}
+
 type UserRepository struct {
 	DB *gorm.DB
 }
@@ -13,6 +35,11 @@ func NewUserRepository(db *gorm.DB) *UserRepository {
 	return &UserRepository{DB: db}
 }
 
+// WithTx returns a copy of the repository that runs its queries on tx.
+func (r *UserRepository) WithTx(tx *gorm.DB) *UserRepository {
+	return &UserRepository{DB: tx}
+}
+
 func (r *UserRepository) CreateUser(user *models.User) error {
 	return r.DB.Create(user).Error
 }
@@ -29,6 +56,75 @@ func (r *UserRepository) GetUserByID(id string) (*models.User, error) {
 	return &user, err
 }
 
+func (r *UserRepository) GetUserByClerkID(clerkUserID string) (*models.User, error) {
+	var user models.User
+	err := r.DB.Where("clerk_user_id = ?", clerkUserID).First(&user).Error
+	return &user, err
+}
+
 func (r *UserRepository) UpdateUser(user *models.User) error {
 	return r.DB.Save(user).Error
 }
+
+// ListUsers pages through users newest first. The cursor is the opaque ID
+// of the last user on the previous page.
+func (r *UserRepository) ListUsers(filter UserFilter, first int, after string) (*UserPage, error) {
+	if first <= 0 {
+		first = DefaultUserPageSize
+	}
+	if first > MaxUserPageSize {
+		first = MaxUserPageSize
+	}
+
+	query := r.DB.Model(&models.User{})
+	if search := strings.TrimSpace(filter.Search); search != "" {
+		pattern := "%" + strings.NewReplacer("%", "\\%", "_", "\\_").Replace(search) + "%"
+		query = query.Where("email ILIKE ? OR name ILIKE ? OR phone ILIKE ?", pattern, pattern, pattern)
+	}
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+
+	page := &UserPage{}
+	if err := query.Session(&gorm.Session{}).Count(&page.TotalCount).Error; err != nil {
+		return nil, err
+	}
+
+	if after != "" {
+		id, err := decodeUserCursor(after)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("id < ?", id)
+	}
+
+	if err := query.Order("id DESC").Limit(first + 1).Find(&page.Users).Error; err != nil {
+		return nil, err
+	}
+
+	if len(page.Users) > first {
+		page.Users = page.Users[:first]
+		page.HasNextPage = true
+	}
+	for _, u := range page.Users {
+		page.Cursors = append(page.Cursors, encodeUserCursor(u.ID))
+	}
+
+	return page, nil
+}
+
+func encodeUserCursor(id uint) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("user:" + strconv.FormatUint(uint64(id), 10)))
+}
+
+func decodeUserCursor(s string) (uint, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	id, err := strconv.ParseUint(strings.TrimPrefix(string(data), "user:"), 10, 32)
+	if err != nil || id == 0 {
+		return 0, ErrInvalidCursor
+	}
+	return uint(id), nil
+}
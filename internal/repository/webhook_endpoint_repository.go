@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WebhookEndpointRepository struct {
+	DB *gorm.DB
+}
+
+func NewWebhookEndpointRepository(db *gorm.DB) *WebhookEndpointRepository {
+	return &WebhookEndpointRepository{DB: db}
+}
+
+// WithTx returns a copy of the repository that runs its queries on tx.
+func (r *WebhookEndpointRepository) WithTx(tx *gorm.DB) *WebhookEndpointRepository {
+	return &WebhookEndpointRepository{DB: tx}
+}
+
+func (r *WebhookEndpointRepository) CreateEndpoint(endpoint *models.WebhookEndpoint) error {
+	return r.DB.Create(endpoint).Error
+}
+
+func (r *WebhookEndpointRepository) UpdateEndpoint(endpoint *models.WebhookEndpoint) error {
+	return r.DB.Save(endpoint).Error
+}
+
+// DeleteEndpoint soft-deletes an endpoint so its delivery log is kept.
+func (r *WebhookEndpointRepository) DeleteEndpoint(id uint) error {
+	result := r.DB.Delete(&models.WebhookEndpoint{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *WebhookEndpointRepository) GetEndpointByID(id uint) (*models.WebhookEndpoint, error) {
+	var endpoint models.WebhookEndpoint
+	err := r.DB.First(&endpoint, id).Error
+	return &endpoint, err
+}
+
+func (r *WebhookEndpointRepository) GetEndpoints() ([]models.WebhookEndpoint, error) {
+	var endpoints []models.WebhookEndpoint
+	err := r.DB.Order("id ASC").Find(&endpoints).Error
+	return endpoints, err
+}
+
+// GetActiveEndpointsFor returns the active endpoints subscribed to
+// eventType, including those subscribed to everything.
+func (r *WebhookEndpointRepository) GetActiveEndpointsFor(eventType string) ([]models.WebhookEndpoint, error) {
+	var endpoints []models.WebhookEndpoint
+	err := r.DB.
+		Where("active AND (COALESCE(cardinality(events), 0) = 0 OR ? = ANY(events))", eventType).
+		Order("id ASC").
+		Find(&endpoints).Error
+	return endpoints, err
+}
+
+// CreateDeliveries queues deliveries, skipping any for an endpoint and
+// outbox event that is already queued.
+func (r *WebhookEndpointRepository) CreateDeliveries(deliveries []models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return r.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&deliveries).Error
+}
+
+func (r *WebhookEndpointRepository) CreateDelivery(delivery *models.WebhookDelivery) error {
+	return r.DB.Create(delivery).Error
+}
+
+// UpdateDelivery saves a delivery but not its loaded endpoint, which may
+// have been changed since it was claimed.
+func (r *WebhookEndpointRepository) UpdateDelivery(delivery *models.WebhookDelivery) error {
+	return r.DB.Omit(clause.Associations).Save(delivery).Error
+}
+
+func (r *WebhookEndpointRepository) GetDeliveryByID(id uint) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	err := r.DB.First(&delivery, id).Error
+	return &delivery, err
+}
+
+// GetDeliveries returns an endpoint's delivery log, newest first, filtered
+// by status when given.
+func (r *WebhookEndpointRepository) GetDeliveries(endpointID uint, status *string, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	query := r.DB.Where("endpoint_id = ?", endpointID).Order("id DESC").Limit(limit)
+	if status != nil {
+		query = query.Where("status = ?", *status)
+	}
+	err := query.Find(&deliveries).Error
+	return deliveries, err
+}
+
+// ClaimDueDeliveries leases up to limit pending deliveries to active
+// endpoints that are due, the same way OutboxRepository.ClaimDue does, and
+// loads their endpoints. Deliveries to disabled or deleted endpoints wait
+// until the endpoint is enabled again.
+func (r *WebhookEndpointRepository) ClaimDueDeliveries(now, leaseUntil time.Time, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "webhook_deliveries"}, Options: "SKIP LOCKED"}).
+			Joins("JOIN webhook_endpoints ON webhook_endpoints.id = webhook_deliveries.endpoint_id").
+			Where("webhook_deliveries.status = ? AND webhook_deliveries.next_attempt_at <= ?", constants.WebhookDeliveryPending, now).
+			Where("webhook_endpoints.active AND webhook_endpoints.deleted_at IS NULL").
+			Order("webhook_deliveries.id ASC").
+			Limit(limit).
+			Preload("Endpoint").
+			Find(&deliveries).Error; err != nil {
+			return err
+		}
+		if len(deliveries) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, len(deliveries))
+		for i := range deliveries {
+			ids[i] = deliveries[i].ID
+			deliveries[i].NextAttemptAt = leaseUntil
+		}
+		return tx.Model(&models.WebhookDelivery{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", leaseUntil).Error
+	})
+	return deliveries, err
+}
+
+// DeleteSucceededBefore removes deliveries that succeeded before t and
+// returns how many were removed.
+func (r *WebhookEndpointRepository) DeleteSucceededBefore(t time.Time) (int64, error) {
+	result := r.DB.
+		Where("status = ? AND delivered_at < ?", constants.WebhookDeliverySucceeded, t).
+		Delete(&models.WebhookDelivery{})
+	return result.RowsAffected, result.Error
+}
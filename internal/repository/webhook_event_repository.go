@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WebhookEventRepository struct {
+	DB *gorm.DB
+}
+
+func NewWebhookEventRepository(db *gorm.DB) *WebhookEventRepository {
+	return &WebhookEventRepository{DB: db}
+}
+
+// MarkProcessed records the event inside tx. It returns false if the event
+// was already recorded, in which case the caller should skip processing.
+func (r *WebhookEventRepository) MarkProcessed(tx *gorm.DB, provider, eventID, eventType string) (bool, error) {
+	event := models.WebhookEvent{
+		Provider:    provider,
+		EventID:     eventID,
+		EventType:   eventType,
+		ProcessedAt: time.Now(),
+	}
+
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&event)
+	return result.RowsAffected > 0, result.Error
+}
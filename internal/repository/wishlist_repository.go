@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WishlistRepository struct {
+	DB *gorm.DB
+}
+
+func NewWishlistRepository(db *gorm.DB) *WishlistRepository {
+	return &WishlistRepository{DB: db}
+}
+
+// WithTx returns a copy of the repository that runs its queries on tx.
+func (r *WishlistRepository) WithTx(tx *gorm.DB) *WishlistRepository {
+	return &WishlistRepository{DB: tx}
+}
+
+// GetWishlistByUserID returns a customer's wishlist with its items, most
+// recently saved first.
+func (r *WishlistRepository) GetWishlistByUserID(userID string) (*models.Wishlist, error) {
+	var wishlist models.Wishlist
+	err := r.DB.
+		Preload("Items", func(db *gorm.DB) *gorm.DB {
+			return db.Order("added_at DESC, id DESC")
+		}).
+		Where("user_id = ?", userID).
+		First(&wishlist).Error
+	return &wishlist, err
+}
+
+// GetOrCreateWishlist returns a customer's wishlist, without its items,
+// creating it if they have none yet.
+func (r *WishlistRepository) GetOrCreateWishlist(userID string) (*models.Wishlist, error) {
+	if err := r.DB.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.Wishlist{UserID: userID}).Error; err != nil {
+		return nil, err
+	}
+	var wishlist models.Wishlist
+	err := r.DB.Where("user_id = ?", userID).First(&wishlist).Error
+	return &wishlist, err
+}
+
+// TouchWishlist marks a wishlist as changed now.
+func (r *WishlistRepository) TouchWishlist(wishlist *models.Wishlist) error {
+	return r.DB.Model(wishlist).Update("updated_at", time.Now()).Error
+}
+
+// FindItem returns the item saving a product, in variantID or without a
+// variant when it is nil.
+func (r *WishlistRepository) FindItem(wishlistID, productID uint, variantID *uint) (*models.WishlistItem, error) {
+	var item models.WishlistItem
+	query := r.DB.Where("wishlist_id = ? AND product_id = ?", wishlistID, productID)
+	if variantID != nil {
+		query = query.Where("variant_id = ?", *variantID)
+	} else {
+		query = query.Where("variant_id IS NULL")
+	}
+	err := query.First(&item).Error
+	return &item, err
+}
+
+// GetItemForUser returns a wishlist item with its product and variant if
+// it is on the customer's wishlist.
+func (r *WishlistRepository) GetItemForUser(userID string, itemID uint) (*models.WishlistItem, error) {
+	var item models.WishlistItem
+	err := r.DB.
+		Joins("JOIN wishlists ON wishlists.id = wishlist_items.wishlist_id").
+		Where("wishlists.user_id = ?", userID).
+		Preload("Product").
+		Preload("Variant").
+		First(&item, itemID).Error
+	return &item, err
+}
+
+func (r *WishlistRepository) CreateItem(item *models.WishlistItem) error {
+	return r.DB.Omit(clause.Associations).Create(item).Error
+}
+
+func (r *WishlistRepository) UpdateItem(item *models.WishlistItem) error {
+	return r.DB.Omit(clause.Associations).Save(item).Error
+}
+
+func (r *WishlistRepository) DeleteItem(itemID uint) error {
+	return r.DB.Delete(&models.WishlistItem{}, itemID).Error
+}
+
+// GetBackInStockWatchers returns the items waiting for a variant to come
+// back in stock: those saved in that variant and those saved without one.
+func (r *WishlistRepository) GetBackInStockWatchers(productID, variantID uint) ([]models.WishlistItem, error) {
+	var items []models.WishlistItem
+	err := r.DB.
+		Where("notify_back_in_stock AND awaiting_stock AND product_id = ? AND (variant_id = ? OR variant_id IS NULL)", productID, variantID).
+		Preload("Product").
+		Preload("Variant").
+		Order("id ASC").
+		Find(&items).Error
+	return items, err
+}
+
+// MarkAwaitingStock flags the back-in-stock alerts for a variant that has
+// run out, and for the whole product too when productSoldOut is set.
+func (r *WishlistRepository) MarkAwaitingStock(productID, variantID uint, productSoldOut bool) error {
+	query := r.DB.Model(&models.WishlistItem{}).Where("notify_back_in_stock AND NOT awaiting_stock")
+	if productSoldOut {
+		query = query.Where("variant_id = ? OR (variant_id IS NULL AND product_id = ?)", variantID, productID)
+	} else {
+		query = query.Where("variant_id = ?", variantID)
+	}
+	return query.Update("awaiting_stock", true).Error
+}
+
+// GetPriceDropWatchers returns the items of a product with price drop
+// alerts on.
+func (r *WishlistRepository) GetPriceDropWatchers(productID uint) ([]models.WishlistItem, error) {
+	var items []models.WishlistItem
+	err := r.DB.
+		Where("notify_price_drop AND product_id = ?", productID).
+		Preload("Product").
+		Preload("Variant").
+		Order("id ASC").
+		Find(&items).Error
+	return items, err
+}
+
+// GetUserIDs maps wishlist IDs to the customers they belong to.
+func (r *WishlistRepository) GetUserIDs(wishlistIDs []uint) (map[uint]string, error) {
+	var wishlists []models.Wishlist
+	if err := r.DB.Select("id", "user_id").Where("id IN ?", wishlistIDs).Find(&wishlists).Error; err != nil {
+		return nil, err
+	}
+	userIDs := make(map[uint]string, len(wishlists))
+	for _, w := range wishlists {
+		userIDs[w.ID] = w.UserID
+	}
+	return userIDs, nil
+}
+
+// AvailableStock sums what can still be ordered of a product, or of one of
+// its variants when variantID is set.
+func (r *WishlistRepository) AvailableStock(productID uint, variantID *uint) (int, error) {
+	var total int
+	query := r.DB.Model(&models.Inventory{}).
+		Select("COALESCE(SUM(GREATEST(inventories.stock_quantity - inventories.reserved_quantity, 0)), 0)").
+		Joins("JOIN product_variants ON product_variants.id = inventories.variant_id").
+		Where("product_variants.product_id = ?", productID)
+	if variantID != nil {
+		query = query.Where("product_variants.id = ?", *variantID)
+	}
+	err := query.Scan(&total).Error
+	return total, err
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/repository"
+	"gorm.io/gorm"
+)
+
+var ErrInvalidAddress = errors.New("invalid address")
+
+var (
+	// PIN codes are six digits and never start with 0.
+	pinCodePattern = regexp.MustCompile(`^[1-9][0-9]{5}$`)
+	// Indian mobile numbers are ten digits starting with 6-9, optionally
+	// prefixed with +91, 91 or 0.
+	indianPhonePattern = regexp.MustCompile(`^(?:\+91|91|0)?([6-9][0-9]{9})$`)
+	phoneSeparators    = strings.NewReplacer(" ", "", "-", "", "(", "", ")", "")
+)
+
+type AddressService struct {
+	DB                *gorm.DB
+	AddressRepository *repository.AddressRepository
+}
+
+func NewAddressService(db *gorm.DB, addressRepo *repository.AddressRepository) *AddressService {
+	return &AddressService{
+		DB:                db,
+		AddressRepository: addressRepo,
+	}
+}
+
+// NormalizeIndianPhone validates an Indian mobile number and returns it as
+// +91XXXXXXXXXX.
+func NormalizeIndianPhone(phone string) (string, error) {
+	m := indianPhonePattern.FindStringSubmatch(phoneSeparators.Replace(strings.TrimSpace(phone)))
+	if m == nil {
+		return "", fmt.Errorf("%w: %q is not a valid Indian mobile number", ErrInvalidAddress, phone)
+	}
+	return "+91" + m[1], nil
+}
+
+// NormalizeAddress trims and validates an address in place. Only Indian
+// addresses are accepted, since that is where orders ship.
+func NormalizeAddress(a *models.Address) error {
+	a.Name = strings.TrimSpace(a.Name)
+	a.Line1 = strings.TrimSpace(a.Line1)
+	a.City = strings.TrimSpace(a.City)
+	a.State = strings.TrimSpace(a.State)
+	a.PostalCode = strings.ReplaceAll(strings.TrimSpace(a.PostalCode), " ", "")
+	a.Country = strings.ToUpper(strings.TrimSpace(a.Country))
+	if a.Country == "" {
+		a.Country = "IN"
+	}
+	if a.Line2 != nil {
+		line2 := strings.TrimSpace(*a.Line2)
+		a.Line2 = nil
+		if line2 != "" {
+			a.Line2 = &line2
+		}
+	}
+
+	required := []struct{ field, value string }{
+		{"name", a.Name},
+		{"line1", a.Line1},
+		{"city", a.City},
+		{"state", a.State},
+		{"phone", a.Phone},
+	}
+	for _, r := range required {
+		if r.value == "" {
+			return fmt.Errorf("%w: %s is required", ErrInvalidAddress, r.field)
+		}
+	}
+
+	if a.Country != "IN" {
+		return fmt.Errorf("%w: only Indian addresses are supported", ErrInvalidAddress)
+	}
+	if !pinCodePattern.MatchString(a.PostalCode) {
+		return fmt.Errorf("%w: %q is not a valid PIN code", ErrInvalidAddress, a.PostalCode)
+	}
+
+	phone, err := NormalizeIndianPhone(a.Phone)
+	if err != nil {
+		return err
+	}
+	a.Phone = phone
+
+	return nil
+}
+
+// SnapshotAddress copies an address for storing on an order.
+func SnapshotAddress(a *models.Address) models.OrderAddress {
+	return models.OrderAddress{
+		Name:       a.Name,
+		Line1:      a.Line1,
+		Line2:      a.Line2,
+		City:       a.City,
+		State:      a.State,
+		PostalCode: a.PostalCode,
+		Country:    a.Country,
+		Phone:      a.Phone,
+	}
+}
+
+// FormatAddress renders an address on one line, as stored in the legacy
+// Order.ShippingAddress column.
+func FormatAddress(a models.OrderAddress) string {
+	parts := []string{a.Name, a.Line1}
+	if a.Line2 != nil {
+		parts = append(parts, *a.Line2)
+	}
+	parts = append(parts, a.City, a.State+" "+a.PostalCode, a.Country, a.Phone)
+	return strings.Join(parts, ", ")
+}
+
+// AddAddress validates and saves a new address. A user's first address
+// becomes their default.
+func (s *AddressService) AddAddress(address *models.Address) error {
+	if err := NormalizeAddress(address); err != nil {
+		return err
+	}
+
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		repo := s.AddressRepository.WithTx(tx)
+
+		count, err := repo.CountByUserID(address.UserID)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			address.IsDefault = true
+		}
+
+		if address.IsDefault {
+			if err := repo.ClearDefault(address.UserID); err != nil {
+				return err
+			}
+		}
+		return repo.CreateAddress(address)
+	})
+}
+
+// UpdateAddress validates and saves changes to an existing address.
+func (s *AddressService) UpdateAddress(address *models.Address) error {
+	if err := NormalizeAddress(address); err != nil {
+		return err
+	}
+
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		repo := s.AddressRepository.WithTx(tx)
+
+		if address.IsDefault {
+			if err := repo.ClearDefault(address.UserID); err != nil {
+				return err
+			}
+		}
+		return repo.UpdateAddress(address)
+	})
+}
+
+// DeleteAddress removes an address. If it was the default, the most recently
+// updated remaining address takes over.
+func (s *AddressService) DeleteAddress(address *models.Address) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		repo := s.AddressRepository.WithTx(tx)
+
+		if err := repo.DeleteAddress(address); err != nil {
+			return err
+		}
+		if !address.IsDefault {
+			return nil
+		}
+
+		remaining, err := repo.GetAddressesByUserID(address.UserID)
+		if err != nil || len(remaining) == 0 {
+			return err
+		}
+		return tx.Model(&remaining[0]).Update("is_default", true).Error
+	})
+}
+
+// SetDefaultAddress makes address the user's default shipping address.
+func (s *AddressService) SetDefaultAddress(address *models.Address) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := s.AddressRepository.WithTx(tx).ClearDefault(address.UserID); err != nil {
+			return err
+		}
+		address.IsDefault = true
+		return tx.Model(address).Update("is_default", true).Error
+	})
+}
@@ -0,0 +1,371 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/events"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/repository"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/tax"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidRecoveryLink = errors.New("invalid cart recovery link")
+	ErrRecoveryLinkUsed    = errors.New("cart recovery link has already been used")
+	ErrRecoveryLinkExpired = errors.New("cart recovery link has expired")
+
+	errRecoveryTaken = errors.New("cart recovery already recorded")
+)
+
+// recoveryBatchSize caps how many carts one run reminds customers about.
+const recoveryBatchSize = 50
+
+// promoCodeAlphabet leaves out characters easily mistaken for each other.
+const promoCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// CartReminder is a reminder about an abandoned cart. Quote has a line per
+// cart item, in order; PromoCode is nil when no code comes with it.
+type CartReminder struct {
+	User        *models.User
+	Cart        *models.Cart
+	Quote       tax.Result
+	RecoveryURL string
+	PromoCode   *models.PromoCode
+}
+
+// CartReminderNotifier sends abandoned cart reminders. It reports whether
+// the reminder went out; false means the customer doesn't want them.
+// CanSendCartReminder says up front whether a reminder to user would go
+// out, so no promo code is made for one that won't.
+type CartReminderNotifier interface {
+	CanSendCartReminder(user *models.User) bool
+	SendCartReminder(ctx context.Context, reminder CartReminder) (bool, error)
+}
+
+// CartRecoveryConfig sets when carts count as abandoned and what the
+// reminder offers.
+type CartRecoveryConfig struct {
+	// IdleAfter is how long a cart goes unchanged before it is abandoned.
+	IdleAfter time.Duration
+	// MaxAge is how long after its last change a cart is still worth a
+	// reminder.
+	MaxAge time.Duration
+	// LinkTTL is how long a recovery link works.
+	LinkTTL time.Duration
+	// RecoveryURL is the storefront page that redeems recovery links; the
+	// token is added as ?token=.
+	RecoveryURL string
+	// DiscountPercent, when above zero, adds a single-use promo code for
+	// that much off to each reminder, valid for PromoTTL.
+	DiscountPercent int
+	PromoTTL        time.Duration
+	// AttributionWindow is how long after a reminder an order by the same
+	// customer counts as recovered.
+	AttributionWindow time.Duration
+}
+
+// CartRecoveryService reminds customers about carts they left and tracks
+// whether the reminders bring them back.
+type CartRecoveryService struct {
+	DB             *gorm.DB
+	Repo           *repository.CartRecoveryRepository
+	UserRepository *repository.UserRepository
+	TaxService     *TaxService
+	Notifier       CartReminderNotifier
+	Config         CartRecoveryConfig
+}
+
+func NewCartRecoveryService(
+	db *gorm.DB,
+	repo *repository.CartRecoveryRepository,
+	userRepo *repository.UserRepository,
+	taxService *TaxService,
+	notifier CartReminderNotifier,
+	cfg CartRecoveryConfig,
+) *CartRecoveryService {
+	return &CartRecoveryService{
+		DB:             db,
+		Repo:           repo,
+		UserRepository: userRepo,
+		TaxService:     taxService,
+		Notifier:       notifier,
+		Config:         cfg,
+	}
+}
+
+// Run reminds customers about the carts abandoned since the last run and
+// returns how many recoveries it recorded.
+func (s *CartRecoveryService) Run(ctx context.Context) (int, error) {
+	now := time.Now()
+	carts, err := s.Repo.GetAbandonedCarts(now.Add(-s.Config.MaxAge), now.Add(-s.Config.IdleAfter), recoveryBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	recorded := 0
+	for i := range carts {
+		if ctx.Err() != nil {
+			break
+		}
+		ok, err := s.remind(ctx, &carts[i])
+		if err != nil {
+			log.Printf("RECOVERY: cart %d: %v", carts[i].ID, err)
+		}
+		if ok {
+			recorded++
+		}
+	}
+	return recorded, nil
+}
+
+// remind records a recovery for a cart, with its promo code, and sends the
+// reminder, reporting whether the recovery was recorded. It is recorded
+// first so a cart is never reminded about twice for the same idle spell,
+// even by runs on other servers; a reminder that fails to send is not
+// tried again. A cart no reminder can be sent for is recorded as skipped,
+// so it isn't picked up again on every run.
+func (s *CartRecoveryService) remind(ctx context.Context, cart *models.Cart) (bool, error) {
+	token, hash, err := newRecoveryToken()
+	if err != nil {
+		return false, err
+	}
+	now := time.Now()
+	rec := &models.CartRecovery{
+		CartID:        cart.ID,
+		CartUpdatedAt: cart.UpdatedAt,
+		UserID:        *cart.UserID,
+		TokenHash:     hash,
+		CartValue:     money.INR(0),
+		Status:        constants.CartRecoveryPending,
+		ExpiresAt:     now.Add(s.Config.LinkTTL),
+	}
+
+	user, err := s.UserRepository.GetUserByClerkID(*cart.UserID)
+	if err != nil {
+		return s.skip(rec, fmt.Errorf("failed to load user: %w", err))
+	}
+
+	// Items whose product has since been removed can't be bought
+	items := make([]models.CartItem, 0, len(cart.CartItems))
+	for _, item := range cart.CartItems {
+		if item.Variant.Product != nil {
+			items = append(items, item)
+			rec.ItemCount += item.Quantity
+		}
+	}
+	if len(items) == 0 {
+		return s.skip(rec, nil)
+	}
+	cart.CartItems = items
+	quote := s.TaxService.QuoteCart(items)
+	rec.CartValue = quote.Gross
+
+	if !s.Notifier.CanSendCartReminder(user) {
+		return s.skip(rec, nil)
+	}
+
+	created := false
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if s.Config.DiscountPercent > 0 {
+			promo, err := s.newPromoCode(now)
+			if err != nil {
+				return err
+			}
+			if err := repository.NewPromoCodeRepository(tx).Create(promo); err != nil {
+				return err
+			}
+			rec.PromoCodeID = &promo.ID
+			rec.PromoCode = promo
+		}
+
+		created, err = s.Repo.WithTx(tx).CreateRecovery(rec)
+		if err == nil && !created {
+			// Another run got here first; drop the promo code with it
+			return errRecoveryTaken
+		}
+		return err
+	})
+	if errors.Is(err, errRecoveryTaken) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to record recovery: %w", err)
+	}
+
+	sent, sendErr := s.Notifier.SendCartReminder(ctx, CartReminder{
+		User:        user,
+		Cart:        cart,
+		Quote:       quote,
+		RecoveryURL: s.Config.RecoveryURL + "?token=" + url.QueryEscape(token),
+		PromoCode:   rec.PromoCode,
+	})
+	switch {
+	case sendErr != nil:
+		msg := sendErr.Error()
+		rec.Status = constants.CartRecoveryFailed
+		rec.LastError = &msg
+	case sent:
+		rec.Status = constants.CartRecoverySent
+		rec.SentAt = &now
+	default:
+		rec.Status = constants.CartRecoverySkipped
+	}
+	if err := s.Repo.UpdateRecovery(rec); err != nil {
+		return true, fmt.Errorf("failed to record reminder: %w", err)
+	}
+	if sendErr != nil {
+		return true, fmt.Errorf("failed to send reminder: %w", sendErr)
+	}
+	if sent {
+		log.Printf("RECOVERY: reminded %s about cart %d", rec.UserID, cart.ID)
+	}
+	return true, nil
+}
+
+// skip records rec as skipped, with the reason when it is an error, and
+// reports whether it was recorded.
+func (s *CartRecoveryService) skip(rec *models.CartRecovery, reason error) (bool, error) {
+	rec.Status = constants.CartRecoverySkipped
+	if reason != nil {
+		msg := reason.Error()
+		rec.LastError = &msg
+	}
+	created, err := s.Repo.CreateRecovery(rec)
+	if err != nil {
+		return false, fmt.Errorf("failed to record skipped recovery: %w", err)
+	}
+	if reason != nil {
+		return created, reason
+	}
+	return created, nil
+}
+
+func (s *CartRecoveryService) newPromoCode(now time.Time) (*models.PromoCode, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate promo code: %w", err)
+	}
+	code := []byte("BACK-")
+	for _, c := range b {
+		code = append(code, promoCodeAlphabet[int(c)%len(promoCodeAlphabet)])
+	}
+
+	limit := 1
+	validUntil := now.Add(s.Config.PromoTTL)
+	return &models.PromoCode{
+		Code:          string(code),
+		DiscountType:  models.DiscountTypePercentage,
+		DiscountValue: int64(s.Config.DiscountPercent) * 100,
+		ValidFrom:     &now,
+		ValidUntil:    &validUntil,
+		IsActive:      true,
+		UsageLimit:    &limit,
+	}, nil
+}
+
+// newRecoveryToken returns a recovery link token and the hash stored for
+// it, so the links can't be rebuilt from the database.
+func newRecoveryToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("failed to generate recovery token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, hashRecoveryToken(token), nil
+}
+
+func hashRecoveryToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Redeem uses up a recovery link for the customer it was sent to and
+// returns the recovery with its promo code.
+func (s *CartRecoveryService) Redeem(userID, token string) (*models.CartRecovery, error) {
+	var rec *models.CartRecovery
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		repo := s.Repo.WithTx(tx)
+		var err error
+		rec, err = repo.LockRecoveryByTokenHash(hashRecoveryToken(token))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidRecoveryLink
+		}
+		if err != nil {
+			return err
+		}
+
+		// Someone else's link is as good as no link
+		if rec.UserID != userID {
+			return ErrInvalidRecoveryLink
+		}
+		if rec.RecoveredAt != nil {
+			return ErrRecoveryLinkUsed
+		}
+		now := time.Now()
+		if now.After(rec.ExpiresAt) {
+			return ErrRecoveryLinkExpired
+		}
+
+		rec.RecoveredAt = &now
+		return repo.UpdateRecovery(rec)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// Subscribe registers the handler that credits orders to reminders with
+// bus.
+func (s *CartRecoveryService) Subscribe(bus *EventBus) {
+	bus.Subscribe(events.OrderPlaced, "cart-recovery-conversion", s.attributeOrder)
+}
+
+// attributeOrder credits a new order to the customer's latest reminder
+// within the attribution window.
+func (s *CartRecoveryService) attributeOrder(ctx context.Context, e events.Event) error {
+	var p events.Order
+	if err := e.Decode(&p); err != nil {
+		return err
+	}
+	orderID, err := strconv.ParseUint(p.OrderID, 10, 32)
+	if err != nil {
+		return fmt.Errorf("bad order ID %q in %s event %d", p.OrderID, e.Type, e.ID)
+	}
+
+	credited, err := s.Repo.AttributeOrder(p.UserID, uint(orderID), e.OccurredAt.Add(-s.Config.AttributionWindow), e.OccurredAt)
+	if err != nil {
+		return err
+	}
+	if credited {
+		log.Printf("RECOVERY: order %d recovered an abandoned cart of %s", orderID, p.UserID)
+	}
+	return nil
+}
+
+// Report sums up the reminders sent from from until to.
+func (s *CartRecoveryService) Report(from, to *time.Time) (*repository.CartRecoveryReport, error) {
+	return s.Repo.Report(from, to)
+}
+
+// Start looks for abandoned carts every interval until ctx is cancelled.
+func (s *CartRecoveryService) Start(ctx context.Context, interval time.Duration) {
+	every(ctx, interval, func() {
+		if err := drain(ctx, recoveryBatchSize, s.Run); err != nil {
+			log.Printf("RECOVERY: run failed: %v", err)
+		}
+	})
+}
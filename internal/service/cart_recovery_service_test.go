@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/mail"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/notify"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/repository"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/tax"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/testdb"
+	"gorm.io/gorm"
+)
+
+type recoveryFixture struct {
+	db            *gorm.DB
+	mailer        *mail.Memory
+	notifications *NotificationService
+	recovery      *CartRecoveryService
+}
+
+// newRecoveryFixture sets up cart reminders with a 10% promo code.
+func newRecoveryFixture(t *testing.T) *recoveryFixture {
+	db := testdb.Open(t)
+	templates, err := notify.Default()
+	if err != nil {
+		t.Fatal(err)
+	}
+	userRepo := repository.NewUserRepository(db)
+	mailer := mail.NewMemory()
+	notifications := NewNotificationService(
+		userRepo,
+		repository.NewOrderRepository(db),
+		repository.NewShipmentRepository(db),
+		mailer,
+		templates,
+		NotificationConfig{StoreName: "Tee Shop", UnsubscribeSecret: "unsubscribe-secret"},
+	)
+	recovery := NewCartRecoveryService(
+		db,
+		repository.NewCartRecoveryRepository(db),
+		userRepo,
+		NewTaxService(tax.Default()),
+		notifications,
+		CartRecoveryConfig{
+			IdleAfter:       time.Hour,
+			MaxAge:          7 * 24 * time.Hour,
+			LinkTTL:         24 * time.Hour,
+			RecoveryURL:     "https://shop.example.com/cart/recover",
+			DiscountPercent: 10,
+			PromoTTL:        24 * time.Hour,
+		},
+	)
+	return &recoveryFixture{db: db, mailer: mailer, notifications: notifications, recovery: recovery}
+}
+
+// idleCart creates a customer with a cart of one T-shirt, last changed two
+// hours ago.
+func (f *recoveryFixture) idleCart(t *testing.T, clerkID string) *models.Cart {
+	t.Helper()
+	email := clerkID + "@example.com"
+	if err := f.db.Create(&models.User{ClerkUserID: &clerkID, Email: &email, Name: clerkID}).Error; err != nil {
+		t.Fatal(err)
+	}
+	variant := createVariant(t, f.db, "Plain Tee", money.INR(49900), 1)
+	cart := &models.Cart{UserID: &clerkID}
+	if err := f.db.Create(cart).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := f.db.Create(&models.CartItem{CartID: cart.ID, VariantID: variant.ID, Quantity: 1}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := f.db.Model(cart).UpdateColumn("updated_at", time.Now().Add(-2*time.Hour)).Error; err != nil {
+		t.Fatal(err)
+	}
+	return cart
+}
+
+func (f *recoveryFixture) recoveries(t *testing.T, cartID uint) []models.CartRecovery {
+	t.Helper()
+	var recs []models.CartRecovery
+	if err := f.db.Where("cart_id = ?", cartID).Find(&recs).Error; err != nil {
+		t.Fatal(err)
+	}
+	return recs
+}
+
+func (f *recoveryFixture) promoCodes(t *testing.T) int64 {
+	t.Helper()
+	var n int64
+	if err := f.db.Model(&models.PromoCode{}).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestCartRecoverySkipsCartsWithNothingToBuy(t *testing.T) {
+	f := newRecoveryFixture(t)
+	gone := f.idleCart(t, "user_gone")
+	if err := f.db.Where("1 = 1").Delete(&models.Product{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	live := f.idleCart(t, "user_live")
+
+	if _, err := f.recovery.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	recs := f.recoveries(t, gone.ID)
+	if len(recs) != 1 || recs[0].Status != constants.CartRecoverySkipped || recs[0].PromoCodeID != nil {
+		t.Fatalf("recoveries of the cart with deleted products = %+v, want one skipped without a promo code", recs)
+	}
+	if recs := f.recoveries(t, live.ID); len(recs) != 1 || recs[0].Status != constants.CartRecoverySent {
+		t.Fatalf("recoveries of the other cart = %+v, want one sent", recs)
+	}
+
+	// The skipped cart is not picked up again
+	if n, err := f.recovery.Run(context.Background()); err != nil || n != 0 {
+		t.Fatalf("second run recorded %d recoveries (err %v), want 0", n, err)
+	}
+	if n := len(f.mailer.Sent()); n != 1 {
+		t.Fatalf("%d reminders sent, want 1", n)
+	}
+}
+
+func TestCartRecoveryWithoutUnsubscribeSecret(t *testing.T) {
+	f := newRecoveryFixture(t)
+	f.notifications.Config.UnsubscribeSecret = ""
+	cart := f.idleCart(t, "user_asha")
+
+	if _, err := f.recovery.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if recs := f.recoveries(t, cart.ID); len(recs) != 1 || recs[0].Status != constants.CartRecoverySkipped {
+		t.Fatalf("recoveries = %+v, want one skipped", recs)
+	}
+	if n := f.promoCodes(t); n != 0 {
+		t.Fatalf("%d promo codes made for reminders that can't be sent", n)
+	}
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CartService struct {
+	DB             *gorm.DB
+	CartRepository *repository.CartRepository
+}
+
+func NewCartService(db *gorm.DB, cartRepo *repository.CartRepository) *CartService {
+	return &CartService{
+		DB:             db,
+		CartRepository: cartRepo,
+	}
+}
+
+// MergeGuestCart moves a guest cart's items into the user's cart when the
+// guest signs in. Quantities of the same variant are summed and capped at the
+// stock currently available; the guest cart is deleted afterwards. If the user
+// has no cart yet, the guest cart simply becomes theirs.
+func (s *CartService) MergeGuestCart(guestCartID uint, userID string) (*models.Cart, error) {
+	var cartID uint
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var guestCart models.Cart
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("CartItems").
+			First(&guestCart, guestCartID).Error; err != nil {
+			return err
+		}
+		if guestCart.UserID != nil {
+			return fmt.Errorf("cart %d is not a guest cart", guestCartID)
+		}
+
+		var userCart models.Cart
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("CartItems").
+			Where("user_id = ?", userID).
+			First(&userCart).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			cartID = guestCart.ID
+			return s.adoptGuestCart(tx, &guestCart, userID)
+		}
+		if err != nil {
+			return err
+		}
+
+		cartID = userCart.ID
+		return s.mergeInto(tx, &userCart, &guestCart)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var cart models.Cart
+	if err := s.DB.Preload("CartItems").
+		Preload("CartItems.Variant").
+		Preload("CartItems.Variant.Product").
+		First(&cart, cartID).Error; err != nil {
+		return nil, err
+	}
+	return &cart, nil
+}
+
+func (s *CartService) adoptGuestCart(tx *gorm.DB, cart *models.Cart, userID string) error {
+	for i := range cart.CartItems {
+		item := &cart.CartItems[i]
+		if err := s.capToStock(tx, item, item.Quantity); err != nil {
+			return err
+		}
+	}
+
+	return tx.Model(cart).Updates(map[string]interface{}{
+		"user_id":          userID,
+		"guest_session_id": nil,
+	}).Error
+}
+
+func (s *CartService) mergeInto(tx *gorm.DB, userCart, guestCart *models.Cart) error {
+	existing := make(map[uint]*models.CartItem, len(userCart.CartItems))
+	for i := range userCart.CartItems {
+		existing[userCart.CartItems[i].VariantID] = &userCart.CartItems[i]
+	}
+
+	for _, guestItem := range guestCart.CartItems {
+		if item, ok := existing[guestItem.VariantID]; ok {
+			if err := s.capToStock(tx, item, item.Quantity+guestItem.Quantity); err != nil {
+				return err
+			}
+			continue
+		}
+
+		item := &models.CartItem{
+			CartID:    userCart.ID,
+			VariantID: guestItem.VariantID,
+			Quantity:  guestItem.Quantity,
+			AddedAt:   guestItem.AddedAt,
+		}
+		available, err := availableStock(tx, item.VariantID)
+		if err != nil {
+			return err
+		}
+		if available <= 0 {
+			continue
+		}
+		if item.Quantity > available {
+			item.Quantity = available
+		}
+		if err := tx.Create(item).Error; err != nil {
+			return err
+		}
+	}
+
+	// Hard delete so the guest session can start a fresh cart later
+	if err := tx.Where("cart_id = ?", guestCart.ID).Delete(&models.CartItem{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(userCart).Update("updated_at", time.Now()).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Delete(guestCart).Error
+}
+
+// capToStock sets the item's quantity to want, or to the available stock if
+// that is lower. Items with nothing left in stock are removed.
+func (s *CartService) capToStock(tx *gorm.DB, item *models.CartItem, want int) error {
+	available, err := availableStock(tx, item.VariantID)
+	if err != nil {
+		return err
+	}
+
+	if want > available {
+		want = available
+	}
+	if want <= 0 {
+		return tx.Delete(item).Error
+	}
+	if want == item.Quantity {
+		return nil
+	}
+
+	item.Quantity = want
+	return tx.Model(item).Update("quantity", want).Error
+}
+
+func availableStock(tx *gorm.DB, variantID uint) (int, error) {
+	var inventory models.Inventory
+	err := tx.Where("variant_id = ?", variantID).First(&inventory).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return inventory.StockQuantity - inventory.ReservedQuantity, nil
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/events"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/repository"
+	"gorm.io/gorm"
+)
+
+const (
+	// dispatchBatchSize caps how many events one dispatch delivers.
+	dispatchBatchSize = 50
+	// dispatchLease is how long a dispatcher has to deliver the events it
+	// claimed before another one may take them over.
+	dispatchLease = 5 * time.Minute
+	// Failed deliveries are retried after retryBaseDelay, doubling each
+	// time up to retryMaxDelay.
+	retryBaseDelay = 30 * time.Second
+	retryMaxDelay  = time.Hour
+)
+
+type subscription struct {
+	name    string
+	handler events.Handler
+}
+
+// EventBus records domain events in the outbox and delivers them to the
+// handlers subscribed in this process. Publishing is part of the caller's
+// transaction, so an event exists exactly when the change it describes was
+// committed; delivery happens afterwards, at least once.
+type EventBus struct {
+	DB   *gorm.DB
+	Repo *repository.OutboxRepository
+	// MaxAttempts is how often an event is tried before it is marked
+	// failed and left for someone to look at.
+	MaxAttempts int
+	// Retention is how long delivered events are kept.
+	Retention time.Duration
+
+	subscriptions map[string][]subscription
+}
+
+func NewEventBus(db *gorm.DB, repo *repository.OutboxRepository, maxAttempts int, retention time.Duration) *EventBus {
+	return &EventBus{
+		DB:            db,
+		Repo:          repo,
+		MaxAttempts:   maxAttempts,
+		Retention:     retention,
+		subscriptions: map[string][]subscription{},
+	}
+}
+
+// Subscribe registers handler for events of eventType, or for every event
+// with events.All. name identifies the handler in the outbox, so it must be
+// unique and stay the same across restarts. Subscribe before Start.
+func (b *EventBus) Subscribe(eventType, name string, handler events.Handler) {
+	b.subscriptions[eventType] = append(b.subscriptions[eventType], subscription{name: name, handler: handler})
+}
+
+// Publish records an event in tx. payload is one of the events payload
+// types.
+func (b *EventBus) Publish(tx *gorm.DB, eventType string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+	return b.Repo.WithTx(tx).CreateEvent(&models.OutboxEvent{
+		Type:          eventType,
+		Payload:       data,
+		Status:        constants.OutboxPending,
+		NextAttemptAt: time.Now(),
+	})
+}
+
+// Dispatch delivers the events that are due and returns how many were
+// delivered to all their handlers.
+func (b *EventBus) Dispatch(ctx context.Context) (int, error) {
+	now := time.Now()
+	claimed, err := b.Repo.ClaimDue(now, now.Add(dispatchLease), dispatchBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for i := range claimed {
+		if ctx.Err() != nil {
+			break
+		}
+		if b.deliver(ctx, &claimed[i]) {
+			delivered++
+		}
+	}
+	return delivered, nil
+}
+
+// deliver runs the handlers of one event that haven't succeeded yet and
+// records the outcome. It reports whether the event is now delivered.
+func (b *EventBus) deliver(ctx context.Context, row *models.OutboxEvent) bool {
+	e := events.Event{
+		ID:         row.ID,
+		Type:       row.Type,
+		Payload:    row.Payload,
+		OccurredAt: row.CreatedAt,
+	}
+	done := map[string]bool{}
+	for _, name := range row.Handlers {
+		done[name] = true
+	}
+
+	var failure error
+	for _, sub := range b.handlersFor(row.Type) {
+		if done[sub.name] {
+			continue
+		}
+		if err := runHandler(ctx, sub, e); err != nil {
+			log.Printf("OUTBOX: %s failed on %s event %d: %v", sub.name, row.Type, row.ID, err)
+			failure = err
+			continue
+		}
+		row.Handlers = append(row.Handlers, sub.name)
+	}
+
+	row.Attempts++
+	now := time.Now()
+	switch {
+	case failure == nil:
+		row.Status = constants.OutboxDelivered
+		row.DeliveredAt = &now
+		row.LastError = nil
+	case row.Attempts >= b.MaxAttempts:
+		log.Printf("OUTBOX: giving up on %s event %d after %d attempts", row.Type, row.ID, row.Attempts)
+		row.Status = constants.OutboxFailed
+	default:
+		row.NextAttemptAt = now.Add(backoff(row.Attempts, retryBaseDelay, retryMaxDelay))
+	}
+	if failure != nil {
+		msg := failure.Error()
+		row.LastError = &msg
+	}
+
+	if err := b.Repo.UpdateEvent(row); err != nil {
+		// The lease runs out and the event is delivered again, which
+		// handlers have to cope with anyway.
+		log.Printf("OUTBOX: failed to record delivery of event %d: %v", row.ID, err)
+		return false
+	}
+	return failure == nil
+}
+
+func (b *EventBus) handlersFor(eventType string) []subscription {
+	subs := append([]subscription(nil), b.subscriptions[eventType]...)
+	return append(subs, b.subscriptions[events.All]...)
+}
+
+// runHandler calls a handler, turning a panic into an error so one bad
+// handler can't take the dispatcher down.
+func runHandler(ctx context.Context, sub subscription, e events.Event) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return sub.handler(ctx, e)
+}
+
+// backoff is how long to wait before the next attempt after attempts failed
+// ones: base, doubling each time up to max.
+func backoff(attempts int, base, max time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
+
+// Start delivers due events every interval and prunes old delivered ones
+// until ctx is cancelled.
+func (b *EventBus) Start(ctx context.Context, interval time.Duration) {
+	prune := hourly(func(now time.Time) {
+		n, err := b.Repo.DeleteDeliveredBefore(now.Add(-b.Retention))
+		if err != nil {
+			log.Printf("OUTBOX: prune failed: %v", err)
+		} else if n > 0 {
+			log.Printf("OUTBOX: pruned %d delivered events", n)
+		}
+	})
+
+	every(ctx, interval, func() {
+		if err := drain(ctx, dispatchBatchSize, b.Dispatch); err != nil {
+			log.Printf("OUTBOX: dispatch failed: %v", err)
+		}
+		prune()
+	})
+}
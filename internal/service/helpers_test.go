@@ -0,0 +1,96 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/repository"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/testdb"
+	"gorm.io/gorm"
+)
+
+// testServices is the order, payment and inventory services wired to one
+// event bus, over a fresh test database.
+type testServices struct {
+	db        *gorm.DB
+	bus       *EventBus
+	payments  *PaymentService
+	inventory *InventoryService
+	orders    *OrderService
+}
+
+func newTestServices(t *testing.T) *testServices {
+	db := testdb.Open(t)
+	bus := NewEventBus(db, repository.NewOutboxRepository(db), 3, time.Hour)
+	paymentRepo := repository.NewPaymentRepository(db)
+	payments := NewPaymentService(paymentRepo, repository.NewRefundRepository(db), bus)
+	inventory := NewInventoryService(db, repository.NewInventoryRepository(db), time.Hour, bus, 0)
+	orders := NewOrderService(db, repository.NewOrderRepository(db), paymentRepo, inventory, payments, bus, false)
+	return &testServices{db: db, bus: bus, payments: payments, inventory: inventory, orders: orders}
+}
+
+// createVariant creates a product called name at price with a single size M
+// variant that has stock pieces in stock. The variant's Product is set.
+func createVariant(t *testing.T, db *gorm.DB, name string, price money.Money, stock int) *models.ProductVariant {
+	t.Helper()
+	product := &models.Product{Name: name, BasePrice: price, Weight: 0.2, IsActive: true}
+	if err := db.Create(product).Error; err != nil {
+		t.Fatal(err)
+	}
+	variant := &models.ProductVariant{ProductID: product.ID, Size: "M", SKU: fmt.Sprintf("TEE-M-%d", product.ID)}
+	if err := db.Create(variant).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.Inventory{VariantID: variant.ID, StockQuantity: stock}).Error; err != nil {
+		t.Fatal(err)
+	}
+	variant.Product = product
+	return variant
+}
+
+// createOrder creates an order by userID, in status, for quantity pieces of
+// variant at its product's price, shipped to an address in Bengaluru.
+func createOrder(t *testing.T, db *gorm.DB, userID, status string, variant *models.ProductVariant, quantity int) *models.Order {
+	t.Helper()
+	price := variant.Product.BasePrice
+	subtotal := price.Mul(quantity)
+	order := &models.Order{
+		UserID:          userID,
+		Subtotal:        subtotal,
+		TotalAmount:     subtotal,
+		Status:          status,
+		ShippingAddress: "1 MG Road, Bengaluru 560001",
+		ShippingDetails: models.OrderAddress{
+			Name:       "Asha",
+			Line1:      "1 MG Road",
+			City:       "Bengaluru",
+			State:      "Karnataka",
+			PostalCode: "560001",
+			Country:    "IN",
+			Phone:      "9999999999",
+		},
+		OrderItems: []models.OrderItem{{
+			VariantID: variant.ID,
+			Quantity:  quantity,
+			UnitPrice: price,
+			Subtotal:  subtotal,
+		}},
+	}
+	if err := db.Create(order).Error; err != nil {
+		t.Fatal(err)
+	}
+	return order
+}
+
+// eventCount counts the events of eventType published to the outbox.
+func eventCount(t *testing.T, db *gorm.DB, eventType string) int64 {
+	t.Helper()
+	var n int64
+	if err := db.Model(&models.OutboxEvent{}).Where("type = ?", eventType).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
@@ -0,0 +1,303 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/events"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrInsufficientStock = errors.New("insufficient stock")
+
+// sweepBatchSize caps how many expired orders one sweep releases.
+const sweepBatchSize = 100
+
+type InventoryService struct {
+	DB             *gorm.DB
+	Repo           *repository.InventoryRepository
+	ReservationTTL time.Duration
+	Events         *EventBus
+	// LowStockThreshold is the available quantity at or below which a
+	// variant is reported low on stock.
+	LowStockThreshold int
+}
+
+func NewInventoryService(
+	db *gorm.DB,
+	repo *repository.InventoryRepository,
+	ttl time.Duration,
+	eventBus *EventBus,
+	lowStockThreshold int,
+) *InventoryService {
+	return &InventoryService{
+		DB:                db,
+		Repo:              repo,
+		ReservationTTL:    ttl,
+		Events:            eventBus,
+		LowStockThreshold: lowStockThreshold,
+	}
+}
+
+// ReserveForOrder holds stock for every item of a freshly created order. It
+// must run inside the order's transaction so a failed reservation rolls the
+// order back.
+func (s *InventoryService) ReserveForOrder(tx *gorm.DB, orderID uint, items []models.OrderItem) error {
+	expiresAt := time.Now().Add(s.ReservationTTL)
+	reservations := make([]models.InventoryReservation, 0, len(items))
+
+	for _, item := range items {
+		affected, err := s.Repo.Reserve(tx, item.VariantID, item.Quantity)
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return fmt.Errorf("out of stock for variant %d: %w", item.VariantID, ErrInsufficientStock)
+		}
+		if err := s.checkLowStock(tx, item.VariantID, item.Quantity); err != nil {
+			return err
+		}
+
+		reservations = append(reservations, models.InventoryReservation{
+			OrderID:   orderID,
+			VariantID: item.VariantID,
+			Quantity:  item.Quantity,
+			Status:    constants.ReservationActive,
+			ExpiresAt: expiresAt,
+		})
+	}
+
+	return s.Repo.CreateReservations(tx, reservations)
+}
+
+// CommitReservation converts an order's reservations into sold stock once it
+// is paid. Reservations that already expired no longer hold stock, so they
+// are deducted from whatever is still available. Committing twice is a no-op.
+func (s *InventoryService) CommitReservation(tx *gorm.DB, orderID uint) error {
+	reservations, err := s.Repo.GetReservationsByOrderID(tx, orderID,
+		constants.ReservationActive, constants.ReservationExpired)
+	if err != nil {
+		return err
+	}
+
+	for _, res := range reservations {
+		if res.Status == constants.ReservationActive {
+			if err := s.Repo.Commit(tx, res.VariantID, res.Quantity); err != nil {
+				return err
+			}
+		} else {
+			affected, err := s.Repo.Deduct(tx, res.VariantID, res.Quantity)
+			if err != nil {
+				return err
+			}
+			if affected == 0 {
+				return fmt.Errorf("out of stock for variant %d: %w", res.VariantID, ErrInsufficientStock)
+			}
+			if err := s.checkLowStock(tx, res.VariantID, res.Quantity); err != nil {
+				return err
+			}
+		}
+
+		if err := s.Repo.UpdateReservationStatus(tx, res.ID, constants.ReservationCommitted); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReleaseReservation returns an order's active reservations to available
+// stock and marks them with status (released or expired). Like every change
+// that puts stock back, it publishes stock.updated.
+func (s *InventoryService) ReleaseReservation(tx *gorm.DB, orderID uint, status string) error {
+	reservations, err := s.Repo.GetReservationsByOrderID(tx, orderID, constants.ReservationActive)
+	if err != nil {
+		return err
+	}
+
+	for _, res := range reservations {
+		if err := s.Repo.Release(tx, res.VariantID, res.Quantity); err != nil {
+			return err
+		}
+		if err := s.Repo.UpdateReservationStatus(tx, res.ID, status); err != nil {
+			return err
+		}
+		if err := s.stockUpdated(tx, res.VariantID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RestockItems returns the quantities of already sold order items to stock.
+func (s *InventoryService) RestockItems(tx *gorm.DB, items []models.OrderItem) error {
+	for _, item := range items {
+		if err := s.Repo.Restock(tx, item.VariantID, item.Quantity); err != nil {
+			return err
+		}
+		if err := s.stockUpdated(tx, item.VariantID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HoldStock reserves quantity of a variant outside any order, such as the
+// replacement for an exchange. The hold lasts until ReleaseHold or
+// CommitHold; the reservation sweeper doesn't see it.
+func (s *InventoryService) HoldStock(tx *gorm.DB, variantID uint, quantity int) error {
+	affected, err := s.Repo.Reserve(tx, variantID, quantity)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("out of stock for variant %d: %w", variantID, ErrInsufficientStock)
+	}
+	return s.checkLowStock(tx, variantID, quantity)
+}
+
+// ReleaseHold gives stock held by HoldStock back.
+func (s *InventoryService) ReleaseHold(tx *gorm.DB, variantID uint, quantity int) error {
+	if err := s.Repo.Release(tx, variantID, quantity); err != nil {
+		return err
+	}
+	return s.stockUpdated(tx, variantID)
+}
+
+// CommitHold turns stock held by HoldStock into a sale.
+func (s *InventoryService) CommitHold(tx *gorm.DB, variantID uint, quantity int) error {
+	return s.Repo.Commit(tx, variantID, quantity)
+}
+
+// SetStock sets the stock count of a variant after a stocktake or a
+// delivery from the supplier.
+func (s *InventoryService) SetStock(tx *gorm.DB, variantID uint, quantity int) (*models.Inventory, error) {
+	var inv models.Inventory
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("variant_id = ?", variantID).
+		First(&inv).Error; err != nil {
+		return nil, err
+	}
+
+	taken := inv.StockQuantity - quantity
+	inv.StockQuantity = quantity
+	if err := tx.Save(&inv).Error; err != nil {
+		return nil, err
+	}
+	if err := s.Events.Publish(tx, events.StockUpdated, events.NewStock(&inv)); err != nil {
+		return nil, err
+	}
+	if taken > 0 {
+		if err := s.checkLowStock(tx, variantID, taken); err != nil {
+			return nil, err
+		}
+	}
+	return &inv, nil
+}
+
+// stockUpdated publishes stock.updated with a variant's stock as it now is.
+// A variant without stock records has nothing to report.
+func (s *InventoryService) stockUpdated(tx *gorm.DB, variantID uint) error {
+	var inv models.Inventory
+	err := tx.Where("variant_id = ?", variantID).First(&inv).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.Events.Publish(tx, events.StockUpdated, events.NewStock(&inv))
+}
+
+// checkLowStock publishes stock.low if taking quantity out of a variant's
+// available stock has just brought it down to the threshold, and again if
+// it has just run out, so a variant is reported when it runs low and when
+// it sells out rather than on every sale.
+func (s *InventoryService) checkLowStock(tx *gorm.DB, variantID uint, quantity int) error {
+	var inv models.Inventory
+	if err := tx.Where("variant_id = ?", variantID).First(&inv).Error; err != nil {
+		return err
+	}
+	available := inv.StockQuantity - inv.ReservedQuantity
+	crossed := func(level int) bool {
+		return available <= level && available+quantity > level
+	}
+	if !crossed(s.LowStockThreshold) && !crossed(0) {
+		return nil
+	}
+	return s.Events.Publish(tx, events.StockLow, events.NewStock(&inv))
+}
+
+// HasExpiredReservation reports whether the order lost its stock hold to the
+// expiry sweeper, as opposed to being cancelled by someone.
+func (s *InventoryService) HasExpiredReservation(tx *gorm.DB, orderID uint) (bool, error) {
+	reservations, err := s.Repo.GetReservationsByOrderID(tx, orderID, constants.ReservationExpired)
+	if err != nil {
+		return false, err
+	}
+	return len(reservations) > 0, nil
+}
+
+// ReleaseExpired cancels pending orders whose reservations have run out and
+// frees their stock. It returns the number of orders released.
+func (s *InventoryService) ReleaseExpired() (int, error) {
+	orderIDs, err := s.Repo.GetExpiredOrderIDs(time.Now(), sweepBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	released := 0
+	for _, orderID := range orderIDs {
+		err := s.DB.Transaction(func(tx *gorm.DB) error {
+			var order models.Order
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				First(&order, orderID).Error; err != nil {
+				return err
+			}
+
+			if err := s.ReleaseReservation(tx, order.ID, constants.ReservationExpired); err != nil {
+				return err
+			}
+
+			if order.Status != constants.OrderPending {
+				return nil
+			}
+
+			if err := tx.Model(&order).Update("status", constants.OrderCancelled).Error; err != nil {
+				return err
+			}
+			order.Status = constants.OrderCancelled
+			return s.Events.Publish(tx, events.OrderCancelled, events.NewOrder(&order, constants.OrderPending))
+		})
+		if err != nil {
+			log.Printf("Failed to release reservation for order %d: %v", orderID, err)
+			continue
+		}
+		released++
+	}
+
+	return released, nil
+}
+
+// StartReservationSweeper periodically releases expired reservations until
+// ctx is cancelled.
+func (s *InventoryService) StartReservationSweeper(ctx context.Context, interval time.Duration) {
+	every(ctx, interval, func() {
+		n, err := s.ReleaseExpired()
+		if err != nil {
+			log.Printf("Reservation sweep failed: %v", err)
+			return
+		}
+		if n > 0 {
+			log.Printf("Reservation sweep released %d expired orders", n)
+		}
+	})
+}
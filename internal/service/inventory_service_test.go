@@ -0,0 +1,80 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/events"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
+	"gorm.io/gorm"
+)
+
+func TestStockComingBackPublishesStockUpdated(t *testing.T) {
+	tests := []struct {
+		name string
+		// take takes the variant's only piece; give puts it back.
+		take, give func(s *InventoryService, tx *gorm.DB, variantID uint) error
+	}{
+		{
+			name: "reservation released",
+			take: func(s *InventoryService, tx *gorm.DB, variantID uint) error {
+				return s.ReserveForOrder(tx, 1, []models.OrderItem{{VariantID: variantID, Quantity: 1}})
+			},
+			give: func(s *InventoryService, tx *gorm.DB, variantID uint) error {
+				return s.ReleaseReservation(tx, 1, constants.ReservationExpired)
+			},
+		},
+		{
+			name: "sold items restocked",
+			take: func(s *InventoryService, tx *gorm.DB, variantID uint) error {
+				_, err := s.SetStock(tx, variantID, 0)
+				return err
+			},
+			give: func(s *InventoryService, tx *gorm.DB, variantID uint) error {
+				return s.RestockItems(tx, []models.OrderItem{{VariantID: variantID, Quantity: 1}})
+			},
+		},
+		{
+			name: "exchange hold released",
+			take: func(s *InventoryService, tx *gorm.DB, variantID uint) error {
+				return s.HoldStock(tx, variantID, 1)
+			},
+			give: func(s *InventoryService, tx *gorm.DB, variantID uint) error {
+				return s.ReleaseHold(tx, variantID, 1)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			services := newTestServices(t)
+			db, s := services.db, services.inventory
+			variant := createVariant(t, db, "Plain Tee", money.INR(49900), 1)
+
+			if err := db.Transaction(func(tx *gorm.DB) error { return tt.take(s, tx, variant.ID) }); err != nil {
+				t.Fatal(err)
+			}
+			if err := db.Where("type = ?", events.StockUpdated).Delete(&models.OutboxEvent{}).Error; err != nil {
+				t.Fatal(err)
+			}
+			if err := db.Transaction(func(tx *gorm.DB) error { return tt.give(s, tx, variant.ID) }); err != nil {
+				t.Fatal(err)
+			}
+
+			var published []models.OutboxEvent
+			if err := db.Where("type = ?", events.StockUpdated).Find(&published).Error; err != nil {
+				t.Fatal(err)
+			}
+			if len(published) != 1 {
+				t.Fatalf("%d stock.updated events, want 1", len(published))
+			}
+			var stock events.Stock
+			if err := (events.Event{Payload: published[0].Payload}).Decode(&stock); err != nil {
+				t.Fatal(err)
+			}
+			if stock.Available != 1 {
+				t.Fatalf("stock.updated says %d available, want 1", stock.Available)
+			}
+		})
+	}
+}
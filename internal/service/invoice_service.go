@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/events"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/invoice"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrInvoiceNotAvailable = errors.New("invoice not available")
+
+type InvoiceService struct {
+	DB                *gorm.DB
+	InvoiceRepository *repository.InvoiceRepository
+	TaxService        *TaxService
+	// Seller is printed on every invoice. Its GSTIN decides whether a sale
+	// is intra-state.
+	Seller invoice.Party
+}
+
+func NewInvoiceService(
+	db *gorm.DB,
+	invoiceRepo *repository.InvoiceRepository,
+	taxService *TaxService,
+	seller invoice.Party,
+) *InvoiceService {
+	return &InvoiceService{
+		DB:                db,
+		InvoiceRepository: invoiceRepo,
+		TaxService:        taxService,
+		Seller:            seller,
+	}
+}
+
+// Subscribe issues each order's invoice when the order is confirmed.
+func (s *InvoiceService) Subscribe(bus *EventBus) {
+	bus.Subscribe(events.OrderConfirmed, "invoice", s.handleOrderConfirmed)
+}
+
+// handleOrderConfirmed issues the invoice dated when the order was
+// confirmed, which is the time of supply, however late the event is
+// delivered.
+func (s *InvoiceService) handleOrderConfirmed(ctx context.Context, e events.Event) error {
+	var p events.Order
+	if err := e.Decode(&p); err != nil {
+		return err
+	}
+	orderID, err := strconv.ParseUint(p.OrderID, 10, 32)
+	if err != nil {
+		return fmt.Errorf("bad order ID %q in %s event %d", p.OrderID, e.Type, e.ID)
+	}
+	_, err = s.issue(uint(orderID), e.OccurredAt)
+	if errors.Is(err, ErrInvoiceNotAvailable) {
+		// Retrying won't help; the order or the seller details need fixing
+		log.Printf("INVOICE: not issuing an invoice for order %d: %v", orderID, err)
+		return nil
+	}
+	return err
+}
+
+// IssueInvoice returns the order's invoice. Invoices are issued when the
+// order is confirmed; this issues it now for an order confirmed before
+// that was in place, or whose order.confirmed event is still queued.
+// Only orders confirmed by payment get an invoice, and once issued it is
+// returned unchanged from then on.
+func (s *InvoiceService) IssueInvoice(orderID uint) (*models.Invoice, error) {
+	return s.issue(orderID, time.Now())
+}
+
+// issue returns the order's invoice, issuing it dated issuedAt if it has
+// none yet.
+func (s *InvoiceService) issue(orderID uint, issuedAt time.Time) (*models.Invoice, error) {
+	existing, err := s.InvoiceRepository.GetInvoiceByOrderID(orderID)
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	sellerState, err := s.sellerStateCode()
+	if err != nil {
+		return nil, err
+	}
+
+	var issued *models.Invoice
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the order so concurrent requests issue a single invoice
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("OrderItems", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+			Preload("OrderItems.Variant").
+			Preload("OrderItems.Variant.Product", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+			First(&order, orderID).Error; err != nil {
+			return err
+		}
+
+		invoices := s.InvoiceRepository.WithTx(tx)
+		if existing, err := invoices.GetInvoiceByOrderID(orderID); err == nil {
+			issued = existing
+			return nil
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if order.Status == constants.OrderPending || order.Status == constants.OrderCancelled {
+			return fmt.Errorf("%w: order %d is %s", ErrInvoiceNotAvailable, order.ID, order.Status)
+		}
+
+		buyerState, ok := invoice.StateCode(order.ShippingDetails.State)
+		if !ok {
+			return fmt.Errorf("%w: cannot tell the place of supply from shipping state %q", ErrInvoiceNotAvailable, order.ShippingDetails.State)
+		}
+
+		fy := invoice.FinancialYear(issuedAt)
+		seq, err := invoices.NextInvoiceNumber(fy)
+		if err != nil {
+			return err
+		}
+
+		doc := &invoice.Document{
+			Number:    invoice.FormatNumber(fy, seq),
+			IssuedAt:  issuedAt,
+			OrderID:   order.ID,
+			OrderDate: order.CreatedAt,
+			Seller:    s.Seller,
+			Buyer:     buyerParty(&order, buyerState),
+			Breakdown: invoice.NewBreakdown(
+				invoiceItems(order.OrderItems, s.TaxService.Rates.HSN),
+				s.TaxService.OrderTax(&order),
+				buyerState != sellerState,
+			),
+		}
+		if order.ShippingTaxRate == nil {
+			doc.Shipping = order.ShippingCost
+		}
+		doc.Seller.StateCode = sellerState
+		if order.PromoCode != nil {
+			doc.PromoCode = strings.ToUpper(*order.PromoCode)
+		}
+
+		pdf, err := invoice.Render(doc)
+		if err != nil {
+			return fmt.Errorf("failed to render invoice: %w", err)
+		}
+
+		issued = &models.Invoice{
+			OrderID:       order.ID,
+			Number:        doc.Number,
+			PlaceOfSupply: buyerState,
+			TaxableAmount: doc.Breakdown.Taxable,
+			CGST:          doc.Breakdown.CGST,
+			SGST:          doc.Breakdown.SGST,
+			IGST:          doc.Breakdown.IGST,
+			TotalAmount:   doc.Total(),
+			PDF:           pdf,
+			IssuedAt:      issuedAt,
+		}
+		return invoices.CreateInvoice(issued)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return issued, nil
+}
+
+// sellerStateCode reads the seller's state from the first two digits of
+// their GSTIN.
+func (s *InvoiceService) sellerStateCode() (string, error) {
+	gstin := s.Seller.GSTIN
+	if len(gstin) != 15 {
+		return "", fmt.Errorf("%w: seller GSTIN is not configured", ErrInvoiceNotAvailable)
+	}
+	if invoice.StateName(gstin[:2]) == "" {
+		return "", fmt.Errorf("%w: seller GSTIN %s has an unknown state code", ErrInvoiceNotAvailable, gstin)
+	}
+	return gstin[:2], nil
+}
+
+func buyerParty(order *models.Order, stateCode string) invoice.Party {
+	a := order.ShippingDetails
+	address := []string{a.Line1}
+	if a.Line2 != nil && *a.Line2 != "" {
+		address = append(address, *a.Line2)
+	}
+	address = append(address, a.City+", "+a.State+" "+a.PostalCode)
+
+	return invoice.Party{
+		Name:      a.Name,
+		Address:   address,
+		Phone:     a.Phone,
+		StateCode: stateCode,
+	}
+}
+
+func invoiceItems(orderItems []models.OrderItem, hsn string) []invoice.Item {
+	items := make([]invoice.Item, len(orderItems))
+	for i, oi := range orderItems {
+		description := oi.Variant.SKU
+		if oi.Variant.Product != nil {
+			description = oi.Variant.Product.Name
+		}
+		description += " (" + oi.Variant.Size
+		if oi.Variant.Color != nil && *oi.Variant.Color != "" {
+			description += ", " + *oi.Variant.Color
+		}
+		description += ")"
+
+		items[i] = invoice.Item{
+			Description: description,
+			HSN:         hsn,
+			Quantity:    oi.Quantity,
+			UnitPrice:   oi.UnitPrice,
+		}
+	}
+	return items
+}
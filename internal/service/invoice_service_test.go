@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/events"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/invoice"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/repository"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/tax"
+	"gorm.io/gorm"
+)
+
+func TestInvoiceIssuedWhenOrderIsConfirmed(t *testing.T) {
+	s := newTestServices(t)
+	invoices := NewInvoiceService(
+		s.db,
+		repository.NewInvoiceRepository(s.db),
+		NewTaxService(tax.Default()),
+		invoice.Party{Name: "Tee Shop", GSTIN: "29ABCDE1234F1Z5"},
+	)
+	invoices.Subscribe(s.bus)
+	variant := createVariant(t, s.db, "Plain Tee", money.INR(49900), 1)
+	order := createOrder(t, s.db, "user_1", constants.OrderPending, variant, 1)
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.inventory.ReserveForOrder(tx, order.ID, order.OrderItems); err != nil {
+			return err
+		}
+		_, err := s.orders.ConfirmPayment(tx, order, "pay_1", "upi")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Confirmed late on 31 March, with the event delivered in April
+	ist := time.FixedZone("IST", 5*3600+1800)
+	confirmedAt := time.Date(2026, time.March, 31, 23, 30, 0, 0, ist)
+	if err := s.db.Model(&models.OutboxEvent{}).Where("type = ?", events.OrderConfirmed).
+		UpdateColumn("created_at", confirmedAt).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.bus.Dispatch(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	issued, err := invoices.InvoiceRepository.GetInvoiceByOrderID(order.ID)
+	if err != nil {
+		t.Fatalf("no invoice issued on confirmation: %v", err)
+	}
+	if issued.Number != "INV/25-26/000001" || !issued.IssuedAt.Equal(confirmedAt) {
+		t.Fatalf("invoice %s issued at %v, want INV/25-26/000001 at %v", issued.Number, issued.IssuedAt, confirmedAt)
+	}
+
+	// Downloading it later returns the same invoice
+	downloaded, err := invoices.IssueInvoice(order.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if downloaded.ID != issued.ID || downloaded.Number != issued.Number {
+		t.Fatalf("download returned invoice %s, want %s", downloaded.Number, issued.Number)
+	}
+}
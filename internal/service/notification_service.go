@@ -0,0 +1,343 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/events"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/mail"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/notify"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/repository"
+	"gorm.io/gorm"
+)
+
+// NotificationConfig holds the store details put into emails.
+type NotificationConfig struct {
+	StoreName string
+	// StoreURL is the storefront; orders link to StoreURL/orders/ID.
+	StoreURL string
+	// APIURL is where this API is reachable, for the unsubscribe link.
+	APIURL string
+	// UnsubscribeSecret signs unsubscribe links. Marketing mail is not
+	// sent without it.
+	UnsubscribeSecret string
+}
+
+// NotificationService emails customers about their orders. It sends from
+// event handlers, so every email is queued in the outbox with the change
+// that caused it and retried until the mailer accepts it.
+type NotificationService struct {
+	UserRepository     *repository.UserRepository
+	OrderRepository    *repository.OrderRepository
+	ShipmentRepository *repository.ShipmentRepository
+	Mailer             mail.Mailer
+	Templates          *notify.Templates
+	Config             NotificationConfig
+}
+
+func NewNotificationService(
+	userRepo *repository.UserRepository,
+	orderRepo *repository.OrderRepository,
+	shipmentRepo *repository.ShipmentRepository,
+	mailer mail.Mailer,
+	templates *notify.Templates,
+	cfg NotificationConfig,
+) *NotificationService {
+	return &NotificationService{
+		UserRepository:     userRepo,
+		OrderRepository:    orderRepo,
+		ShipmentRepository: shipmentRepo,
+		Mailer:             mailer,
+		Templates:          templates,
+		Config:             cfg,
+	}
+}
+
+// orderEmails is the email sent for each order event.
+var orderEmails = map[string]string{
+	events.OrderPlaced:     notify.OrderPlaced,
+	events.PaymentCaptured: notify.PaymentReceived,
+	events.OrderShipped:    notify.OrderShipped,
+	events.OrderDelivered:  notify.OrderDelivered,
+	events.OrderCancelled:  notify.OrderCancelled,
+	events.RefundIssued:    notify.RefundIssued,
+}
+
+// Subscribe registers the order emails with bus.
+func (s *NotificationService) Subscribe(bus *EventBus) {
+	for eventType, name := range orderEmails {
+		bus.Subscribe(eventType, "email-"+name, s.orderEmailHandler(name))
+	}
+}
+
+// orderEventPayload holds the fields of the order, payment and refund
+// payloads the emails use.
+type orderEventPayload struct {
+	OrderID        string `json:"orderId"`
+	PreviousStatus string `json:"previousStatus"`
+	Amount         string `json:"amount"`
+	Currency       string `json:"currency"`
+	Reason         string `json:"reason"`
+}
+
+func (s *NotificationService) orderEmailHandler(name string) events.Handler {
+	return func(ctx context.Context, e events.Event) error {
+		var p orderEventPayload
+		if err := e.Decode(&p); err != nil {
+			return err
+		}
+		orderID, err := strconv.ParseUint(p.OrderID, 10, 32)
+		if err != nil {
+			return fmt.Errorf("bad order ID %q in %s event %d", p.OrderID, e.Type, e.ID)
+		}
+
+		order, err := s.OrderRepository.GetOrderByID(uint(orderID))
+		if err != nil {
+			return fmt.Errorf("failed to load order %d: %w", orderID, err)
+		}
+		user, err := s.UserRepository.GetUserByClerkID(order.UserID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Nothing to retry: the customer has deleted their account
+			log.Printf("NOTIFY: no user %s for order %d; skipping %s email", order.UserID, order.ID, name)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if user.Email == nil {
+			log.Printf("NOTIFY: user %s has no email; skipping %s email for order %d", order.UserID, name, order.ID)
+			return nil
+		}
+
+		data := notify.Data{
+			StoreName: s.Config.StoreName,
+			Name:      user.Name,
+			Order:     s.orderView(order),
+		}
+		data.Order.PreviousStatus = p.PreviousStatus
+		switch name {
+		case notify.PaymentReceived, notify.RefundIssued:
+			amount, err := money.Parse(p.Amount, p.Currency)
+			if err != nil {
+				return fmt.Errorf("bad amount in %s event %d: %w", e.Type, e.ID, err)
+			}
+			if name == notify.PaymentReceived {
+				data.Payment = &notify.Payment{Amount: notify.FormatMoney(amount)}
+			} else {
+				data.Refund = &notify.Refund{Amount: notify.FormatMoney(amount), Reason: p.Reason}
+			}
+		case notify.OrderShipped:
+			shipment, err := s.ShipmentRepository.GetShipmentByOrderID(order.ID)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			if err == nil {
+				data.Shipment = shipmentView(shipment)
+			}
+		}
+
+		msg, err := s.Templates.Render(name, *user.Email, data)
+		if err != nil {
+			return err
+		}
+		if err := s.Mailer.Send(ctx, msg); err != nil {
+			return fmt.Errorf("failed to send %s email for order %d: %w", name, order.ID, err)
+		}
+		log.Printf("NOTIFY: sent %s email for order %d", name, order.ID)
+		return nil
+	}
+}
+
+// SendMarketing sends a marketing email to a user who hasn't unsubscribed,
+// with an unsubscribe link in the footer and the List-Unsubscribe headers
+// mail clients use for their own unsubscribe button. It reports whether
+// the email was sent.
+func (s *NotificationService) SendMarketing(ctx context.Context, user *models.User, name string, data notify.Data) (bool, error) {
+	if !s.CanSendMarketing(user) {
+		return false, nil
+	}
+
+	token := notify.UnsubscribeToken(s.Config.UnsubscribeSecret, *user.ClerkUserID)
+	unsubscribeURL := strings.TrimRight(s.Config.APIURL, "/") + "/unsubscribe?token=" + url.QueryEscape(token)
+	data.StoreName = s.Config.StoreName
+	data.Name = user.Name
+	data.UnsubscribeURL = unsubscribeURL
+
+	msg, err := s.Templates.Render(name, *user.Email, data)
+	if err != nil {
+		return false, err
+	}
+	msg.Headers = map[string]string{
+		"List-Unsubscribe":      "<" + unsubscribeURL + ">",
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+	}
+	if err := s.Mailer.Send(ctx, msg); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// CanSendMarketing reports whether SendMarketing would email user: they
+// have an address, haven't unsubscribed, and unsubscribe links can be
+// signed.
+func (s *NotificationService) CanSendMarketing(user *models.User) bool {
+	return user.MarketingEmails && user.Email != nil && user.ClerkUserID != nil && s.Config.UnsubscribeSecret != ""
+}
+
+// CanSendCartReminder reports whether SendCartReminder would email user.
+func (s *NotificationService) CanSendCartReminder(user *models.User) bool {
+	return s.CanSendMarketing(user)
+}
+
+// SendCartReminder emails a customer about the cart they left, as
+// marketing mail.
+func (s *NotificationService) SendCartReminder(ctx context.Context, r CartReminder) (bool, error) {
+	cart := &notify.Cart{
+		URL:      r.RecoveryURL,
+		Subtotal: notify.FormatMoney(r.Quote.Gross),
+	}
+	for i, ci := range r.Cart.CartItems {
+		cart.Items = append(cart.Items, itemView(&ci.Variant, ci.Quantity, r.Quote.Lines[i].Gross))
+	}
+	if p := r.PromoCode; p != nil {
+		cart.Promo = &notify.Promo{Code: p.Code}
+		if p.DiscountType == models.DiscountTypePercentage {
+			cart.Promo.Discount = strconv.FormatFloat(float64(p.DiscountValue)/100, 'f', -1, 64) + "%"
+		} else {
+			cart.Promo.Discount = notify.FormatMoney(money.INR(p.DiscountValue))
+		}
+		if p.ValidUntil != nil {
+			cart.Promo.ValidUntil = p.ValidUntil.Format("Mon, 2 Jan")
+		}
+	}
+
+	return s.SendMarketing(ctx, r.User, notify.CartReminder, notify.Data{Cart: cart})
+}
+
+// SendBackInStock tells a customer that an item on their wishlist is back
+// in stock, as marketing mail.
+func (s *NotificationService) SendBackInStock(ctx context.Context, a WishlistAlert) (bool, error) {
+	return s.SendMarketing(ctx, a.User, notify.BackInStock, notify.Data{Product: s.productView(a)})
+}
+
+// SendPriceDrop tells a customer that an item on their wishlist got
+// cheaper, as marketing mail.
+func (s *NotificationService) SendPriceDrop(ctx context.Context, a WishlistAlert) (bool, error) {
+	view := s.productView(a)
+	view.PreviousPrice = notify.FormatMoney(a.PreviousPrice)
+	return s.SendMarketing(ctx, a.User, notify.PriceDrop, notify.Data{Product: view})
+}
+
+// Unsubscribe turns marketing mail off for the user an unsubscribe token
+// was made for.
+func (s *NotificationService) Unsubscribe(token string) error {
+	userID, err := notify.ParseUnsubscribeToken(s.Config.UnsubscribeSecret, token)
+	if err != nil {
+		return err
+	}
+	user, err := s.UserRepository.GetUserByClerkID(userID)
+	if err != nil {
+		return err
+	}
+	if !user.MarketingEmails {
+		return nil
+	}
+	user.MarketingEmails = false
+	return s.UserRepository.UpdateUser(user)
+}
+
+func (s *NotificationService) orderView(order *models.Order) *notify.Order {
+	view := &notify.Order{
+		Number:      strconv.FormatUint(uint64(order.ID), 10),
+		URL:         strings.TrimRight(s.Config.StoreURL, "/") + "/orders/" + strconv.FormatUint(uint64(order.ID), 10),
+		Subtotal:    notify.FormatMoney(order.Subtotal),
+		Tax:         notify.FormatMoney(order.TaxAmount),
+		TaxIncluded: order.TaxIncluded,
+		Shipping:    notify.FormatMoney(order.ShippingCost),
+		Total:       notify.FormatMoney(order.TotalAmount),
+		Address:     addressLines(order),
+	}
+	if order.Discount.Amount > 0 {
+		view.Discount = notify.FormatMoney(order.Discount)
+	}
+
+	for _, oi := range order.OrderItems {
+		view.Items = append(view.Items, itemView(&oi.Variant, oi.Quantity, oi.Subtotal))
+	}
+	return view
+}
+
+func (s *NotificationService) productView(a WishlistAlert) *notify.Product {
+	product := a.Item.Product
+	view := &notify.Product{
+		Name:     product.Name,
+		URL:      strings.TrimRight(s.Config.StoreURL, "/") + "/products/" + strconv.FormatUint(uint64(product.ID), 10),
+		ImageURL: product.DesignImageURL,
+		Price:    notify.FormatMoney(a.Price),
+	}
+	if len(product.ImageURLs) > 0 {
+		view.ImageURL = product.ImageURLs[0]
+	}
+	if v := a.Item.Variant; v != nil {
+		view.Variant = v.Size
+		if v.Color != nil && *v.Color != "" {
+			view.Variant += ", " + *v.Color
+		}
+	}
+	return view
+}
+
+func itemView(variant *models.ProductVariant, quantity int, total money.Money) notify.Item {
+	item := notify.Item{
+		Name:     variant.SKU,
+		Variant:  variant.Size,
+		Quantity: quantity,
+		Total:    notify.FormatMoney(total),
+	}
+	if variant.Product != nil {
+		item.Name = variant.Product.Name
+	}
+	if variant.Color != nil && *variant.Color != "" {
+		item.Variant += ", " + *variant.Color
+	}
+	return item
+}
+
+// addressLines splits the order's shipping address into lines, falling
+// back on the free-text address of older orders.
+func addressLines(order *models.Order) []string {
+	a := order.ShippingDetails
+	if a.PostalCode == "" {
+		if order.ShippingAddress == "" {
+			return nil
+		}
+		return []string{order.ShippingAddress}
+	}
+
+	lines := []string{a.Name, a.Line1}
+	if a.Line2 != nil && *a.Line2 != "" {
+		lines = append(lines, *a.Line2)
+	}
+	return append(lines, a.City+", "+a.State+" "+a.PostalCode)
+}
+
+func shipmentView(shipment *models.Shipment) *notify.Shipment {
+	view := &notify.Shipment{
+		CourierName:    shipment.CourierName,
+		TrackingNumber: shipment.TrackingNumber,
+	}
+	if shipment.TrackingURL != nil {
+		view.TrackingURL = *shipment.TrackingURL
+	}
+	if shipment.EstimatedDelivery != nil {
+		view.EstimatedDelivery = shipment.EstimatedDelivery.Format("Mon, 2 Jan")
+	}
+	return view
+}
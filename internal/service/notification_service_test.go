@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/events"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/mail"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/notify"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/repository"
+	"gorm.io/gorm"
+)
+
+// flakyMailer fails the next fails sends, then hands messages to the
+// in-memory sink.
+type flakyMailer struct {
+	*mail.Memory
+	fails int
+}
+
+func (m *flakyMailer) Send(ctx context.Context, msg mail.Message) error {
+	if m.fails > 0 {
+		m.fails--
+		return errors.New("smtp: 421 service not available")
+	}
+	return m.Memory.Send(ctx, msg)
+}
+
+type notificationFixture struct {
+	*testServices
+	mailer        *flakyMailer
+	notifications *NotificationService
+	user          *models.User
+}
+
+// newNotificationFixture subscribes the order emails to a bus and creates
+// a customer.
+func newNotificationFixture(t *testing.T) *notificationFixture {
+	s := newTestServices(t)
+	templates, err := notify.Default()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mailer := &flakyMailer{Memory: mail.NewMemory()}
+	notifications := NewNotificationService(
+		repository.NewUserRepository(s.db),
+		repository.NewOrderRepository(s.db),
+		repository.NewShipmentRepository(s.db),
+		mailer,
+		templates,
+		NotificationConfig{
+			StoreName:         "Tee Shop",
+			StoreURL:          "https://shop.example.com/",
+			APIURL:            "https://api.example.com",
+			UnsubscribeSecret: "unsubscribe-secret",
+		},
+	)
+	notifications.Subscribe(s.bus)
+
+	clerkID, email := "user_asha", "asha@example.com"
+	user := &models.User{ClerkUserID: &clerkID, Email: &email, Name: "Asha"}
+	if err := s.db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &notificationFixture{testServices: s, mailer: mailer, notifications: notifications, user: user}
+}
+
+// placeOrder creates an order for the fixture's user and publishes
+// order.placed for it.
+func (f *notificationFixture) placeOrder(t *testing.T) *models.Order {
+	t.Helper()
+	variant := createVariant(t, f.db, "Tee <Limited>", money.INR(49900), 0)
+	order := createOrder(t, f.db, *f.user.ClerkUserID, constants.OrderPending, variant, 2)
+	err := f.db.Transaction(func(tx *gorm.DB) error {
+		return f.bus.Publish(tx, events.OrderPlaced, events.Order{
+			OrderID:     strconv.FormatUint(uint64(order.ID), 10),
+			UserID:      order.UserID,
+			Status:      order.Status,
+			TotalAmount: order.TotalAmount.String(),
+			Currency:    order.TotalAmount.Currency,
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return order
+}
+
+func (f *notificationFixture) dispatch(t *testing.T) int {
+	t.Helper()
+	n, err := f.bus.Dispatch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestOrderPlacedEmail(t *testing.T) {
+	f := newNotificationFixture(t)
+	order := f.placeOrder(t)
+
+	if n := f.dispatch(t); n != 1 {
+		t.Fatalf("%d events delivered, want 1", n)
+	}
+	sent := f.mailer.Sent()
+	if len(sent) != 1 {
+		t.Fatalf("%d emails sent, want 1", len(sent))
+	}
+	msg := sent[0]
+	number := strconv.FormatUint(uint64(order.ID), 10)
+	if msg.To != "asha@example.com" || msg.Subject != "We received your order #"+number {
+		t.Fatalf("email to %q with subject %q", msg.To, msg.Subject)
+	}
+	for _, want := range []string{"Hi Asha", "Tee <Limited>", "https://shop.example.com/orders/" + number} {
+		if !strings.Contains(msg.Text, want) {
+			t.Errorf("text body is missing %q:\n%s", want, msg.Text)
+		}
+	}
+	if !strings.Contains(msg.HTML, "Tee &lt;Limited&gt;") || strings.Contains(msg.HTML, "<Limited>") {
+		t.Errorf("HTML body does not escape the product name:\n%s", msg.HTML)
+	}
+	if msg.Headers["List-Unsubscribe"] != "" {
+		t.Error("order email has an unsubscribe header")
+	}
+}
+
+func TestOrderEmailToUnsubscribedUser(t *testing.T) {
+	f := newNotificationFixture(t)
+	if err := f.db.Model(f.user).UpdateColumn("marketing_emails", false).Error; err != nil {
+		t.Fatal(err)
+	}
+	f.user.MarketingEmails = false
+
+	// Order emails are not marketing and still go out
+	f.placeOrder(t)
+	f.dispatch(t)
+	if n := len(f.mailer.Sent()); n != 1 {
+		t.Fatalf("%d order emails sent, want 1", n)
+	}
+
+	sent, err := f.notifications.SendMarketing(context.Background(), f.user, notify.CartReminder, notify.Data{Cart: &notify.Cart{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent || len(f.mailer.Sent()) != 1 {
+		t.Fatal("marketing email sent to a user who unsubscribed")
+	}
+}
+
+func TestOrderEmailRetriesWhenMailerFails(t *testing.T) {
+	f := newNotificationFixture(t)
+	f.mailer.fails = 1
+	f.placeOrder(t)
+
+	if n := f.dispatch(t); n != 0 {
+		t.Fatalf("%d events delivered while the mailer is down, want 0", n)
+	}
+	var event models.OutboxEvent
+	if err := f.db.Where("type = ?", events.OrderPlaced).First(&event).Error; err != nil {
+		t.Fatal(err)
+	}
+	if event.Status != constants.OutboxPending || event.Attempts != 1 || event.LastError == nil {
+		t.Fatalf("event is %s after %d attempts (error %v), want pending for a retry", event.Status, event.Attempts, event.LastError)
+	}
+
+	// The retry is due once the backoff has passed
+	if err := f.db.Model(&event).UpdateColumn("next_attempt_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if n := f.dispatch(t); n != 1 {
+		t.Fatalf("%d events delivered on retry, want 1", n)
+	}
+	if n := len(f.mailer.Sent()); n != 1 {
+		t.Fatalf("%d emails sent, want exactly 1", n)
+	}
+}
@@ -1,26 +1,208 @@
 package service
 
 import (
-    "github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
-    "gorm.io/gorm"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/events"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type OrderService struct {
-    DB *gorm.DB
+	DB                *gorm.DB
+	OrderRepository   *repository.OrderRepository
+	PaymentRepository *repository.PaymentRepository
+	InventoryService  *InventoryService
+	PaymentService    *PaymentService
+	Events            *EventBus
+
+	// AutoRefundOnCancel refunds the captured payment when a paid order is
+	// cancelled.
+	AutoRefundOnCancel bool
 }
 
-func NewOrderService(db *gorm.DB) *OrderService {
-    return &OrderService{DB: db}
+func NewOrderService(
+	db *gorm.DB,
+	orderRepo *repository.OrderRepository,
+	paymentRepo *repository.PaymentRepository,
+	inventoryService *InventoryService,
+	paymentService *PaymentService,
+	eventBus *EventBus,
+	autoRefundOnCancel bool,
+) *OrderService {
+	return &OrderService{
+		DB:                 db,
+		OrderRepository:    orderRepo,
+		PaymentRepository:  paymentRepo,
+		InventoryService:   inventoryService,
+		PaymentService:     paymentService,
+		Events:             eventBus,
+		AutoRefundOnCancel: autoRefundOnCancel,
+	}
 }
 
+// MarkOrderPaid confirms the order that was created for a Razorpay order.
 func (s *OrderService) MarkOrderPaid(
-    razorpayOrderID string,
-    razorpayPaymentID string,
-) error {
-    return s.DB.Model(&models.Order{}).
-        Where("razorpay_order_id = ?", razorpayOrderID).
-        Updates(map[string]interface{}{
-            "status":              "PAID",
-            "razorpay_payment_id": razorpayPaymentID,
-        }).Error
+	tx *gorm.DB,
+	razorpayOrderID string,
+	razorpayPaymentID string,
+	method string,
+) (*models.Payment, error) {
+	order, err := s.OrderRepository.WithTx(tx).GetOrderByRazorpayOrderID(razorpayOrderID)
+	if err != nil {
+		return nil, fmt.Errorf("order not found for razorpay order %s: %w", razorpayOrderID, err)
+	}
+	return s.ConfirmPayment(tx, order, razorpayPaymentID, method)
+}
+
+// ConfirmPayment records a completed payment, commits the order's reserved
+// stock and moves the order to confirmed. It is safe to call again for an
+// order that is already paid, which happens when both the browser and the
+// webhook report the same payment, and Razorpay sends both payment.captured
+// and order.paid. A payment refunded since stays refunded.
+func (s *OrderService) ConfirmPayment(
+	tx *gorm.DB,
+	order *models.Order,
+	transactionID string,
+	method string,
+) (*models.Payment, error) {
+	// Serialize concurrent confirmations of the same order
+	if err := lockOrder(tx, order); err != nil {
+		return nil, err
+	}
+
+	payment, err := s.PaymentRepository.WithTx(tx).GetPaymentByOrderID(order.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("error checking existing payment: %w", err)
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		payment = &models.Payment{
+			OrderID: order.ID,
+			Amount:  order.TotalAmount,
+		}
+	} else if constants.IsCapturedPaymentStatus(payment.Status) && order.Status != constants.OrderPending {
+		return payment, nil
+	}
+
+	payment.Status = constants.PaymentCompleted
+	payment.PaymentMethod = method
+	payment.TransactionID = transactionID
+	if err := tx.Save(payment).Error; err != nil {
+		return nil, fmt.Errorf("failed to save payment: %w", err)
+	}
+	if err := s.Events.Publish(tx, events.PaymentCaptured, events.NewPayment(payment, order.UserID)); err != nil {
+		return nil, err
+	}
+
+	switch order.Status {
+	case constants.OrderPending:
+		if err := s.TransitionStatus(tx, order, constants.OrderConfirmed); err != nil {
+			return nil, err
+		}
+
+	case constants.OrderCancelled:
+		revived, err := s.reviveExpired(tx, order)
+		if err != nil {
+			return nil, err
+		}
+		if !revived {
+			log.Printf("Payment %s received for cancelled order %d", transactionID, order.ID)
+			if err := s.refundCancelled(tx, order.ID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return payment, nil
+}
+
+// reviveExpired confirms a cancelled order that has just been paid for. The
+// reservation can expire while the customer is still on the payment page;
+// such an order is revived if its stock is still there. Orders cancelled on
+// purpose, and those whose stock has sold since, stay cancelled.
+func (s *OrderService) reviveExpired(tx *gorm.DB, order *models.Order) (bool, error) {
+	expired, err := s.InventoryService.HasExpiredReservation(tx, order.ID)
+	if err != nil || !expired {
+		return false, err
+	}
+	// A savepoint undoes the items committed before one that ran out
+	err = tx.Transaction(func(tx *gorm.DB) error {
+		return s.InventoryService.CommitReservation(tx, order.ID)
+	})
+	if errors.Is(err, ErrInsufficientStock) {
+		log.Printf("Order %d was paid after its stock sold: %v", order.ID, err)
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := tx.Model(order).Update("status", constants.OrderConfirmed).Error; err != nil {
+		return false, fmt.Errorf("failed to update order status: %w", err)
+	}
+	if err := s.publishStatus(tx, order.ID, constants.OrderCancelled); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// FailPayment records a failed payment attempt. A payment that has already
+// been captured, and perhaps refunded, is left untouched, since Razorpay can
+// report failed attempts that were followed by a successful retry.
+func (s *OrderService) FailPayment(tx *gorm.DB, order *models.Order, transactionID string, method string) error {
+	payment, err := s.PaymentRepository.WithTx(tx).GetPaymentByOrderID(order.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		payment = &models.Payment{
+			OrderID: order.ID,
+			Amount:  order.TotalAmount,
+		}
+	} else if constants.IsCapturedPaymentStatus(payment.Status) {
+		return nil
+	}
+
+	payment.Status = constants.PaymentFailed
+	payment.PaymentMethod = method
+	payment.TransactionID = transactionID
+	if err := tx.Save(payment).Error; err != nil {
+		return err
+	}
+	return s.Events.Publish(tx, events.PaymentFailed, events.NewPayment(payment, order.UserID))
+}
+
+// refundCancelled refunds whatever is left of a cancelled order's payment if
+// automatic refunds are enabled.
+func (s *OrderService) refundCancelled(tx *gorm.DB, orderID uint) error {
+	if !s.AutoRefundOnCancel {
+		return nil
+	}
+
+	refund, err := s.PaymentService.RefundPayment(tx, orderID, nil, "order cancelled")
+	if err != nil {
+		return fmt.Errorf("failed to refund cancelled order %d: %w", orderID, err)
+	}
+
+	log.Printf("Refunded %s for cancelled order %d", refund.Amount, orderID)
+	return nil
+}
+
+// lockOrder takes a row lock on the order and refreshes its status so checks
+// made afterwards see the latest committed value.
+func lockOrder(tx *gorm.DB, order *models.Order) error {
+	var current models.Order
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id", "status").
+		First(&current, order.ID).Error; err != nil {
+		return err
+	}
+	order.Status = current.Status
+	return nil
 }
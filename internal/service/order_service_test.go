@@ -0,0 +1,100 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/events"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
+	"gorm.io/gorm"
+)
+
+func TestPaymentAfterReservationExpired(t *testing.T) {
+	tests := []struct {
+		name string
+		// soldOut sells the last piece to someone else before the payment
+		// for the expired order comes in
+		soldOut    bool
+		wantStatus string
+		wantRefund bool
+	}{
+		{name: "stock still there", wantStatus: constants.OrderConfirmed},
+		{name: "stock sold since", soldOut: true, wantStatus: constants.OrderCancelled, wantRefund: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServices(t)
+			s.orders.AutoRefundOnCancel = true
+			variant := createVariant(t, s.db, "Plain Tee", money.INR(49900), 1)
+			order := createOrder(t, s.db, "user_1", constants.OrderPending, variant, 1)
+
+			// The reservation runs out while the customer is paying
+			err := s.db.Transaction(func(tx *gorm.DB) error {
+				if err := s.inventory.ReserveForOrder(tx, order.ID, order.OrderItems); err != nil {
+					return err
+				}
+				if err := s.inventory.ReleaseReservation(tx, order.ID, constants.ReservationExpired); err != nil {
+					return err
+				}
+				return tx.Model(order).Update("status", constants.OrderCancelled).Error
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.soldOut {
+				other := createOrder(t, s.db, "user_2", constants.OrderPending, variant, 1)
+				err := s.db.Transaction(func(tx *gorm.DB) error {
+					if err := s.inventory.ReserveForOrder(tx, other.ID, other.OrderItems); err != nil {
+						return err
+					}
+					return s.inventory.CommitReservation(tx, other.ID)
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err = s.db.Transaction(func(tx *gorm.DB) error {
+				_, err := s.orders.ConfirmPayment(tx, order, "pay_late", "upi")
+				return err
+			})
+			if err != nil {
+				t.Fatalf("late payment: %v", err)
+			}
+
+			var got models.Order
+			if err := s.db.First(&got, order.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if got.Status != tt.wantStatus {
+				t.Fatalf("order is %s, want %s", got.Status, tt.wantStatus)
+			}
+			payment, err := s.payments.PaymentRepository.GetPaymentByOrderID(order.ID)
+			if err != nil {
+				t.Fatalf("payment not recorded: %v", err)
+			}
+			if payment.TransactionID != "pay_late" {
+				t.Fatalf("payment %q recorded, want pay_late", payment.TransactionID)
+			}
+			var inv models.Inventory
+			if err := s.db.Where("variant_id = ?", variant.ID).First(&inv).Error; err != nil {
+				t.Fatal(err)
+			}
+			// The one piece is sold once, to whoever paid for it first
+			if inv.StockQuantity != 0 || inv.ReservedQuantity != 0 {
+				t.Fatalf("stock = %d (%d reserved), want 0", inv.StockQuantity, inv.ReservedQuantity)
+			}
+			var refunds []models.Refund
+			if err := s.db.Where("payment_id = ?", payment.ID).Find(&refunds).Error; err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantRefund != (len(refunds) == 1) || (tt.wantRefund && refunds[0].Amount != money.INR(49900)) {
+				t.Fatalf("refunds = %+v, want a full refund: %v", refunds, tt.wantRefund)
+			}
+			if n := eventCount(t, s.db, events.OrderConfirmed); (n == 1) != (tt.wantStatus == constants.OrderConfirmed) {
+				t.Fatalf("%d order.confirmed events", n)
+			}
+		})
+	}
+}
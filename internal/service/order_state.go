@@ -0,0 +1,159 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/events"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/repository"
+	"gorm.io/gorm"
+)
+
+// orderTransitions lists the statuses each order status may move to.
+// Cancelled and returned are terminal.
+var orderTransitions = map[string][]string{
+	constants.OrderPending:   {constants.OrderConfirmed, constants.OrderCancelled},
+	constants.OrderConfirmed: {constants.OrderShipped, constants.OrderCancelled},
+	constants.OrderShipped:   {constants.OrderDelivered, constants.OrderReturned},
+	constants.OrderDelivered: {constants.OrderReturned},
+	constants.OrderCancelled: {},
+	constants.OrderReturned:  {},
+}
+
+// orderStatusEvents is the event published when an order reaches each
+// status.
+var orderStatusEvents = map[string]string{
+	constants.OrderConfirmed: events.OrderConfirmed,
+	constants.OrderCancelled: events.OrderCancelled,
+	constants.OrderShipped:   events.OrderShipped,
+	constants.OrderDelivered: events.OrderDelivered,
+	constants.OrderReturned:  events.OrderReturned,
+}
+
+var ErrPaymentNotCompleted = errors.New("order has no completed payment")
+
+// UnknownStatusError is returned for a status that is not part of the order
+// lifecycle.
+type UnknownStatusError struct {
+	Status string
+}
+
+func (e *UnknownStatusError) Error() string {
+	return fmt.Sprintf("unknown order status %q", e.Status)
+}
+
+// TransitionError is returned when an order cannot move from its current
+// status to the requested one.
+type TransitionError struct {
+	From string
+	To   string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("cannot change order status from %s to %s", e.From, e.To)
+}
+
+// CanTransition reports whether an order in status from may move to status to.
+func CanTransition(from, to string) bool {
+	for _, next := range orderTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionStatus moves the order to status to, running the side effects of
+// that transition in tx.
+func (s *OrderService) TransitionStatus(tx *gorm.DB, order *models.Order, to string) error {
+	if _, ok := orderTransitions[to]; !ok {
+		return &UnknownStatusError{Status: to}
+	}
+
+	if err := lockOrder(tx, order); err != nil {
+		return err
+	}
+
+	if order.OrderItems == nil {
+		if err := tx.Where("order_id = ?", order.ID).Find(&order.OrderItems).Error; err != nil {
+			return err
+		}
+	}
+
+	from := order.Status
+	if !CanTransition(from, to) {
+		return &TransitionError{From: from, To: to}
+	}
+
+	if err := s.applyTransition(tx, order, from, to); err != nil {
+		return err
+	}
+
+	if err := tx.Model(order).Update("status", to).Error; err != nil {
+		return err
+	}
+	return s.publishStatus(tx, order.ID, from)
+}
+
+// publishStatus records the event for the status the order has just moved
+// to from status from.
+func (s *OrderService) publishStatus(tx *gorm.DB, orderID uint, from string) error {
+	var order models.Order
+	if err := tx.First(&order, orderID).Error; err != nil {
+		return err
+	}
+	return s.Events.Publish(tx, orderStatusEvents[order.Status], events.NewOrder(&order, from))
+}
+
+func (s *OrderService) applyTransition(tx *gorm.DB, order *models.Order, from, to string) error {
+	switch to {
+	case constants.OrderConfirmed:
+		payment, err := s.PaymentRepository.WithTx(tx).GetPaymentByOrderID(order.ID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrPaymentNotCompleted
+		}
+		if err != nil {
+			return err
+		}
+		if payment.Status != constants.PaymentCompleted {
+			return ErrPaymentNotCompleted
+		}
+		return s.InventoryService.CommitReservation(tx, order.ID)
+
+	case constants.OrderCancelled:
+		// A pending order only holds a reservation; a confirmed one has
+		// already taken its items out of stock.
+		if from == constants.OrderPending {
+			return s.InventoryService.ReleaseReservation(tx, order.ID, constants.ReservationReleased)
+		}
+		if err := s.InventoryService.RestockItems(tx, order.OrderItems); err != nil {
+			return err
+		}
+		return s.refundCancelled(tx, order.ID)
+
+	case constants.OrderReturned:
+		return s.restockReturned(tx, order.OrderItems)
+	}
+
+	return nil
+}
+
+// restockReturned puts a returned order's items back in stock, less the
+// pieces claimed by return requests. Those go back to stock when their
+// return passes inspection, or not at all if it fails.
+func (s *OrderService) restockReturned(tx *gorm.DB, items []models.OrderItem) error {
+	returns := repository.NewReturnRepository(tx)
+	var restock []models.OrderItem
+	for _, item := range items {
+		claimed, err := returns.OpenQuantity(item.ID)
+		if err != nil {
+			return err
+		}
+		if item.Quantity -= claimed; item.Quantity > 0 {
+			restock = append(restock, item)
+		}
+	}
+	return s.InventoryService.RestockItems(tx, restock)
+}
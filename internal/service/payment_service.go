@@ -1,41 +1,86 @@
 package service
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"log"
+	"net/http"
 	"os"
+	"strconv"
 
 	"github.com/razorpay/razorpay-go"
+	rzperrors "github.com/razorpay/razorpay-go/errors"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/events"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrPaymentNotRefundable = errors.New("payment cannot be refunded")
+	ErrInvalidRefundAmount  = errors.New("invalid refund amount")
 )
 
 type PaymentService struct {
-	Client *razorpay.Client
+	Client            *razorpay.Client
+	PaymentRepository *repository.PaymentRepository
+	RefundRepository  *repository.RefundRepository
+	Events            *EventBus
 }
 
-func NewPaymentService() *PaymentService {
+func NewPaymentService(paymentRepo *repository.PaymentRepository, refundRepo *repository.RefundRepository, eventBus *EventBus) *PaymentService {
 	client := razorpay.NewClient(
 		os.Getenv("RAZORPAY_KEY_ID"),
 		os.Getenv("RAZORPAY_KEY_SECRET"),
 	)
 
+	// Allows pointing the client at a fake Razorpay server
+	if baseURL := os.Getenv("RAZORPAY_API_URL"); baseURL != "" {
+		client.Request.BaseURL = baseURL
+	}
+	client.Request.HTTPClient.Transport = onlyBadRequests{http.DefaultTransport}
+
 	return &PaymentService{
-		Client: client,
+		Client:            client,
+		PaymentRepository: paymentRepo,
+		RefundRepository:  refundRepo,
+		Events:            eventBus,
 	}
 }
 
-func (ps *PaymentService) CreateOrder(amount float64, currency string, receipt string) (map[string]interface{}, error) {
+// onlyBadRequests turns Razorpay responses other than 400 Bad Request into
+// transport errors. razorpay-go picks its error type from a field Razorpay
+// seldom sets, so without this a server error or rate limit looks like
+// Razorpay turning the request down.
+type onlyBadRequests struct {
+	http.RoundTripper
+}
+
+func (t onlyBadRequests) RoundTrip(r *http.Request) (*http.Response, error) {
+	resp, err := t.RoundTripper.RoundTrip(r)
+	if err == nil && resp.StatusCode >= http.StatusMultipleChoices && resp.StatusCode != http.StatusBadRequest {
+		resp.Body.Close()
+		return nil, fmt.Errorf("razorpay responded %s", resp.Status)
+	}
+	return resp, err
+}
+
+func (ps *PaymentService) CreateOrder(amount money.Money, receipt string) (map[string]interface{}, error) {
 	if ps.Client == nil {
 		return nil, fmt.Errorf("payment client not initialized")
 	}
 
-	// Convert amount to paise (multiply by 100)
-	amountPaise := int64(amount * 100)
-
+	// Razorpay takes amounts in the currency's smallest unit, as Money stores them
 	params := map[string]interface{}{
-		"amount":   amountPaise,
-		"currency": currency,
+		"amount":   amount.Amount,
+		"currency": amount.Currency,
 		"receipt":  receipt,
 	}
 
@@ -60,3 +105,244 @@ func (ps *PaymentService) VerifySignature(razorpayOrderID, razorpayPaymentID, ra
 
 	return expectedSignature == razorpaySignature
 }
+
+// VerifyWebhookSignature checks the X-Razorpay-Signature header, which is the
+// hex HMAC-SHA256 of the raw request body keyed with the webhook secret.
+func (ps *PaymentService) VerifyWebhookSignature(body []byte, signature string) bool {
+	secret := os.Getenv("RAZORPAY_WEBHOOK_SECRET")
+	if secret == "" || signature == "" {
+		return false
+	}
+
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write(body)
+	expectedSignature := hex.EncodeToString(h.Sum(nil))
+
+	return hmac.Equal([]byte(expectedSignature), []byte(signature))
+}
+
+// RefundPayment records a refund of amount of an order's payment, or of
+// whatever is still refundable when amount is nil, and queues it for
+// Razorpay. The amount counts as refunded from now on, so concurrent refunds
+// cannot exceed the captured amount; SubmitRefund sends it once tx commits
+// and gives it back if Razorpay turns it down. Razorpay is never called with
+// the payment row locked.
+func (ps *PaymentService) RefundPayment(tx *gorm.DB, orderID uint, amount *money.Money, reason string) (*models.Refund, error) {
+	var payment models.Payment
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_id = ?", orderID).
+		First(&payment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: order %d has no payment", ErrPaymentNotRefundable, orderID)
+		}
+		return nil, err
+	}
+
+	if payment.Status != constants.PaymentCompleted && payment.Status != constants.PaymentPartiallyRefunded {
+		return nil, fmt.Errorf("%w: payment is %s", ErrPaymentNotRefundable, payment.Status)
+	}
+	if payment.TransactionID == "" {
+		return nil, fmt.Errorf("%w: payment has no Razorpay payment ID", ErrPaymentNotRefundable)
+	}
+
+	refundable := payment.Amount.Sub(payment.AmountRefunded)
+	refundAmount := refundable
+	if amount != nil {
+		refundAmount = *amount
+	}
+	if refundAmount.Amount <= 0 || refundAmount.Cmp(refundable) > 0 {
+		return nil, fmt.Errorf("%w: %s (refundable %s)", ErrInvalidRefundAmount, refundAmount, refundable)
+	}
+
+	refund := &models.Refund{
+		PaymentID: payment.ID,
+		OrderID:   orderID,
+		Amount:    refundAmount,
+		Reason:    reason,
+		Status:    constants.RefundPending,
+	}
+	if err := ps.RefundRepository.WithTx(tx).CreateRefund(refund); err != nil {
+		return nil, fmt.Errorf("failed to record refund: %w", err)
+	}
+	if err := ps.setRefunded(tx, &payment, payment.AmountRefunded.Add(refundAmount)); err != nil {
+		return nil, err
+	}
+	if err := ps.Events.Publish(tx, events.RefundRequested, events.NewRefund(refund)); err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
+
+// setRefunded records how much of a payment has been refunded and the
+// status that goes with it.
+func (ps *PaymentService) setRefunded(tx *gorm.DB, payment *models.Payment, refunded money.Money) error {
+	payment.AmountRefunded = refunded
+	switch {
+	case refunded.Cmp(payment.Amount) >= 0:
+		payment.Status = constants.PaymentRefunded
+	case refunded.Amount > 0:
+		payment.Status = constants.PaymentPartiallyRefunded
+	default:
+		payment.Status = constants.PaymentCompleted
+	}
+
+	if err := tx.Model(payment).Updates(map[string]interface{}{
+		"amount_refunded": payment.AmountRefunded,
+		"status":          payment.Status,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to update payment: %w", err)
+	}
+	return nil
+}
+
+// Subscribe registers the handler that sends recorded refunds to Razorpay
+// with bus.
+func (ps *PaymentService) Subscribe(bus *EventBus) {
+	bus.Subscribe(events.RefundRequested, "razorpay-refunds", ps.submitRefund)
+}
+
+func (ps *PaymentService) submitRefund(ctx context.Context, e events.Event) error {
+	var p events.Refund
+	if err := e.Decode(&p); err != nil {
+		return err
+	}
+	id, err := strconv.ParseUint(p.RefundID, 10, 32)
+	if err != nil {
+		return fmt.Errorf("bad refund ID %q in %s event %d", p.RefundID, e.Type, e.ID)
+	}
+	_, err = ps.SubmitRefund(uint(id))
+	return err
+}
+
+// SubmitRefund sends a pending refund to Razorpay and records the outcome.
+// A refund Razorpay already has, because an earlier attempt got through but
+// was not recorded, is found by the refund_id note and not sent again.
+// Refunds Razorpay rejects are marked failed and their amount is given back
+// to the payment; other errors are returned so the attempt is retried.
+func (ps *PaymentService) SubmitRefund(refundID uint) (*models.Refund, error) {
+	refund, err := ps.RefundRepository.GetRefundByID(refundID)
+	if err != nil {
+		return nil, err
+	}
+	if refund.Status != constants.RefundPending || refund.RazorpayRefundID != nil {
+		return refund, nil
+	}
+	payment, err := ps.PaymentRepository.GetPaymentByID(refund.PaymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := ps.findRazorpayRefund(payment.TransactionID, refund.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up razorpay refunds: %w", err)
+	}
+	if response == nil {
+		response, err = ps.Client.Payment.Refund(payment.TransactionID, int(refund.Amount.Amount), map[string]interface{}{
+			"speed": "normal",
+			"notes": map[string]interface{}{
+				"order_id":  strconv.FormatUint(uint64(refund.OrderID), 10),
+				"refund_id": strconv.FormatUint(uint64(refund.ID), 10),
+				"reason":    refund.Reason,
+			},
+		}, nil)
+	}
+
+	var rejected *rzperrors.BadRequestError
+	if errors.As(err, &rejected) {
+		return ps.failRefund(refund.ID, rejected.Message)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("razorpay refund failed: %w", err)
+	}
+	// razorpay-go answers some Bad Request responses with an empty result
+	// rather than an error
+	if id, _ := response["id"].(string); id == "" {
+		return ps.failRefund(refund.ID, "bad request")
+	}
+	return ps.settleRefund(refund.ID, response)
+}
+
+// findRazorpayRefund returns the refund of a Razorpay payment whose
+// refund_id note is refundID, or nil if Razorpay has none.
+func (ps *PaymentService) findRazorpayRefund(paymentID string, refundID uint) (map[string]interface{}, error) {
+	response, err := ps.Client.Payment.FetchMultipleRefund(paymentID, map[string]interface{}{"count": 100}, nil)
+	if err != nil {
+		return nil, err
+	}
+	items, _ := response["items"].([]interface{})
+	want := strconv.FormatUint(uint64(refundID), 10)
+	for _, item := range items {
+		refund, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if notes, ok := refund["notes"].(map[string]interface{}); ok && notes["refund_id"] == want {
+			return refund, nil
+		}
+	}
+	return nil, nil
+}
+
+// settleRefund records the refund Razorpay created for a pending refund.
+func (ps *PaymentService) settleRefund(refundID uint, response map[string]interface{}) (*models.Refund, error) {
+	var refund *models.Refund
+	err := ps.PaymentRepository.DB.Transaction(func(tx *gorm.DB) error {
+		refunds := ps.RefundRepository.WithTx(tx)
+		var err error
+		refund, err = refunds.LockRefund(refundID)
+		if err != nil {
+			return err
+		}
+		// The refund.processed webhook may have got here first
+		if refund.Status != constants.RefundPending || refund.RazorpayRefundID != nil {
+			return nil
+		}
+
+		id := response["id"].(string)
+		refund.RazorpayRefundID = &id
+		if status, ok := response["status"].(string); ok && status != "" {
+			refund.Status = status
+		}
+		if err := refunds.UpdateRefund(refund); err != nil {
+			return fmt.Errorf("failed to record refund: %w", err)
+		}
+		return ps.Events.Publish(tx, events.RefundIssued, events.NewRefund(refund))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
+
+// failRefund marks a refund Razorpay rejected as failed and takes its
+// amount off the payment's refunded total again.
+func (ps *PaymentService) failRefund(refundID uint, reason string) (*models.Refund, error) {
+	var refund *models.Refund
+	err := ps.PaymentRepository.DB.Transaction(func(tx *gorm.DB) error {
+		refunds := ps.RefundRepository.WithTx(tx)
+		var err error
+		refund, err = refunds.LockRefund(refundID)
+		if err != nil {
+			return err
+		}
+		if refund.Status != constants.RefundPending || refund.RazorpayRefundID != nil {
+			return nil
+		}
+
+		var payment models.Payment
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&payment, refund.PaymentID).Error; err != nil {
+			return err
+		}
+		if err := ps.setRefunded(tx, &payment, payment.AmountRefunded.Sub(refund.Amount)); err != nil {
+			return err
+		}
+
+		refund.Status = constants.RefundFailed
+		return refunds.UpdateRefund(refund)
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("PAYMENT: razorpay rejected refund %d: %s", refundID, reason)
+	return refund, nil
+}
@@ -0,0 +1,355 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/events"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
+	"gorm.io/gorm"
+)
+
+// fakeRazorpay serves the refund endpoints of the Razorpay API.
+type fakeRazorpay struct {
+	mu       sync.Mutex
+	refunds  map[string][]map[string]interface{}
+	captured map[string]int64
+	posts    int
+	// fail makes the next refund request answer with this status and
+	// Razorpay error code
+	failStatus int
+	failCode   string
+}
+
+func newFakeRazorpay(t *testing.T) (*fakeRazorpay, *httptest.Server) {
+	f := &fakeRazorpay{
+		refunds:  map[string][]map[string]interface{}{},
+		captured: map[string]int64{},
+	}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeRazorpay) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 4 || parts[0] != "v1" || parts[1] != "payments" {
+		http.NotFound(w, r)
+		return
+	}
+	paymentID := parts[2]
+
+	switch {
+	case r.Method == http.MethodGet && parts[3] == "refunds":
+		items := f.refunds[paymentID]
+		if items == nil {
+			items = []map[string]interface{}{}
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"entity": "collection", "count": len(items), "items": items})
+
+	case r.Method == http.MethodPost && parts[3] == "refund":
+		f.posts++
+		if f.failStatus != 0 {
+			status, code := f.failStatus, f.failCode
+			f.failStatus, f.failCode = 0, ""
+			writeJSON(w, status, map[string]interface{}{
+				"error": map[string]interface{}{"code": code, "description": "refund failed"},
+			})
+			return
+		}
+
+		var body struct {
+			Amount int64                  `json:"amount"`
+			Notes  map[string]interface{} `json:"notes"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var refunded int64
+		for _, refund := range f.refunds[paymentID] {
+			refunded += refund["amount"].(int64)
+		}
+		if refunded+body.Amount > f.captured[paymentID] {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"error": map[string]interface{}{
+					"code":        "BAD_REQUEST_ERROR",
+					"description": "The total refund amount is greater than the refund payment amount",
+				},
+			})
+			return
+		}
+
+		refund := map[string]interface{}{
+			"id":         fmt.Sprintf("rfnd_%d", f.posts),
+			"entity":     "refund",
+			"payment_id": paymentID,
+			"amount":     body.Amount,
+			"notes":      body.Notes,
+			"status":     constants.RefundProcessed,
+		}
+		f.refunds[paymentID] = append(f.refunds[paymentID], refund)
+		writeJSON(w, http.StatusOK, refund)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+type refundFixture struct {
+	*testServices
+	razorpay *fakeRazorpay
+}
+
+func newRefundFixture(t *testing.T) *refundFixture {
+	razorpay, srv := newFakeRazorpay(t)
+	t.Setenv("RAZORPAY_API_URL", srv.URL)
+
+	s := newTestServices(t)
+	s.payments.Subscribe(s.bus)
+	return &refundFixture{testServices: s, razorpay: razorpay}
+}
+
+// capturedOrder creates an order with a captured payment of amount paise.
+func (f *refundFixture) capturedOrder(t *testing.T, amount int64) *models.Order {
+	t.Helper()
+	variant := createVariant(t, f.db, "Plain Tee", money.INR(amount), 0)
+	order := createOrder(t, f.db, "user_1", constants.OrderConfirmed, variant, 1)
+	payment := &models.Payment{
+		OrderID:       order.ID,
+		Amount:        money.INR(amount),
+		Status:        constants.PaymentCompleted,
+		PaymentMethod: "razorpay",
+		TransactionID: fmt.Sprintf("pay_%d", order.ID),
+	}
+	if err := f.db.Create(payment).Error; err != nil {
+		t.Fatal(err)
+	}
+	f.razorpay.captured[payment.TransactionID] = amount
+	return order
+}
+
+func (f *refundFixture) refund(orderID uint, amount *money.Money) (*models.Refund, error) {
+	var refund *models.Refund
+	err := f.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		refund, err = f.payments.RefundPayment(tx, orderID, amount, "test")
+		return err
+	})
+	return refund, err
+}
+
+func (f *refundFixture) dispatch(t *testing.T) {
+	t.Helper()
+	if _, err := f.bus.Dispatch(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (f *refundFixture) payment(t *testing.T, orderID uint) *models.Payment {
+	t.Helper()
+	payment, err := f.payments.PaymentRepository.GetPaymentByOrderID(orderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return payment
+}
+
+func (f *refundFixture) reload(t *testing.T, refund *models.Refund) *models.Refund {
+	t.Helper()
+	refund, err := f.payments.RefundRepository.GetRefundByID(refund.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return refund
+}
+
+func TestRefundPaymentPartial(t *testing.T) {
+	f := newRefundFixture(t)
+	order := f.capturedOrder(t, 100000)
+
+	amount := money.INR(30000)
+	refund, err := f.refund(order.ID, &amount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refund.Status != constants.RefundPending || refund.RazorpayRefundID != nil {
+		t.Fatalf("refund = %s %v, want pending without a Razorpay ID", refund.Status, refund.RazorpayRefundID)
+	}
+	if f.razorpay.posts != 0 {
+		t.Fatal("Razorpay was called before the refund was committed")
+	}
+	// The amount is held against the payment while the refund is queued
+	if payment := f.payment(t, order.ID); payment.AmountRefunded != amount || payment.Status != constants.PaymentPartiallyRefunded {
+		t.Fatalf("payment = %s refunded, %s", payment.AmountRefunded, payment.Status)
+	}
+
+	f.dispatch(t)
+
+	refund = f.reload(t, refund)
+	if refund.Status != constants.RefundProcessed || refund.RazorpayRefundID == nil || *refund.RazorpayRefundID != "rfnd_1" {
+		t.Fatalf("refund = %s %v, want processed rfnd_1", refund.Status, refund.RazorpayRefundID)
+	}
+	if payment := f.payment(t, order.ID); payment.AmountRefunded != amount || payment.Status != constants.PaymentPartiallyRefunded {
+		t.Fatalf("payment = %s refunded, %s", payment.AmountRefunded, payment.Status)
+	}
+	if n := eventCount(t, f.db, events.RefundIssued); n != 1 {
+		t.Fatalf("%d refund.issued events, want 1", n)
+	}
+	notes := f.razorpay.refunds["pay_1"][0]["notes"].(map[string]interface{})
+	if notes["refund_id"] != fmt.Sprint(refund.ID) {
+		t.Fatalf("refund_id note = %v, want %d", notes["refund_id"], refund.ID)
+	}
+}
+
+func TestRefundPaymentFull(t *testing.T) {
+	f := newRefundFixture(t)
+	order := f.capturedOrder(t, 100000)
+
+	first := money.INR(40000)
+	if _, err := f.refund(order.ID, &first); err != nil {
+		t.Fatal(err)
+	}
+	// nil refunds whatever is left
+	rest, err := f.refund(order.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rest.Amount != money.INR(60000) {
+		t.Fatalf("refunded %s, want the remaining 600.00", rest.Amount)
+	}
+
+	f.dispatch(t)
+
+	if payment := f.payment(t, order.ID); payment.AmountRefunded != money.INR(100000) || payment.Status != constants.PaymentRefunded {
+		t.Fatalf("payment = %s refunded, %s", payment.AmountRefunded, payment.Status)
+	}
+	if f.razorpay.posts != 2 {
+		t.Fatalf("%d refunds sent to Razorpay, want 2", f.razorpay.posts)
+	}
+	if _, err := f.refund(order.ID, nil); !errors.Is(err, ErrPaymentNotRefundable) {
+		t.Fatalf("refunding a refunded payment: err = %v, want ErrPaymentNotRefundable", err)
+	}
+}
+
+func TestRefundPaymentOverRefund(t *testing.T) {
+	f := newRefundFixture(t)
+	order := f.capturedOrder(t, 100000)
+
+	first := money.INR(70000)
+	if _, err := f.refund(order.ID, &first); err != nil {
+		t.Fatal(err)
+	}
+	// A queued refund counts against what is left before Razorpay sees it
+	second := money.INR(40000)
+	if _, err := f.refund(order.ID, &second); !errors.Is(err, ErrInvalidRefundAmount) {
+		t.Fatalf("err = %v, want ErrInvalidRefundAmount", err)
+	}
+	if f.razorpay.posts != 0 {
+		t.Fatal("an invalid refund was sent to Razorpay")
+	}
+}
+
+func TestRefundPaymentRejected(t *testing.T) {
+	f := newRefundFixture(t)
+	order := f.capturedOrder(t, 100000)
+	// Someone refunded most of the payment from the Razorpay dashboard
+	f.razorpay.refunds["pay_1"] = []map[string]interface{}{{"id": "rfnd_dash", "amount": int64(90000)}}
+
+	amount := money.INR(30000)
+	refund, err := f.refund(order.ID, &amount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.dispatch(t)
+
+	refund = f.reload(t, refund)
+	if refund.Status != constants.RefundFailed || refund.RazorpayRefundID != nil {
+		t.Fatalf("refund = %s %v, want failed", refund.Status, refund.RazorpayRefundID)
+	}
+	if payment := f.payment(t, order.ID); payment.AmountRefunded.Amount != 0 || payment.Status != constants.PaymentCompleted {
+		t.Fatalf("payment = %s refunded, %s; want the amount given back", payment.AmountRefunded, payment.Status)
+	}
+	if n := eventCount(t, f.db, events.RefundIssued); n != 0 {
+		t.Fatalf("%d refund.issued events for a rejected refund", n)
+	}
+}
+
+func TestSubmitRefundRetries(t *testing.T) {
+	f := newRefundFixture(t)
+	order := f.capturedOrder(t, 100000)
+	f.razorpay.failStatus, f.razorpay.failCode = http.StatusInternalServerError, "SERVER_ERROR"
+
+	amount := money.INR(30000)
+	refund, err := f.refund(order.ID, &amount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.payments.SubmitRefund(refund.ID); err == nil {
+		t.Fatal("SubmitRefund succeeded on a Razorpay server error")
+	}
+	if refund = f.reload(t, refund); refund.Status != constants.RefundPending {
+		t.Fatalf("refund = %s, want pending for a retry", refund.Status)
+	}
+
+	if _, err := f.payments.SubmitRefund(refund.ID); err != nil {
+		t.Fatal(err)
+	}
+	// The outbox delivers the event again too; Razorpay must not see a
+	// second refund
+	if _, err := f.payments.SubmitRefund(refund.ID); err != nil {
+		t.Fatal(err)
+	}
+	if refund = f.reload(t, refund); refund.Status != constants.RefundProcessed {
+		t.Fatalf("refund = %s, want processed", refund.Status)
+	}
+	if n := len(f.razorpay.refunds["pay_1"]); n != 1 {
+		t.Fatalf("Razorpay holds %d refunds, want 1", n)
+	}
+}
+
+func TestSubmitRefundFindsUnrecordedRefund(t *testing.T) {
+	f := newRefundFixture(t)
+	order := f.capturedOrder(t, 100000)
+
+	amount := money.INR(30000)
+	refund, err := f.refund(order.ID, &amount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// An earlier attempt reached Razorpay but died before recording it
+	f.razorpay.refunds["pay_1"] = []map[string]interface{}{{
+		"id":     "rfnd_earlier",
+		"amount": int64(30000),
+		"notes":  map[string]interface{}{"refund_id": fmt.Sprint(refund.ID)},
+		"status": constants.RefundProcessed,
+	}}
+
+	if _, err := f.payments.SubmitRefund(refund.ID); err != nil {
+		t.Fatal(err)
+	}
+	if f.razorpay.posts != 0 {
+		t.Fatal("the refund was sent to Razorpay twice")
+	}
+	if refund = f.reload(t, refund); refund.RazorpayRefundID == nil || *refund.RazorpayRefundID != "rfnd_earlier" {
+		t.Fatalf("Razorpay refund ID = %v, want rfnd_earlier", refund.RazorpayRefundID)
+	}
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/repository"
+)
+
+// ProductOptions holds the storefront filter facets with product counts.
+type ProductOptions struct {
+	Sizes       []repository.FacetCount
+	Colors      []repository.FacetCount
+	Materials   []repository.FacetCount
+	Necklines   []repository.FacetCount
+	SleeveTypes []repository.FacetCount
+	Fits        []repository.FacetCount
+}
+
+// maxCachedFilters bounds the cache, since clients choose the filters.
+const maxCachedFilters = 500
+
+type cachedOptions struct {
+	options   *ProductOptions
+	expiresAt time.Time
+}
+
+// ProductOptionsService computes facets from active products and caches them
+// per filter. Catalog mutations call Invalidate; the TTL bounds staleness from
+// changes made elsewhere, such as stock moving through orders or another
+// replica editing the catalog.
+type ProductOptionsService struct {
+	Repo *repository.ProductRepository
+	TTL  time.Duration
+
+	mu    sync.RWMutex
+	cache map[string]cachedOptions
+	// generation counts Invalidate calls, so facets computed from the
+	// catalog as it was before one are not cached after it
+	generation uint64
+}
+
+func NewProductOptionsService(repo *repository.ProductRepository, ttl time.Duration) *ProductOptionsService {
+	return &ProductOptionsService{
+		Repo:  repo,
+		TTL:   ttl,
+		cache: make(map[string]cachedOptions),
+	}
+}
+
+func (s *ProductOptionsService) GetOptions(filter repository.ProductFilter) (*ProductOptions, error) {
+	active := true
+	filter.IsActive = &active
+
+	key, err := json.Marshal(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	entry, ok := s.cache[string(key)]
+	generation := s.generation
+	s.mu.RUnlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.options, nil
+	}
+
+	options := &ProductOptions{}
+	facets := []struct {
+		name string
+		dest *[]repository.FacetCount
+	}{
+		{repository.FacetSize, &options.Sizes},
+		{repository.FacetColor, &options.Colors},
+		{repository.FacetMaterial, &options.Materials},
+		{repository.FacetNeckline, &options.Necklines},
+		{repository.FacetSleeveType, &options.SleeveTypes},
+		{repository.FacetFit, &options.Fits},
+	}
+	for _, f := range facets {
+		counts, err := s.Repo.FacetCounts(filter, f.name)
+		if err != nil {
+			return nil, err
+		}
+		*f.dest = counts
+	}
+
+	s.mu.Lock()
+	if s.generation == generation {
+		if len(s.cache) >= maxCachedFilters {
+			s.cache = make(map[string]cachedOptions)
+		}
+		s.cache[string(key)] = cachedOptions{
+			options:   options,
+			expiresAt: time.Now().Add(s.TTL),
+		}
+	}
+	s.mu.Unlock()
+
+	return options, nil
+}
+
+// Invalidate drops every cached result. Call it after any change to products,
+// variants or inventory.
+func (s *ProductOptionsService) Invalidate() {
+	s.mu.Lock()
+	s.cache = make(map[string]cachedOptions)
+	s.generation++
+	s.mu.Unlock()
+}
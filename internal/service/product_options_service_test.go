@@ -0,0 +1,128 @@
+package service
+
+import (
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/repository"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/testdb"
+	"gorm.io/gorm"
+)
+
+func facetCounts(facets []repository.FacetCount) map[string]int64 {
+	m := map[string]int64{}
+	for _, f := range facets {
+		m[f.Value] = f.Count
+	}
+	return m
+}
+
+func TestProductOptionsCountsWithinOtherFilters(t *testing.T) {
+	db := testdb.Open(t)
+	s := NewProductOptionsService(repository.NewProductRepository(db), time.Minute)
+
+	// Red comes in S only, black in S and L; one shirt has a crew neck
+	for _, p := range []struct {
+		name, neckline string
+		variants       [][2]string
+	}{
+		{"Crew Tee", "Crew", [][2]string{{"S", "Red"}, {"L", "Black"}}},
+		{"V-Neck Tee", "V-Neck", [][2]string{{"S", "Black"}, {"L", "Black"}}},
+	} {
+		product := &models.Product{Name: p.name, Neckline: p.neckline, BasePrice: money.INR(49900), IsActive: true}
+		if err := db.Create(product).Error; err != nil {
+			t.Fatal(err)
+		}
+		for _, v := range p.variants {
+			variant := &models.ProductVariant{ProductID: product.ID, Size: v[0], Color: &v[1], SKU: p.name + v[0] + v[1]}
+			if err := db.Create(variant).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	tests := []struct {
+		name                     string
+		filter                   repository.ProductFilter
+		sizes, colors, necklines map[string]int64
+	}{
+		{
+			name:      "red",
+			filter:    repository.ProductFilter{Color: "red"},
+			sizes:     map[string]int64{"S": 1},
+			colors:    map[string]int64{"Black": 2, "Red": 1},
+			necklines: map[string]int64{"Crew": 1},
+		},
+		{
+			name:      "size L",
+			filter:    repository.ProductFilter{Size: "L"},
+			sizes:     map[string]int64{"S": 2, "L": 2},
+			colors:    map[string]int64{"Black": 2},
+			necklines: map[string]int64{"Crew": 1, "V-Neck": 1},
+		},
+		{
+			name:      "crew neck",
+			filter:    repository.ProductFilter{Neckline: "crew"},
+			sizes:     map[string]int64{"S": 1, "L": 1},
+			colors:    map[string]int64{"Black": 1, "Red": 1},
+			necklines: map[string]int64{"Crew": 1, "V-Neck": 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options, err := s.GetOptions(tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for facet, c := range map[string]struct {
+				got  []repository.FacetCount
+				want map[string]int64
+			}{
+				"size":     {options.Sizes, tt.sizes},
+				"color":    {options.Colors, tt.colors},
+				"neckline": {options.Necklines, tt.necklines},
+			} {
+				if got := facetCounts(c.got); !maps.Equal(got, c.want) {
+					t.Errorf("%s counts = %v, want %v", facet, got, c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestProductOptionsNotCachedAcrossInvalidate(t *testing.T) {
+	db := testdb.Open(t)
+	s := NewProductOptionsService(repository.NewProductRepository(db), time.Minute)
+	medium := createVariant(t, db, "Plain Tee", money.INR(49900), 1)
+	large := &models.ProductVariant{ProductID: medium.ProductID, Size: "L", SKU: "TEE-L"}
+
+	// A variant is added, and the cache invalidated, once the sizes have
+	// been counted but before the facets are cached
+	queries := 0
+	err := db.Callback().Row().Before("gorm:row").Register("test:add_variant", func(tx *gorm.DB) {
+		if queries++; queries != 2 {
+			return
+		}
+		if err := db.Session(&gorm.Session{NewDB: true}).Create(large).Error; err != nil {
+			t.Error(err)
+		}
+		s.Invalidate()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetOptions(repository.ProductFilter{}); err != nil {
+		t.Fatal(err)
+	}
+
+	options, err := s.GetOptions(repository.ProductFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := facetCounts(options.Sizes); !maps.Equal(got, map[string]int64{"M": 1, "L": 1}) {
+		t.Fatalf("size counts = %v after the cache was invalidated, want M and L", got)
+	}
+}
@@ -2,8 +2,10 @@ package service
 
 import (
 	"context"
+	"errors"
 	"time"
 	"github.com/vishnujoshi062/tshirt-ecommerce-api/graph/model"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
 	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
 	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/repository"
 )
@@ -18,16 +20,16 @@ func NewPromoService(repo *repository.PromoCodeRepository) *PromoService {
 
 type ValidationResult struct {
 	IsValid        bool
-	DiscountAmount float64
+	DiscountAmount money.Money
 	Message        string
 }
 
-func (s *PromoService) ValidatePromoCode(code string, orderAmount float64) (*ValidationResult, error) {
+func (s *PromoService) ValidatePromoCode(code string, orderAmount money.Money) (*ValidationResult, error) {
 	promo, err := s.repo.FindByCode(code)
 	if err != nil {
 		return &ValidationResult{
 			IsValid: false,
-			DiscountAmount: 0,
+			DiscountAmount: money.Money{},
 			Message: "Code not found",
 		}, nil
 	}
@@ -38,7 +40,7 @@ func (s *PromoService) ValidatePromoCode(code string, orderAmount float64) (*Val
 	if !promo.IsActive {
 		return &ValidationResult{
 			IsValid: false,
-			DiscountAmount: 0,
+			DiscountAmount: money.Money{},
 			Message: "Code is inactive",
 		}, nil
 	}
@@ -47,7 +49,7 @@ func (s *PromoService) ValidatePromoCode(code string, orderAmount float64) (*Val
 	if promo.ValidFrom != nil && now.Before(*promo.ValidFrom) {
 		return &ValidationResult{
 			IsValid: false,
-			DiscountAmount: 0,
+			DiscountAmount: money.Money{},
 			Message: "Code not yet valid",
 		}, nil
 	}
@@ -56,7 +58,7 @@ func (s *PromoService) ValidatePromoCode(code string, orderAmount float64) (*Val
 	if promo.ValidUntil != nil && now.After(*promo.ValidUntil) {
 		return &ValidationResult{
 			IsValid: false,
-			DiscountAmount: 0,
+			DiscountAmount: money.Money{},
 			Message: "Code has expired",
 		}, nil
 	}
@@ -65,23 +67,21 @@ func (s *PromoService) ValidatePromoCode(code string, orderAmount float64) (*Val
 	if promo.UsageLimit != nil && promo.UsageCount >= *promo.UsageLimit {
 		return &ValidationResult{
 			IsValid: false,
-			DiscountAmount: 0,
+			DiscountAmount: money.Money{},
 			Message: "Usage limit reached",
 		}, nil
 	}
 
-	// Calculate discount
-	var discount float64
+	// Calculate discount, rounding percentages half to even
+	var discount money.Money
 	if promo.DiscountType == models.DiscountTypePercentage {
-		discount = (orderAmount * promo.DiscountValue) / 100.0
+		discount = orderAmount.Percent(promo.DiscountValue)
 	} else {
-		discount = promo.DiscountValue
+		discount = money.New(promo.DiscountValue, orderAmount.Currency)
 	}
 
 	// Cap discount at order amount
-	if discount > orderAmount {
-		discount = orderAmount
-	}
+	discount = discount.Min(orderAmount)
 
 	return &ValidationResult{
 		IsValid: true,
@@ -92,10 +92,15 @@ func (s *PromoService) ValidatePromoCode(code string, orderAmount float64) (*Val
 
 
 func (s *PromoService) CreatePromoCode(ctx context.Context, input model.PromoCodeInput) (*models.PromoCode, error) {
+	discountValue, err := DiscountValueFromInput(input.DiscountType, input.DiscountValue)
+	if err != nil {
+		return nil, err
+	}
+
 	promo := &models.PromoCode{
 		Code:           input.Code,
 		DiscountType:   input.DiscountType,
-		DiscountValue:  input.DiscountValue,
+		DiscountValue:  discountValue,
 		IsActive:       input.IsActive != nil && *input.IsActive,
 		UsageLimit:     input.UsageLimit,
 		UsageCount:     0,
@@ -120,10 +125,15 @@ func (s *PromoService) CreatePromoCode(ctx context.Context, input model.PromoCod
 }
 
 func (s *PromoService) UpdatePromoCode(ctx context.Context, id string, input model.PromoCodeInput) (*models.PromoCode, error) {
+	discountValue, err := DiscountValueFromInput(input.DiscountType, input.DiscountValue)
+	if err != nil {
+		return nil, err
+	}
+
 	updates := map[string]interface{}{
 		"code":            input.Code,
 		"discount_type":   input.DiscountType,
-		"discount_value":  input.DiscountValue,
+		"discount_value":  discountValue,
 		"is_active":       input.IsActive != nil && *input.IsActive,
 		"usage_limit":     input.UsageLimit,
 	}
@@ -170,3 +180,20 @@ func (s *PromoService) GetAllPromoCodes(ctx context.Context) ([]*models.PromoCod
 func (s *PromoService) GetPromoCodeByID(ctx context.Context, code string) (*models.PromoCode, error) {
 	return s.repo.FindByCode(code)
 }
+
+// DiscountValueFromInput converts a percentage or a rupee amount to the basis
+// points or paise stored on the promo code. Both are hundredths of the input,
+// rounded half to even.
+func DiscountValueFromInput(discountType models.DiscountType, value float64) (int64, error) {
+	hundredths, err := money.FromFloat(value, "")
+	if err != nil {
+		return 0, err
+	}
+	if hundredths.Amount <= 0 {
+		return 0, errors.New("discount value must be positive")
+	}
+	if discountType == models.DiscountTypePercentage && hundredths.Amount > 10000 {
+		return 0, errors.New("percentage discount cannot exceed 100")
+	}
+	return hundredths.Amount, nil
+}
@@ -0,0 +1,403 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/events"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidReturn    = errors.New("invalid return request")
+	ErrReturnNotAllowed = errors.New("return is not allowed")
+)
+
+// maxReturnPhotos caps the photos attached to one return request.
+const maxReturnPhotos = 5
+
+// returnTransitions lists the statuses each return status may move to.
+// Rejected, cancelled, refunded and exchanged are terminal.
+var returnTransitions = map[string][]string{
+	constants.ReturnRequested:       {constants.ReturnApproved, constants.ReturnRejected, constants.ReturnCancelled},
+	constants.ReturnApproved:        {constants.ReturnPickupScheduled, constants.ReturnReceived, constants.ReturnCancelled},
+	constants.ReturnPickupScheduled: {constants.ReturnReceived},
+	constants.ReturnReceived:        {constants.ReturnInspected, constants.ReturnRejected},
+	constants.ReturnInspected:       {constants.ReturnRefunded, constants.ReturnExchanged},
+}
+
+// ReturnInput is what a customer fills in to send an order item back.
+// ExchangeVariantID is set for an exchange and nil for a refund.
+type ReturnInput struct {
+	OrderItemID       uint
+	Quantity          int
+	Reason            string
+	Comment           *string
+	Photos            []string
+	ExchangeVariantID *uint
+}
+
+type ReturnService struct {
+	DB                 *gorm.DB
+	ReturnRepository   *repository.ReturnRepository
+	ShipmentRepository *repository.ShipmentRepository
+	InventoryService   *InventoryService
+	PaymentService     *PaymentService
+	TaxService         *TaxService
+	Events             *EventBus
+	// Window is how long after delivery an item can be returned.
+	Window time.Duration
+}
+
+func NewReturnService(
+	db *gorm.DB,
+	returnRepo *repository.ReturnRepository,
+	shipmentRepo *repository.ShipmentRepository,
+	inventoryService *InventoryService,
+	paymentService *PaymentService,
+	taxService *TaxService,
+	eventBus *EventBus,
+	window time.Duration,
+) *ReturnService {
+	return &ReturnService{
+		DB:                 db,
+		ReturnRepository:   returnRepo,
+		ShipmentRepository: shipmentRepo,
+		InventoryService:   inventoryService,
+		PaymentService:     paymentService,
+		TaxService:         taxService,
+		Events:             eventBus,
+		Window:             window,
+	}
+}
+
+// RequestReturn opens a return for an item of one of userID's delivered
+// orders. An exchange holds its replacement straight away, so the size the
+// customer asked for can't sell out while the original is on its way back.
+func (s *ReturnService) RequestReturn(userID string, in ReturnInput) (*models.ReturnRequest, error) {
+	if err := validateReturnInput(in); err != nil {
+		return nil, err
+	}
+
+	req := &models.ReturnRequest{
+		OrderItemID: in.OrderItemID,
+		UserID:      userID,
+		Quantity:    in.Quantity,
+		Reason:      in.Reason,
+		Comment:     in.Comment,
+		Photos:      in.Photos,
+		Resolution:  constants.ReturnResolutionRefund,
+		Status:      constants.ReturnRequested,
+	}
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var item models.OrderItem
+		if err := tx.Preload("Variant").First(&item, in.OrderItemID).Error; err != nil {
+			return err
+		}
+		order := &models.Order{ID: item.OrderID}
+		// Locking the order serialises returns of its items, so two requests
+		// can't both claim the last piece.
+		if err := lockOrder(tx, order); err != nil {
+			return err
+		}
+		if err := tx.First(order, order.ID).Error; err != nil {
+			return err
+		}
+		if order.UserID != userID {
+			return fmt.Errorf("order item %d: %w", item.ID, gorm.ErrRecordNotFound)
+		}
+		if err := s.checkReturnable(tx, order); err != nil {
+			return err
+		}
+
+		returns := s.ReturnRepository.WithTx(tx)
+		open, err := returns.OpenQuantity(item.ID)
+		if err != nil {
+			return err
+		}
+		if left := item.Quantity - open; in.Quantity > left {
+			return fmt.Errorf("%w: only %d of order item %d can still be returned", ErrReturnNotAllowed, left, item.ID)
+		}
+
+		if in.ExchangeVariantID != nil {
+			if err := s.holdExchange(tx, &item, *in.ExchangeVariantID, in.Quantity); err != nil {
+				return err
+			}
+			req.Resolution = constants.ReturnResolutionExchange
+			req.ExchangeVariantID = in.ExchangeVariantID
+			req.ExchangeHeld = true
+		}
+
+		req.OrderID = order.ID
+		if err := returns.CreateReturnRequest(req); err != nil {
+			return err
+		}
+		return s.Events.Publish(tx, events.ReturnRequested, events.NewReturn(req))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// CancelReturn withdraws a return before the item is picked up.
+func (s *ReturnService) CancelReturn(id uint) (*models.ReturnRequest, error) {
+	return s.transition(id, constants.ReturnCancelled, s.releaseExchange)
+}
+
+// Approve accepts a requested return.
+func (s *ReturnService) Approve(id uint) (*models.ReturnRequest, error) {
+	return s.transition(id, constants.ReturnApproved, nil)
+}
+
+// Reject turns down a requested return and frees any replacement it held.
+func (s *ReturnService) Reject(id uint, reason string) (*models.ReturnRequest, error) {
+	return s.transition(id, constants.ReturnRejected, func(tx *gorm.DB, req *models.ReturnRequest) error {
+		if req.Status != constants.ReturnRequested {
+			return fmt.Errorf("%w: return %d has been received; reject it by failing inspection", ErrReturnNotAllowed, req.ID)
+		}
+		req.RejectionReason = optionalString(reason)
+		return s.releaseExchange(tx, req)
+	})
+}
+
+// SchedulePickup records that a courier will collect the item. The
+// tracking number is optional for pickups arranged by phone.
+func (s *ReturnService) SchedulePickup(id uint, trackingNumber string) (*models.ReturnRequest, error) {
+	return s.transition(id, constants.ReturnPickupScheduled, func(tx *gorm.DB, req *models.ReturnRequest) error {
+		req.PickupTrackingNumber = optionalString(trackingNumber)
+		return nil
+	})
+}
+
+// MarkReceived records that the item is back at the warehouse, whether it
+// was picked up or dropped off by the customer.
+func (s *ReturnService) MarkReceived(id uint) (*models.ReturnRequest, error) {
+	return s.transition(id, constants.ReturnReceived, nil)
+}
+
+// Inspect records the warehouse's verdict on a received item. An item that
+// passes goes back to stock; one that fails rejects the return and is sent
+// back to the customer by hand.
+func (s *ReturnService) Inspect(id uint, passed bool, notes string) (*models.ReturnRequest, error) {
+	to := constants.ReturnInspected
+	if !passed {
+		to = constants.ReturnRejected
+	}
+	return s.transition(id, to, func(tx *gorm.DB, req *models.ReturnRequest) error {
+		req.InspectionNotes = optionalString(notes)
+		if !passed {
+			req.RejectionReason = optionalString("failed inspection")
+			return s.releaseExchange(tx, req)
+		}
+
+		var item models.OrderItem
+		if err := tx.Select("id", "variant_id").First(&item, req.OrderItemID).Error; err != nil {
+			return err
+		}
+		item.Quantity = req.Quantity
+		return s.InventoryService.RestockItems(tx, []models.OrderItem{item})
+	})
+}
+
+// Complete settles an inspected return: a refund of what the customer paid
+// for the returned pieces, or, for an exchange, the sale of the held
+// replacement, which fulfilment then ships. The refund is only recorded
+// here; PaymentService sends it to Razorpay once the return is saved.
+func (s *ReturnService) Complete(id uint) (*models.ReturnRequest, error) {
+	req, err := s.ReturnRepository.GetReturnRequestByID(id)
+	if err != nil {
+		return nil, err
+	}
+	to := constants.ReturnRefunded
+	if req.Resolution == constants.ReturnResolutionExchange {
+		to = constants.ReturnExchanged
+	}
+
+	return s.transition(id, to, func(tx *gorm.DB, req *models.ReturnRequest) error {
+		if req.Resolution == constants.ReturnResolutionExchange {
+			if err := s.InventoryService.CommitHold(tx, *req.ExchangeVariantID, req.Quantity); err != nil {
+				return err
+			}
+			req.ExchangeHeld = false
+			return nil
+		}
+
+		amount, err := s.refundAmount(tx, req)
+		if err != nil {
+			return err
+		}
+		reason := fmt.Sprintf("return %d: %s", req.ID, req.Reason)
+		refund, err := s.PaymentService.RefundPayment(tx, req.OrderID, &amount, reason)
+		if err != nil {
+			return err
+		}
+		req.RefundAmount = amount
+		req.RefundID = &refund.ID
+		return nil
+	})
+}
+
+// transition moves a return to status to, running apply on the locked
+// request first. The request is saved with whatever apply changed.
+func (s *ReturnService) transition(id uint, to string, apply func(tx *gorm.DB, req *models.ReturnRequest) error) (*models.ReturnRequest, error) {
+	var req *models.ReturnRequest
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		returns := s.ReturnRepository.WithTx(tx)
+		var err error
+		req, err = returns.LockReturnRequest(id)
+		if err != nil {
+			return err
+		}
+		if !canTransitionReturn(req.Status, to) {
+			return fmt.Errorf("%w: cannot change return %d from %s to %s", ErrReturnNotAllowed, req.ID, req.Status, to)
+		}
+		if apply != nil {
+			if err := apply(tx, req); err != nil {
+				return err
+			}
+		}
+		req.Status = to
+		if err := returns.UpdateReturnRequest(req); err != nil {
+			return err
+		}
+		return s.Events.Publish(tx, events.ReturnUpdated, events.NewReturn(req))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func canTransitionReturn(from, to string) bool {
+	for _, next := range returnTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// checkReturnable requires the order to have been delivered within the
+// return window. Orders delivered without a tracked shipment are timed
+// from their last update, which is when they were marked delivered.
+func (s *ReturnService) checkReturnable(tx *gorm.DB, order *models.Order) error {
+	if order.Status != constants.OrderDelivered {
+		return fmt.Errorf("%w: order %d is %s", ErrReturnNotAllowed, order.ID, order.Status)
+	}
+
+	deliveredAt := order.UpdatedAt
+	shipment, err := s.ShipmentRepository.WithTx(tx).GetShipmentByOrderID(order.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if err == nil && shipment.DeliveredAt != nil {
+		deliveredAt = *shipment.DeliveredAt
+	}
+	if time.Since(deliveredAt) > s.Window {
+		return fmt.Errorf("%w: the return window for order %d closed on %s",
+			ErrReturnNotAllowed, order.ID, deliveredAt.Add(s.Window).Format("2006-01-02"))
+	}
+	return nil
+}
+
+// holdExchange checks that the replacement is another size or colour of
+// the same product at the same price, then holds it.
+func (s *ReturnService) holdExchange(tx *gorm.DB, item *models.OrderItem, variantID uint, quantity int) error {
+	var variant models.ProductVariant
+	if err := tx.First(&variant, variantID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: variant %d not found", ErrInvalidReturn, variantID)
+		}
+		return err
+	}
+	if variant.ID == item.VariantID {
+		return fmt.Errorf("%w: exchange for the same variant", ErrInvalidReturn)
+	}
+	if variant.ProductID != item.Variant.ProductID || variant.PriceModifier.Cmp(item.Variant.PriceModifier) != 0 {
+		return fmt.Errorf("%w: items can only be exchanged for the same product at the same price", ErrInvalidReturn)
+	}
+	return s.InventoryService.HoldStock(tx, variant.ID, quantity)
+}
+
+// releaseExchange frees the replacement held for an exchange, if any.
+func (s *ReturnService) releaseExchange(tx *gorm.DB, req *models.ReturnRequest) error {
+	if !req.ExchangeHeld {
+		return nil
+	}
+	if err := s.InventoryService.ReleaseHold(tx, *req.ExchangeVariantID, req.Quantity); err != nil {
+		return err
+	}
+	req.ExchangeHeld = false
+	return nil
+}
+
+// refundAmount is what the customer paid for the returned pieces: their
+// share of the item after discount, tax included, without shipping. It is
+// worked out from the pieces refunded so far, so refunding an item in
+// several returns never adds up to more than was paid for it.
+func (s *ReturnService) refundAmount(tx *gorm.DB, req *models.ReturnRequest) (money.Money, error) {
+	var order models.Order
+	if err := tx.Preload("OrderItems", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+		First(&order, req.OrderID).Error; err != nil {
+		return money.Money{}, err
+	}
+
+	lines := s.TaxService.OrderTax(&order).Lines
+	for i, item := range order.OrderItems {
+		if item.ID != req.OrderItemID {
+			continue
+		}
+		refunded, err := s.ReturnRepository.WithTx(tx).RefundedQuantity(item.ID)
+		if err != nil {
+			return money.Money{}, err
+		}
+		total := lines[i].Total
+		share := func(pieces int) int64 {
+			return money.DivRound(total.Amount*int64(pieces), int64(item.Quantity))
+		}
+		return money.New(share(refunded+req.Quantity)-share(refunded), total.Currency), nil
+	}
+	return money.Money{}, fmt.Errorf("order item %d not found on order %d", req.OrderItemID, req.OrderID)
+}
+
+func validateReturnInput(in ReturnInput) error {
+	if in.Quantity <= 0 {
+		return fmt.Errorf("%w: quantity must be positive", ErrInvalidReturn)
+	}
+	if !constants.IsValidReturnReason(in.Reason) {
+		return fmt.Errorf("%w: unknown reason %q", ErrInvalidReturn, in.Reason)
+	}
+	if len(in.Photos) > maxReturnPhotos {
+		return fmt.Errorf("%w: at most %d photos", ErrInvalidReturn, maxReturnPhotos)
+	}
+	for _, photo := range in.Photos {
+		u, err := url.Parse(photo)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("%w: photo %q is not a URL", ErrInvalidReturn, photo)
+		}
+	}
+	// Damage claims are settled on the photos, before anything is shipped
+	// back.
+	if (in.Reason == constants.ReturnReasonDefective || in.Reason == constants.ReturnReasonDamaged) && len(in.Photos) == 0 {
+		return fmt.Errorf("%w: add a photo of the damage", ErrInvalidReturn)
+	}
+	return nil
+}
+
+// optionalString returns nil for a blank s.
+func optionalString(s string) *string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	return &s
+}
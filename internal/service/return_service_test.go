@@ -0,0 +1,69 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/repository"
+	"gorm.io/gorm"
+)
+
+func TestReturnedOrderSkipsPiecesReturnedSeparately(t *testing.T) {
+	s := newTestServices(t)
+	db := s.db
+	returns := NewReturnService(
+		db,
+		repository.NewReturnRepository(db),
+		repository.NewShipmentRepository(db),
+		s.inventory,
+		s.payments,
+		nil,
+		s.bus,
+		30*24*time.Hour,
+	)
+	variant := createVariant(t, db, "Plain Tee", money.INR(49900), 10)
+	order := createOrder(t, db, "user_1", constants.OrderDelivered, variant, 3)
+	stock := func() int {
+		t.Helper()
+		var inv models.Inventory
+		if err := db.Where("variant_id = ?", variant.ID).First(&inv).Error; err != nil {
+			t.Fatal(err)
+		}
+		return inv.StockQuantity
+	}
+
+	// One piece comes back through a return and passes inspection
+	req, err := returns.RequestReturn("user_1", ReturnInput{
+		OrderItemID: order.OrderItems[0].ID,
+		Quantity:    1,
+		Reason:      constants.ReturnReasonChangedMind,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, step := range []func(uint) (*models.ReturnRequest, error){returns.Approve, returns.MarkReceived} {
+		if _, err := step(req.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := returns.Inspect(req.ID, true, ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := stock(); got != 11 {
+		t.Fatalf("stock after inspection = %d, want 11", got)
+	}
+
+	// Then the whole order is marked returned
+	err = db.Transaction(func(tx *gorm.DB) error {
+		return s.orders.TransitionStatus(tx, &models.Order{ID: order.ID}, constants.OrderReturned)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := stock(); got != 13 {
+		t.Fatalf("stock after the order was returned = %d, want 13", got)
+	}
+}
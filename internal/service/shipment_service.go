@@ -0,0 +1,337 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/courier"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// bookingTimeout is how long a shipment may stay booking before another
+// CreateShipment takes it over.
+const bookingTimeout = 5 * time.Minute
+
+var (
+	ErrNoCourier            = errors.New("no courier is configured")
+	ErrShipmentNotAvailable = errors.New("order cannot be shipped")
+)
+
+type ShipmentService struct {
+	DB                 *gorm.DB
+	ShipmentRepository *repository.ShipmentRepository
+	OrderService       *OrderService
+	ShippingService    *ShippingService
+	// Courier books new shipments. It is nil when none is configured.
+	Courier courier.Courier
+	// HSN is sent to the courier for every item.
+	HSN string
+}
+
+func NewShipmentService(
+	db *gorm.DB,
+	shipmentRepo *repository.ShipmentRepository,
+	orderService *OrderService,
+	shippingService *ShippingService,
+	c courier.Courier,
+	hsn string,
+) *ShipmentService {
+	return &ShipmentService{
+		DB:                 db,
+		ShipmentRepository: shipmentRepo,
+		OrderService:       orderService,
+		ShippingService:    shippingService,
+		Courier:            c,
+		HSN:                hsn,
+	}
+}
+
+// CreateShipment books a confirmed order with the courier and marks it
+// shipped. The shipment is recorded as booking before the courier is
+// called, so the order is booked once even if fulfilment staff click twice,
+// and no transaction stays open while the courier answers.
+func (s *ShipmentService) CreateShipment(ctx context.Context, orderID uint) (*models.Shipment, error) {
+	if s.Courier == nil {
+		return nil, ErrNoCourier
+	}
+
+	shipment, req, err := s.startBooking(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	booking, err := s.Courier.Book(ctx, *req)
+	if err != nil {
+		if err := s.ShipmentRepository.DeleteShipment(shipment); err != nil {
+			log.Printf("SHIPMENT: failed to drop booking shipment for order %d: %v", orderID, err)
+		}
+		return nil, fmt.Errorf("failed to book shipment with %s: %w", s.Courier.Name(), err)
+	}
+
+	if err := s.finishBooking(shipment, booking); err != nil {
+		// The courier has the parcel booked even though we failed to
+		// record it, so leave enough in the log to cancel it by hand.
+		log.Printf("SHIPMENT: failed to record %s shipment %s for order %d: %v",
+			shipment.Carrier, booking.TrackingNumber, orderID, err)
+		return nil, err
+	}
+	return s.ShipmentRepository.GetShipmentByOrderID(orderID)
+}
+
+// startBooking records a booking shipment for a confirmed order and
+// describes its parcel for the courier. A booking left behind by a booking
+// that died half way is taken over once bookingTimeout has passed.
+func (s *ShipmentService) startBooking(orderID uint) (*models.Shipment, *courier.BookingRequest, error) {
+	var shipment *models.Shipment
+	var req *courier.BookingRequest
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("OrderItems", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+			Preload("OrderItems.Variant").
+			Preload("OrderItems.Variant.Product", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+			First(&order, orderID).Error; err != nil {
+			return err
+		}
+
+		shipments := s.ShipmentRepository.WithTx(tx)
+		existing, err := shipments.GetShipmentByOrderID(order.ID)
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			existing = nil
+		case err != nil:
+			return err
+		case existing.Status != constants.ShipmentBooking:
+			return fmt.Errorf("%w: order %d already has a shipment", ErrShipmentNotAvailable, order.ID)
+		case time.Since(existing.UpdatedAt) < bookingTimeout:
+			return fmt.Errorf("%w: order %d is being booked", ErrShipmentNotAvailable, order.ID)
+		default:
+			log.Printf("SHIPMENT: retrying booking of order %d started at %s; %s may hold a duplicate",
+				order.ID, existing.UpdatedAt.Format(time.RFC3339), existing.Carrier)
+		}
+
+		if order.Status != constants.OrderConfirmed {
+			return fmt.Errorf("%w: order %d is %s", ErrShipmentNotAvailable, order.ID, order.Status)
+		}
+		if order.ShippingDetails.PostalCode == "" {
+			return fmt.Errorf("%w: order %d has a free-text address; ship it by hand", ErrShipmentNotAvailable, order.ID)
+		}
+
+		req, err = s.bookingRequest(tx, &order)
+		if err != nil {
+			return err
+		}
+
+		if existing != nil {
+			shipment = existing
+			shipment.Carrier = s.Courier.Name()
+			return shipments.UpdateShipment(shipment)
+		}
+		shipment = &models.Shipment{
+			OrderID:           order.ID,
+			Carrier:           s.Courier.Name(),
+			Status:            constants.ShipmentBooking,
+			EstimatedDelivery: s.estimatedDelivery(&order, time.Now()),
+		}
+		return shipments.CreateShipment(shipment)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return shipment, req, nil
+}
+
+// finishBooking records the courier's booking of a booking shipment and
+// marks its order shipped.
+func (s *ShipmentService) finishBooking(shipment *models.Shipment, booking *courier.Booking) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		shipment.CourierName = booking.CourierName
+		shipment.TrackingNumber = booking.TrackingNumber
+		shipment.Status = constants.ShipmentBooked
+		if booking.TrackingURL != "" {
+			shipment.TrackingURL = &booking.TrackingURL
+		}
+		if booking.EstimatedDelivery != nil {
+			shipment.EstimatedDelivery = booking.EstimatedDelivery
+		}
+
+		shipments := s.ShipmentRepository.WithTx(tx)
+		if err := shipments.UpdateShipment(shipment); err != nil {
+			return err
+		}
+		if err := shipments.AddEvents([]models.ShipmentEvent{{
+			ShipmentID:  shipment.ID,
+			Status:      constants.ShipmentBooked,
+			Description: "Shipment booked with " + s.Courier.Name(),
+			OccurredAt:  now,
+		}}); err != nil {
+			return err
+		}
+		return s.OrderService.TransitionStatus(tx, &models.Order{ID: shipment.OrderID}, constants.OrderShipped)
+	})
+}
+
+// TrackShipment returns the order's shipment, refreshed from the courier
+// unless it has reached a final status. If the courier can't be reached the
+// last known tracking is returned.
+func (s *ShipmentService) TrackShipment(ctx context.Context, orderID uint) (*models.Shipment, error) {
+	shipment, err := s.ShipmentRepository.GetShipmentByOrderID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if shipment.Status == constants.ShipmentBooking || constants.IsFinalShipmentStatus(shipment.Status) ||
+		s.Courier == nil || s.Courier.Name() != shipment.Carrier {
+		return shipment, nil
+	}
+
+	tracking, err := s.Courier.Track(ctx, shipment.TrackingNumber)
+	if err != nil {
+		log.Printf("SHIPMENT: failed to track %s shipment %s: %v", shipment.Carrier, shipment.TrackingNumber, err)
+		return shipment, nil
+	}
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		return s.ApplyTracking(tx, shipment.Carrier, tracking)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.ShipmentRepository.GetShipmentByOrderID(orderID)
+}
+
+// ApplyTracking records tracking from the courier, whether pushed by a
+// webhook or fetched by TrackShipment. Events can arrive late or more than
+// once, so the shipment takes the status of its latest event. A delivered
+// shipment moves its order to delivered.
+func (s *ShipmentService) ApplyTracking(tx *gorm.DB, carrier string, tracking *courier.Tracking) error {
+	shipments := s.ShipmentRepository.WithTx(tx)
+	shipment, err := shipments.LockShipmentByTrackingNumber(carrier, tracking.TrackingNumber)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%w: %s %s", courier.ErrUnknownShipment, carrier, tracking.TrackingNumber)
+	}
+	if err != nil {
+		return err
+	}
+
+	events := make([]models.ShipmentEvent, 0, len(tracking.Events))
+	for _, e := range tracking.Events {
+		if !constants.IsValidShipmentStatus(e.Status) {
+			continue
+		}
+		events = append(events, models.ShipmentEvent{
+			ShipmentID:  shipment.ID,
+			Status:      e.Status,
+			Description: e.Description,
+			Location:    e.Location,
+			OccurredAt:  e.OccurredAt,
+		})
+	}
+	if err := shipments.AddEvents(events); err != nil {
+		return err
+	}
+
+	latest, err := shipments.LatestEvent(shipment.ID)
+	if err != nil {
+		return err
+	}
+	shipment.Status = latest.Status
+	if tracking.EstimatedDelivery != nil {
+		shipment.EstimatedDelivery = tracking.EstimatedDelivery
+	}
+	if shipment.Status == constants.ShipmentDelivered && shipment.DeliveredAt == nil {
+		deliveredAt := latest.OccurredAt
+		shipment.DeliveredAt = &deliveredAt
+	}
+	if err := shipments.UpdateShipment(shipment); err != nil {
+		return err
+	}
+
+	if shipment.Status != constants.ShipmentDelivered {
+		return nil
+	}
+	order := &models.Order{ID: shipment.OrderID}
+	if err := lockOrder(tx, order); err != nil {
+		return err
+	}
+	if order.Status != constants.OrderShipped {
+		return nil
+	}
+	return s.OrderService.TransitionStatus(tx, order, constants.OrderDelivered)
+}
+
+// bookingRequest describes the order's parcel for the courier. The order
+// needs its items, variants and products loaded.
+func (s *ShipmentService) bookingRequest(tx *gorm.DB, order *models.Order) (*courier.BookingRequest, error) {
+	a := order.ShippingDetails
+	to := courier.Address{
+		Name:       a.Name,
+		Phone:      a.Phone,
+		Line1:      a.Line1,
+		City:       a.City,
+		State:      a.State,
+		PostalCode: a.PostalCode,
+		Country:    a.Country,
+	}
+	if a.Line2 != nil {
+		to.Line2 = *a.Line2
+	}
+
+	var user models.User
+	err := tx.Select("email").Where("clerk_user_id = ?", order.UserID).First(&user).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if user.Email != nil {
+		to.Email = *user.Email
+	}
+
+	// The goods are declared at what the customer paid for them
+	value := order.TotalAmount.Sub(order.ShippingCost)
+	if !order.TaxIncluded {
+		value = value.Sub(order.ShippingTaxAmount)
+	}
+	req := &courier.BookingRequest{
+		Reference: strconv.FormatUint(uint64(order.ID), 10),
+		OrderDate: order.CreatedAt,
+		To:        to,
+		Value:     value,
+		WeightKg:  s.ShippingService.OrderWeightKg(order.OrderItems),
+	}
+	for _, oi := range order.OrderItems {
+		name := oi.Variant.SKU
+		if oi.Variant.Product != nil {
+			name = oi.Variant.Product.Name
+		}
+		req.Items = append(req.Items, courier.Item{
+			SKU:       oi.Variant.SKU,
+			Name:      name,
+			HSN:       s.HSN,
+			Quantity:  oi.Quantity,
+			UnitPrice: oi.UnitPrice,
+		})
+	}
+	return req, nil
+}
+
+// estimatedDelivery falls back on the slowest delivery time of the order's
+// shipping method when the courier gives no estimate.
+func (s *ShipmentService) estimatedDelivery(order *models.Order, shippedAt time.Time) *time.Time {
+	if order.ShippingMethod == nil {
+		return nil
+	}
+	method, ok := s.ShippingService.Method(*order.ShippingMethod)
+	if !ok || method.MaxDays == 0 {
+		return nil
+	}
+	eta := shippedAt.AddDate(0, 0, method.MaxDays)
+	return &eta
+}
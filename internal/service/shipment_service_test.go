@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/courier"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/events"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/repository"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/shipping"
+	"gorm.io/gorm"
+)
+
+// unreachableCourier fails every booking.
+type unreachableCourier struct {
+	*courier.Fake
+}
+
+func (unreachableCourier) Book(ctx context.Context, req courier.BookingRequest) (*courier.Booking, error) {
+	return nil, errors.New("courier unreachable")
+}
+
+type shipmentFixture struct {
+	*testServices
+	courier   *courier.Fake
+	shipments *ShipmentService
+}
+
+func newShipmentFixture(t *testing.T) *shipmentFixture {
+	s := newTestServices(t)
+	fake := courier.NewFake("courier-secret")
+	shipments := NewShipmentService(
+		s.db,
+		repository.NewShipmentRepository(s.db),
+		s.orders,
+		NewShippingService(shipping.Default()),
+		fake,
+		"6109",
+	)
+	return &shipmentFixture{testServices: s, courier: fake, shipments: shipments}
+}
+
+// confirmedOrder creates a confirmed order for one T-shirt.
+func (f *shipmentFixture) confirmedOrder(t *testing.T) *models.Order {
+	t.Helper()
+	variant := createVariant(t, f.db, "Plain Tee", money.INR(49900), 0)
+	return createOrder(t, f.db, "user_1", constants.OrderConfirmed, variant, 1)
+}
+
+func (f *shipmentFixture) orderStatus(t *testing.T, orderID uint) string {
+	t.Helper()
+	var order models.Order
+	if err := f.db.First(&order, orderID).Error; err != nil {
+		t.Fatal(err)
+	}
+	return order.Status
+}
+
+func TestCreateShipment(t *testing.T) {
+	f := newShipmentFixture(t)
+	order := f.confirmedOrder(t)
+
+	shipment, err := f.shipments.CreateShipment(context.Background(), order.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shipment.Status != constants.ShipmentBooked || shipment.TrackingNumber == "" || shipment.Carrier != courier.FakeName {
+		t.Fatalf("shipment = %s %q via %s, want booked with a tracking number", shipment.Status, shipment.TrackingNumber, shipment.Carrier)
+	}
+	if len(shipment.Events) != 1 || shipment.Events[0].Status != constants.ShipmentBooked {
+		t.Fatalf("events = %+v, want one booked event", shipment.Events)
+	}
+	if shipment.EstimatedDelivery == nil {
+		t.Fatal("no estimated delivery")
+	}
+	if status := f.orderStatus(t, order.ID); status != constants.OrderShipped {
+		t.Fatalf("order is %s, want shipped", status)
+	}
+	if n := eventCount(t, f.db, events.OrderShipped); n != 1 {
+		t.Fatalf("%d order.shipped events, want 1", n)
+	}
+
+	// A second click must not book the parcel again
+	if _, err := f.shipments.CreateShipment(context.Background(), order.ID); !errors.Is(err, ErrShipmentNotAvailable) {
+		t.Fatalf("second CreateShipment: err = %v, want ErrShipmentNotAvailable", err)
+	}
+	if _, err := f.courier.Track(context.Background(), "FAKE0000000002"); !errors.Is(err, courier.ErrUnknownShipment) {
+		t.Fatal("the courier booked the order twice")
+	}
+}
+
+func TestCreateShipmentCourierFails(t *testing.T) {
+	f := newShipmentFixture(t)
+	order := f.confirmedOrder(t)
+	f.shipments.Courier = unreachableCourier{f.courier}
+
+	if _, err := f.shipments.CreateShipment(context.Background(), order.ID); err == nil {
+		t.Fatal("CreateShipment succeeded with an unreachable courier")
+	}
+	if status := f.orderStatus(t, order.ID); status != constants.OrderConfirmed {
+		t.Fatalf("order is %s, want confirmed", status)
+	}
+
+	// Nothing is left behind to stop a retry
+	f.shipments.Courier = f.courier
+	if _, err := f.shipments.CreateShipment(context.Background(), order.ID); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+}
+
+func TestCreateShipmentWhileBooking(t *testing.T) {
+	f := newShipmentFixture(t)
+	order := f.confirmedOrder(t)
+	booking := &models.Shipment{OrderID: order.ID, Carrier: courier.FakeName, Status: constants.ShipmentBooking}
+	if err := f.db.Create(booking).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.shipments.CreateShipment(context.Background(), order.ID); !errors.Is(err, ErrShipmentNotAvailable) {
+		t.Fatalf("err = %v, want ErrShipmentNotAvailable while another booking runs", err)
+	}
+
+	// A booking that died half way is taken over once it has timed out
+	stale := time.Now().Add(-2 * bookingTimeout)
+	if err := f.db.Model(booking).UpdateColumn("updated_at", stale).Error; err != nil {
+		t.Fatal(err)
+	}
+	shipment, err := f.shipments.CreateShipment(context.Background(), order.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shipment.ID != booking.ID || shipment.Status != constants.ShipmentBooked {
+		t.Fatalf("shipment %d is %s, want booking %d booked", shipment.ID, shipment.Status, booking.ID)
+	}
+}
+
+// bookedShipment books a confirmed order and returns its shipment.
+func (f *shipmentFixture) bookedShipment(t *testing.T) *models.Shipment {
+	t.Helper()
+	order := f.confirmedOrder(t)
+	shipment, err := f.shipments.CreateShipment(context.Background(), order.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return shipment
+}
+
+func (f *shipmentFixture) apply(t *testing.T, tracking *courier.Tracking) *models.Shipment {
+	t.Helper()
+	err := f.db.Transaction(func(tx *gorm.DB) error {
+		return f.shipments.ApplyTracking(tx, courier.FakeName, tracking)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	shipment, err := f.shipments.ShipmentRepository.GetShipmentByOrderID(f.orderOf(t, tracking.TrackingNumber))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return shipment
+}
+
+func (f *shipmentFixture) orderOf(t *testing.T, trackingNumber string) uint {
+	t.Helper()
+	var shipment models.Shipment
+	if err := f.db.Where("tracking_number = ?", trackingNumber).First(&shipment).Error; err != nil {
+		t.Fatal(err)
+	}
+	return shipment.OrderID
+}
+
+func scan(trackingNumber, status string, at time.Time) *courier.Tracking {
+	return &courier.Tracking{
+		TrackingNumber: trackingNumber,
+		Status:         status,
+		Events:         []courier.Event{{Status: status, Description: "Shipment " + status, OccurredAt: at}},
+	}
+}
+
+func TestApplyTrackingLateAndDuplicateEvents(t *testing.T) {
+	f := newShipmentFixture(t)
+	shipment := f.bookedShipment(t)
+	base := time.Now().Truncate(time.Second)
+
+	outForDelivery := scan(shipment.TrackingNumber, constants.ShipmentOutForDelivery, base.Add(2*time.Hour))
+	f.apply(t, outForDelivery)
+	// The in-transit scan from an hour earlier turns up late
+	shipment = f.apply(t, scan(shipment.TrackingNumber, constants.ShipmentInTransit, base.Add(time.Hour)))
+	if shipment.Status != constants.ShipmentOutForDelivery {
+		t.Fatalf("shipment is %s after a late event, want out_for_delivery", shipment.Status)
+	}
+
+	// And the out-for-delivery scan is pushed again
+	shipment = f.apply(t, outForDelivery)
+	if len(shipment.Events) != 3 {
+		t.Fatalf("%d events, want booked, in transit and out for delivery once each", len(shipment.Events))
+	}
+	if shipment.Events[1].Status != constants.ShipmentInTransit {
+		t.Fatalf("events are not in the order they happened: %+v", shipment.Events)
+	}
+	if status := f.orderStatus(t, shipment.OrderID); status != constants.OrderShipped {
+		t.Fatalf("order is %s, want shipped", status)
+	}
+}
+
+func TestApplyTrackingDelivered(t *testing.T) {
+	f := newShipmentFixture(t)
+	shipment := f.bookedShipment(t)
+	deliveredAt := time.Now().Truncate(time.Second).Add(time.Hour)
+
+	delivered := scan(shipment.TrackingNumber, constants.ShipmentDelivered, deliveredAt)
+	shipment = f.apply(t, delivered)
+	if shipment.Status != constants.ShipmentDelivered || shipment.DeliveredAt == nil || !shipment.DeliveredAt.Equal(deliveredAt) {
+		t.Fatalf("shipment = %s delivered at %v, want delivered at %v", shipment.Status, shipment.DeliveredAt, deliveredAt)
+	}
+	if status := f.orderStatus(t, shipment.OrderID); status != constants.OrderDelivered {
+		t.Fatalf("order is %s, want delivered", status)
+	}
+
+	// Delivering twice must not try to move the order again
+	f.apply(t, delivered)
+	if n := eventCount(t, f.db, events.OrderDelivered); n != 1 {
+		t.Fatalf("%d order.delivered events, want 1", n)
+	}
+}
+
+func TestApplyTrackingUnknownShipment(t *testing.T) {
+	f := newShipmentFixture(t)
+	err := f.db.Transaction(func(tx *gorm.DB) error {
+		return f.shipments.ApplyTracking(tx, courier.FakeName, scan("FAKE9999999999", constants.ShipmentInTransit, time.Now()))
+	})
+	if !errors.Is(err, courier.ErrUnknownShipment) {
+		t.Fatalf("err = %v, want ErrUnknownShipment", err)
+	}
+}
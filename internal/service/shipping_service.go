@@ -0,0 +1,117 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/shipping"
+)
+
+var ErrShippingUnavailable = errors.New("shipping method not available")
+
+type ShippingService struct {
+	Config *shipping.Config
+}
+
+func NewShippingService(config *shipping.Config) *ShippingService {
+	return &ShippingService{Config: config}
+}
+
+// Options lists the methods that can deliver the items to a PIN code,
+// cheapest first. The items need their variant and product loaded.
+func (s *ShippingService) Options(items []models.CartItem, postalCode string) ([]shipping.Option, error) {
+	postalCode = strings.ReplaceAll(strings.TrimSpace(postalCode), " ", "")
+	if !pinCodePattern.MatchString(postalCode) {
+		return nil, fmt.Errorf("%w: %q is not a valid PIN code", ErrInvalidAddress, postalCode)
+	}
+
+	parcel := s.parcel(items, postalCode)
+	options := []shipping.Option{}
+	for _, method := range s.Config.Methods {
+		if option, ok := method.Quote(parcel); ok {
+			options = append(options, option)
+		}
+	}
+	sort.SliceStable(options, func(i, j int) bool {
+		return options[i].Cost.Cmp(options[j].Cost) < 0
+	})
+	return options, nil
+}
+
+// Quote prices delivery of the items with the chosen method. With no method
+// the cheapest one that delivers to postalCode is used. postalCode is empty
+// for orders shipped to a free-text address, which only methods that don't
+// price by zone can deliver.
+func (s *ShippingService) Quote(items []models.CartItem, postalCode string, code string) (shipping.Option, error) {
+	parcel := s.parcel(items, postalCode)
+
+	var best *shipping.Option
+	known := code == ""
+	for _, method := range s.Config.Methods {
+		if code != "" && method.Code != code {
+			continue
+		}
+		known = true
+		option, ok := method.Quote(parcel)
+		if !ok {
+			continue
+		}
+		if best == nil || option.Cost.Cmp(best.Cost) < 0 {
+			best = &option
+		}
+	}
+
+	if !known {
+		return shipping.Option{}, fmt.Errorf("%w: unknown method %q", ErrShippingUnavailable, code)
+	}
+	if best == nil {
+		if code != "" {
+			return shipping.Option{}, fmt.Errorf("%w: %s does not deliver this order", ErrShippingUnavailable, code)
+		}
+		return shipping.Option{}, fmt.Errorf("%w: no method delivers this order", ErrShippingUnavailable)
+	}
+	return *best, nil
+}
+
+// parcel weighs the items and values them at list price, so the free
+// shipping threshold doesn't move when a promo code is applied.
+func (s *ShippingService) parcel(items []models.CartItem, postalCode string) shipping.Parcel {
+	parcel := shipping.Parcel{Value: money.INR(0), PostalCode: postalCode}
+	for _, it := range items {
+		product := it.Variant.Product
+		parcel.WeightKg += s.pieceWeightKg(product) * float64(it.Quantity)
+		parcel.Value = parcel.Value.Add(product.BasePrice.Add(it.Variant.PriceModifier).Mul(it.Quantity))
+	}
+	return parcel
+}
+
+// OrderWeightKg weighs an order's items. The items need their variant and
+// product loaded.
+func (s *ShippingService) OrderWeightKg(items []models.OrderItem) float64 {
+	var weight float64
+	for _, it := range items {
+		weight += s.pieceWeightKg(it.Variant.Product) * float64(it.Quantity)
+	}
+	return weight
+}
+
+// Method returns the configured shipping method with the code.
+func (s *ShippingService) Method(code string) (shipping.Method, bool) {
+	for _, method := range s.Config.Methods {
+		if method.Code == code {
+			return method, true
+		}
+	}
+	return shipping.Method{}, false
+}
+
+func (s *ShippingService) pieceWeightKg(product *models.Product) float64 {
+	if product != nil && product.Weight > 0 {
+		return product.Weight
+	}
+	return s.Config.DefaultItemWeightKg
+}
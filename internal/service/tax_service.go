@@ -0,0 +1,130 @@
+package service
+
+import (
+	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/tax"
+)
+
+type TaxService struct {
+	Rates *tax.Rates
+}
+
+func NewTaxService(rates *tax.Rates) *TaxService {
+	return &TaxService{Rates: rates}
+}
+
+// QuoteCart works out the tax on a cart at today's rates. The items need
+// their variant and product loaded.
+func (s *TaxService) QuoteCart(items []models.CartItem) tax.Result {
+	taxItems := make([]tax.Item, len(items))
+	for i, it := range items {
+		taxItems[i] = tax.Item{
+			Quantity:  it.Quantity,
+			UnitPrice: it.Variant.Product.BasePrice.Add(it.Variant.PriceModifier),
+		}
+	}
+	return s.Rates.Calculate(time.Now(), taxItems, money.INR(0), s.Rates.PricesIncludeTax)
+}
+
+// ApplyToOrder works out the tax on a new order from its items' unit prices
+// and its discount, stores it on the items and sets the order's totals. The
+// order's shipping cost is taxed with the goods unless the rate table says
+// shipping is not taxable, in which case it is added to the total as is.
+func (s *TaxService) ApplyToOrder(order *models.Order, at time.Time) {
+	res := s.Rates.Calculate(at, orderTaxItems(order.OrderItems), order.Discount, s.Rates.PricesIncludeTax)
+	if s.Rates.ShippingTaxable && !order.ShippingCost.IsZero() {
+		res.AddShipping(order.ShippingCost)
+	}
+
+	for i := range order.OrderItems {
+		item, line := &order.OrderItems[i], res.Lines[i]
+		rate := line.Rate
+		item.Discount = line.Discount
+		item.TaxableAmount = line.Taxable
+		item.TaxRate = &rate
+		item.TaxAmount = line.Tax
+	}
+
+	order.Subtotal = res.Gross
+	order.Discount = res.Discount
+	order.TaxAmount = res.Tax
+	order.TaxIncluded = res.Included
+	order.ShippingTaxRate = nil
+	order.ShippingTaxAmount = money.INR(0)
+	if res.Shipping != nil {
+		rate := res.Shipping.Rate
+		order.ShippingTaxRate = &rate
+		order.ShippingTaxAmount = res.Shipping.Tax
+		order.TotalAmount = res.Total
+	} else {
+		order.TotalAmount = res.Total.Add(order.ShippingCost)
+	}
+}
+
+// OrderTax returns the tax on a placed order, with its shipping when that
+// was taxed. Orders from before tax was stored per item charged list prices,
+// so their tax is worked out again as included in them at the rates of the
+// order date.
+func (s *TaxService) OrderTax(order *models.Order) tax.Result {
+	for _, item := range order.OrderItems {
+		if item.TaxRate == nil {
+			return s.Rates.Calculate(order.CreatedAt, orderTaxItems(order.OrderItems), order.Discount, true)
+		}
+	}
+
+	res := tax.Result{
+		Lines:    make([]tax.Line, len(order.OrderItems)),
+		Included: order.TaxIncluded,
+		Gross:    money.INR(0),
+		Discount: money.INR(0),
+		Taxable:  money.INR(0),
+		Tax:      money.INR(0),
+		Total:    money.INR(0),
+	}
+	for i, item := range order.OrderItems {
+		line := tax.Line{
+			Gross:    item.Subtotal,
+			Discount: item.Discount,
+			Taxable:  item.TaxableAmount,
+			Rate:     *item.TaxRate,
+			Tax:      item.TaxAmount,
+			Total:    item.TaxableAmount.Add(item.TaxAmount),
+		}
+		res.Lines[i] = line
+		res.Gross = res.Gross.Add(line.Gross)
+		res.Discount = res.Discount.Add(line.Discount)
+		res.Taxable = res.Taxable.Add(line.Taxable)
+		res.Tax = res.Tax.Add(line.Tax)
+		res.Total = res.Total.Add(line.Total)
+	}
+
+	if order.ShippingTaxRate != nil {
+		line := tax.Line{
+			Gross:    order.ShippingCost,
+			Discount: money.INR(0),
+			Taxable:  order.ShippingCost,
+			Rate:     *order.ShippingTaxRate,
+			Tax:      order.ShippingTaxAmount,
+		}
+		if order.TaxIncluded {
+			line.Taxable = line.Taxable.Sub(line.Tax)
+		}
+		line.Total = line.Taxable.Add(line.Tax)
+		res.Shipping = &line
+		res.Taxable = res.Taxable.Add(line.Taxable)
+		res.Tax = res.Tax.Add(line.Tax)
+		res.Total = res.Total.Add(line.Total)
+	}
+	return res
+}
+
+func orderTaxItems(orderItems []models.OrderItem) []tax.Item {
+	items := make([]tax.Item, len(orderItems))
+	for i, oi := range orderItems {
+		items[i] = tax.Item{Quantity: oi.Quantity, UnitPrice: oi.UnitPrice}
+	}
+	return items
+}
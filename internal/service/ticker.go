@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"time"
+)
+
+// every calls tick every interval until ctx is cancelled, in a goroutine of
+// its own.
+func every(ctx context.Context, interval time.Duration, tick func()) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				tick()
+			}
+		}
+	}()
+}
+
+// drain calls run, which handles up to batchSize items, until it returns a
+// short batch or an error. A full batch means there is probably more
+// waiting.
+func drain(ctx context.Context, batchSize int, run func(context.Context) (int, error)) error {
+	for ctx.Err() == nil {
+		n, err := run(ctx)
+		if err != nil {
+			return err
+		}
+		if n < batchSize {
+			break
+		}
+	}
+	return nil
+}
+
+// hourly returns a func that calls f at most once an hour, with the time
+// of the call.
+func hourly(f func(now time.Time)) func() {
+	var last time.Time
+	return func() {
+		if time.Since(last) >= time.Hour {
+			last = time.Now()
+			f(last)
+		}
+	}
+}
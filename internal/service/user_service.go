@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	clerkuser "github.com/clerk/clerk-sdk-go/v2/user"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/auth"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/repository"
+	"gorm.io/gorm"
+)
+
+// maxSyncedUsers is the size at which stale entries are pruned from the
+// sync cache.
+const maxSyncedUsers = 10000
+
+// UserService keeps the local users table in step with Clerk.
+type UserService struct {
+	DB             *gorm.DB
+	UserRepository *repository.UserRepository
+	// SyncInterval is how long a user seen on a request is trusted before the
+	// next request checks their row again.
+	SyncInterval time.Duration
+
+	mu       sync.Mutex
+	syncedAt map[string]time.Time
+}
+
+func NewUserService(db *gorm.DB, userRepo *repository.UserRepository, syncInterval time.Duration) *UserService {
+	return &UserService{
+		DB:             db,
+		UserRepository: userRepo,
+		SyncInterval:   syncInterval,
+		syncedAt:       make(map[string]time.Time),
+	}
+}
+
+// SyncUser makes sure the signed-in user has a local row, creating it from
+// Clerk on their first request and updating it when the token's email or role
+// no longer match. New users are always looked up in Clerk, since only
+// Clerk says whether their email is verified.
+func (s *UserService) SyncUser(ctx context.Context, claims *auth.ClerkClaims) error {
+	if s.recentlySynced(claims.UserID) {
+		return nil
+	}
+
+	user, err := s.UserRepository.GetUserByClerkID(claims.UserID)
+	found := err == nil
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	sameEmail := claims.Email == "" || (user.Email != nil && claims.Email == *user.Email)
+	if found && sameEmail && (claims.Role == "" || claims.Role == user.Role) {
+		s.markSynced(claims.UserID)
+		return nil
+	}
+
+	profile := &auth.ClerkProfile{
+		ClerkUserID: claims.UserID,
+		Email:       claims.Email,
+		Role:        claims.Role,
+	}
+	if !found {
+		if profile, err = auth.FetchClerkProfile(ctx, claims.UserID); err != nil {
+			return err
+		}
+		if claims.Role != "" {
+			profile.Role = claims.Role
+		}
+	}
+
+	if _, err := s.UpsertFromClerk(s.DB, profile); err != nil {
+		return err
+	}
+
+	s.markSynced(claims.UserID)
+	return nil
+}
+
+// UpsertFromClerk creates or updates the local user for a Clerk profile. A
+// user registered before Clerk (or deleted earlier) with the same verified
+// email and no Clerk account of their own is linked and restored rather
+// than duplicated. Empty profile fields leave the stored values unchanged.
+func (s *UserService) UpsertFromClerk(tx *gorm.DB, profile *auth.ClerkProfile) (*models.User, error) {
+	var user models.User
+	err := tx.Unscoped().Where("clerk_user_id = ?", profile.ClerkUserID).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) && profile.Email != "" && profile.EmailVerified {
+		err = tx.Unscoped().Where("email = ? AND clerk_user_id IS NULL", profile.Email).First(&user).Error
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	email, emailErr := freeEmail(tx, profile, user.ID)
+	if emailErr != nil {
+		return nil, emailErr
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		user = models.User{
+			ClerkUserID:   &profile.ClerkUserID,
+			Email:         email,
+			Name:          profile.Name,
+			Phone:         profile.Phone,
+			Role:          constants.RoleUser,
+			OAuthProvider: "clerk",
+			OAuthID:       profile.ClerkUserID,
+		}
+		if profile.Role != "" {
+			user.Role = profile.Role
+		}
+		if err := tx.Create(&user).Error; err != nil {
+			return nil, err
+		}
+		return &user, nil
+	}
+
+	user.ClerkUserID = &profile.ClerkUserID
+	user.DeletedAt = gorm.DeletedAt{}
+	if email != nil {
+		user.Email = email
+	}
+	if profile.Name != "" {
+		user.Name = profile.Name
+	}
+	if profile.Phone != "" {
+		user.Phone = profile.Phone
+	}
+	if profile.Role != "" {
+		user.Role = profile.Role
+	}
+
+	if err := tx.Unscoped().Save(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// freeEmail returns the profile's email for storing on user userID, or nil
+// when it is blank or another user has it. Such a user keeps their old
+// email, or none, rather than failing to sync.
+func freeEmail(tx *gorm.DB, profile *auth.ClerkProfile, userID uint) (*string, error) {
+	if profile.Email == "" {
+		return nil, nil
+	}
+	var taken int64
+	if err := tx.Unscoped().Model(&models.User{}).
+		Where("email = ? AND id <> ?", profile.Email, userID).
+		Count(&taken).Error; err != nil {
+		return nil, err
+	}
+	if taken > 0 {
+		log.Printf("USER SYNC: another user has the email of Clerk user %s; not storing it", profile.ClerkUserID)
+		return nil, nil
+	}
+	email := profile.Email
+	return &email, nil
+}
+
+// DeleteFromClerk soft-deletes the local user of a deleted Clerk account.
+// Their orders are kept.
+func (s *UserService) DeleteFromClerk(tx *gorm.DB, clerkUserID string) error {
+	s.mu.Lock()
+	delete(s.syncedAt, clerkUserID)
+	s.mu.Unlock()
+
+	return tx.Where("clerk_user_id = ?", clerkUserID).Delete(&models.User{}).Error
+}
+
+// SetRole gives a user a new role. Clerk is updated first, since session
+// tokens carry the role from Clerk's public metadata; the local row follows.
+func (s *UserService) SetRole(ctx context.Context, user *models.User, role string) error {
+	if !constants.IsValidRole(role) {
+		return fmt.Errorf("unknown role %q", role)
+	}
+
+	if user.ClerkUserID != nil {
+		metadata, err := json.Marshal(map[string]string{"role": role})
+		if err != nil {
+			return err
+		}
+		raw := json.RawMessage(metadata)
+		if _, err := clerkuser.UpdateMetadata(ctx, *user.ClerkUserID, &clerkuser.UpdateMetadataParams{
+			PublicMetadata: &raw,
+		}); err != nil {
+			return fmt.Errorf("failed to update role in Clerk: %w", err)
+		}
+		// Tokens issued before the change still carry the old role; don't let
+		// them revert it.
+		s.markSynced(*user.ClerkUserID)
+	}
+
+	user.Role = role
+	return s.DB.Model(user).Update("role", role).Error
+}
+
+func (s *UserService) recentlySynced(clerkUserID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	at, ok := s.syncedAt[clerkUserID]
+	return ok && time.Since(at) < s.SyncInterval
+}
+
+func (s *UserService) markSynced(clerkUserID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.syncedAt) >= maxSyncedUsers {
+		for id, at := range s.syncedAt {
+			if time.Since(at) >= s.SyncInterval {
+				delete(s.syncedAt, id)
+			}
+		}
+	}
+	s.syncedAt[clerkUserID] = time.Now()
+}
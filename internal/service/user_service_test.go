@@ -0,0 +1,68 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/auth"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/repository"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/testdb"
+)
+
+func newTestUserService(t *testing.T) *UserService {
+	db := testdb.Open(t)
+	return NewUserService(db, repository.NewUserRepository(db), time.Minute)
+}
+
+func TestUpsertFromClerkPhoneOnlyUsers(t *testing.T) {
+	s := newTestUserService(t)
+
+	for _, id := range []string{"user_a", "user_b"} {
+		user, err := s.UpsertFromClerk(s.DB, &auth.ClerkProfile{ClerkUserID: id, Phone: "+91999999999" + id[len(id)-1:]})
+		if err != nil {
+			t.Fatalf("%s: %v", id, err)
+		}
+		if user.Email != nil {
+			t.Fatalf("%s: email = %q, want none", id, *user.Email)
+		}
+	}
+}
+
+func TestUpsertFromClerkLinksByVerifiedEmailOnly(t *testing.T) {
+	s := newTestUserService(t)
+	email := "asha@example.com"
+	legacy := &models.User{Email: &email, Name: "Asha"}
+	if err := s.DB.Create(legacy).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// An unverified address proves nothing about who owns the old account
+	user, err := s.UpsertFromClerk(s.DB, &auth.ClerkProfile{ClerkUserID: "user_mallory", Email: email})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID == legacy.ID {
+		t.Fatal("an unverified email took over an existing account")
+	}
+	if user.Email != nil {
+		t.Fatalf("email = %q, want none while another user has it", *user.Email)
+	}
+
+	user, err = s.UpsertFromClerk(s.DB, &auth.ClerkProfile{ClerkUserID: "user_asha", Email: email, EmailVerified: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != legacy.ID || user.ClerkUserID == nil || *user.ClerkUserID != "user_asha" {
+		t.Fatalf("verified email did not link the existing account: got user %d", user.ID)
+	}
+
+	// Once linked, the account belongs to that Clerk user
+	user, err = s.UpsertFromClerk(s.DB, &auth.ClerkProfile{ClerkUserID: "user_other", Email: email, EmailVerified: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID == legacy.ID {
+		t.Fatal("a linked account was taken over by another Clerk user")
+	}
+}
@@ -0,0 +1,521 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/events"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidWebhookEndpoint = errors.New("invalid webhook endpoint")
+	// ErrWebhookAddressNotAllowed is returned when an endpoint resolves to
+	// an address on our own network, which merchants must not reach
+	// through us.
+	ErrWebhookAddressNotAllowed = errors.New("webhook endpoint address is not allowed")
+)
+
+// Merchant webhook event types. These are what endpoints subscribe to and
+// are kept stable for integrations, whatever the domain events are called.
+const (
+	WebhookOrderCreated       = "order.created"
+	WebhookOrderStatusChanged = "order.status_changed"
+	WebhookPaymentCompleted   = "payment.completed"
+	WebhookPaymentFailed      = "payment.failed"
+	WebhookRefundCreated      = "refund.created"
+	WebhookInventoryUpdated   = "inventory.updated"
+	WebhookInventoryLow       = "inventory.low"
+	WebhookReturnCreated      = "return.created"
+	WebhookReturnUpdated      = "return.updated"
+)
+
+// WebhookEventTypes lists the event types endpoints can subscribe to.
+var WebhookEventTypes = []string{
+	WebhookOrderCreated, WebhookOrderStatusChanged,
+	WebhookPaymentCompleted, WebhookPaymentFailed, WebhookRefundCreated,
+	WebhookInventoryUpdated, WebhookInventoryLow,
+	WebhookReturnCreated, WebhookReturnUpdated,
+}
+
+// webhookEvents maps domain events to the webhook event sent for them. The
+// order status events all become order.status_changed, whose payload has
+// the old and new status.
+var webhookEvents = map[string]string{
+	events.OrderPlaced:     WebhookOrderCreated,
+	events.OrderConfirmed:  WebhookOrderStatusChanged,
+	events.OrderCancelled:  WebhookOrderStatusChanged,
+	events.OrderShipped:    WebhookOrderStatusChanged,
+	events.OrderDelivered:  WebhookOrderStatusChanged,
+	events.OrderReturned:   WebhookOrderStatusChanged,
+	events.PaymentCaptured: WebhookPaymentCompleted,
+	events.PaymentFailed:   WebhookPaymentFailed,
+	events.RefundIssued:    WebhookRefundCreated,
+	events.StockUpdated:    WebhookInventoryUpdated,
+	events.StockLow:        WebhookInventoryLow,
+	events.ReturnRequested: WebhookReturnCreated,
+	events.ReturnUpdated:   WebhookReturnUpdated,
+}
+
+const (
+	// webhookBatchSize caps how many deliveries one run sends.
+	webhookBatchSize = 20
+	// webhookLease is how long a sender has to send the deliveries it
+	// claimed; it must cover webhookBatchSize requests timing out.
+	webhookLease = 5 * time.Minute
+	// webhookTimeout is how long an endpoint has to answer.
+	webhookTimeout = 10 * time.Second
+	// Failed deliveries are retried after webhookRetryBaseDelay, doubling
+	// each time up to webhookRetryMaxDelay.
+	webhookRetryBaseDelay = time.Minute
+	webhookRetryMaxDelay  = 6 * time.Hour
+	// maxWebhookResponseBody is how much of an endpoint's answer is kept
+	// in the delivery log.
+	maxWebhookResponseBody = 1024
+	// minWebhookSecretLength is the shortest secret an admin may choose.
+	minWebhookSecretLength = 16
+)
+
+// WebhookEndpointInput creates or changes an endpoint. On update, nil
+// fields are left as they are; an empty Events subscribes to every event.
+type WebhookEndpointInput struct {
+	URL         *string
+	Description *string
+	Events      []string
+	Secret      *string
+	Active      *bool
+}
+
+// webhookEnvelope is the JSON body POSTed to endpoints.
+type webhookEnvelope struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt string          `json:"createdAt"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// WebhookService sends our events to merchant systems. Events reach it
+// through the outbox, which queues a delivery per subscribed endpoint;
+// deliveries are then sent on their own schedule so a slow or broken
+// endpoint only delays itself.
+type WebhookService struct {
+	DB     *gorm.DB
+	Repo   *repository.WebhookEndpointRepository
+	Client *http.Client
+	// MaxAttempts is how often a delivery is tried before it is marked
+	// failed and left to be replayed.
+	MaxAttempts int
+	// Retention is how long successful deliveries stay in the log.
+	Retention time.Duration
+	// AllowHTTP lets endpoints use plain http, for local development.
+	AllowHTTP bool
+	// AllowPrivateNetworks lets endpoints be on loopback, link-local and
+	// private addresses, for local development.
+	AllowPrivateNetworks bool
+}
+
+func NewWebhookService(
+	db *gorm.DB,
+	repo *repository.WebhookEndpointRepository,
+	maxAttempts int,
+	retention time.Duration,
+	allowHTTP bool,
+	allowPrivateNetworks bool,
+) *WebhookService {
+	s := &WebhookService{
+		DB:                   db,
+		Repo:                 repo,
+		MaxAttempts:          maxAttempts,
+		Retention:            retention,
+		AllowHTTP:            allowHTTP,
+		AllowPrivateNetworks: allowPrivateNetworks,
+	}
+	// The address is checked as the connection is made, after DNS, so a
+	// public name that resolves to a private address is refused too. No
+	// proxy is used, as it would be the proxy's address that was checked.
+	dialer := &net.Dialer{
+		Timeout: webhookTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			if s.AllowPrivateNetworks {
+				return nil
+			}
+			addr, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !isPublicAddr(addr.Addr()) {
+				return fmt.Errorf("%w: %s", ErrWebhookAddressNotAllowed, addr.Addr())
+			}
+			return nil
+		},
+	}
+	s.Client = &http.Client{
+		Timeout: webhookTimeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: webhookTimeout,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		// A redirect is reported as the endpoint's answer, not followed
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return s
+}
+
+// nonPublicPrefixes are ranges netip.Addr has no method for that must not
+// be reachable from webhooks: "this network", carrier-grade NAT (where
+// some clouds serve instance metadata), IETF protocol assignments and
+// benchmarking.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+}
+
+// isPublicAddr reports whether addr is on the internet rather than on our
+// host or network. IPv4-mapped IPv6 addresses are judged as IPv4.
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, p := range nonPublicPrefixes {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// CreateEndpoint registers an endpoint and returns it with its signing
+// secret, which is generated unless the input gives one.
+func (s *WebhookService) CreateEndpoint(input WebhookEndpointInput) (*models.WebhookEndpoint, error) {
+	if input.URL == nil {
+		return nil, fmt.Errorf("%w: url is required", ErrInvalidWebhookEndpoint)
+	}
+	endpoint := &models.WebhookEndpoint{Active: true}
+	if err := s.apply(endpoint, input); err != nil {
+		return nil, err
+	}
+	if endpoint.Secret == "" {
+		secret, err := newWebhookSecret()
+		if err != nil {
+			return nil, err
+		}
+		endpoint.Secret = secret
+	}
+	if err := s.Repo.CreateEndpoint(endpoint); err != nil {
+		return nil, err
+	}
+	return endpoint, nil
+}
+
+// UpdateEndpoint changes the fields of an endpoint the input sets.
+func (s *WebhookService) UpdateEndpoint(id uint, input WebhookEndpointInput) (*models.WebhookEndpoint, error) {
+	endpoint, err := s.Repo.GetEndpointByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.apply(endpoint, input); err != nil {
+		return nil, err
+	}
+	if err := s.Repo.UpdateEndpoint(endpoint); err != nil {
+		return nil, err
+	}
+	return endpoint, nil
+}
+
+// RotateSecret gives an endpoint a new signing secret. Deliveries sent
+// from now on, retries included, are signed with it.
+func (s *WebhookService) RotateSecret(id uint) (*models.WebhookEndpoint, error) {
+	endpoint, err := s.Repo.GetEndpointByID(id)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+	endpoint.Secret = secret
+	if err := s.Repo.UpdateEndpoint(endpoint); err != nil {
+		return nil, err
+	}
+	return endpoint, nil
+}
+
+func (s *WebhookService) DeleteEndpoint(id uint) error {
+	return s.Repo.DeleteEndpoint(id)
+}
+
+func (s *WebhookService) apply(endpoint *models.WebhookEndpoint, input WebhookEndpointInput) error {
+	if input.URL != nil {
+		u, err := url.Parse(strings.TrimSpace(*input.URL))
+		if err != nil || u.Host == "" || (u.Scheme != "https" && !(s.AllowHTTP && u.Scheme == "http")) {
+			if s.AllowHTTP {
+				return fmt.Errorf("%w: url must be an http(s) URL", ErrInvalidWebhookEndpoint)
+			}
+			return fmt.Errorf("%w: url must be an https URL", ErrInvalidWebhookEndpoint)
+		}
+		// Names are checked when a delivery connects; addresses and
+		// localhost can be refused now
+		if !s.AllowPrivateNetworks {
+			host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+			addr, err := netip.ParseAddr(host)
+			if host == "localhost" || strings.HasSuffix(host, ".localhost") || (err == nil && !isPublicAddr(addr)) {
+				return fmt.Errorf("%w: url must not point at a private or local address", ErrInvalidWebhookEndpoint)
+			}
+		}
+		endpoint.URL = u.String()
+	}
+	if input.Description != nil {
+		endpoint.Description = strings.TrimSpace(*input.Description)
+	}
+	if input.Events != nil {
+		seen := map[string]bool{}
+		eventTypes := []string{}
+		for _, t := range input.Events {
+			if !isWebhookEventType(t) {
+				return fmt.Errorf("%w: unknown event type %q", ErrInvalidWebhookEndpoint, t)
+			}
+			if !seen[t] {
+				seen[t] = true
+				eventTypes = append(eventTypes, t)
+			}
+		}
+		endpoint.Events = eventTypes
+	}
+	if input.Secret != nil {
+		if len(*input.Secret) < minWebhookSecretLength {
+			return fmt.Errorf("%w: secret must be at least %d characters", ErrInvalidWebhookEndpoint, minWebhookSecretLength)
+		}
+		endpoint.Secret = *input.Secret
+	}
+	if input.Active != nil {
+		endpoint.Active = *input.Active
+	}
+	return nil
+}
+
+func isWebhookEventType(eventType string) bool {
+	for _, t := range WebhookEventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// Replay queues a delivery to be sent again as a new delivery, so the
+// original stays in the log as it was.
+func (s *WebhookService) Replay(deliveryID uint) (*models.WebhookDelivery, error) {
+	original, err := s.Repo.GetDeliveryByID(deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.Repo.GetEndpointByID(original.EndpointID); err != nil {
+		return nil, err
+	}
+
+	replay := &models.WebhookDelivery{
+		EndpointID:    original.EndpointID,
+		EventType:     original.EventType,
+		Payload:       original.Payload,
+		Status:        constants.WebhookDeliveryPending,
+		NextAttemptAt: time.Now(),
+		ReplayOfID:    &original.ID,
+	}
+	if err := s.Repo.CreateDelivery(replay); err != nil {
+		return nil, err
+	}
+	return replay, nil
+}
+
+// Subscribe registers the handler that queues deliveries with bus.
+func (s *WebhookService) Subscribe(bus *EventBus) {
+	bus.Subscribe(events.All, "merchant-webhooks", s.enqueue)
+}
+
+// enqueue queues a delivery of an event for each endpoint subscribed to
+// it. Deliveries are keyed by endpoint and event, so running it again for
+// the same event queues nothing new.
+func (s *WebhookService) enqueue(ctx context.Context, e events.Event) error {
+	eventType, ok := webhookEvents[e.Type]
+	if !ok {
+		return nil
+	}
+	endpoints, err := s.Repo.GetActiveEndpointsFor(eventType)
+	if err != nil || len(endpoints) == 0 {
+		return err
+	}
+
+	body, err := json.Marshal(webhookEnvelope{
+		ID:        "evt_" + strconv.FormatUint(uint64(e.ID), 10),
+		Type:      eventType,
+		CreatedAt: e.OccurredAt.UTC().Format("2006-01-02T15:04:05Z07:00"),
+		Data:      e.Payload,
+	})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	deliveries := make([]models.WebhookDelivery, len(endpoints))
+	for i := range endpoints {
+		deliveries[i] = models.WebhookDelivery{
+			EndpointID:    endpoints[i].ID,
+			EventID:       &e.ID,
+			EventType:     eventType,
+			Payload:       body,
+			Status:        constants.WebhookDeliveryPending,
+			NextAttemptAt: now,
+		}
+	}
+	return s.Repo.CreateDeliveries(deliveries)
+}
+
+// DeliverDue sends the deliveries that are due and returns how many were
+// tried.
+func (s *WebhookService) DeliverDue(ctx context.Context) (int, error) {
+	now := time.Now()
+	claimed, err := s.Repo.ClaimDueDeliveries(now, now.Add(webhookLease), webhookBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for i := range claimed {
+		if ctx.Err() != nil {
+			break
+		}
+		s.deliver(ctx, &claimed[i])
+		sent++
+	}
+	return sent, nil
+}
+
+// deliver POSTs one delivery to its endpoint and records the outcome.
+func (s *WebhookService) deliver(ctx context.Context, delivery *models.WebhookDelivery) {
+	status, body, err := s.send(ctx, delivery)
+	if status != 0 {
+		delivery.ResponseStatus = &status
+		delivery.ResponseBody = &body
+	} else {
+		delivery.ResponseStatus = nil
+		delivery.ResponseBody = nil
+	}
+
+	delivery.Attempts++
+	now := time.Now()
+	switch {
+	case err == nil:
+		delivery.Status = constants.WebhookDeliverySucceeded
+		delivery.DeliveredAt = &now
+		delivery.LastError = nil
+	case delivery.Attempts >= s.MaxAttempts:
+		log.Printf("WEBHOOK: giving up on delivery %d to endpoint %d after %d attempts: %v", delivery.ID, delivery.EndpointID, delivery.Attempts, err)
+		delivery.Status = constants.WebhookDeliveryFailed
+	default:
+		delivery.NextAttemptAt = now.Add(backoff(delivery.Attempts, webhookRetryBaseDelay, webhookRetryMaxDelay))
+	}
+	if err != nil {
+		msg := err.Error()
+		delivery.LastError = &msg
+	}
+
+	if err := s.Repo.UpdateDelivery(delivery); err != nil {
+		// The lease runs out and the delivery is sent again; endpoints
+		// tell repeats apart by the X-Webhook-Id header.
+		log.Printf("WEBHOOK: failed to record delivery %d: %v", delivery.ID, err)
+	}
+}
+
+// send POSTs a delivery and returns the endpoint's status code and the
+// start of its answer. Anything but a 2xx is an error.
+func (s *WebhookService) send(ctx context.Context, delivery *models.WebhookDelivery) (int, string, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Endpoint.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "tshirt-ecommerce-webhooks/1.0")
+	req.Header.Set("X-Webhook-Id", strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set("X-Webhook-Event", delivery.EventType)
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+SignWebhook(delivery.Endpoint.Secret, timestamp, delivery.Payload))
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxWebhookResponseBody))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, string(body), fmt.Errorf("endpoint answered %s", resp.Status)
+	}
+	return resp.StatusCode, string(body), nil
+}
+
+// SignWebhook is the hex HMAC-SHA256, keyed with the endpoint's secret, of
+// the timestamp header, a dot and the body. Receivers recompute it to check
+// a delivery came from us and reject old timestamps to stop replays.
+func SignWebhook(secret, timestamp string, body []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(timestamp))
+	h.Write([]byte("."))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Start sends due deliveries every interval and prunes old successful ones
+// until ctx is cancelled.
+func (s *WebhookService) Start(ctx context.Context, interval time.Duration) {
+	prune := hourly(func(now time.Time) {
+		n, err := s.Repo.DeleteSucceededBefore(now.Add(-s.Retention))
+		if err != nil {
+			log.Printf("WEBHOOK: prune failed: %v", err)
+		} else if n > 0 {
+			log.Printf("WEBHOOK: pruned %d delivered webhooks", n)
+		}
+	})
+
+	every(ctx, interval, func() {
+		if err := drain(ctx, webhookBatchSize, s.DeliverDue); err != nil {
+			log.Printf("WEBHOOK: delivery run failed: %v", err)
+		}
+		prune()
+	})
+}
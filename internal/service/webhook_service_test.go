@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+)
+
+func TestWebhookEndpointOnPrivateAddress(t *testing.T) {
+	s := NewWebhookService(nil, nil, 3, time.Hour, true, false)
+
+	for _, u := range []string{
+		"http://169.254.169.254/latest/meta-data/",
+		"http://127.0.0.1:8080/hook",
+		"http://[::1]/hook",
+		"http://[::ffff:10.0.0.1]/hook",
+		"http://100.100.100.200/",
+		"http://localhost:3000/hook",
+		"http://0.0.0.0/hook",
+	} {
+		err := s.apply(&models.WebhookEndpoint{}, WebhookEndpointInput{URL: &u})
+		if !errors.Is(err, ErrInvalidWebhookEndpoint) {
+			t.Errorf("%s accepted (err %v)", u, err)
+		}
+	}
+	u := "https://hooks.example.com/orders"
+	if err := s.apply(&models.WebhookEndpoint{}, WebhookEndpointInput{URL: &u}); err != nil {
+		t.Errorf("%s refused: %v", u, err)
+	}
+}
+
+func TestWebhookDeliveryToPrivateAddressIsNotSent(t *testing.T) {
+	hit := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit = true
+	}))
+	defer server.Close()
+	delivery := &models.WebhookDelivery{
+		EventType: WebhookOrderCreated,
+		Payload:   []byte(`{}`),
+		Endpoint:  &models.WebhookEndpoint{URL: server.URL, Secret: "whsec_test"},
+	}
+
+	// Stored URLs are checked again as the connection is made, which also
+	// covers public names that resolve to private addresses
+	s := NewWebhookService(nil, nil, 3, time.Hour, true, false)
+	if _, _, err := s.send(context.Background(), delivery); !errors.Is(err, ErrWebhookAddressNotAllowed) {
+		t.Fatalf("send to %s: err = %v, want ErrWebhookAddressNotAllowed", server.URL, err)
+	}
+	if hit {
+		t.Fatal("the endpoint on a private address was called")
+	}
+
+	s = NewWebhookService(nil, nil, 3, time.Hour, true, true)
+	if status, _, err := s.send(context.Background(), delivery); err != nil || status != http.StatusOK {
+		t.Fatalf("send with private networks allowed: status %d, err %v", status, err)
+	}
+}
@@ -0,0 +1,520 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/events"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvalidWishlistItem  = errors.New("invalid wishlist item")
+	ErrWishlistItemNotFound = errors.New("wishlist item not found")
+)
+
+// WishlistInput saves a product to a wishlist, in one variant when
+// VariantID is set. Nil alert settings leave an item already on the list
+// as it is, and are off for a new one.
+type WishlistInput struct {
+	ProductID         uint
+	VariantID         *uint
+	NotifyBackInStock *bool
+	NotifyPriceDrop   *bool
+}
+
+// WishlistAlert tells a customer about a change to an item on their
+// wishlist. PreviousPrice is set for price drops.
+type WishlistAlert struct {
+	User          *models.User
+	Item          *models.WishlistItem
+	Price         money.Money
+	PreviousPrice money.Money
+}
+
+// WishlistAlertNotifier sends wishlist alerts. Like CartReminderNotifier it
+// reports whether the alert went out.
+type WishlistAlertNotifier interface {
+	SendBackInStock(ctx context.Context, alert WishlistAlert) (bool, error)
+	SendPriceDrop(ctx context.Context, alert WishlistAlert) (bool, error)
+}
+
+// WishlistService keeps customers' wishlists, moves items between them and
+// the cart, and alerts customers when saved items come back in stock or get
+// cheaper.
+type WishlistService struct {
+	DB                *gorm.DB
+	Repo              *repository.WishlistRepository
+	ProductRepository *repository.ProductRepository
+	CartRepository    *repository.CartRepository
+	UserRepository    *repository.UserRepository
+	Notifier          WishlistAlertNotifier
+}
+
+func NewWishlistService(
+	db *gorm.DB,
+	repo *repository.WishlistRepository,
+	productRepo *repository.ProductRepository,
+	cartRepo *repository.CartRepository,
+	userRepo *repository.UserRepository,
+	notifier WishlistAlertNotifier,
+) *WishlistService {
+	return &WishlistService{
+		DB:                db,
+		Repo:              repo,
+		ProductRepository: productRepo,
+		CartRepository:    cartRepo,
+		UserRepository:    userRepo,
+		Notifier:          notifier,
+	}
+}
+
+// GetWishlist returns a customer's wishlist, empty if they have not saved
+// anything yet.
+func (s *WishlistService) GetWishlist(userID string) (*models.Wishlist, error) {
+	wishlist, err := s.Repo.GetWishlistByUserID(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.Wishlist{UserID: userID}, nil
+	}
+	return wishlist, err
+}
+
+// Add saves a product to a customer's wishlist. Saving one that is already
+// there only updates its alerts.
+func (s *WishlistService) Add(userID string, input WishlistInput) (*models.WishlistItem, error) {
+	product, err := s.ProductRepository.GetProductByID(input.ProductID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !product.IsActive) {
+		return nil, fmt.Errorf("%w: product %d not found", ErrInvalidWishlistItem, input.ProductID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	variant, err := productVariant(product, input.VariantID)
+	if err != nil {
+		return nil, err
+	}
+
+	var item *models.WishlistItem
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		repo := s.Repo.WithTx(tx)
+		wishlist, err := repo.GetOrCreateWishlist(userID)
+		if err != nil {
+			return err
+		}
+
+		item, err = repo.FindItem(wishlist.ID, product.ID, input.VariantID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			item = &models.WishlistItem{
+				WishlistID: wishlist.ID,
+				ProductID:  product.ID,
+				VariantID:  input.VariantID,
+				AlertPrice: unitPrice(product, variant),
+				AddedAt:    time.Now(),
+			}
+			err = repo.CreateItem(item)
+		}
+		if err != nil {
+			return err
+		}
+		item.Product = product
+		item.Variant = variant
+
+		if err := s.setAlerts(repo, item, input.NotifyBackInStock, input.NotifyPriceDrop); err != nil {
+			return err
+		}
+		return repo.TouchWishlist(wishlist)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// SetAlerts turns the alerts for an item on a customer's wishlist on or
+// off, leaving those that are nil as they are.
+func (s *WishlistService) SetAlerts(userID string, itemID uint, backInStock, priceDrop *bool) (*models.WishlistItem, error) {
+	item, err := s.getItem(s.Repo, userID, itemID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.setAlerts(s.Repo, item, backInStock, priceDrop); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// setAlerts applies alert settings to an item and saves it. An alert
+// turned on starts from how the item is now: out of stock, or at today's
+// price.
+func (s *WishlistService) setAlerts(repo *repository.WishlistRepository, item *models.WishlistItem, backInStock, priceDrop *bool) error {
+	if backInStock != nil {
+		if *backInStock && !item.NotifyBackInStock {
+			available, err := repo.AvailableStock(item.ProductID, item.VariantID)
+			if err != nil {
+				return err
+			}
+			item.AwaitingStock = available <= 0
+		}
+		item.NotifyBackInStock = *backInStock
+	}
+	if priceDrop != nil {
+		if *priceDrop && !item.NotifyPriceDrop && item.Product != nil {
+			item.AlertPrice = unitPrice(item.Product, item.Variant)
+		}
+		item.NotifyPriceDrop = *priceDrop
+	}
+	return repo.UpdateItem(item)
+}
+
+// Remove takes an item off a customer's wishlist.
+func (s *WishlistService) Remove(userID string, itemID uint) error {
+	item, err := s.getItem(s.Repo, userID, itemID)
+	if err != nil {
+		return err
+	}
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		repo := s.Repo.WithTx(tx)
+		if err := repo.DeleteItem(item.ID); err != nil {
+			return err
+		}
+		return repo.TouchWishlist(&models.Wishlist{ID: item.WishlistID})
+	})
+}
+
+// MoveToCart puts quantity of a wishlist item into the customer's cart and
+// takes it off the wishlist. Items saved without a variant need one picked
+// now. It returns the cart's ID.
+func (s *WishlistService) MoveToCart(userID string, itemID uint, variantID *uint, quantity int) (uint, error) {
+	if quantity <= 0 {
+		return 0, fmt.Errorf("%w: quantity must be positive", ErrInvalidWishlistItem)
+	}
+	item, err := s.getItem(s.Repo, userID, itemID)
+	if err != nil {
+		return 0, err
+	}
+	if item.Product == nil || !item.Product.IsActive {
+		return 0, fmt.Errorf("%w: product %d is no longer available", ErrInvalidWishlistItem, item.ProductID)
+	}
+	if variantID == nil {
+		variantID = item.VariantID
+	}
+	if variantID == nil {
+		return 0, fmt.Errorf("%w: pick a size to add to the cart", ErrInvalidWishlistItem)
+	}
+	product, err := s.ProductRepository.GetProductByID(item.ProductID)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := productVariant(product, variantID); err != nil {
+		return 0, err
+	}
+
+	var cartID uint
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		cart := models.Cart{UserID: &userID}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ?", userID).
+			FirstOrCreate(&cart).Error; err != nil {
+			return err
+		}
+		cartID = cart.ID
+
+		var cartItem models.CartItem
+		err := tx.Where("cart_id = ? AND variant_id = ?", cart.ID, *variantID).First(&cartItem).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			cartItem = models.CartItem{CartID: cart.ID, VariantID: *variantID, AddedAt: time.Now()}
+		} else if err != nil {
+			return err
+		}
+
+		available, err := availableStock(tx, *variantID)
+		if err != nil {
+			return err
+		}
+		if cartItem.Quantity+quantity > available {
+			return ErrInsufficientStock
+		}
+		cartItem.Quantity += quantity
+
+		cartRepo := repository.NewCartRepository(tx)
+		if cartItem.ID == 0 {
+			err = cartRepo.AddItem(&cartItem)
+		} else {
+			err = cartRepo.UpdateItem(&cartItem)
+		}
+		if err != nil {
+			return err
+		}
+		if err := cartRepo.Touch(cart.ID); err != nil {
+			return err
+		}
+
+		repo := s.Repo.WithTx(tx)
+		if err := repo.DeleteItem(item.ID); err != nil {
+			return err
+		}
+		return repo.TouchWishlist(&models.Wishlist{ID: item.WishlistID})
+	})
+	if err != nil {
+		return 0, err
+	}
+	return cartID, nil
+}
+
+// SaveForLater moves an item out of the customer's cart onto their
+// wishlist, in the same variant.
+func (s *WishlistService) SaveForLater(userID string, cartItemID uint) (*models.WishlistItem, error) {
+	var cartItem models.CartItem
+	err := s.DB.Preload("Cart").Preload("Variant").First(&cartItem, cartItemID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && (cartItem.Cart.UserID == nil || *cartItem.Cart.UserID != userID)) {
+		return nil, fmt.Errorf("%w: cart item %d not found", ErrInvalidWishlistItem, cartItemID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var item *models.WishlistItem
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		repo := s.Repo.WithTx(tx)
+		wishlist, err := repo.GetOrCreateWishlist(userID)
+		if err != nil {
+			return err
+		}
+
+		variantID := cartItem.VariantID
+		item, err = repo.FindItem(wishlist.ID, cartItem.Variant.ProductID, &variantID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			product, err := s.ProductRepository.WithTx(tx).GetProductByID(cartItem.Variant.ProductID)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: product %d is no longer available", ErrInvalidWishlistItem, cartItem.Variant.ProductID)
+			}
+			if err != nil {
+				return err
+			}
+			item = &models.WishlistItem{
+				WishlistID: wishlist.ID,
+				ProductID:  product.ID,
+				VariantID:  &variantID,
+				AlertPrice: unitPrice(product, &cartItem.Variant),
+				AddedAt:    time.Now(),
+			}
+			if err := repo.CreateItem(item); err != nil {
+				return err
+			}
+		} else if err != nil {
+			return err
+		}
+
+		cartRepo := repository.NewCartRepository(tx)
+		if err := cartRepo.RemoveItem(cartItem.ID); err != nil {
+			return err
+		}
+		if err := cartRepo.Touch(cartItem.CartID); err != nil {
+			return err
+		}
+		return repo.TouchWishlist(wishlist)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// getItem returns an item on a customer's wishlist with its product and
+// variant.
+func (s *WishlistService) getItem(repo *repository.WishlistRepository, userID string, itemID uint) (*models.WishlistItem, error) {
+	item, err := repo.GetItemForUser(userID, itemID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrWishlistItemNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// Subscribe registers the wishlist alert handlers with bus.
+func (s *WishlistService) Subscribe(bus *EventBus) {
+	bus.Subscribe(events.StockUpdated, "wishlist-stock-updated", s.stockChanged)
+	bus.Subscribe(events.StockLow, "wishlist-stock-low", s.stockChanged)
+	bus.Subscribe(events.ProductPriceChanged, "wishlist-price-drop", s.priceChanged)
+}
+
+// stockChanged flags the back-in-stock alerts for a variant that has sold
+// out, and sends those waiting on it once it is in stock again. It goes by
+// the stock now rather than in the event, which may be out of date by the
+// time it is handled.
+func (s *WishlistService) stockChanged(ctx context.Context, e events.Event) error {
+	var p events.Stock
+	if err := e.Decode(&p); err != nil {
+		return err
+	}
+	id, err := strconv.ParseUint(p.VariantID, 10, 32)
+	if err != nil {
+		return fmt.Errorf("bad variant ID %q in %s event %d", p.VariantID, e.Type, e.ID)
+	}
+	variantID := uint(id)
+
+	var variant models.ProductVariant
+	err = s.DB.First(&variant, variantID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	available, err := s.Repo.AvailableStock(variant.ProductID, &variantID)
+	if err != nil {
+		return err
+	}
+	if available <= 0 {
+		left, err := s.Repo.AvailableStock(variant.ProductID, nil)
+		if err != nil {
+			return err
+		}
+		return s.Repo.MarkAwaitingStock(variant.ProductID, variantID, left <= 0)
+	}
+
+	items, err := s.Repo.GetBackInStockWatchers(variant.ProductID, variantID)
+	if err != nil {
+		return err
+	}
+	return s.alert(ctx, items, func(item *models.WishlistItem, user *models.User) error {
+		if item.Product.IsActive {
+			sent, err := s.Notifier.SendBackInStock(ctx, WishlistAlert{
+				User:  user,
+				Item:  item,
+				Price: unitPrice(item.Product, item.Variant),
+			})
+			if err != nil {
+				return err
+			}
+			if sent {
+				log.Printf("WISHLIST: told %s product %d is back in stock", *user.ClerkUserID, item.ProductID)
+			}
+		}
+		item.AwaitingStock = false
+		return s.Repo.UpdateItem(item)
+	})
+}
+
+// priceChanged sends price drop alerts for the items of a product that now
+// cost less than when the customer saved them or was last alerted.
+func (s *WishlistService) priceChanged(ctx context.Context, e events.Event) error {
+	var p events.ProductPrice
+	if err := e.Decode(&p); err != nil {
+		return err
+	}
+	id, err := strconv.ParseUint(p.ProductID, 10, 32)
+	if err != nil {
+		return fmt.Errorf("bad product ID %q in %s event %d", p.ProductID, e.Type, e.ID)
+	}
+
+	items, err := s.Repo.GetPriceDropWatchers(uint(id))
+	if err != nil {
+		return err
+	}
+	return s.alert(ctx, items, func(item *models.WishlistItem, user *models.User) error {
+		price := unitPrice(item.Product, item.Variant)
+		if !item.Product.IsActive || price.Cmp(item.AlertPrice) >= 0 {
+			return nil
+		}
+		sent, err := s.Notifier.SendPriceDrop(ctx, WishlistAlert{
+			User:          user,
+			Item:          item,
+			Price:         price,
+			PreviousPrice: item.AlertPrice,
+		})
+		if err != nil {
+			return err
+		}
+		if sent {
+			log.Printf("WISHLIST: told %s product %d dropped to %s", *user.ClerkUserID, item.ProductID, price.Decimal())
+		}
+		item.AlertPrice = price
+		return s.Repo.UpdateItem(item)
+	})
+}
+
+// alert calls send for each item with the customer it belongs to, skipping
+// items of deleted products. It carries on past failures and returns the
+// first, so the event is retried for the items that failed; those already
+// handled have their alert settled and are not sent again.
+func (s *WishlistService) alert(ctx context.Context, items []models.WishlistItem, send func(*models.WishlistItem, *models.User) error) error {
+	if len(items) == 0 {
+		return nil
+	}
+	wishlistIDs := make([]uint, 0, len(items))
+	for _, item := range items {
+		wishlistIDs = append(wishlistIDs, item.WishlistID)
+	}
+	userIDs, err := s.Repo.GetUserIDs(wishlistIDs)
+	if err != nil {
+		return err
+	}
+
+	users := make(map[string]*models.User)
+	var firstErr error
+	for i := range items {
+		item := &items[i]
+		if item.Product == nil || ctx.Err() != nil {
+			continue
+		}
+		userID := userIDs[item.WishlistID]
+		user, ok := users[userID]
+		if !ok {
+			user, err = s.UserRepository.GetUserByClerkID(userID)
+			if err != nil {
+				user = nil
+				if !errors.Is(err, gorm.ErrRecordNotFound) && firstErr == nil {
+					firstErr = err
+				}
+			}
+			users[userID] = user
+		}
+		if user == nil {
+			continue
+		}
+		if err := send(item, user); err != nil {
+			log.Printf("WISHLIST: item %d: %v", item.ID, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	return firstErr
+}
+
+// productVariant returns the variant of product with ID variantID, or nil
+// when variantID is.
+func productVariant(product *models.Product, variantID *uint) (*models.ProductVariant, error) {
+	if variantID == nil {
+		return nil, nil
+	}
+	for i := range product.Variants {
+		if product.Variants[i].ID == *variantID {
+			return &product.Variants[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: variant %d is not a variant of product %d", ErrInvalidWishlistItem, *variantID, product.ID)
+}
+
+// unitPrice is what one of a product costs, in variant when it is set.
+func unitPrice(product *models.Product, variant *models.ProductVariant) money.Money {
+	if variant == nil {
+		return product.BasePrice
+	}
+	return product.BasePrice.Add(variant.PriceModifier)
+}
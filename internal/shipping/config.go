@@ -0,0 +1,227 @@
+package shipping
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
+)
+
+var ErrInvalidConfig = errors.New("invalid shipping config")
+
+//go:embed methods.json
+var defaultConfig []byte
+
+var (
+	methodCodePattern = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+	pinPrefixPattern  = regexp.MustCompile(`^[1-9][0-9]{0,5}$`)
+)
+
+// Config is the set of shipping methods offered at checkout.
+type Config struct {
+	// DefaultItemWeightKg is used for products that have no weight set.
+	DefaultItemWeightKg float64
+	Methods             []Method
+}
+
+// configFile is the JSON layout of a shipping config. Amounts are rupee
+// strings and every rate has a type, e.g.
+//
+//	{"code": "standard", "name": "Standard", "minDays": 3, "maxDays": 7,
+//	 "rate": {"type": "freeOver", "threshold": "999.00",
+//	          "rate": {"type": "flat", "amount": "49.00"}}}
+type configFile struct {
+	DefaultItemWeightKg float64 `json:"defaultItemWeightKg"`
+	Methods             []struct {
+		Code    string    `json:"code"`
+		Name    string    `json:"name"`
+		MinDays int       `json:"minDays"`
+		MaxDays int       `json:"maxDays"`
+		Rate    *rateSpec `json:"rate"`
+	} `json:"methods"`
+}
+
+// rateSpec is one rate in a config file. Type picks which of the other
+// fields are read.
+type rateSpec struct {
+	Type string `json:"type"`
+
+	// flat
+	Amount string `json:"amount"`
+
+	// weight
+	Tiers []struct {
+		UpToKg float64 `json:"upToKg"`
+		Amount string  `json:"amount"`
+	} `json:"tiers"`
+
+	// freeOver
+	Threshold string    `json:"threshold"`
+	Rate      *rateSpec `json:"rate"`
+
+	// zones
+	Zones []struct {
+		Name     string    `json:"name"`
+		Prefixes []string  `json:"prefixes"`
+		Rate     *rateSpec `json:"rate"`
+	} `json:"zones"`
+	Default *rateSpec `json:"default"`
+}
+
+// Default returns the shipping config built into the binary.
+func Default() *Config {
+	config, err := Parse(defaultConfig)
+	if err != nil {
+		panic(err)
+	}
+	return config
+}
+
+// Load reads a shipping config from a JSON file.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return config, nil
+}
+
+// Parse reads and validates a JSON shipping config.
+func Parse(data []byte) (*Config, error) {
+	var f configFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+	if f.DefaultItemWeightKg < 0 {
+		return nil, fmt.Errorf("%w: defaultItemWeightKg is negative", ErrInvalidConfig)
+	}
+	if len(f.Methods) == 0 {
+		return nil, fmt.Errorf("%w: no methods", ErrInvalidConfig)
+	}
+
+	config := &Config{DefaultItemWeightKg: f.DefaultItemWeightKg}
+	seen := map[string]bool{}
+	for _, fm := range f.Methods {
+		if !methodCodePattern.MatchString(fm.Code) {
+			return nil, fmt.Errorf("%w: method code %q must be lowercase letters, digits, _ or -", ErrInvalidConfig, fm.Code)
+		}
+		if seen[fm.Code] {
+			return nil, fmt.Errorf("%w: method %s is listed twice", ErrInvalidConfig, fm.Code)
+		}
+		seen[fm.Code] = true
+		if fm.Name == "" {
+			return nil, fmt.Errorf("%w: method %s has no name", ErrInvalidConfig, fm.Code)
+		}
+		if fm.MinDays < 0 || fm.MaxDays < fm.MinDays {
+			return nil, fmt.Errorf("%w: method %s has invalid delivery days", ErrInvalidConfig, fm.Code)
+		}
+
+		rate, err := fm.Rate.build()
+		if err != nil {
+			return nil, fmt.Errorf("%w: method %s: %v", ErrInvalidConfig, fm.Code, err)
+		}
+		config.Methods = append(config.Methods, Method{
+			Code:    fm.Code,
+			Name:    fm.Name,
+			MinDays: fm.MinDays,
+			MaxDays: fm.MaxDays,
+			Rate:    rate,
+		})
+	}
+	return config, nil
+}
+
+func (s *rateSpec) build() (Rate, error) {
+	if s == nil {
+		return nil, errors.New("missing rate")
+	}
+
+	switch s.Type {
+	case "flat":
+		amount, err := parseAmount(s.Amount)
+		if err != nil {
+			return nil, err
+		}
+		return Flat{Amount: amount}, nil
+
+	case "weight":
+		if len(s.Tiers) == 0 {
+			return nil, errors.New("weight rate has no tiers")
+		}
+		r := WeightTiers{}
+		for i, t := range s.Tiers {
+			last := i == len(s.Tiers)-1
+			switch {
+			case t.UpToKg < 0:
+				return nil, fmt.Errorf("weight tier %d has a negative upToKg", i+1)
+			case t.UpToKg == 0 && !last:
+				return nil, errors.New("only the last weight tier may leave out upToKg")
+			case i > 0 && t.UpToKg != 0 && t.UpToKg <= s.Tiers[i-1].UpToKg:
+				return nil, errors.New("weight tiers must be in increasing upToKg order")
+			}
+			amount, err := parseAmount(t.Amount)
+			if err != nil {
+				return nil, err
+			}
+			r.Tiers = append(r.Tiers, WeightTier{UpToKg: t.UpToKg, Amount: amount})
+		}
+		return r, nil
+
+	case "freeOver":
+		threshold, err := parseAmount(s.Threshold)
+		if err != nil {
+			return nil, err
+		}
+		rate, err := s.Rate.build()
+		if err != nil {
+			return nil, err
+		}
+		return FreeOver{Threshold: threshold, Rate: rate}, nil
+
+	case "zones":
+		r := Zones{}
+		for _, z := range s.Zones {
+			if len(z.Prefixes) == 0 {
+				return nil, fmt.Errorf("zone %q has no PIN prefixes", z.Name)
+			}
+			for _, prefix := range z.Prefixes {
+				if !pinPrefixPattern.MatchString(prefix) {
+					return nil, fmt.Errorf("zone %q: %q is not a PIN code prefix", z.Name, prefix)
+				}
+			}
+			rate, err := z.Rate.build()
+			if err != nil {
+				return nil, fmt.Errorf("zone %q: %v", z.Name, err)
+			}
+			r.Zones = append(r.Zones, Zone{Name: z.Name, Prefixes: z.Prefixes, Rate: rate})
+		}
+		if s.Default != nil {
+			rate, err := s.Default.build()
+			if err != nil {
+				return nil, err
+			}
+			r.Default = rate
+		}
+		return r, nil
+	}
+	return nil, fmt.Errorf("unknown rate type %q", s.Type)
+}
+
+func parseAmount(s string) (money.Money, error) {
+	amount, err := money.Parse(s, money.DefaultCurrency)
+	if err != nil {
+		return money.Money{}, err
+	}
+	if amount.IsNegative() {
+		return money.Money{}, fmt.Errorf("amount %s is negative", s)
+	}
+	return amount, nil
+}
@@ -0,0 +1,128 @@
+// Package shipping prices delivery with the methods offered at checkout.
+// Each method is priced by a Rate, and rates compose: a free-over threshold
+// can wrap a zone table whose zones are priced by weight tiers.
+package shipping
+
+import (
+	"strings"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
+)
+
+// Parcel is what is being shipped and where to.
+type Parcel struct {
+	WeightKg   float64
+	Value      money.Money
+	PostalCode string
+}
+
+// Rate prices a parcel. ok is false when the rate doesn't cover the parcel,
+// such as a zone table with no zone for its PIN code.
+type Rate interface {
+	Price(p Parcel) (cost money.Money, ok bool)
+}
+
+// Flat charges the same amount for every parcel.
+type Flat struct {
+	Amount money.Money
+}
+
+func (r Flat) Price(Parcel) (money.Money, bool) {
+	return r.Amount, true
+}
+
+// WeightTier prices parcels weighing at most UpToKg. A zero UpToKg covers
+// any weight.
+type WeightTier struct {
+	UpToKg float64
+	Amount money.Money
+}
+
+// WeightTiers charges by the first tier the parcel's weight fits in. Parcels
+// heavier than the last tier aren't covered.
+type WeightTiers struct {
+	Tiers []WeightTier
+}
+
+func (r WeightTiers) Price(p Parcel) (money.Money, bool) {
+	for _, tier := range r.Tiers {
+		if tier.UpToKg == 0 || p.WeightKg <= tier.UpToKg {
+			return tier.Amount, true
+		}
+	}
+	return money.Money{}, false
+}
+
+// FreeOver ships parcels worth at least Threshold for free and prices the
+// rest with Rate.
+type FreeOver struct {
+	Threshold money.Money
+	Rate      Rate
+}
+
+func (r FreeOver) Price(p Parcel) (money.Money, bool) {
+	if p.Value.Cmp(r.Threshold) >= 0 {
+		return money.INR(0), true
+	}
+	return r.Rate.Price(p)
+}
+
+// Zone is a delivery area made up of PIN code prefixes.
+type Zone struct {
+	Name     string
+	Prefixes []string
+	Rate     Rate
+}
+
+// Zones prices a parcel with the zone whose prefix matches the most digits
+// of its PIN code, falling back to Default. Without a Default, parcels
+// outside every zone aren't covered.
+type Zones struct {
+	Zones   []Zone
+	Default Rate
+}
+
+func (r Zones) Price(p Parcel) (money.Money, bool) {
+	var match Rate
+	longest := -1
+	for _, zone := range r.Zones {
+		for _, prefix := range zone.Prefixes {
+			if strings.HasPrefix(p.PostalCode, prefix) && len(prefix) > longest {
+				match, longest = zone.Rate, len(prefix)
+			}
+		}
+	}
+	if match == nil {
+		match = r.Default
+	}
+	if match == nil {
+		return money.Money{}, false
+	}
+	return match.Price(p)
+}
+
+// Method is a shipping method offered at checkout.
+type Method struct {
+	Code string
+	Name string
+	// MinDays and MaxDays are the usual delivery time in days.
+	MinDays int
+	MaxDays int
+	Rate    Rate
+}
+
+// Option is a method priced for a parcel.
+type Option struct {
+	Method
+	Cost money.Money
+}
+
+// Quote prices the parcel with the method. ok is false when the method
+// can't deliver it.
+func (m Method) Quote(p Parcel) (Option, bool) {
+	cost, ok := m.Rate.Price(p)
+	if !ok {
+		return Option{}, false
+	}
+	return Option{Method: m, Cost: cost}, true
+}
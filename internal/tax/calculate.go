@@ -0,0 +1,166 @@
+package tax
+
+import (
+	"sort"
+	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
+)
+
+// Item is an order line as sold.
+type Item struct {
+	Quantity  int
+	UnitPrice money.Money
+}
+
+// Line is the tax on one item after its share of the order discount.
+type Line struct {
+	Gross    money.Money
+	Discount money.Money
+	Taxable  money.Money
+	Rate     int64
+	Tax      money.Money
+	// Total is what the customer pays for the line, Taxable plus Tax.
+	Total money.Money
+}
+
+// Result is the tax on a whole order.
+type Result struct {
+	Lines []Line
+	// Shipping is the tax on the delivery charge, nil when it is not taxed.
+	// Gross covers the items only; the other totals include shipping.
+	Shipping *Line
+	Included bool
+	Gross    money.Money
+	Discount money.Money
+	Taxable  money.Money
+	Tax      money.Money
+	Total    money.Money
+}
+
+// Calculate works out the tax on items sold at t, after spreading discount
+// over them in proportion to their value, since a discount given at the time
+// of sale reduces the taxable value of the goods. When included is true the
+// unit prices already contain GST.
+func (r *Rates) Calculate(t time.Time, items []Item, discount money.Money, included bool) Result {
+	schedule := r.At(t)
+	res := Result{
+		Lines:    make([]Line, len(items)),
+		Included: included,
+		Gross:    money.INR(0),
+		Taxable:  money.INR(0),
+		Tax:      money.INR(0),
+		Total:    money.INR(0),
+	}
+
+	for i, item := range items {
+		res.Lines[i] = Line{Gross: item.UnitPrice.Mul(item.Quantity)}
+		res.Gross = res.Gross.Add(res.Lines[i].Gross)
+	}
+	res.Discount = discount.Min(res.Gross)
+
+	shares := allocate(res.Discount, res.Lines)
+	for i := range res.Lines {
+		line := &res.Lines[i]
+		line.Discount = shares[i]
+		net := line.Gross.Sub(line.Discount)
+		line.Rate, line.Taxable = schedule.rate(net, items[i].Quantity, included)
+
+		if included {
+			line.Tax = net.Sub(line.Taxable)
+		} else {
+			line.Tax = line.Taxable.Percent(line.Rate)
+		}
+		line.Total = line.Taxable.Add(line.Tax)
+
+		res.Taxable = res.Taxable.Add(line.Taxable)
+		res.Tax = res.Tax.Add(line.Tax)
+		res.Total = res.Total.Add(line.Total)
+	}
+	return res
+}
+
+// AddShipping taxes a delivery charge of cost at the rate of the order's
+// principal supply, its line with the highest taxable value: delivery of
+// goods is part of a composite supply, taxed as the goods themselves are.
+// cost contains the tax when res.Included is true.
+func (res *Result) AddShipping(cost money.Money) {
+	var principal *Line
+	for i := range res.Lines {
+		if principal == nil || res.Lines[i].Taxable.Cmp(principal.Taxable) > 0 {
+			principal = &res.Lines[i]
+		}
+	}
+
+	line := Line{Gross: cost, Discount: money.INR(0), Taxable: cost}
+	if principal != nil {
+		line.Rate = principal.Rate
+	}
+	if res.Included {
+		line.Taxable = money.New(money.DivRound(cost.Amount*10000, 10000+line.Rate), cost.Currency)
+		line.Tax = cost.Sub(line.Taxable)
+	} else {
+		line.Tax = line.Taxable.Percent(line.Rate)
+	}
+	line.Total = line.Taxable.Add(line.Tax)
+
+	res.Shipping = &line
+	res.Taxable = res.Taxable.Add(line.Taxable)
+	res.Tax = res.Tax.Add(line.Tax)
+	res.Total = res.Total.Add(line.Total)
+}
+
+// rate picks the slab from the taxable value of one piece of a line worth
+// net after discount, and returns it with the line's taxable value.
+func (s Schedule) rate(net money.Money, quantity int, included bool) (int64, money.Money) {
+	if quantity <= 0 {
+		quantity = 1
+	}
+	for i, slab := range s.Slabs {
+		taxable := net
+		if included {
+			taxable = money.New(money.DivRound(net.Amount*10000, 10000+slab.Rate), net.Currency)
+		}
+		last := i == len(s.Slabs)-1
+		if last || money.DivRound(taxable.Amount, int64(quantity)) <= slab.UpTo.Amount {
+			return slab.Rate, taxable
+		}
+	}
+	return 0, net
+}
+
+// allocate splits discount across lines in proportion to their gross value,
+// handing leftover paise to the lines with the largest remainders so the
+// shares add up exactly.
+func allocate(discount money.Money, lines []Line) []money.Money {
+	shares := make([]money.Money, len(lines))
+	var gross int64
+	for _, line := range lines {
+		gross += line.Gross.Amount
+	}
+	if gross == 0 {
+		for i := range shares {
+			shares[i] = money.INR(0)
+		}
+		return shares
+	}
+
+	remainders := make([]int64, len(lines))
+	allocated := int64(0)
+	for i, line := range lines {
+		product := discount.Amount * line.Gross.Amount
+		shares[i] = money.INR(product / gross)
+		remainders[i] = product % gross
+		allocated += shares[i].Amount
+	}
+
+	order := make([]int, len(lines))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return remainders[order[a]] > remainders[order[b]] })
+	for _, i := range order[:discount.Amount-allocated] {
+		shares[i] = shares[i].Add(money.INR(1))
+	}
+	return shares
+}
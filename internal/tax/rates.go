@@ -0,0 +1,178 @@
+// Package tax works out GST on order lines from a rate table that can be
+// changed without a release.
+package tax
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
+)
+
+var ErrInvalidRates = errors.New("invalid tax rates")
+
+//go:embed rates.json
+var defaultRates []byte
+
+// ist is the time zone schedule dates are read in.
+var ist = time.FixedZone("IST", 5*60*60+30*60)
+
+// Rates is the GST rate table for the goods we sell.
+type Rates struct {
+	// HSN is printed against every line of an invoice.
+	HSN string
+	// PricesIncludeTax is true when catalogue prices already include GST, so
+	// tax is carved out of the price instead of added on top.
+	PricesIncludeTax bool
+	// ShippingTaxable charges GST on the delivery charge at the rate of the
+	// goods delivered, as for a composite supply. Turn it off only when
+	// delivery is not part of the sale, e.g. billed by the courier itself.
+	ShippingTaxable bool
+	// Schedules are sorted by EffectiveFrom. Each applies until the next one
+	// starts, so a notified rate change is added as a new schedule ahead of
+	// its date.
+	Schedules []Schedule
+}
+
+// Schedule is the set of slabs in force from a date.
+type Schedule struct {
+	EffectiveFrom time.Time
+	Slabs         []Slab
+}
+
+// Slab taxes pieces whose taxable value is at most UpTo at Rate basis
+// points. The last slab has no UpTo and covers everything above.
+type Slab struct {
+	UpTo *money.Money
+	Rate int64
+}
+
+// ratesFile is the JSON layout of a rate table. Dates are YYYY-MM-DD in IST,
+// amounts are rupee strings and rates are percentages, e.g.
+//
+//	{"effectiveFrom": "2025-09-22", "slabs": [{"upTo": "2500.00", "rate": 5}, {"rate": 18}]}
+type ratesFile struct {
+	HSN              string `json:"hsn"`
+	PricesIncludeTax bool   `json:"pricesIncludeTax"`
+	// ShippingTaxable defaults to true when left out.
+	ShippingTaxable *bool `json:"shippingTaxable"`
+	Schedules       []struct {
+		EffectiveFrom string `json:"effectiveFrom"`
+		Slabs         []struct {
+			UpTo *string `json:"upTo"`
+			Rate float64 `json:"rate"`
+		} `json:"slabs"`
+	} `json:"schedules"`
+}
+
+// Default returns the rate table built into the binary.
+func Default() *Rates {
+	rates, err := Parse(defaultRates)
+	if err != nil {
+		panic(err)
+	}
+	return rates
+}
+
+// Load reads a rate table from a JSON file.
+func Load(path string) (*Rates, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rates, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return rates, nil
+}
+
+// Parse reads and validates a JSON rate table.
+func Parse(data []byte) (*Rates, error) {
+	var f ratesFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRates, err)
+	}
+
+	rates := &Rates{HSN: f.HSN, PricesIncludeTax: f.PricesIncludeTax, ShippingTaxable: true}
+	if f.ShippingTaxable != nil {
+		rates.ShippingTaxable = *f.ShippingTaxable
+	}
+	for _, fs := range f.Schedules {
+		from, err := time.ParseInLocation("2006-01-02", fs.EffectiveFrom, ist)
+		if err != nil {
+			return nil, fmt.Errorf("%w: effectiveFrom %q is not a date", ErrInvalidRates, fs.EffectiveFrom)
+		}
+
+		schedule := Schedule{EffectiveFrom: from}
+		for _, s := range fs.Slabs {
+			if s.Rate < 0 || s.Rate > 100 {
+				return nil, fmt.Errorf("%w: rate %v%% in schedule %s", ErrInvalidRates, s.Rate, fs.EffectiveFrom)
+			}
+			slab := Slab{Rate: int64(math.Round(s.Rate * 100))}
+			if s.UpTo != nil {
+				upTo, err := money.Parse(*s.UpTo, money.DefaultCurrency)
+				if err != nil {
+					return nil, fmt.Errorf("%w: upTo in schedule %s: %v", ErrInvalidRates, fs.EffectiveFrom, err)
+				}
+				slab.UpTo = &upTo
+			}
+			schedule.Slabs = append(schedule.Slabs, slab)
+		}
+		rates.Schedules = append(rates.Schedules, schedule)
+	}
+
+	if err := rates.validate(); err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+func (r *Rates) validate() error {
+	if len(r.Schedules) == 0 {
+		return fmt.Errorf("%w: no schedules", ErrInvalidRates)
+	}
+	sort.SliceStable(r.Schedules, func(i, j int) bool {
+		return r.Schedules[i].EffectiveFrom.Before(r.Schedules[j].EffectiveFrom)
+	})
+
+	for i, s := range r.Schedules {
+		date := s.EffectiveFrom.Format("2006-01-02")
+		if i > 0 && s.EffectiveFrom.Equal(r.Schedules[i-1].EffectiveFrom) {
+			return fmt.Errorf("%w: two schedules start on %s", ErrInvalidRates, date)
+		}
+		if len(s.Slabs) == 0 {
+			return fmt.Errorf("%w: schedule %s has no slabs", ErrInvalidRates, date)
+		}
+		for j, slab := range s.Slabs {
+			last := j == len(s.Slabs)-1
+			switch {
+			case last && slab.UpTo != nil:
+				return fmt.Errorf("%w: the last slab of schedule %s must not have upTo", ErrInvalidRates, date)
+			case !last && slab.UpTo == nil:
+				return fmt.Errorf("%w: only the last slab of schedule %s may leave out upTo", ErrInvalidRates, date)
+			case !last && j > 0 && slab.UpTo.Cmp(*s.Slabs[j-1].UpTo) <= 0:
+				return fmt.Errorf("%w: slabs of schedule %s must be in increasing upTo order", ErrInvalidRates, date)
+			}
+		}
+	}
+	return nil
+}
+
+// At returns the schedule in force at t. Before the first schedule starts,
+// the first schedule applies.
+func (r *Rates) At(t time.Time) Schedule {
+	i := sort.Search(len(r.Schedules), func(i int) bool {
+		return r.Schedules[i].EffectiveFrom.After(t)
+	})
+	if i == 0 {
+		return r.Schedules[0]
+	}
+	return r.Schedules[i-1]
+}
@@ -0,0 +1,176 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/clerk/clerk-sdk-go/v2"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/auth"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/repository"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/service"
+	"gorm.io/gorm"
+)
+
+const (
+	providerClerk = "clerk"
+
+	// svixTolerance is how far a delivery's timestamp may be from now,
+	// which bounds replays of captured requests.
+	svixTolerance = 5 * time.Minute
+)
+
+// clerkEvent is the Clerk webhook envelope. Data is a Clerk user for the
+// user.* events we handle.
+type clerkEvent struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+type ClerkHandler struct {
+	DB              *gorm.DB
+	EventRepository *repository.WebhookEventRepository
+	UserService     *service.UserService
+}
+
+func NewClerkHandler(
+	db *gorm.DB,
+	eventRepo *repository.WebhookEventRepository,
+	userService *service.UserService,
+) *ClerkHandler {
+	return &ClerkHandler{
+		DB:              db,
+		EventRepository: eventRepo,
+		UserService:     userService,
+	}
+}
+
+// ServeHTTP handles POST /webhooks/clerk. Clerk delivers through Svix, which
+// retries any non-2xx response.
+func (h *ClerkHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodyBytes))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	msgID := r.Header.Get("svix-id")
+	if err := verifySvixSignature(
+		os.Getenv("CLERK_WEBHOOK_SECRET"),
+		msgID,
+		r.Header.Get("svix-timestamp"),
+		r.Header.Get("svix-signature"),
+		body,
+		time.Now(),
+	); err != nil {
+		log.Printf("CLERK WEBHOOK: %v", err)
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	var event clerkEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("CLERK WEBHOOK: event=%s id=%s", event.Type, msgID)
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		isNew, err := h.EventRepository.MarkProcessed(tx, providerClerk, msgID, event.Type)
+		if err != nil {
+			return err
+		}
+		if !isNew {
+			log.Printf("CLERK WEBHOOK: event %s already processed", msgID)
+			return nil
+		}
+		return h.process(tx, &event)
+	})
+	if err != nil {
+		log.Printf("CLERK WEBHOOK: failed to process event %s: %v", msgID, err)
+		http.Error(w, "failed to process event", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *ClerkHandler) process(tx *gorm.DB, event *clerkEvent) error {
+	switch event.Type {
+	case "user.created", "user.updated":
+		var u clerk.User
+		if err := json.Unmarshal(event.Data, &u); err != nil {
+			return err
+		}
+		if u.ID == "" {
+			return errors.New(event.Type + " without user id")
+		}
+		_, err := h.UserService.UpsertFromClerk(tx, auth.ProfileFromClerkUser(&u))
+		return err
+
+	case "user.deleted":
+		var deleted struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(event.Data, &deleted); err != nil {
+			return err
+		}
+		if deleted.ID == "" {
+			return errors.New("user.deleted without user id")
+		}
+		return h.UserService.DeleteFromClerk(tx, deleted.ID)
+
+	default:
+		log.Printf("CLERK WEBHOOK: ignoring unsupported event %s", event.Type)
+		return nil
+	}
+}
+
+// verifySvixSignature checks a Svix delivery. The signature header holds one
+// or more space-separated "v1,<base64>" entries, each an HMAC-SHA256 of
+// "<id>.<timestamp>.<body>" keyed with the base64 part of the whsec_ secret.
+func verifySvixSignature(secret, msgID, timestamp, signatures string, body []byte, now time.Time) error {
+	if secret == "" {
+		return errors.New("CLERK_WEBHOOK_SECRET is not set")
+	}
+	if msgID == "" || timestamp == "" || signatures == "" {
+		return errors.New("missing svix headers")
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("invalid svix timestamp")
+	}
+	sentAt := time.Unix(ts, 0)
+	if now.Sub(sentAt) > svixTolerance || sentAt.Sub(now) > svixTolerance {
+		return errors.New("svix timestamp outside tolerance")
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(secret, "whsec_"))
+	if err != nil {
+		return errors.New("invalid CLERK_WEBHOOK_SECRET")
+	}
+
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(msgID + "." + timestamp + "."))
+	h.Write(body)
+	expected := base64.StdEncoding.EncodeToString(h.Sum(nil))
+
+	for _, sig := range strings.Fields(signatures) {
+		version, value, ok := strings.Cut(sig, ",")
+		if ok && version == "v1" && hmac.Equal([]byte(value), []byte(expected)) {
+			return nil
+		}
+	}
+	return errors.New("no matching svix signature")
+}
@@ -0,0 +1,65 @@
+package webhooks
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/courier"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/service"
+	"gorm.io/gorm"
+)
+
+type CourierHandler struct {
+	DB              *gorm.DB
+	Courier         courier.Courier
+	ShipmentService *service.ShipmentService
+}
+
+func NewCourierHandler(db *gorm.DB, c courier.Courier, shipmentService *service.ShipmentService) *CourierHandler {
+	return &CourierHandler{
+		DB:              db,
+		Courier:         c,
+		ShipmentService: shipmentService,
+	}
+}
+
+// ServeHTTP handles POST /webhooks/courier, the tracking updates pushed by
+// the configured courier. Updates repeat events we may already have, which
+// ApplyTracking stores once, so redeliveries are harmless.
+func (h *CourierHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodyBytes))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	updates, err := h.Courier.ParseWebhook(r.Header, body)
+	if err != nil {
+		log.Printf("COURIER WEBHOOK: %v", err)
+		http.Error(w, "invalid webhook", http.StatusUnauthorized)
+		return
+	}
+
+	for i := range updates {
+		tracking := &updates[i]
+		log.Printf("COURIER WEBHOOK: %s shipment %s is %s", h.Courier.Name(), tracking.TrackingNumber, tracking.Status)
+
+		err := h.DB.Transaction(func(tx *gorm.DB) error {
+			return h.ShipmentService.ApplyTracking(tx, h.Courier.Name(), tracking)
+		})
+		if errors.Is(err, courier.ErrUnknownShipment) {
+			// Shipments booked outside this API, e.g. from the courier's panel
+			log.Printf("COURIER WEBHOOK: ignoring update: %v", err)
+			continue
+		}
+		if err != nil {
+			log.Printf("COURIER WEBHOOK: failed to apply tracking for %s: %v", tracking.TrackingNumber, err)
+			http.Error(w, "failed to process update", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,151 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/courier"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/repository"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/service"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/shipping"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/testdb"
+	"gorm.io/gorm"
+)
+
+const testCourierSecret = "courier-secret"
+
+type courierFixture struct {
+	db       *gorm.DB
+	fake     *courier.Fake
+	handler  *CourierHandler
+	shipment *models.Shipment
+}
+
+// newCourierFixture sets up the courier webhook with an order booked with
+// the fake courier.
+func newCourierFixture(t *testing.T) *courierFixture {
+	db := testdb.Open(t)
+	bus := service.NewEventBus(db, repository.NewOutboxRepository(db), 3, time.Hour)
+	paymentRepo := repository.NewPaymentRepository(db)
+	payments := service.NewPaymentService(paymentRepo, repository.NewRefundRepository(db), bus)
+	inventory := service.NewInventoryService(db, repository.NewInventoryRepository(db), time.Hour, bus, 0)
+	orders := service.NewOrderService(db, repository.NewOrderRepository(db), paymentRepo, inventory, payments, bus, false)
+	fake := courier.NewFake(testCourierSecret)
+	shipments := service.NewShipmentService(
+		db,
+		repository.NewShipmentRepository(db),
+		orders,
+		service.NewShippingService(shipping.Default()),
+		fake,
+		"6109",
+	)
+
+	order := &models.Order{
+		UserID:          "user_1",
+		Subtotal:        money.INR(49900),
+		TotalAmount:     money.INR(49900),
+		Status:          constants.OrderConfirmed,
+		ShippingAddress: "1 MG Road, Bengaluru 560001",
+		ShippingDetails: models.OrderAddress{Name: "Asha", Line1: "1 MG Road", City: "Bengaluru", PostalCode: "560001", Country: "IN"},
+	}
+	if err := db.Create(order).Error; err != nil {
+		t.Fatal(err)
+	}
+	shipment, err := shipments.CreateShipment(context.Background(), order.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &courierFixture{
+		db:       db,
+		fake:     fake,
+		handler:  NewCourierHandler(db, fake, shipments),
+		shipment: shipment,
+	}
+}
+
+func (f *courierFixture) post(body, signature string) int {
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/courier", strings.NewReader(body))
+	if signature != "" {
+		req.Header.Set("X-Courier-Signature", signature)
+	}
+	rec := httptest.NewRecorder()
+	f.handler.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func (f *courierFixture) status(t *testing.T) string {
+	t.Helper()
+	var shipment models.Shipment
+	if err := f.db.First(&shipment, f.shipment.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	return shipment.Status
+}
+
+func signCourier(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestCourierWebhookAuth(t *testing.T) {
+	f := newCourierFixture(t)
+	body := `{"trackingNumber":"` + f.shipment.TrackingNumber + `","status":"in_transit"}`
+
+	tests := []struct {
+		name      string
+		signature string
+	}{
+		{"unsigned", ""},
+		{"wrong secret", signCourier("not-the-secret", body)},
+		{"signature of another body", signCourier(testCourierSecret, body+" ")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := f.post(body, tt.signature); code != http.StatusUnauthorized {
+				t.Fatalf("status %d, want 401", code)
+			}
+			if status := f.status(t); status != constants.ShipmentBooked {
+				t.Fatalf("shipment moved to %s on a rejected webhook", status)
+			}
+		})
+	}
+
+	if code := f.post(body, signCourier(testCourierSecret, body)); code != http.StatusOK {
+		t.Fatalf("signed webhook: status %d, want 200", code)
+	}
+	if status := f.status(t); status != constants.ShipmentInTransit {
+		t.Fatalf("shipment is %s, want in_transit", status)
+	}
+}
+
+func TestCourierWebhookWithoutSecret(t *testing.T) {
+	f := newCourierFixture(t)
+	f.fake.Secret = ""
+	body := `{"trackingNumber":"` + f.shipment.TrackingNumber + `","status":"in_transit"}`
+
+	// An unconfigured secret must not make every signature valid
+	if code := f.post(body, signCourier("", body)); code != http.StatusUnauthorized {
+		t.Fatalf("status %d, want 401", code)
+	}
+}
+
+func TestCourierWebhookUnknownShipment(t *testing.T) {
+	f := newCourierFixture(t)
+	body := `{"trackingNumber":"BOOKED-ELSEWHERE","status":"in_transit"}`
+
+	// Acknowledged so the courier stops retrying
+	if code := f.post(body, signCourier(testCourierSecret, body)); code != http.StatusOK {
+		t.Fatalf("status %d, want 200", code)
+	}
+}
@@ -0,0 +1,332 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/events"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/repository"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/service"
+	"gorm.io/gorm"
+)
+
+const (
+	providerRazorpay = "razorpay"
+
+	// maxBodyBytes bounds webhook payloads; Razorpay events are a few KB.
+	maxBodyBytes = 1 << 20
+)
+
+// errUnknownOrder is returned for events about orders this API did not
+// create. Those are acknowledged rather than retried.
+var errUnknownOrder = errors.New("unknown order")
+
+// razorpayEvent is the subset of the Razorpay webhook envelope we use.
+type razorpayEvent struct {
+	Event   string `json:"event"`
+	Payload struct {
+		Payment *struct {
+			Entity razorpayPayment `json:"entity"`
+		} `json:"payment"`
+		Order *struct {
+			Entity razorpayOrder `json:"entity"`
+		} `json:"order"`
+		Refund *struct {
+			Entity razorpayRefund `json:"entity"`
+		} `json:"refund"`
+	} `json:"payload"`
+}
+
+type razorpayPayment struct {
+	ID             string `json:"id"`
+	OrderID        string `json:"order_id"`
+	Amount         int64  `json:"amount"`
+	AmountRefunded int64  `json:"amount_refunded"`
+	Method         string `json:"method"`
+	Status         string `json:"status"`
+}
+
+type razorpayOrder struct {
+	ID      string `json:"id"`
+	Receipt string `json:"receipt"`
+}
+
+type razorpayRefund struct {
+	ID        string `json:"id"`
+	PaymentID string `json:"payment_id"`
+	Amount    int64  `json:"amount"`
+	Notes     struct {
+		RefundID string `json:"refund_id"`
+	} `json:"notes"`
+}
+
+type RazorpayHandler struct {
+	DB                *gorm.DB
+	OrderRepository   *repository.OrderRepository
+	PaymentRepository *repository.PaymentRepository
+	RefundRepository  *repository.RefundRepository
+	EventRepository   *repository.WebhookEventRepository
+	PaymentService    *service.PaymentService
+	OrderService      *service.OrderService
+}
+
+func NewRazorpayHandler(
+	db *gorm.DB,
+	orderRepo *repository.OrderRepository,
+	paymentRepo *repository.PaymentRepository,
+	refundRepo *repository.RefundRepository,
+	eventRepo *repository.WebhookEventRepository,
+	paymentService *service.PaymentService,
+	orderService *service.OrderService,
+) *RazorpayHandler {
+	return &RazorpayHandler{
+		DB:                db,
+		OrderRepository:   orderRepo,
+		PaymentRepository: paymentRepo,
+		RefundRepository:  refundRepo,
+		EventRepository:   eventRepo,
+		PaymentService:    paymentService,
+		OrderService:      orderService,
+	}
+}
+
+// ServeHTTP handles POST /webhooks/razorpay. Any non-2xx response makes
+// Razorpay retry the delivery, so errors are only returned for failures that
+// a retry could fix.
+func (h *RazorpayHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodyBytes))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	if !h.PaymentService.VerifyWebhookSignature(body, r.Header.Get("X-Razorpay-Signature")) {
+		log.Printf("RAZORPAY WEBHOOK: invalid signature")
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	var event razorpayEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	eventID := r.Header.Get("X-Razorpay-Event-Id")
+	if eventID == "" {
+		http.Error(w, "missing event id", http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("RAZORPAY WEBHOOK: event=%s id=%s", event.Event, eventID)
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		isNew, err := h.EventRepository.MarkProcessed(tx, providerRazorpay, eventID, event.Event)
+		if err != nil {
+			return err
+		}
+		if !isNew {
+			log.Printf("RAZORPAY WEBHOOK: event %s already processed", eventID)
+			return nil
+		}
+		err = h.process(tx, &event)
+		if errors.Is(err, errUnknownOrder) {
+			log.Printf("RAZORPAY WEBHOOK: ignoring event %s: %v", eventID, err)
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		log.Printf("RAZORPAY WEBHOOK: failed to process event %s: %v", eventID, err)
+		http.Error(w, "failed to process event", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *RazorpayHandler) process(tx *gorm.DB, event *razorpayEvent) error {
+	switch event.Event {
+	case "payment.captured":
+		if event.Payload.Payment == nil {
+			return errors.New("payment.captured without payment entity")
+		}
+		p := event.Payload.Payment.Entity
+		order, err := h.findOrder(tx, p.OrderID, "")
+		if err != nil {
+			return err
+		}
+		_, err = h.OrderService.ConfirmPayment(tx, order, p.ID, p.Method)
+		return err
+
+	case "order.paid":
+		if event.Payload.Order == nil || event.Payload.Payment == nil {
+			return errors.New("order.paid without order or payment entity")
+		}
+		o := event.Payload.Order.Entity
+		p := event.Payload.Payment.Entity
+		order, err := h.findOrder(tx, o.ID, o.Receipt)
+		if err != nil {
+			return err
+		}
+		_, err = h.OrderService.ConfirmPayment(tx, order, p.ID, p.Method)
+		return err
+
+	case "payment.failed":
+		if event.Payload.Payment == nil {
+			return errors.New("payment.failed without payment entity")
+		}
+		p := event.Payload.Payment.Entity
+		order, err := h.findOrder(tx, p.OrderID, "")
+		if err != nil {
+			return err
+		}
+		return h.OrderService.FailPayment(tx, order, p.ID, p.Method)
+
+	case "refund.processed":
+		if event.Payload.Refund == nil {
+			return errors.New("refund.processed without refund entity")
+		}
+		return h.applyRefund(tx, event)
+
+	default:
+		log.Printf("RAZORPAY WEBHOOK: ignoring unsupported event %s", event.Event)
+		return nil
+	}
+}
+
+// findOrder resolves our order from the Razorpay order ID, falling back to the
+// "order_<id>" receipt we set when creating the Razorpay order.
+func (h *RazorpayHandler) findOrder(tx *gorm.DB, razorpayOrderID, receipt string) (*models.Order, error) {
+	orders := h.OrderRepository.WithTx(tx)
+
+	if razorpayOrderID != "" {
+		order, err := orders.GetOrderByRazorpayOrderID(razorpayOrderID)
+		if err == nil {
+			return order, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	if id, ok := strings.CutPrefix(receipt, "order_"); ok {
+		if orderID, err := strconv.ParseUint(id, 10, 32); err == nil {
+			order, err := orders.GetOrderByID(uint(orderID))
+			if err == nil {
+				return order, nil
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, err
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("%w: razorpay order %q", errUnknownOrder, razorpayOrderID)
+}
+
+func (h *RazorpayHandler) applyRefund(tx *gorm.DB, event *razorpayEvent) error {
+	refund := event.Payload.Refund.Entity
+
+	payment, err := h.PaymentRepository.WithTx(tx).GetPaymentByTransactionID(refund.PaymentID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%w: razorpay payment %q", errUnknownOrder, refund.PaymentID)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Refunds issued through refundPayment are counted in the payment's
+	// AmountRefunded when they are recorded, and carry our refund ID in their
+	// notes in case this event beats SubmitRefund to recording the Razorpay
+	// ID. Ones made from the Razorpay dashboard are recorded and counted here.
+	refunds := h.RefundRepository.WithTx(tx)
+	refunded := payment.AmountRefunded
+	record, err := refunds.GetRefundByRazorpayID(refund.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) && refund.Notes.RefundID != "" {
+		record, err = h.findNotedRefund(tx, payment, refund)
+	}
+	switch {
+	case err == nil:
+		if record.Status == constants.RefundFailed {
+			// SubmitRefund gave the amount back when it thought Razorpay had
+			// turned the refund down
+			refunded = refunded.Add(record.Amount)
+		}
+		issued := record.RazorpayRefundID == nil
+		refundID := refund.ID
+		record.RazorpayRefundID = &refundID
+		record.Status = constants.RefundProcessed
+		if err := refunds.UpdateRefund(record); err != nil {
+			return err
+		}
+		if issued {
+			if err := h.PaymentService.Events.Publish(tx, events.RefundIssued, events.NewRefund(record)); err != nil {
+				return err
+			}
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		refundID := refund.ID
+		if err := refunds.CreateRefund(&models.Refund{
+			PaymentID:        payment.ID,
+			OrderID:          payment.OrderID,
+			Amount:           money.INR(refund.Amount),
+			Reason:           "refunded outside the API",
+			Status:           constants.RefundProcessed,
+			RazorpayRefundID: &refundID,
+		}); err != nil {
+			return err
+		}
+		refunded = refunded.Add(money.INR(refund.Amount))
+	default:
+		return err
+	}
+
+	// The payment entity's running total catches refunds whose events we
+	// missed, but it does not know about refunds still waiting for
+	// SubmitRefund, so it only ever raises ours.
+	captured := payment.Amount
+	if event.Payload.Payment != nil {
+		if total := money.INR(event.Payload.Payment.Entity.AmountRefunded); total.Cmp(refunded) > 0 {
+			refunded = total
+		}
+		captured = money.INR(event.Payload.Payment.Entity.Amount)
+	}
+
+	status := constants.PaymentPartiallyRefunded
+	if refunded.Cmp(captured) >= 0 {
+		status = constants.PaymentRefunded
+	}
+
+	return tx.Model(payment).Updates(map[string]interface{}{
+		"amount_refunded": refunded,
+		"status":          status,
+	}).Error
+}
+
+// findNotedRefund returns the refund of payment that a Razorpay refund's
+// refund_id note names, if SubmitRefund has not yet recorded it under
+// another Razorpay ID.
+func (h *RazorpayHandler) findNotedRefund(tx *gorm.DB, payment *models.Payment, refund razorpayRefund) (*models.Refund, error) {
+	id, err := strconv.ParseUint(refund.Notes.RefundID, 10, 32)
+	if err != nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	record, err := h.RefundRepository.WithTx(tx).LockRefund(uint(id))
+	if err != nil {
+		return nil, err
+	}
+	if record.PaymentID != payment.ID || record.RazorpayRefundID != nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return record, nil
+}
@@ -0,0 +1,298 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/constants"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/events"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/models"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/money"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/repository"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/service"
+	"github.com/vishnujoshi062/tshirt-ecommerce-api/internal/testdb"
+	"gorm.io/gorm"
+)
+
+const testWebhookSecret = "whsec_test"
+
+type razorpayFixture struct {
+	db      *gorm.DB
+	handler *RazorpayHandler
+	order   *models.Order
+	payment *models.Payment
+}
+
+// newRazorpayFixture sets up the webhook handler and an order with a
+// captured payment of 1000.00. Cancelled orders are refunded automatically.
+func newRazorpayFixture(t *testing.T) *razorpayFixture {
+	db := testdb.Open(t)
+	t.Setenv("RAZORPAY_WEBHOOK_SECRET", testWebhookSecret)
+
+	bus := service.NewEventBus(db, repository.NewOutboxRepository(db), 3, time.Hour)
+	paymentRepo := repository.NewPaymentRepository(db)
+	refundRepo := repository.NewRefundRepository(db)
+	paymentService := service.NewPaymentService(paymentRepo, refundRepo, bus)
+	orderRepo := repository.NewOrderRepository(db)
+	inventory := service.NewInventoryService(db, repository.NewInventoryRepository(db), time.Hour, bus, 0)
+	handler := NewRazorpayHandler(
+		db,
+		orderRepo,
+		paymentRepo,
+		refundRepo,
+		repository.NewWebhookEventRepository(db),
+		paymentService,
+		service.NewOrderService(db, orderRepo, paymentRepo, inventory, paymentService, bus, true),
+	)
+
+	razorpayOrderID := "order_rzp_test"
+	order := &models.Order{
+		RazorpayOrderID: &razorpayOrderID,
+		UserID:          "user_1",
+		Subtotal:        money.INR(100000),
+		TotalAmount:     money.INR(100000),
+		Status:          constants.OrderConfirmed,
+		ShippingAddress: "1 Test Street",
+	}
+	if err := db.Create(order).Error; err != nil {
+		t.Fatal(err)
+	}
+	payment := &models.Payment{
+		OrderID:       order.ID,
+		Amount:        money.INR(100000),
+		Status:        constants.PaymentCompleted,
+		PaymentMethod: "razorpay",
+		TransactionID: "pay_test",
+	}
+	if err := db.Create(payment).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &razorpayFixture{db: db, handler: handler, order: order, payment: payment}
+}
+
+// deliver posts a signed webhook and fails the test unless it is accepted.
+func (f *razorpayFixture) deliver(t *testing.T, eventID string, event map[string]interface{}) {
+	t.Helper()
+	body, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mac := hmac.New(sha256.New, []byte(testWebhookSecret))
+	mac.Write(body)
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/razorpay", strings.NewReader(string(body)))
+	req.Header.Set("X-Razorpay-Signature", hex.EncodeToString(mac.Sum(nil)))
+	req.Header.Set("X-Razorpay-Event-Id", eventID)
+	rec := httptest.NewRecorder()
+	f.handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("webhook %s: %d %s", eventID, rec.Code, rec.Body.String())
+	}
+}
+
+func (f *razorpayFixture) reloadPayment(t *testing.T) *models.Payment {
+	t.Helper()
+	payment, err := f.handler.PaymentRepository.GetPaymentByID(f.payment.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return payment
+}
+
+func refundProcessed(refund map[string]interface{}, payment map[string]interface{}) map[string]interface{} {
+	payload := map[string]interface{}{
+		"refund": map[string]interface{}{"entity": refund},
+	}
+	if payment != nil {
+		payload["payment"] = map[string]interface{}{"entity": payment}
+	}
+	return map[string]interface{}{"event": "refund.processed", "payload": payload}
+}
+
+func TestRefundWebhookWithoutPaymentEntity(t *testing.T) {
+	f := newRazorpayFixture(t)
+	event := refundProcessed(map[string]interface{}{
+		"id":         "rfnd_dash",
+		"payment_id": "pay_test",
+		"amount":     20000,
+	}, nil)
+
+	f.deliver(t, "evt_1", event)
+	// Redelivered, and then sent again under a new event ID
+	f.deliver(t, "evt_1", event)
+	f.deliver(t, "evt_2", event)
+
+	payment := f.reloadPayment(t)
+	if payment.AmountRefunded != money.INR(20000) || payment.Status != constants.PaymentPartiallyRefunded {
+		t.Fatalf("payment = %s refunded, %s; want 200.00 partially_refunded", payment.AmountRefunded, payment.Status)
+	}
+	refund, err := f.handler.RefundRepository.GetRefundByRazorpayID("rfnd_dash")
+	if err != nil {
+		t.Fatalf("dashboard refund not recorded: %v", err)
+	}
+	if refund.Amount != money.INR(20000) || refund.Status != constants.RefundProcessed {
+		t.Fatalf("refund = %s %s", refund.Amount, refund.Status)
+	}
+}
+
+func TestRefundWebhookWithPaymentEntity(t *testing.T) {
+	f := newRazorpayFixture(t)
+
+	// The payment entity also counts an earlier refund whose event we missed
+	f.deliver(t, "evt_1", refundProcessed(map[string]interface{}{
+		"id":         "rfnd_dash",
+		"payment_id": "pay_test",
+		"amount":     20000,
+	}, map[string]interface{}{
+		"id":              "pay_test",
+		"amount":          100000,
+		"amount_refunded": 50000,
+	}))
+
+	payment := f.reloadPayment(t)
+	if payment.AmountRefunded != money.INR(50000) || payment.Status != constants.PaymentPartiallyRefunded {
+		t.Fatalf("payment = %s refunded, %s; want 500.00 partially_refunded", payment.AmountRefunded, payment.Status)
+	}
+
+	f.deliver(t, "evt_2", refundProcessed(map[string]interface{}{
+		"id":         "rfnd_rest",
+		"payment_id": "pay_test",
+		"amount":     50000,
+	}, map[string]interface{}{
+		"id":              "pay_test",
+		"amount":          100000,
+		"amount_refunded": 100000,
+	}))
+
+	payment = f.reloadPayment(t)
+	if payment.AmountRefunded != money.INR(100000) || payment.Status != constants.PaymentRefunded {
+		t.Fatalf("payment = %s refunded, %s; want 1000.00 refunded", payment.AmountRefunded, payment.Status)
+	}
+}
+
+func TestRefundWebhookSettlesQueuedRefund(t *testing.T) {
+	f := newRazorpayFixture(t)
+
+	var refund *models.Refund
+	amount := money.INR(30000)
+	err := f.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		refund, err = f.handler.PaymentService.RefundPayment(tx, f.payment.OrderID, &amount, "test")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Razorpay's webhook arrives before SubmitRefund records the refund,
+	// with a payment entity that does not know about a second queued one
+	second := money.INR(10000)
+	err = f.db.Transaction(func(tx *gorm.DB) error {
+		_, err := f.handler.PaymentService.RefundPayment(tx, f.payment.OrderID, &second, "test")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.deliver(t, "evt_1", refundProcessed(map[string]interface{}{
+		"id":         "rfnd_api",
+		"payment_id": "pay_test",
+		"amount":     30000,
+		"notes":      map[string]interface{}{"refund_id": fmt.Sprint(refund.ID)},
+	}, map[string]interface{}{
+		"id":              "pay_test",
+		"amount":          100000,
+		"amount_refunded": 30000,
+	}))
+
+	refund, err = f.handler.RefundRepository.GetRefundByID(refund.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refund.Status != constants.RefundProcessed || refund.RazorpayRefundID == nil || *refund.RazorpayRefundID != "rfnd_api" {
+		t.Fatalf("refund = %s %v, want processed rfnd_api", refund.Status, refund.RazorpayRefundID)
+	}
+	if payment := f.reloadPayment(t); payment.AmountRefunded != money.INR(40000) {
+		t.Fatalf("payment refunded %s, want both queued refunds counted once (400.00)", payment.AmountRefunded)
+	}
+
+	if issued := f.eventCount(t, events.RefundIssued); issued != 1 {
+		t.Fatalf("%d refund.issued events, want 1", issued)
+	}
+}
+
+func (f *razorpayFixture) eventCount(t *testing.T, eventType string) int64 {
+	t.Helper()
+	var n int64
+	if err := f.db.Model(&models.OutboxEvent{}).Where("type = ?", eventType).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestPaymentWebhooksAfterRefund(t *testing.T) {
+	for _, orderStatus := range []string{constants.OrderConfirmed, constants.OrderCancelled} {
+		t.Run(orderStatus, func(t *testing.T) {
+			f := newRazorpayFixture(t)
+			if err := f.db.Model(f.order).Update("status", orderStatus).Error; err != nil {
+				t.Fatal(err)
+			}
+			f.deliver(t, "evt_refund", refundProcessed(map[string]interface{}{
+				"id":         "rfnd_dash",
+				"payment_id": "pay_test",
+				"amount":     100000,
+			}, map[string]interface{}{
+				"id":              "pay_test",
+				"amount":          100000,
+				"amount_refunded": 100000,
+			}))
+			captured := f.eventCount(t, events.PaymentCaptured)
+
+			// Razorpay's capture events for the payment arrive late, and a
+			// failed earlier attempt is reported after them
+			payment := map[string]interface{}{
+				"entity": map[string]interface{}{"id": "pay_test", "order_id": "order_rzp_test", "method": "upi"},
+			}
+			f.deliver(t, "evt_captured", map[string]interface{}{
+				"event":   "payment.captured",
+				"payload": map[string]interface{}{"payment": payment},
+			})
+			f.deliver(t, "evt_paid", map[string]interface{}{
+				"event": "order.paid",
+				"payload": map[string]interface{}{
+					"order":   map[string]interface{}{"entity": map[string]interface{}{"id": "order_rzp_test"}},
+					"payment": payment,
+				},
+			})
+			f.deliver(t, "evt_failed", map[string]interface{}{
+				"event":   "payment.failed",
+				"payload": map[string]interface{}{"payment": payment},
+			})
+
+			if p := f.reloadPayment(t); p.Status != constants.PaymentRefunded || p.AmountRefunded != money.INR(100000) {
+				t.Fatalf("payment = %s refunded, %s; want it left refunded", p.AmountRefunded, p.Status)
+			}
+			if n := f.eventCount(t, events.PaymentCaptured); n != captured {
+				t.Fatalf("%d payment.captured events, want %d", n, captured)
+			}
+			if n := f.eventCount(t, events.PaymentFailed); n != 0 {
+				t.Fatalf("%d payment.failed events, want 0", n)
+			}
+			var refunds int64
+			if err := f.db.Model(&models.Refund{}).Count(&refunds).Error; err != nil {
+				t.Fatal(err)
+			}
+			if refunds != 1 {
+				t.Fatalf("%d refunds, want only the one already made", refunds)
+			}
+		})
+	}
+}